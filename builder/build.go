@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofrs/flock"
 	"github.com/tinygo-org/tinygo/compileopts"
@@ -61,6 +62,10 @@ type BuildResult struct {
 	// correctly printing test results: the import path isn't always the same as
 	// the path listed on the command line.
 	ImportPath string
+
+	// The C header of a library (-buildmode=c-archive or c-shared), stored in
+	// the tmpdir directory like Binary.
+	Header string
 }
 
 // packageAction is the struct that is serialized to JSON and hashed, to work as
@@ -95,6 +100,37 @@ type packageAction struct {
 // The error value may be of type *MultiError. Callers will likely want to check
 // for this case and print such errors individually.
 func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildResult, error) {
+	// Make sure the cache isn't trimmed by a different TinyGo invocation
+	// while we're using it.
+	unlockCache := lockCacheShared()
+	var trace *buildTrace
+	if config.Options.Trace != "" {
+		// Record a trace of the build (-trace flag).
+		trace = newBuildTrace()
+	}
+	result, err := build(pkgName, outpath, tmpdir, config, trace)
+	unlockCache()
+
+	if trace != nil {
+		// The trace is also written when the build fails, as it can be
+		// useful to see what went wrong.
+		if traceErr := trace.writeFile(config.Options.Trace); traceErr != nil && err == nil {
+			return result, traceErr
+		}
+	}
+	if config.Options.CacheMaxSize != 0 && err == nil {
+		// Keep the cache below the configured maximum size (TINYGOCACHEMAX).
+		err = autoTrimCache(goenv.Get("GOCACHE"), config.Options.CacheMaxSize)
+		if err != nil {
+			return result, fmt.Errorf("could not trim cache: %w", err)
+		}
+	}
+	return result, err
+}
+
+// build is the implementation of Build. If trace is not nil, all jobs are
+// recorded in it.
+func build(pkgName, outpath, tmpdir string, config *compileopts.Config, trace *buildTrace) (BuildResult, error) {
 	// Read the build ID of the tinygo binary.
 	// Used as a cache key for package builds.
 	compilerBuildID, err := ReadBuildID()
@@ -130,6 +166,11 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 			return BuildResult{}, err
 		}
 		defer unlock()
+		if config.BuildMode() != "default" {
+			// Libraries use the libc of the host program. Musl is only
+			// used for its headers.
+			break
+		}
 		libcDependencies = append(libcDependencies, dummyCompileJob(filepath.Join(filepath.Dir(job.result), "crt1.o")))
 		libcDependencies = append(libcDependencies, job)
 	case "picolibc":
@@ -140,11 +181,24 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		defer unlock()
 		libcDependencies = append(libcDependencies, libcJob)
 	case "wasi-libc":
-		path := filepath.Join(root, "lib/wasi-libc/sysroot/lib/wasm32-wasi/libc.a")
+		path := filepath.Join(root, config.WasiLibcSysroot(), "lib/wasm32-wasi/libc.a")
+		if config.WasmThreads() {
+			path = filepath.Join(root, config.WasiLibcSysroot(), "lib/wasm32-wasi-threads/libc.a")
+		}
 		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			if config.WasmThreads() {
+				return BuildResult{}, errors.New("could not find wasi-libc with threads, perhaps you need to run `make wasi-libc-threads`?")
+			}
 			return BuildResult{}, errors.New("could not find wasi-libc, perhaps you need to run `make wasi-libc`?")
 		}
 		libcDependencies = append(libcDependencies, dummyCompileJob(path))
+	case "wasmbuiltins":
+		libcJob, unlock, err := WasmBuiltins.load(config, tmpdir)
+		if err != nil {
+			return BuildResult{}, err
+		}
+		defer unlock()
+		libcDependencies = append(libcDependencies, libcJob)
 	case "mingw-w64":
 		_, unlock, err := MinGW.load(config, tmpdir)
 		if err != nil {
@@ -172,10 +226,12 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		TinyGoVersion:   goenv.Version,
 
 		Scheduler:          config.Scheduler(),
+		PanicStrategy:      config.PanicStrategy(),
 		AutomaticStackSize: config.AutomaticStackSize(),
 		DefaultStackSize:   config.StackSize(),
 		NeedsStackObjects:  config.NeedsStackObjects(),
 		Debug:              !config.Options.SkipDWARF, // emit DWARF except when -internal-nodwarf is passed
+		Safepoints:         config.WasmThreads(),
 	}
 
 	// Load the target machine, which is the LLVM object that contains all
@@ -243,6 +299,27 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	for _, pkg := range lprogram.Sorted() {
 		pkg := pkg // necessary to avoid a race condition
 
+		// Instrument the package for code coverage (-cover), fuzzing (-fuzz)
+		// or AddressSanitizer (-sanitize=address), or wrap its exported
+		// functions (-buildmode=c-archive, c-shared). This is done with a
+		// separate compiler config, so that other packages can still be
+		// loaded from the cache.
+		pkgCompilerConfig := compilerConfig
+		covered := isCoveredPackage(config, lprogram.MainPkg().ImportPath, pkg.ImportPath)
+		fuzzed := isFuzzedPackage(config, pkg.ImportPath)
+		sanitized := isSanitizedPackage(config, pkg.ImportPath)
+		libraryExports := config.BuildMode() != "default" && !pkg.Standard
+		if covered || fuzzed || sanitized || libraryExports {
+			instrumentConfig := *compilerConfig
+			if covered {
+				instrumentConfig.CoverMode = config.TestConfig.CoverMode
+			}
+			instrumentConfig.Fuzz = fuzzed
+			instrumentConfig.SanitizeAddress = sanitized
+			instrumentConfig.LibraryExports = libraryExports
+			pkgCompilerConfig = &instrumentConfig
+		}
+
 		var undefinedGlobals []string
 		for name := range config.Options.GlobalValues[pkg.Pkg.Path()] {
 			undefinedGlobals = append(undefinedGlobals, name)
@@ -315,7 +392,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 					ImportPath:       pkg.ImportPath,
 					CompilerBuildID:  string(compilerBuildID),
 					LLVMVersion:      llvm.Version,
-					Config:           compilerConfig,
+					Config:           pkgCompilerConfig,
 					CFlags:           pkg.CFlags,
 					FileHashes:       make(map[string]string, len(pkg.FileHashes)),
 					EmbeddedFiles:    make(map[string]string, len(allFiles)),
@@ -361,12 +438,15 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 
 				if _, err := os.Stat(job.result); err == nil {
 					// Already cached, don't recreate this package.
+					job.cacheResult = "hit"
+					goenv.MarkCacheUsed(job.result)
 					return nil
 				}
+				job.cacheResult = "miss"
 
 				// Compile AST to IR. The compiler.CompilePackage function will
 				// build the SSA as needed.
-				mod, errs := compiler.CompilePackage(pkg.ImportPath, pkg, program.Package(pkg.Pkg), machine, compilerConfig, config.DumpSSA())
+				mod, errs := compiler.CompilePackage(pkg.ImportPath, pkg, program.Package(pkg.Pkg), machine, pkgCompilerConfig, config.DumpSSA())
 				defer mod.Context().Dispose()
 				defer mod.Dispose()
 				if errs != nil {
@@ -397,6 +477,11 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 
 					// Compile the code (if there is any) to bitcode.
 					flags := append([]string{"-c", "-emit-llvm", "-o", f.Name() + ".bc", f.Name()}, pkg.CFlags...)
+					if config.Sanitize() == "address" {
+						// Only mark the functions with sanitize_address:
+						// they're instrumented together with the Go code.
+						flags = append(flags, "-Xclang", "-disable-llvm-passes")
+					}
 					if config.Options.PrintCommands != nil {
 						config.Options.PrintCommands("clang", flags...)
 					}
@@ -446,10 +531,12 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				if pkgInit.IsNil() {
 					panic("init not found for " + pkg.Pkg.Path())
 				}
+				interpStart := time.Now()
 				err := interp.RunFunc(pkgInit, config.Options.InterpTimeout, config.DumpSSA())
 				if err != nil {
 					return err
 				}
+				job.traceSpan("interp "+pkg.ImportPath, interpStart)
 				if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
 					return errors.New("verification error after interpreting " + pkgInit.Name())
 				}
@@ -505,7 +592,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	programJob := &compileJob{
 		description:  "link+optimize packages (LTO)",
 		dependencies: packageJobs,
-		run: func(*compileJob) error {
+		run: func(job *compileJob) error {
 			// Load and link all the bitcode files. This does not yet optimize
 			// anything, it only links the bitcode files together.
 			ctx := llvm.NewContext()
@@ -542,6 +629,14 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 			}
 			irbuilder.CreateRetVoid()
 
+			if config.BuildMode() != "default" && config.Target.Linker != "wasm-ld" {
+				// Libraries have no main function. Instead, the runtime is
+				// initialized from a constructor when the library is loaded.
+				// WebAssembly reactor modules are initialized by the host
+				// instead, which calls the exported _initialize function.
+				addGlobalConstructor(mod, mod.NamedFunction("tinygo_libraryInit"))
+			}
+
 			// After linking, functions should (as far as possible) be set to
 			// private linkage or internal linkage. The compiler package marks
 			// non-exported functions by setting the visibility to hidden or
@@ -573,7 +668,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 
 			// Run all optimization passes, which are much more effective now
 			// that the optimizer can see the whole program at once.
-			err := optimizeProgram(mod, config)
+			err := optimizeProgram(mod, config, job)
 			if err != nil {
 				return err
 			}
@@ -592,7 +687,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	outext := filepath.Ext(outpath)
 	if outext == ".o" || outext == ".bc" || outext == ".ll" {
 		// Run jobs to produce the LLVM module.
-		err := runJobs(programJob, config.Options.Semaphore)
+		err := runJobs(programJob, config.Options.Semaphore, trace)
 		if err != nil {
 			return result, err
 		}
@@ -641,11 +736,40 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		result.Executable += ".exe"
 	}
 	result.Binary = result.Executable // final file
-	ldflags := append(config.LDFlags(), "-o", result.Executable)
+	var ldflags []string
+	var libraryExports []libraryExport
+	switch config.BuildMode() {
+	case "c-archive":
+		// All object files are linked (with LTO) into a single relocatable
+		// object file, which is then stored in an archive. The host program
+		// provides the libc and the compiler runtime.
+		result.Executable = filepath.Join(tmpdir, "go.o")
+		result.Binary = filepath.Join(tmpdir, "main.a")
+		ldflags = []string{"-r", "-o", result.Executable}
+		libraryExports = findLibraryExports(lprogram)
+	case "c-shared":
+		if config.Target.Linker == "wasm-ld" {
+			// A WASI reactor module: a module without _start, where the
+			// exported functions can be called after _initialize.
+			ldflags = append(config.LDFlags(), "--no-entry", "-o", result.Executable)
+			break
+		}
+		result.Executable = filepath.Join(tmpdir, "main.so")
+		result.Binary = result.Executable
+		libraryExports = findLibraryExports(lprogram)
+		versionScript := filepath.Join(tmpdir, "exports.map")
+		err := writeVersionScript(versionScript, libraryExports)
+		if err != nil {
+			return result, err
+		}
+		ldflags = append(config.LDFlags(), "-shared", "--version-script="+versionScript, "-o", result.Executable)
+	default:
+		ldflags = append(config.LDFlags(), "-o", result.Executable)
+	}
 
 	// Add compiler-rt dependency if needed. Usually this is a simple load from
 	// a cache.
-	if config.Target.RTLib == "compiler-rt" {
+	if config.Target.RTLib == "compiler-rt" && config.BuildMode() != "c-archive" {
 		job, unlock, err := CompilerRT.load(config, tmpdir)
 		if err != nil {
 			return result, err
@@ -654,6 +778,32 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		linkerDependencies = append(linkerDependencies, job)
 	}
 
+	// Add the sanitizer runtimes (-sanitize=...).
+	if config.Sanitize() != "" {
+		job, unlock, err := Sanitizers.load(config, tmpdir)
+		if err != nil {
+			return result, err
+		}
+		defer unlock()
+		linkerDependencies = append(linkerDependencies, job)
+	}
+
+	// Add the profile runtime (-pgo=instrument).
+	if config.PGO() == "instrument" {
+		job, unlock, err := Profile.load(config, tmpdir)
+		if err != nil {
+			return result, err
+		}
+		defer unlock()
+		linkerDependencies = append(linkerDependencies, job)
+		if config.Target.Linker == "ld.lld" {
+			// The profile runtime finds the profile sections through their
+			// __start_ and __stop_ symbols. Make sure the linker doesn't remove
+			// the sections that are only referenced this way (the names).
+			ldflags = append(ldflags, "-z", "nostart-stop-gc")
+		}
+	}
+
 	// Add jobs to compile extra files. These files are in C or assembly and
 	// contain things like the interrupt vector table and low level operations
 	// such as stack switching.
@@ -662,8 +812,12 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		job := &compileJob{
 			description: "compile extra file " + path,
 			run: func(job *compileJob) error {
-				result, err := compileAndCacheCFile(abspath, tmpdir, config.CFlags(), config.Options.PrintCommands)
+				result, cached, err := compileAndCacheCFile(abspath, tmpdir, config.CFlags(), config.Options.PrintCommands)
 				job.result = result
+				job.cacheResult = "miss"
+				if cached {
+					job.cacheResult = "hit"
+				}
 				return err
 			},
 		}
@@ -680,8 +834,12 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 			job := &compileJob{
 				description: "compile CGo file " + abspath,
 				run: func(job *compileJob) error {
-					result, err := compileAndCacheCFile(abspath, tmpdir, pkg.CFlags, config.Options.PrintCommands)
+					result, cached, err := compileAndCacheCFile(abspath, tmpdir, pkg.CFlags, config.Options.PrintCommands)
 					job.result = result
+					job.cacheResult = "miss"
+					if cached {
+						job.cacheResult = "hit"
+					}
 					return err
 				},
 			}
@@ -776,6 +934,21 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				return &commandError{"failed to link", result.Executable, err}
 			}
 
+			if config.BuildMode() == "c-archive" {
+				arfile, err := os.Create(result.Binary)
+				if err != nil {
+					return err
+				}
+				err = makeArchive(arfile, []string{result.Executable})
+				if err != nil {
+					arfile.Close()
+					return err
+				}
+				if err := arfile.Close(); err != nil {
+					return err
+				}
+			}
+
 			var calculatedStacks []string
 			var stackSizes map[string]functionStackSize
 			if config.Options.PrintStacks || config.AutomaticStackSize() {
@@ -846,8 +1019,20 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				}
 			}
 
-			// Print code size if requested.
-			if config.Options.PrintSizes == "short" || config.Options.PrintSizes == "full" {
+			// Turn the module into a component for targets that use the
+			// component model (wasip2).
+			if config.Target.WITPackage != "" {
+				err := makeWasmComponent(config, result.Executable)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Print code size if requested, and check it against the size
+			// budget of the target.
+			printSizes := config.Options.PrintSizes != "" && config.Options.PrintSizes != "none"
+			flashLimit, ramLimit := config.SizeLimits()
+			if printSizes || config.Options.SizeReport != "" || flashLimit != 0 || ramLimit != 0 {
 				packagePathMap := make(map[string]string, len(lprogram.Packages))
 				for _, pkg := range lprogram.Sorted() {
 					packagePathMap[pkg.OriginalDir()] = pkg.Pkg.Path()
@@ -856,10 +1041,19 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				if err != nil {
 					return err
 				}
-				if config.Options.PrintSizes == "short" {
+				switch config.Options.PrintSizes {
+				case "json":
+					// Print the sizes per package and per symbol, for use by
+					// other tools.
+					data, err := json.MarshalIndent(sizes, "", "\t")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(data))
+				case "short":
 					fmt.Printf("   code    data     bss |   flash     ram\n")
 					fmt.Printf("%7d %7d %7d | %7d %7d\n", sizes.Code+sizes.ROData, sizes.Data, sizes.BSS, sizes.Flash(), sizes.RAM())
-				} else {
+				case "full":
 					if !config.Debug() {
 						fmt.Println("warning: data incomplete, remove the -no-debug flag for more detail")
 					}
@@ -868,10 +1062,25 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 					for _, name := range sizes.sortedPackageNames() {
 						pkgSize := sizes.Packages[name]
 						fmt.Printf("%7d %7d %7d %7d | %7d %7d | %s\n", pkgSize.Code, pkgSize.ROData, pkgSize.Data, pkgSize.BSS, pkgSize.Flash(), pkgSize.RAM(), name)
+						// Break the package down into the symbols it contains.
+						for _, symbolName := range pkgSize.sortedSymbolNames() {
+							ss := pkgSize.Symbols[symbolName]
+							fmt.Printf("%7d %7d %7d %7d | %7d %7d |   %s\n", ss.Code, ss.ROData, ss.Data, ss.BSS, ss.Flash(), ss.RAM(), symbolName)
+						}
 					}
 					fmt.Printf("------------------------------- | --------------- | -------\n")
 					fmt.Printf("%7d %7d %7d %7d | %7d %7d | total\n", sizes.Code, sizes.ROData, sizes.Data, sizes.BSS, sizes.Code+sizes.ROData+sizes.Data, sizes.Data+sizes.BSS)
 				}
+				if config.Options.SizeReport != "" {
+					err := writeSizeReport(config.Options.SizeReport, lprogram.MainPkg().ImportPath, sizes)
+					if err != nil {
+						return err
+					}
+				}
+				err = checkSizeLimits(sizes, flashLimit, ramLimit)
+				if err != nil {
+					return err
+				}
 			}
 
 			// Print goroutine stack sizes, as far as possible.
@@ -886,13 +1095,25 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	// Run all jobs to compile and link the program.
 	// Do this now (instead of after elf-to-hex and similar conversions) as it
 	// is simpler and cannot be parallelized.
-	err = runJobs(linkJob, config.Options.Semaphore)
+	err = runJobs(linkJob, config.Options.Semaphore, trace)
 	if err != nil {
 		return result, err
 	}
 
+	if config.BuildMode() != "default" && config.Target.Linker != "wasm-ld" {
+		// Write the C header with the exported functions of the library. It
+		// is moved next to the output file, like the go tool does.
+		result.Header = filepath.Join(tmpdir, "main.h")
+		intBits := int(compiler.Sizes(machine).Sizeof(types.Typ[types.Int])) * 8
+		err := writeCHeader(result.Header, lprogram, libraryExports, intBits)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	// Get an Intel .hex file or .bin file from the .elf file.
 	outputBinaryFormat := config.BinaryFormat(outext)
+	convertStart := time.Now()
 	switch outputBinaryFormat {
 	case "elf":
 		// do nothing, file is already in ELF format
@@ -929,10 +1150,118 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	default:
 		return result, fmt.Errorf("unknown output binary format: %s", outputBinaryFormat)
 	}
+	if outputBinaryFormat != "elf" {
+		// All other jobs have finished by now, so use the first lane.
+		trace.addSpan("objcopy "+outputBinaryFormat, "job", 1, convertStart, nil)
+	}
 
 	return result, nil
 }
 
+// isCoveredPackage returns whether the given package should be instrumented for
+// code coverage. By default only the package under test is covered (not its
+// dependencies), like go test -cover. The -coverpkg flag can be used to cover
+// other packages: import paths, optionally ending in /... to match all packages
+// below it.
+func isCoveredPackage(config *compileopts.Config, mainPkg, importPath string) bool {
+	if config.TestConfig.CoverMode == "" || !config.TestConfig.CompileTestBinary || importPath == "runtime" {
+		return false
+	}
+	if len(config.TestConfig.CoverPackages) == 0 {
+		return importPath == strings.TrimSuffix(mainPkg, ".test")
+	}
+	for _, pattern := range config.TestConfig.CoverPackages {
+		if pattern == importPath {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/...") {
+			prefix := strings.TrimSuffix(pattern, "/...")
+			if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Packages that are not instrumented for fuzzing, because they are used by the
+// fuzzer itself or because they would only add noise. This is the same list as
+// used by the go command, plus the TinyGo internal packages.
+var fuzzNoInstrument = []string{
+	"context",
+	"internal",
+	"reflect",
+	"runtime",
+	"sync",
+	"syscall",
+	"testing",
+	"time",
+}
+
+// isFuzzedPackage returns whether the given package should be instrumented for
+// coverage-guided fuzzing (-fuzz). Like the go command, all packages are
+// instrumented except for those that are used by the fuzzer itself.
+func isFuzzedPackage(config *compileopts.Config, importPath string) bool {
+	if config.TestConfig.Fuzz == "" || !config.TestConfig.CompileTestBinary {
+		return false
+	}
+	for _, prefix := range fuzzNoInstrument {
+		if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Packages that are not instrumented by AddressSanitizer. The runtime scans
+// stacks and the heap conservatively and switches between goroutine stacks,
+// which would trip over the poisoned memory.
+var asanNoInstrument = []string{
+	"internal/task",
+	"runtime",
+}
+
+// addGlobalConstructor adds the given function to llvm.global_ctors, so that
+// it is called when the program or library is loaded. It is placed before the
+// other constructors, because these (in C code) might call exported Go
+// functions.
+func addGlobalConstructor(mod llvm.Module, fn llvm.Value) {
+	ctx := mod.Context()
+	i8ptrType := llvm.PointerType(ctx.Int8Type(), 0)
+	ctors := []llvm.Value{
+		ctx.ConstStruct([]llvm.Value{
+			llvm.ConstInt(ctx.Int32Type(), 65535, false), // default priority
+			fn,
+			llvm.ConstNull(i8ptrType),
+		}, false),
+	}
+	if global := mod.NamedGlobal("llvm.global_ctors"); !global.IsNil() {
+		initializer := global.Initializer()
+		for i := 0; i < initializer.OperandsCount(); i++ {
+			ctors = append(ctors, initializer.Operand(i))
+		}
+		global.EraseFromParentAsGlobal()
+	}
+	initializer := llvm.ConstArray(ctors[0].Type(), ctors)
+	global := llvm.AddGlobal(mod, initializer.Type(), "llvm.global_ctors")
+	global.SetInitializer(initializer)
+	global.SetLinkage(llvm.AppendingLinkage)
+}
+
+// isSanitizedPackage returns whether the functions in the given package should
+// be instrumented by AddressSanitizer (-sanitize=address).
+func isSanitizedPackage(config *compileopts.Config, importPath string) bool {
+	if config.Sanitize() != "address" {
+		return false
+	}
+	for _, prefix := range asanNoInstrument {
+		if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
 // createEmbedObjectFile creates a new object file with the given contents, for
 // the embed package.
 func createEmbedObjectFile(data, hexSum, sourceFile, sourceDir, tmpdir string, compilerConfig *compiler.Config) (string, error) {
@@ -1042,11 +1371,15 @@ func createEmbedObjectFile(data, hexSum, sourceFile, sourceDir, tmpdir string, c
 // optimizeProgram runs a series of optimizations and transformations that are
 // needed to convert a program to its final form. Some transformations are not
 // optional and must be run as the compiler expects them to run.
-func optimizeProgram(mod llvm.Module, config *compileopts.Config) error {
+// The interp and optimization steps are recorded as part of the given job when
+// tracing the build.
+func optimizeProgram(mod llvm.Module, config *compileopts.Config, job *compileJob) error {
+	interpStart := time.Now()
 	err := interp.Run(mod, config.Options.InterpTimeout, config.DumpSSA())
 	if err != nil {
 		return err
 	}
+	job.traceSpan("interp", interpStart)
 	if config.VerifyIR() {
 		// Only verify if we really need it.
 		// The IR has already been verified before writing the bitcode to disk
@@ -1069,10 +1402,12 @@ func optimizeProgram(mod llvm.Module, config *compileopts.Config) error {
 	// Optimization levels here are roughly the same as Clang, but probably not
 	// exactly.
 	optLevel, sizeLevel, inlinerThreshold := config.OptLevels()
+	optimizeStart := time.Now()
 	errs := transform.Optimize(mod, config, optLevel, sizeLevel, inlinerThreshold)
 	if len(errs) > 0 {
 		return newMultiError(errs)
 	}
+	job.traceSpan("optimize", optimizeStart)
 	if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
 		return errors.New("verification failure after LLVM optimization passes")
 	}
@@ -34,7 +34,10 @@ func TestClangAttributes(t *testing.T) {
 		"k210",
 		"nintendoswitch",
 		"riscv-qemu",
+		"riscv64-qemu",
 		"wasi",
+		"wasi-threads",
+		"wasip2",
 		"wasm",
 	}
 	if hasBuiltinTools {
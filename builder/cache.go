@@ -0,0 +1,326 @@
+package builder
+
+// This file manages the build cache (GOCACHE, usually ~/.cache/tinygo): it
+// reports how much space is used and trims the cache to a maximum size by
+// removing the least recently used entries.
+//
+// The cache may be shared by many TinyGo invocations at the same time, for
+// example by parallel CI jobs. All files are written to a temporary file first
+// and then renamed, so readers never see a partially written file. Builds hold
+// a shared lock on the cache for as long as they run, and trimming takes an
+// exclusive lock, so that no file is removed while a build is using it.
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+const (
+	// Name of the lock file that protects the whole cache against trimming.
+	cacheLockName = "cache.lock"
+
+	// Name of the file that stores when the cache was last trimmed.
+	cacheTrimName = "trim.txt"
+
+	// How often the cache is trimmed automatically after a build.
+	cacheTrimInterval = time.Hour
+)
+
+// CacheEntry is a single entry in the build cache: a file, or a directory that
+// is used as a whole (like a compiled library).
+type CacheEntry struct {
+	Path     string
+	Kind     string // package, C object, C dependencies, library, GOROOT, ThinLTO, other
+	Size     int64
+	LastUsed time.Time
+}
+
+// CacheKindStats summarizes all cache entries of a single kind.
+type CacheKindStats struct {
+	Kind    string
+	Entries int
+	Size    int64
+}
+
+// CacheStats summarizes the contents of the build cache.
+type CacheStats struct {
+	Dir         string
+	Kinds       []CacheKindStats // sorted by size, largest first
+	Entries     int
+	Size        int64
+	Oldest      time.Time // least recently used entry
+	LastTrimmed time.Time // zero if the cache was never trimmed
+}
+
+// ReadCacheStats returns statistics about the build cache in the given
+// directory.
+func ReadCacheStats(dir string) (*CacheStats, error) {
+	entries, err := readCacheEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	stats := &CacheStats{
+		Dir:         dir,
+		LastTrimmed: readCacheTrimTime(dir),
+	}
+	kinds := make(map[string]*CacheKindStats)
+	for _, entry := range entries {
+		kind := kinds[entry.Kind]
+		if kind == nil {
+			kind = &CacheKindStats{Kind: entry.Kind}
+			kinds[entry.Kind] = kind
+		}
+		kind.Entries++
+		kind.Size += entry.Size
+		stats.Entries++
+		stats.Size += entry.Size
+		if stats.Oldest.IsZero() || entry.LastUsed.Before(stats.Oldest) {
+			stats.Oldest = entry.LastUsed
+		}
+	}
+	for _, kind := range kinds {
+		stats.Kinds = append(stats.Kinds, *kind)
+	}
+	sort.Slice(stats.Kinds, func(i, j int) bool {
+		if stats.Kinds[i].Size != stats.Kinds[j].Size {
+			return stats.Kinds[i].Size > stats.Kinds[j].Size
+		}
+		return stats.Kinds[i].Kind < stats.Kinds[j].Kind
+	})
+	return stats, nil
+}
+
+// Print the cache statistics in a human readable form.
+func (stats *CacheStats) Print(w io.Writer, maxSize uint64) {
+	fmt.Fprintf(w, "cache directory: %s\n", stats.Dir)
+	if maxSize != 0 {
+		fmt.Fprintf(w, "maximum size:    %s\n", formatCacheSize(int64(maxSize)))
+	} else {
+		fmt.Fprintf(w, "maximum size:    unlimited (set TINYGOCACHEMAX to limit)\n")
+	}
+	if !stats.LastTrimmed.IsZero() {
+		fmt.Fprintf(w, "last trimmed:    %s\n", stats.LastTrimmed.Format(time.RFC3339))
+	}
+	if !stats.Oldest.IsZero() {
+		fmt.Fprintf(w, "oldest entry:    %s\n", stats.Oldest.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "\n entries       size | kind\n")
+	fmt.Fprintf(w, "------------------- | ----\n")
+	for _, kind := range stats.Kinds {
+		fmt.Fprintf(w, "%8d %10s | %s\n", kind.Entries, formatCacheSize(kind.Size), kind.Kind)
+	}
+	fmt.Fprintf(w, "------------------- | ----\n")
+	fmt.Fprintf(w, "%8d %10s | total\n", stats.Entries, formatCacheSize(stats.Size))
+}
+
+// formatCacheSize formats a size in bytes in a human readable form.
+func formatCacheSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(size)/(1<<10))
+	default:
+		return strconv.FormatInt(size, 10) + "B"
+	}
+}
+
+// TrimCache removes the least recently used entries from the cache in the
+// given directory until it is no larger than maxSize. It waits for all running
+// builds that use the cache to finish. It returns the entries that were
+// removed.
+func TrimCache(dir string, maxSize uint64) ([]CacheEntry, error) {
+	unlock := lock(filepath.Join(dir, cacheLockName))
+	defer unlock()
+	return trimCache(dir, maxSize)
+}
+
+// autoTrimCache trims the cache in the given directory if it hasn't been
+// trimmed recently. It doesn't wait for other builds: if the cache is in use,
+// trimming is left to a later build.
+func autoTrimCache(dir string, maxSize uint64) error {
+	if time.Since(readCacheTrimTime(dir)) < cacheTrimInterval {
+		return nil
+	}
+	fileLock := flock.New(filepath.Join(dir, cacheLockName))
+	locked, err := fileLock.TryLock()
+	if err != nil || !locked {
+		return nil
+	}
+	defer fileLock.Close()
+	_, err = trimCache(dir, maxSize)
+	return err
+}
+
+// trimCache implements TrimCache. The caller must hold the exclusive cache
+// lock.
+func trimCache(dir string, maxSize uint64) ([]CacheEntry, error) {
+	entries, err := readCacheEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+
+	// Remove the least recently used entries first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if size <= int64(maxSize) {
+			break
+		}
+		err := os.RemoveAll(entry.Path)
+		if err != nil {
+			return removed, err
+		}
+		// Also remove the lock file of this entry, if there is one. Nobody
+		// can be holding it, since we hold the exclusive cache lock.
+		os.Remove(entry.Path + ".lock")
+		size -= entry.Size
+		removed = append(removed, entry)
+	}
+
+	// Record when the cache was trimmed, to avoid trimming it on every build.
+	err = os.WriteFile(filepath.Join(dir, cacheTrimName), []byte(strconv.FormatInt(time.Now().Unix(), 10)+"\n"), 0666)
+	return removed, err
+}
+
+// readCacheTrimTime returns when the cache was last trimmed, or the zero time
+// if it was never trimmed.
+func readCacheTrimTime(dir string) time.Time {
+	data, err := os.ReadFile(filepath.Join(dir, cacheTrimName))
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// readCacheEntries lists all entries in the cache directory. Lock files and
+// temporary files are not included, as they are either tiny or in use.
+func readCacheEntries(dir string) ([]CacheEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // empty cache
+		}
+		return nil, err
+	}
+	var entries []CacheEntry
+	for _, file := range files {
+		name := file.Name()
+		path := filepath.Join(dir, name)
+		if name == cacheTrimName || strings.HasSuffix(name, ".lock") || strings.HasPrefix(name, "tmp-") || strings.Contains(name, ".tmp") {
+			continue
+		}
+		if name == "thinlto" && file.IsDir() {
+			// The ThinLTO cache is managed by the linker, but still count (and
+			// trim) the files in it.
+			thinltoEntries, err := readCacheEntries(path)
+			if err != nil {
+				return nil, err
+			}
+			for i := range thinltoEntries {
+				thinltoEntries[i].Kind = "ThinLTO"
+			}
+			entries = append(entries, thinltoEntries...)
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // removed in the meantime
+			}
+			return nil, err
+		}
+		entry := CacheEntry{
+			Path:     path,
+			Kind:     cacheEntryKind(name, file.IsDir()),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		}
+		if file.IsDir() {
+			entry.Size, err = directorySize(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// cacheEntryKind returns the kind of the cache entry with the given file name.
+func cacheEntryKind(name string, isDir bool) string {
+	switch {
+	case isDir && strings.HasPrefix(name, "goroot-"):
+		return "GOROOT"
+	case isDir:
+		return "library"
+	case strings.HasPrefix(name, "pkg-") && strings.HasSuffix(name, ".bc"):
+		return "package"
+	case strings.HasPrefix(name, "obj-"):
+		return "C object"
+	case strings.HasPrefix(name, "dep-") && strings.HasSuffix(name, ".json"):
+		return "C dependencies"
+	default:
+		return "other"
+	}
+}
+
+// directorySize returns the size of all files in the given directory. Symbolic
+// links (as used in the cached GOROOT) are not followed.
+func directorySize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// lockCacheShared takes a shared lock on the build cache, which prevents the
+// cache from being trimmed while it is in use. It returns a function to
+// release the lock.
+func lockCacheShared() func() {
+	dir := goenv.Get("GOCACHE")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return func() {}
+	}
+	fileLock := flock.New(filepath.Join(dir, cacheLockName))
+	if err := fileLock.RLock(); err != nil {
+		return func() {}
+	}
+	return func() { fileLock.Close() }
+}
@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheTrim(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// Create a cache with entries of different ages (in hours).
+	writeEntry := func(name string, size int, age int) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-time.Duration(age) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeEntry("pkg-old.bc", 1000, 5)
+	writeEntry("pkg-old.bc.lock", 0, 5)
+	writeEntry("pkg-new.bc", 1000, 1)
+	writeEntry("obj-c.bc", 500, 4)
+	writeEntry("dep-c.json", 10, 1)
+	writeEntry("thinlto/llvmcache-1", 300, 3)
+	writeEntry("tmp-1234.bc", 5000, 10) // in progress, must be ignored
+	writeEntry("compiler-rt-thumbv7em/lib.a", 2000, 2)
+	libTime := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "compiler-rt-thumbv7em"), libTime, libTime); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ReadCacheStats(dir)
+	if err != nil {
+		t.Fatal("could not read cache stats:", err)
+	}
+	if stats.Entries != 6 || stats.Size != 4810 {
+		t.Errorf("expected 6 entries of 4810 bytes, got %d entries of %d bytes", stats.Entries, stats.Size)
+	}
+	expectedKinds := []CacheKindStats{
+		{"library", 1, 2000},
+		{"package", 2, 2000},
+		{"C object", 1, 500},
+		{"ThinLTO", 1, 300},
+		{"C dependencies", 1, 10},
+	}
+	if len(stats.Kinds) != len(expectedKinds) {
+		t.Fatalf("expected kinds %v, got %v", expectedKinds, stats.Kinds)
+	}
+	for i, kind := range stats.Kinds {
+		if kind != expectedKinds[i] {
+			t.Errorf("kind %d: expected %v, got %v", i, expectedKinds[i], kind)
+		}
+	}
+
+	// Trim the cache: the oldest entries should be removed first, until the
+	// cache is small enough.
+	removed, err := TrimCache(dir, 3500)
+	if err != nil {
+		t.Fatal("could not trim cache:", err)
+	}
+	var removedNames []string
+	for _, entry := range removed {
+		removedNames = append(removedNames, filepath.Base(entry.Path))
+	}
+	if len(removedNames) != 2 || removedNames[0] != "pkg-old.bc" || removedNames[1] != "obj-c.bc" {
+		t.Errorf("expected pkg-old.bc and obj-c.bc to be removed, got %v", removedNames)
+	}
+	for _, name := range []string{"pkg-old.bc", "pkg-old.bc.lock", "obj-c.bc"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+	for _, name := range []string{"pkg-new.bc", "tmp-1234.bc", "compiler-rt-thumbv7em/lib.a"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+	if time.Since(readCacheTrimTime(dir)) > time.Minute {
+		t.Errorf("trim time was not recorded")
+	}
+
+	// Automatic trimming is skipped when the cache was trimmed recently.
+	if err := autoTrimCache(dir, 0); err != nil {
+		t.Fatal("could not trim cache:", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pkg-new.bc")); err != nil {
+		t.Errorf("expected cache not to be trimmed again: %v", err)
+	}
+}
@@ -23,7 +23,8 @@ import (
 
 // compileAndCacheCFile compiles a C or assembly file using a build cache.
 // Compiling the same file again (if nothing changed, including included header
-// files) the output is loaded from the build cache instead.
+// files) the output is loaded from the build cache instead. The returned
+// boolean reports whether that was the case.
 //
 // Its operation is a bit complex (more complex than Go package build caching)
 // because the list of file dependencies is only known after the file is
@@ -56,11 +57,11 @@ import (
 //     depfile but without invalidating its name. For this reason, the depfile is
 //     written on each new compilation (even when it seems unnecessary). However, it
 //     could in rare cases lead to a stale file fetched from the cache.
-func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands func(string, ...string)) (string, error) {
+func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands func(string, ...string)) (string, bool, error) {
 	// Hash input file.
 	fileHash, err := hashFile(abspath)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	// Acquire a lock (if supported).
@@ -95,31 +96,33 @@ func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands
 		// Parse it first.
 		err := json.Unmarshal(depfileBuf, &dependencies)
 		if err != nil {
-			return "", fmt.Errorf("could not parse dependencies JSON: %w", err)
+			return "", false, fmt.Errorf("could not parse dependencies JSON: %w", err)
 		}
 
 		// Obtain hashes of all the files listed as a dependency.
 		outpath, err := makeCFileCachePath(dependencies, depfileNameHash)
 		if err == nil {
 			if _, err := os.Stat(outpath); err == nil {
-				return outpath, nil
+				goenv.MarkCacheUsed(outpath)
+				goenv.MarkCacheUsed(depfileCachePath)
+				return outpath, true, nil
 			} else if !errors.Is(err, fs.ErrNotExist) {
-				return "", err
+				return "", false, err
 			}
 		}
 	} else if !errors.Is(err, fs.ErrNotExist) {
 		// expected either nil or IsNotExist
-		return "", err
+		return "", false, err
 	}
 
 	objTmpFile, err := os.CreateTemp(goenv.Get("GOCACHE"), "tmp-*.bc")
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	objTmpFile.Close()
 	depTmpFile, err := os.CreateTemp(tmpdir, "dep-*.d")
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	depTmpFile.Close()
 	flags := append([]string{}, cflags...)                                                 // copy cflags
@@ -137,13 +140,13 @@ func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands
 	}
 	err = runCCompiler(flags...)
 	if err != nil {
-		return "", &commandError{"failed to build", abspath, err}
+		return "", false, &commandError{"failed to build", abspath, err}
 	}
 
 	// Create sorted and uniqued slice of dependencies.
 	dependencyPaths, err := readDepFile(depTmpFile.Name())
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	dependencyPaths = append(dependencyPaths, abspath) // necessary for .s files
 	dependencySet := make(map[string]struct{}, len(dependencyPaths))
@@ -160,7 +163,7 @@ func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands
 	// Write dependencies file.
 	f, err := os.CreateTemp(filepath.Dir(depfileCachePath), depfileName)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	buf, err = json.MarshalIndent(dependencySlice, "", "\t")
@@ -169,28 +172,28 @@ func compileAndCacheCFile(abspath, tmpdir string, cflags []string, printCommands
 	}
 	_, err = f.Write(buf)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	err = f.Close()
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	err = os.Rename(f.Name(), depfileCachePath)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	// Move temporary object file to final location.
 	outpath, err := makeCFileCachePath(dependencySlice, depfileNameHash)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	err = os.Rename(objTmpFile.Name(), outpath)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	return outpath, nil
+	return outpath, false, nil
 }
 
 // Create a cache path (a path in GOCACHE) to store the output of a compiler
@@ -0,0 +1,271 @@
+package builder
+
+// This file generates the C header and the linker version script of a library
+// (-buildmode=c-archive and -buildmode=c-shared). The header declares the
+// exported functions with the same type names as the header generated by cgo,
+// so that C code can be shared between gc and TinyGo libraries.
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/loader"
+)
+
+// libraryExport is a function that is exported by a library, with the //export
+// pragma.
+type libraryExport struct {
+	name string
+	sig  *types.Signature
+}
+
+// findLibraryExports returns the exported functions of a library: the
+// functions with an //export pragma and a body in all packages outside the
+// standard library, in the same order as the packages are initialized. This
+// matches the functions that the compiler wraps for calls from C.
+func findLibraryExports(lprogram *loader.Program) []libraryExport {
+	var exports []libraryExport
+	for _, pkg := range lprogram.Sorted() {
+		if pkg.Standard {
+			continue
+		}
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok || decl.Recv != nil || decl.Body == nil || decl.Doc == nil {
+					continue
+				}
+				name := exportName(decl.Doc)
+				if name == "" {
+					continue
+				}
+				fn, ok := pkg.Pkg.Scope().Lookup(decl.Name.Name).(*types.Func)
+				if !ok {
+					continue
+				}
+				exports = append(exports, libraryExport{
+					name: name,
+					sig:  fn.Type().(*types.Signature),
+				})
+			}
+		}
+	}
+	return exports
+}
+
+// exportName returns the name in the //export (or //go:export) pragma of a
+// function, or "" if there is none.
+func exportName(doc *ast.CommentGroup) string {
+	name := ""
+	for _, comment := range doc.List {
+		parts := strings.Fields(comment.Text)
+		if len(parts) == 2 && (parts[0] == "//export" || parts[0] == "//go:export") {
+			name = parts[1]
+		}
+	}
+	return name
+}
+
+// Start of the generated header: the types that are used in the prototypes of
+// exported functions. These are the same as in the header generated by cgo.
+const cHeaderPrologue = `
+#include <stddef.h>
+
+#ifndef GO_CGO_GOSTRING_TYPEDEF
+typedef struct { const char *p; ptrdiff_t n; } _GoString_;
+#endif
+`
+
+const cHeaderTypes = `
+#ifndef GO_CGO_PROLOGUE_H
+#define GO_CGO_PROLOGUE_H
+
+typedef signed char GoInt8;
+typedef unsigned char GoUint8;
+typedef short GoInt16;
+typedef unsigned short GoUint16;
+typedef int GoInt32;
+typedef unsigned int GoUint32;
+typedef long long GoInt64;
+typedef unsigned long long GoUint64;
+typedef GoInt%[1]d GoInt;
+typedef GoUint%[1]d GoUint;
+typedef size_t GoUintptr;
+typedef float GoFloat32;
+typedef double GoFloat64;
+
+#ifndef GO_CGO_GOSTRING_TYPEDEF
+typedef _GoString_ GoString;
+#endif
+typedef void *GoMap;
+typedef void *GoChan;
+typedef struct { void *t; void *v; } GoInterface;
+
+#endif
+`
+
+// generateCHeader returns the C header of a library with the given exported
+// functions. The preambles (the C code above import "C" in the packages of
+// the library) are included, because the exported functions may use C types.
+func generateCHeader(exports []libraryExport, preambles []string, intBits int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("/* Code generated by TinyGo. DO NOT EDIT. */\n")
+	buf.WriteString(cHeaderPrologue)
+	if len(preambles) != 0 {
+		buf.WriteString("\n/* Start of preamble from import \"C\" comments.  */\n\n")
+		for _, preamble := range preambles {
+			buf.WriteString(preamble)
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n/* End of preamble from import \"C\" comments.  */\n")
+	}
+	fmt.Fprintf(buf, cHeaderTypes, intBits)
+	buf.WriteString("\n#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, export := range exports {
+		result := "void"
+		switch export.sig.Results().Len() {
+		case 0:
+		case 1:
+			typ, err := cTypeName(export.sig.Results().At(0).Type())
+			if err != nil {
+				return nil, fmt.Errorf("//export %s: %w", export.name, err)
+			}
+			result = typ
+		default:
+			return nil, fmt.Errorf("//export %s: too many return values", export.name)
+		}
+		var params []string
+		for i := 0; i < export.sig.Params().Len(); i++ {
+			param := export.sig.Params().At(i)
+			typ, err := cTypeName(param.Type())
+			if err != nil {
+				return nil, fmt.Errorf("//export %s: %w", export.name, err)
+			}
+			name := param.Name()
+			if name == "" || name == "_" {
+				name = fmt.Sprintf("p%d", i)
+			}
+			params = append(params, typ+" "+name)
+		}
+		if len(params) == 0 {
+			params = append(params, "void")
+		}
+		fmt.Fprintf(buf, "extern %s %s(%s);\n", result, export.name, strings.Join(params, ", "))
+	}
+	buf.WriteString("\n#ifdef __cplusplus\n}\n#endif\n")
+	return buf.Bytes(), nil
+}
+
+// Names of the C types that cgo makes available with a different name in Go,
+// such as C.uint for unsigned int.
+var cgoTypeNames = map[string]string{
+	"schar":     "signed char",
+	"uchar":     "unsigned char",
+	"ushort":    "unsigned short",
+	"uint":      "unsigned int",
+	"ulong":     "unsigned long",
+	"longlong":  "long long",
+	"ulonglong": "unsigned long long",
+}
+
+// cTypeName returns the C type for the given Go type in a prototype of an
+// exported function. C types (from cgo) keep their name, Go types are mapped
+// in the same way as cgo does. Pointers to Go types that have no C equivalent
+// become void pointers.
+func cTypeName(typ types.Type) (string, error) {
+	if named, ok := typ.(*types.Named); ok && strings.HasPrefix(named.Obj().Name(), "C.") {
+		name := strings.TrimPrefix(named.Obj().Name(), "C.")
+		if cname, ok := cgoTypeNames[name]; ok {
+			return cname, nil
+		}
+		for _, prefix := range []string{"struct_", "union_", "enum_"} {
+			if strings.HasPrefix(name, prefix) {
+				return strings.TrimSuffix(prefix, "_") + " " + name[len(prefix):], nil
+			}
+		}
+		return name, nil
+	}
+	switch typ := typ.Underlying().(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Bool, types.Uint8:
+			return "GoUint8", nil
+		case types.Int8:
+			return "GoInt8", nil
+		case types.Int16:
+			return "GoInt16", nil
+		case types.Uint16:
+			return "GoUint16", nil
+		case types.Int32:
+			return "GoInt32", nil
+		case types.Uint32:
+			return "GoUint32", nil
+		case types.Int64:
+			return "GoInt64", nil
+		case types.Uint64:
+			return "GoUint64", nil
+		case types.Int:
+			return "GoInt", nil
+		case types.Uint:
+			return "GoUint", nil
+		case types.Uintptr:
+			return "GoUintptr", nil
+		case types.Float32:
+			return "GoFloat32", nil
+		case types.Float64:
+			return "GoFloat64", nil
+		case types.String:
+			return "GoString", nil
+		case types.UnsafePointer:
+			return "void*", nil
+		}
+	case *types.Pointer:
+		if elem, err := cTypeName(typ.Elem()); err == nil {
+			return elem + "*", nil
+		}
+		return "void*", nil
+	case *types.Map:
+		return "GoMap", nil
+	case *types.Chan:
+		return "GoChan", nil
+	case *types.Interface:
+		return "GoInterface", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", typ.String())
+}
+
+// writeCHeader writes the C header of a library to the given path.
+func writeCHeader(path string, lprogram *loader.Program, exports []libraryExport, intBits int) error {
+	var preambles []string
+	for _, pkg := range lprogram.Sorted() {
+		if !pkg.Standard {
+			preambles = append(preambles, pkg.CGoHeaders...)
+		}
+	}
+	data, err := generateCHeader(exports, preambles, intBits)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0666)
+}
+
+// writeVersionScript writes a linker version script for a shared library
+// (-buildmode=c-shared), which exports only the exported Go functions. All
+// other symbols (for example of the runtime or compiler-rt) are local to the
+// shared library.
+func writeVersionScript(path string, exports []libraryExport) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{\n")
+	if len(exports) != 0 {
+		buf.WriteString("\tglobal:\n")
+		for _, export := range exports {
+			fmt.Fprintf(buf, "\t\t%s;\n", export.name)
+		}
+	}
+	buf.WriteString("\tlocal: *;\n};\n")
+	return os.WriteFile(path, buf.Bytes(), 0666)
+}
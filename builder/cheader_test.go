@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestCHeader(t *testing.T) {
+	// Type check a small library and generate the header for its exported
+	// functions.
+	const src = `package main
+
+import "unsafe"
+
+//export add
+func add(a, b int) int { return a + b }
+
+//export greet
+func greet(name string, _ *int32, p unsafe.Pointer) {}
+
+//export ready
+func ready() bool { return true }
+
+//export lookup
+func lookup(m map[string]int, c chan int, v interface{}) *struct{ x int } { return nil }
+
+// Not exported.
+func helper() {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal("could not parse:", err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("main", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal("could not type check:", err)
+	}
+	var exports []libraryExport
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Doc != nil {
+			if name := exportName(decl.Doc); name != "" {
+				fn := pkg.Scope().Lookup(decl.Name.Name).(*types.Func)
+				exports = append(exports, libraryExport{name, fn.Type().(*types.Signature)})
+			}
+		}
+	}
+	header, err := generateCHeader(exports, []string{"#include <stdint.h>"}, 64)
+	if err != nil {
+		t.Fatal("could not generate header:", err)
+	}
+	for _, expected := range []string{
+		"typedef GoInt64 GoInt;",
+		"#include <stdint.h>",
+		"extern GoInt add(GoInt a, GoInt b);",
+		"extern void greet(GoString name, GoInt32* p1, void* p);",
+		"extern GoUint8 ready(void);",
+		"extern void* lookup(GoMap m, GoChan c, GoInterface v);",
+	} {
+		if !strings.Contains(string(header), expected) {
+			t.Errorf("header does not contain %q:\n%s", expected, header)
+		}
+	}
+	if strings.Contains(string(header), "helper") {
+		t.Errorf("header contains unexported function:\n%s", header)
+	}
+
+	// Slices have no C equivalent.
+	sig := types.NewSignature(nil, types.NewTuple(types.NewVar(token.NoPos, nil, "b", types.NewSlice(types.Universe.Lookup("byte").Type()))), nil, false)
+	_, err = generateCHeader([]libraryExport{{"bytes", sig}}, nil, 64)
+	if err == nil || err.Error() != "//export bytes: unsupported type []byte" {
+		t.Errorf("unexpected error for slice parameter: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package builder
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/tinygo-org/tinygo/compileopts"
 	"github.com/tinygo-org/tinygo/goenv"
@@ -27,19 +29,123 @@ func NewConfig(options *compileopts.Options) (*compileopts.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	if major != 1 || minor < 18 || minor > 21 {
+	if major != 1 || minor < 19 || minor > 23 {
 		// Note: when this gets updated, also update the Go compatibility matrix:
 		// https://github.com/tinygo-org/tinygo-site/blob/dev/content/docs/reference/go-compat-matrix.md
-		return nil, fmt.Errorf("requires go version 1.18 through 1.21, got go%d.%d", major, minor)
+		return nil, fmt.Errorf("requires go version 1.19 through 1.23, got go%d.%d", major, minor)
+	}
+
+	if options.Sanitize != "" {
+		// The sanitizer runtimes only support Linux (with musl), and
+		// AddressSanitizer only on architectures with a known shadow memory
+		// layout.
+		if spec.GOOS != "linux" || spec.Libc != "musl" {
+			return nil, fmt.Errorf("-sanitize=%s is only supported on Linux", options.Sanitize)
+		}
+		switch spec.GOARCH {
+		case "386", "amd64", "arm", "arm64":
+		default:
+			if options.Sanitize == "address" {
+				return nil, fmt.Errorf("-sanitize=address is not supported on GOARCH=%s", spec.GOARCH)
+			}
+		}
+	}
+
+	switch options.PGO {
+	case "":
+	case "instrument":
+		// The profile is written to a file on Linux and WASI, and to the
+		// standard output on baremetal targets that run in QEMU (from where
+		// `tinygo run` and `tinygo test` write it to a file).
+		isWASI := spec.Libc == "wasi-libc" && spec.GOOS != "js"
+		isQEMU := strings.HasPrefix(spec.Emulator, "qemu-system-")
+		if spec.Libc != "musl" && !isWASI && !isQEMU {
+			return nil, fmt.Errorf("-pgo=instrument is not supported on this target")
+		}
+	default:
+		// A profile to optimize with.
+		if _, err := os.Stat(options.PGO); err != nil {
+			return nil, fmt.Errorf("could not read -pgo profile: %w", err)
+		}
+	}
+
+	switch options.BuildMode {
+	case "", "default":
+	case "c-archive", "c-shared":
+		// Exported functions return strings and interfaces in two registers.
+		// C does the same for a struct of two words on 64-bit architectures,
+		// but returns it in memory on 32-bit architectures.
+		// On WASI, -buildmode=c-shared creates a reactor module instead (like
+		// GOOS=wasip1 in Go), which is called through the WebAssembly ABI.
+		isWASI := spec.Libc == "wasi-libc" && spec.GOOS != "js"
+		isLinux64 := spec.GOOS == "linux" && spec.Libc == "musl" && (spec.GOARCH == "amd64" || spec.GOARCH == "arm64")
+		if options.BuildMode == "c-shared" && !isLinux64 && !isWASI {
+			return nil, fmt.Errorf("-buildmode=c-shared is only supported on linux/amd64, linux/arm64 and wasi")
+		}
+		if options.BuildMode == "c-archive" && !isLinux64 {
+			return nil, fmt.Errorf("-buildmode=c-archive is only supported on linux/amd64 and linux/arm64")
+		}
+		if options.Sanitize != "" || options.PGO == "instrument" {
+			return nil, fmt.Errorf("-buildmode=%s cannot be combined with -sanitize or -pgo=instrument", options.BuildMode)
+		}
+	}
+
+	if options.WasmRuntime == "builtin" {
+		// The builtin runtime (wazero) implements WASI preview 1, but not the
+		// component model or the JavaScript environment of GOOS=js.
+		if spec.Libc != "wasi-libc" || spec.GOOS == "js" {
+			return nil, fmt.Errorf("-wasm-runtime=builtin is only supported on WASI preview 1 targets")
+		}
+		// Run the binary with the builtin runtime, through the hidden
+		// "tinygo wasmrun" command which accepts the same flags as wasmtime.
+		spec.Emulator = "wasmrun --mapdir=/tmp::{tmpDir} {}"
 	}
 
 	clangHeaderPath := getClangHeaderPath(goenv.Get("TINYGOROOT"))
 
-	return &compileopts.Config{
+	config := &compileopts.Config{
 		Options:        options,
 		Target:         spec,
 		GoMinorVersion: minor,
 		ClangHeaders:   clangHeaderPath,
 		TestConfig:     options.TestConfig,
-	}, nil
+	}
+
+	if config.WasmThreads() {
+		// Goroutines run in parallel on multiple threads. This needs a heap
+		// that can be locked and stopped for a GC cycle, and a scheduler that
+		// runs on every thread.
+		switch config.GC() {
+		case "conservative", "precise", "none":
+		default:
+			return nil, fmt.Errorf("-gc=%s is not supported with WebAssembly threads", config.GC())
+		}
+		if config.BuildMode() != "default" {
+			return nil, fmt.Errorf("-buildmode=%s is not supported with WebAssembly threads", config.BuildMode())
+		}
+		if config.GOOS() == "js" {
+			// Threads in a browser or Node.js would have to be started as
+			// Web Workers by wasm_exec.js, which isn't implemented.
+			return nil, fmt.Errorf("WebAssembly threads are not supported with GOOS=js")
+		}
+		if options.WasmRuntime == "builtin" {
+			return nil, fmt.Errorf("-wasm-runtime=builtin does not support WebAssembly threads")
+		}
+	}
+
+	if config.WasmExceptions() {
+		// Panics are implemented using WebAssembly exceptions (see
+		// compiler/exceptions.go). Asyncify, the default scheduler on most
+		// WebAssembly targets, can't unwind through them so it has to be
+		// disabled explicitly. Without the exception-handling feature,
+		// asyncify remains the default.
+		if config.Scheduler() == "asyncify" {
+			return nil, fmt.Errorf("-scheduler=asyncify does not support WebAssembly exceptions, use -scheduler=none instead")
+		}
+		if options.WasmRuntime == "builtin" {
+			return nil, fmt.Errorf("-wasm-runtime=builtin does not support WebAssembly exceptions")
+		}
+	}
+
+	return config, nil
 }
@@ -35,6 +35,17 @@ type compileJob struct {
 	run          func(*compileJob) (err error)
 	err          error         // error if finished
 	duration     time.Duration // how long it took to run this job (only set after finishing)
+	startTime    time.Time     // when this job started running
+	cacheResult  string        // "hit" or "miss" for jobs that use the cache, only used for tracing
+	trace        *buildTrace   // build trace, or nil if not tracing (-trace flag)
+	traceLane    int           // lane (like a thread ID) in the build trace
+}
+
+// traceSpan records a part of this job that started at the given time and
+// ended now, for example the interp step of the LTO job. It is a no-op when the
+// build is not traced.
+func (job *compileJob) traceSpan(name string, start time.Time) {
+	job.trace.addSpan(name, "step", job.traceLane, start, nil)
 }
 
 // dummyCompileJob returns a new *compileJob that produces an output without
@@ -53,7 +64,8 @@ func dummyCompileJob(result string) *compileJob {
 // It runs all jobs in the order of the dependencies slice, depth-first.
 // Therefore, if some jobs are preferred to run before others, they should be
 // ordered as such in the job dependencies.
-func runJobs(job *compileJob, sema chan struct{}) error {
+// If trace is not nil, a span is recorded in the trace for every job that runs.
+func runJobs(job *compileJob, sema chan struct{}, trace *buildTrace) error {
 	if sema == nil {
 		// Have a default, if the semaphore isn't set. This is useful for
 		// tests.
@@ -108,6 +120,8 @@ func runJobs(job *compileJob, sema chan struct{}) error {
 	// Send each job in the jobs slice to a worker, taking care of job
 	// dependencies.
 	numRunningJobs := 0
+	var freeLanes []int // lanes in the trace that are not in use
+	numLanes := 0
 	var totalTime time.Duration
 	start := time.Now()
 	for len(ready.IntSlice) > 0 || numRunningJobs != 0 {
@@ -120,6 +134,18 @@ func runJobs(job *compileJob, sema chan struct{}) error {
 				if jobRunnerDebug {
 					fmt.Println("## start:   ", job.description)
 				}
+				if trace != nil {
+					// Pick the lowest free lane, so that the trace shows at
+					// most as many lanes as there were parallel jobs.
+					if len(freeLanes) == 0 {
+						numLanes++
+						freeLanes = append(freeLanes, numLanes)
+					}
+					sort.Ints(freeLanes)
+					job.trace = trace
+					job.traceLane = freeLanes[0]
+					freeLanes = freeLanes[1:]
+				}
 				go runJob(job, doneChan)
 				numRunningJobs++
 				continue
@@ -136,6 +162,14 @@ func runJobs(job *compileJob, sema chan struct{}) error {
 		if jobRunnerDebug {
 			fmt.Println("## finished:", completed.description, "(time "+completed.duration.String()+")")
 		}
+		if trace != nil {
+			if completed.run != nil || completed.cacheResult != "" {
+				// Only show jobs that did something (or loaded something
+				// from the cache).
+				trace.addJob(completed)
+			}
+			freeLanes = append(freeLanes, completed.traceLane)
+		}
 		if completed.err != nil {
 			// Wait for any current jobs to finish.
 			for numRunningJobs != 0 {
@@ -217,13 +251,13 @@ func (h *intHeap) Pop() interface{} {
 
 // runJob runs a compile job and notifies doneChan of completion.
 func runJob(job *compileJob, doneChan chan *compileJob) {
-	start := time.Now()
+	job.startTime = time.Now()
 	if job.run != nil {
 		err := job.run(job)
 		if err != nil {
 			job.err = err
 		}
 	}
-	job.duration = time.Since(start)
+	job.duration = time.Since(job.startTime)
 	doneChan <- job
 }
@@ -39,12 +39,14 @@ type Library struct {
 // The resulting directory may be stored in the provided tmpdir, which is
 // expected to be removed after the Load call.
 func (l *Library) Load(config *compileopts.Config, tmpdir string) (dir string, err error) {
+	unlockCache := lockCacheShared()
+	defer unlockCache()
 	job, unlock, err := l.load(config, tmpdir)
 	if err != nil {
 		return "", err
 	}
 	defer unlock()
-	err = runJobs(job, config.Options.Semaphore)
+	err = runJobs(job, config.Options.Semaphore, nil)
 	return filepath.Dir(job.result), err
 }
 
@@ -78,7 +80,11 @@ func (l *Library) load(config *compileopts.Config, tmpdir string) (job *compileJ
 
 	// Try to fetch this library from the cache.
 	if _, err := os.Stat(archiveFilePath); err == nil {
-		return dummyCompileJob(archiveFilePath), func() {}, nil
+		goenv.MarkCacheUsed(outdir)
+		job := dummyCompileJob(archiveFilePath)
+		job.description = "load " + l.name + "/lib.a"
+		job.cacheResult = "hit"
+		return job, func() {}, nil
 	}
 	// Cache miss, build it now.
 
@@ -158,6 +164,16 @@ func (l *Library) load(config *compileopts.Config, tmpdir string) (job *compileJ
 	if config.ABI() != "" {
 		args = append(args, "-mabi="+config.ABI())
 	}
+	if config.WasmThreads() {
+		// Object files linked into a module with shared memory must be
+		// compiled with atomics.
+		args = append(args, "-matomics", "-mbulk-memory")
+	}
+	if config.PositionIndependentLibrary() {
+		// The library may be linked into a shared library (see
+		// Config.LibcPath).
+		args = append(args, "-fPIC")
+	}
 	if strings.HasPrefix(target, "arm") || strings.HasPrefix(target, "thumb") {
 		if strings.Split(target, "-")[2] == "linux" {
 			args = append(args, "-fno-unwind-tables", "-fno-asynchronous-unwind-tables")
@@ -193,6 +209,7 @@ func (l *Library) load(config *compileopts.Config, tmpdir string) (job *compileJ
 	job = &compileJob{
 		description: "ar " + l.name + "/lib.a",
 		result:      filepath.Join(goenv.Get("GOCACHE"), outname, "lib.a"),
+		cacheResult: "miss",
 		run: func(*compileJob) error {
 			defer once.Do(unlock)
 
@@ -0,0 +1,22 @@
+package builder
+
+import (
+	"path/filepath"
+
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// Profile is the runtime that writes the profile counters of a program built
+// with -pgo=instrument. The compiler-rt profile runtime needs a file system and
+// a full libc, which are not available on baremetal targets, so TinyGo has its
+// own runtime that only supports the features TinyGo uses.
+var Profile = Library{
+	name: "profile",
+	cflags: func(target, headerPath string) []string {
+		return []string{"-Werror", "-Wall", "-std=c11", "-nostdlibinc"}
+	},
+	sourceDir: func() string { return filepath.Join(goenv.Get("TINYGOROOT"), "lib/profile") },
+	librarySources: func(target string) ([]string, error) {
+		return []string{"profile.c"}, nil
+	},
+}
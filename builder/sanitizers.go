@@ -0,0 +1,22 @@
+package builder
+
+import (
+	"path/filepath"
+
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// Sanitizers contains the runtimes of AddressSanitizer and the minimal runtime
+// of UndefinedBehaviorSanitizer (-sanitize flag). The compiler-rt runtimes need
+// glibc and dynamic linking, while TinyGo links statically with musl, so TinyGo
+// has its own much smaller runtimes.
+var Sanitizers = Library{
+	name: "sanitizers",
+	cflags: func(target, headerPath string) []string {
+		return []string{"-Werror", "-Wall", "-std=c11", "-nostdlibinc"}
+	},
+	sourceDir: func() string { return filepath.Join(goenv.Get("TINYGOROOT"), "lib/sanitizers") },
+	librarySources: func(target string) ([]string, error) {
+		return []string{"asan.c", "ubsan.c"}, nil
+	},
+}
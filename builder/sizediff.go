@@ -0,0 +1,287 @@
+package builder
+
+// This file implements the `tinygo sizediff` command, which compares the size
+// of two builds of a program per package and per symbol.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// SizeDiff is the difference in flash and RAM usage between two builds of a
+// program. Positive values mean the new build is larger.
+type SizeDiff struct {
+	Packages []PackageSizeDiff
+	Flash    int64
+	RAM      int64
+}
+
+// PackageSizeDiff is the difference in flash and RAM usage of a single package.
+type PackageSizeDiff struct {
+	Name    string
+	Flash   int64
+	RAM     int64
+	Symbols []SymbolSizeDiff
+}
+
+// SymbolSizeDiff is the difference in flash and RAM usage of a single symbol
+// within a package.
+type SymbolSizeDiff struct {
+	Name  string
+	Kind  string
+	Flash int64
+	RAM   int64
+}
+
+// LoadSizeDiff reads the two executables and calculates the size difference
+// between them. Packages are identified by the directory of their source files
+// in the debug information, which is converted back to an import path where
+// possible (see guessPackagePath). This way, executables built from different
+// checkouts or with a different GOROOT can still be compared.
+func LoadSizeDiff(oldPath, newPath string) (*SizeDiff, error) {
+	oldSizes, err := loadProgramSize(oldPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", oldPath, err)
+	}
+	newSizes, err := loadProgramSize(newPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", newPath, err)
+	}
+	modulePaths := make(map[string]string)
+	normalizePackagePaths(oldSizes, modulePaths)
+	normalizePackagePaths(newSizes, modulePaths)
+	return diffProgramSize(oldSizes, newSizes), nil
+}
+
+// cachedGorootRegexp matches a source directory inside the GOROOT that TinyGo
+// creates in its cache (see loader.GetCachedGoroot), and captures the import
+// path.
+var cachedGorootRegexp = regexp.MustCompile(`[/\\]goroot-[0-9a-f]+[/\\]src[/\\](.+)$`)
+
+// normalizePackagePaths replaces the package directories in sizes with import
+// paths, merging packages that end up with the same import path. The
+// modulePaths map caches the module path of module root directories.
+func normalizePackagePaths(sizes *programSize, modulePaths map[string]string) {
+	packages := make(map[string]packageSize, len(sizes.Packages))
+	for name, pkg := range sizes.Packages {
+		name = guessPackagePath(name, modulePaths)
+		if existing, ok := packages[name]; ok {
+			pkg = mergePackageSizes(existing, pkg)
+		}
+		packages[name] = pkg
+	}
+	sizes.Packages = packages
+}
+
+// guessPackagePath returns the import path of the package in the given source
+// directory. Names that aren't absolute paths (such as "C compiler-rt") and
+// directories that aren't part of a known GOROOT or module are returned
+// unchanged.
+func guessPackagePath(dir string, modulePaths map[string]string) string {
+	if !filepath.IsAbs(dir) {
+		return dir
+	}
+
+	// Packages in the GOROOT that TinyGo constructs in its cache.
+	if match := cachedGorootRegexp.FindStringSubmatch(dir); match != nil {
+		return filepath.ToSlash(match[1])
+	}
+
+	// Packages in the standard library, either from Go or from TinyGo.
+	for _, root := range []string{goenv.Get("TINYGOROOT"), goenv.Get("GOROOT")} {
+		if root == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(filepath.Join(root, "src"), dir); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	// Packages in a module: look for the go.mod file of the module.
+	for root := dir; ; {
+		modulePath, ok := modulePaths[root]
+		if !ok {
+			modulePath = readModulePath(filepath.Join(root, "go.mod"))
+			modulePaths[root] = modulePath
+		}
+		if modulePath != "" {
+			rel, _ := filepath.Rel(root, dir)
+			if rel == "." {
+				return modulePath
+			}
+			return modulePath + "/" + filepath.ToSlash(rel)
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			break
+		}
+		root = parent
+	}
+	return dir
+}
+
+// readModulePath returns the module path declared in the given go.mod file, or
+// the empty string if it can't be read.
+func readModulePath(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			if modulePath, err := strconv.Unquote(fields[1]); err == nil {
+				return modulePath
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// mergePackageSizes returns the combined size of two packages.
+func mergePackageSizes(a, b packageSize) packageSize {
+	merged := packageSize{
+		Code:    a.Code + b.Code,
+		ROData:  a.ROData + b.ROData,
+		Data:    a.Data + b.Data,
+		BSS:     a.BSS + b.BSS,
+		Symbols: make(map[string]*symbolSize, len(a.Symbols)+len(b.Symbols)),
+	}
+	for _, symbols := range []map[string]*symbolSize{a.Symbols, b.Symbols} {
+		for name, symbol := range symbols {
+			if existing, ok := merged.Symbols[name]; ok {
+				merged.Symbols[name] = &symbolSize{
+					Kind:   existing.Kind,
+					Code:   existing.Code + symbol.Code,
+					ROData: existing.ROData + symbol.ROData,
+					Data:   existing.Data + symbol.Data,
+					BSS:    existing.BSS + symbol.BSS,
+				}
+			} else {
+				merged.Symbols[name] = symbol
+			}
+		}
+	}
+	return merged
+}
+
+// diffProgramSize calculates the difference between two program sizes. Only
+// packages and symbols that changed in size are included, sorted by the impact
+// of the change (largest first).
+func diffProgramSize(oldSizes, newSizes *programSize) *SizeDiff {
+	diff := &SizeDiff{
+		Flash: int64(newSizes.Flash()) - int64(oldSizes.Flash()),
+		RAM:   int64(newSizes.RAM()) - int64(oldSizes.RAM()),
+	}
+	packageNames := make(map[string]struct{})
+	for name := range oldSizes.Packages {
+		packageNames[name] = struct{}{}
+	}
+	for name := range newSizes.Packages {
+		packageNames[name] = struct{}{}
+	}
+	for _, name := range sortedKeys(packageNames) {
+		oldPkg := oldSizes.Packages[name]
+		newPkg := newSizes.Packages[name]
+		pkgDiff := PackageSizeDiff{
+			Name:  name,
+			Flash: int64(newPkg.Flash()) - int64(oldPkg.Flash()),
+			RAM:   int64(newPkg.RAM()) - int64(oldPkg.RAM()),
+		}
+		symbolNames := make(map[string]struct{})
+		for symbolName := range oldPkg.Symbols {
+			symbolNames[symbolName] = struct{}{}
+		}
+		for symbolName := range newPkg.Symbols {
+			symbolNames[symbolName] = struct{}{}
+		}
+		for _, symbolName := range sortedKeys(symbolNames) {
+			oldSymbol := oldPkg.Symbols[symbolName]
+			newSymbol := newPkg.Symbols[symbolName]
+			symbolDiff := SymbolSizeDiff{Name: symbolName}
+			if newSymbol != nil {
+				symbolDiff.Kind = newSymbol.Kind
+				symbolDiff.Flash += int64(newSymbol.Flash())
+				symbolDiff.RAM += int64(newSymbol.RAM())
+			}
+			if oldSymbol != nil {
+				symbolDiff.Kind = oldSymbol.Kind
+				symbolDiff.Flash -= int64(oldSymbol.Flash())
+				symbolDiff.RAM -= int64(oldSymbol.RAM())
+			}
+			if symbolDiff.Flash != 0 || symbolDiff.RAM != 0 {
+				pkgDiff.Symbols = append(pkgDiff.Symbols, symbolDiff)
+			}
+		}
+		sort.SliceStable(pkgDiff.Symbols, func(i, j int) bool {
+			return sizeImpact(pkgDiff.Symbols[i].Flash, pkgDiff.Symbols[i].RAM) > sizeImpact(pkgDiff.Symbols[j].Flash, pkgDiff.Symbols[j].RAM)
+		})
+		if pkgDiff.Flash != 0 || pkgDiff.RAM != 0 || len(pkgDiff.Symbols) != 0 {
+			diff.Packages = append(diff.Packages, pkgDiff)
+		}
+	}
+	sort.SliceStable(diff.Packages, func(i, j int) bool {
+		return sizeImpact(diff.Packages[i].Flash, diff.Packages[i].RAM) > sizeImpact(diff.Packages[j].Flash, diff.Packages[j].RAM)
+	})
+	return diff
+}
+
+// Print the size difference in a human readable form. If symbols is set, the
+// changed symbols are printed below each package.
+func (diff *SizeDiff) Print(w io.Writer, symbols bool) {
+	fmt.Fprintf(w, "  flash     ram | package\n")
+	fmt.Fprintf(w, "--------------- | -------\n")
+	for _, pkg := range diff.Packages {
+		fmt.Fprintf(w, "%7s %7s | %s\n", formatSizeDelta(pkg.Flash), formatSizeDelta(pkg.RAM), pkg.Name)
+		if !symbols {
+			continue
+		}
+		for _, symbol := range pkg.Symbols {
+			fmt.Fprintf(w, "%7s %7s |   %s (%s)\n", formatSizeDelta(symbol.Flash), formatSizeDelta(symbol.RAM), symbol.Name, symbol.Kind)
+		}
+	}
+	fmt.Fprintf(w, "--------------- | -------\n")
+	fmt.Fprintf(w, "%7s %7s | total\n", formatSizeDelta(diff.Flash), formatSizeDelta(diff.RAM))
+}
+
+// sizeImpact returns a single number to sort size differences by: the absolute
+// flash and RAM difference combined.
+func sizeImpact(flash, ram int64) int64 {
+	if flash < 0 {
+		flash = -flash
+	}
+	if ram < 0 {
+		ram = -ram
+	}
+	return flash + ram
+}
+
+// formatSizeDelta formats a size difference with an explicit sign.
+func formatSizeDelta(delta int64) string {
+	if delta == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%+d", delta)
+}
+
+// sortedKeys returns the keys of the given set in sorted order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+func TestSizeDiff(t *testing.T) {
+	oldSizes := &programSize{
+		Packages: map[string]packageSize{
+			"main": {Code: 100, Data: 8, BSS: 16, Symbols: map[string]*symbolSize{
+				"main.main": {Kind: "function", Code: 60},
+				"main.foo":  {Kind: "function", Code: 40},
+				"main.buf":  {Kind: "global", BSS: 16},
+			}},
+			"runtime": {Code: 500},
+			"fmt":     {Code: 300},
+		},
+		Code: 900, Data: 8, BSS: 16,
+	}
+	newSizes := &programSize{
+		Packages: map[string]packageSize{
+			"main": {Code: 90, Data: 8, BSS: 32, Symbols: map[string]*symbolSize{
+				"main.main": {Kind: "function", Code: 90},
+				"main.buf":  {Kind: "global", BSS: 32},
+			}},
+			"runtime": {Code: 500},
+			"strconv": {Code: 200},
+		},
+		Code: 790, Data: 8, BSS: 32,
+	}
+	diff := diffProgramSize(oldSizes, newSizes)
+	expected := &SizeDiff{
+		Flash: -110,
+		RAM:   16,
+		Packages: []PackageSizeDiff{
+			{Name: "fmt", Flash: -300},
+			{Name: "strconv", Flash: 200},
+			{Name: "main", Flash: -10, RAM: 16, Symbols: []SymbolSizeDiff{
+				{Name: "main.foo", Kind: "function", Flash: -40},
+				{Name: "main.main", Kind: "function", Flash: 30},
+				{Name: "main.buf", Kind: "global", RAM: 16},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("unexpected size diff:\nexpected: %+v\nactual:   %+v", expected, diff)
+	}
+}
+
+func TestGuessPackagePath(t *testing.T) {
+	// Create a module with a package in a subdirectory.
+	moduleDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module example.com/hello\n\ngo 1.19\n"), 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modulePaths := make(map[string]string)
+	for _, tc := range []struct {
+		dir      string
+		expected string
+	}{
+		{"C compiler-rt", "C compiler-rt"},
+		{"runtime", "runtime"},
+		{filepath.Join(moduleDir), "example.com/hello"},
+		{filepath.Join(moduleDir, "internal", "greet"), "example.com/hello/internal/greet"},
+		{filepath.Join(goenv.Get("GOCACHE"), "goroot-0123456789abcdef", "src", "net", "netip"), "net/netip"},
+		{filepath.Join(goenv.Get("TINYGOROOT"), "src", "runtime"), "runtime"},
+	} {
+		if actual := guessPackagePath(tc.dir, modulePaths); actual != tc.expected {
+			t.Errorf("guessPackagePath(%q): expected %q, got %q", tc.dir, tc.expected, actual)
+		}
+	}
+}
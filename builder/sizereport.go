@@ -0,0 +1,293 @@
+package builder
+
+// This file writes the HTML size report of `tinygo size-report`: a single
+// self-contained HTML file with a treemap of flash and RAM usage per package,
+// source file and symbol.
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// sizeReportNode is a single node (program, package, file or symbol) in the
+// tree shown in the HTML size report.
+type sizeReportNode struct {
+	Name     string            `json:"name"`
+	Kind     string            `json:"kind,omitempty"`
+	Code     uint64            `json:"code"`
+	ROData   uint64            `json:"rodata"`
+	Data     uint64            `json:"data"`
+	BSS      uint64            `json:"bss"`
+	Children []*sizeReportNode `json:"children,omitempty"`
+}
+
+// newSizeReportTree converts the program size into a tree of nodes: the
+// program at the root, then packages, source files, and finally symbols.
+func newSizeReportTree(name string, sizes *programSize) *sizeReportNode {
+	root := &sizeReportNode{
+		Name:   name,
+		Kind:   "program",
+		Code:   sizes.Code,
+		ROData: sizes.ROData,
+		Data:   sizes.Data,
+		BSS:    sizes.BSS,
+	}
+	for _, pkgName := range sizes.sortedPackageNames() {
+		pkg := sizes.Packages[pkgName]
+		pkgNode := &sizeReportNode{
+			Name:   pkgName,
+			Kind:   "package",
+			Code:   pkg.Code,
+			ROData: pkg.ROData,
+			Data:   pkg.Data,
+			BSS:    pkg.BSS,
+		}
+		root.Children = append(root.Children, pkgNode)
+
+		files := make([]string, 0, len(pkg.files))
+		for file := range pkg.files {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			symbolNodes := newSizeReportSymbols(pkg.files[file])
+			if file == "" {
+				// Not part of a source file, so add the symbols directly to the
+				// package.
+				pkgNode.Children = append(pkgNode.Children, symbolNodes...)
+				continue
+			}
+			fileNode := &sizeReportNode{
+				Name:     filepath.Base(file),
+				Kind:     "file",
+				Children: symbolNodes,
+			}
+			for _, symbolNode := range symbolNodes {
+				fileNode.Code += symbolNode.Code
+				fileNode.ROData += symbolNode.ROData
+				fileNode.Data += symbolNode.Data
+				fileNode.BSS += symbolNode.BSS
+			}
+			pkgNode.Children = append(pkgNode.Children, fileNode)
+		}
+	}
+	return root
+}
+
+// newSizeReportSymbols returns the nodes for the given symbols, sorted by name.
+func newSizeReportSymbols(symbols map[string]*symbolSize) []*sizeReportNode {
+	names := make([]string, 0, len(symbols))
+	for name := range symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var nodes []*sizeReportNode
+	for _, name := range names {
+		symbol := symbols[name]
+		node := &sizeReportNode{
+			Name:   name,
+			Kind:   symbol.Kind,
+			Code:   symbol.Code,
+			ROData: symbol.ROData,
+			Data:   symbol.Data,
+			BSS:    symbol.BSS,
+		}
+		if name == "" {
+			// Bytes that aren't part of any symbol, like padding.
+			node.Name = "(other)"
+			node.Kind = "other"
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// writeSizeReport writes the HTML size report for the given program to path.
+func writeSizeReport(path, name string, sizes *programSize) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = sizeReportTemplate.Execute(f, newSizeReportTree(name, sizes))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var sizeReportTemplate = template.Must(template.New("size-report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Size report: {{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 16px; color: #222; }
+h1 { font-size: 20px; margin: 0 0 8px 0; }
+#summary, #controls, #path { margin-bottom: 8px; }
+#path a { color: #06c; cursor: pointer; text-decoration: underline; }
+#treemap { position: relative; width: 100%; height: 600px; border: 1px solid #888; }
+.cell { position: absolute; box-sizing: border-box; border: 1px solid #fff; overflow: hidden; font-size: 12px; padding: 2px; cursor: pointer; }
+.cell:hover { filter: brightness(0.9); }
+.kind-package { background: #9ecae1; }
+.kind-file { background: #a1d99b; }
+.kind-function { background: #fdd0a2; }
+.kind-global { background: #dadaeb; }
+.kind-type { background: #fcbba1; }
+.kind-string { background: #c7e9c0; }
+.kind-interface { background: #fdae6b; }
+.kind-other { background: #d9d9d9; }
+table { border-collapse: collapse; margin-top: 12px; }
+td, th { padding: 2px 8px; text-align: right; }
+td:last-child, th:last-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Size report: {{.Name}}</h1>
+<div id="summary"></div>
+<div id="controls">
+Show:
+<label><input type="radio" name="metric" value="flash" checked> flash</label>
+<label><input type="radio" name="metric" value="ram"> RAM</label>
+</div>
+<div id="path"></div>
+<div id="treemap"></div>
+<table>
+<thead><tr><th>code</th><th>rodata</th><th>data</th><th>bss</th><th>flash</th><th>ram</th><th>name</th></tr></thead>
+<tbody id="table"></tbody>
+</table>
+<script>
+var root = {{.}};
+var metric = "flash";
+var stack = [root];
+
+function flash(node) { return node.code + node.rodata + node.data; }
+function ram(node) { return node.data + node.bss; }
+function value(node) { return metric === "flash" ? flash(node) : ram(node); }
+
+// Squarified treemap layout: lay out the nodes (sorted by value, largest
+// first) in rows along the shortest side of the remaining rectangle.
+function layout(nodes, x, y, w, h) {
+	var total = 0;
+	nodes.forEach(function (n) { total += value(n); });
+	var scale = total > 0 ? (w * h) / total : 0;
+	var rects = [];
+	var i = 0;
+	while (i < nodes.length) {
+		var side = Math.min(w, h);
+		var row = [];
+		var rowArea = 0;
+		var worst = Infinity;
+		while (i < nodes.length) {
+			var area = value(nodes[i]) * scale;
+			var newArea = rowArea + area;
+			var rowMin = Math.min(area, row.length ? value(row[row.length - 1]) * scale : area);
+			var rowMax = row.length ? value(row[0]) * scale : area;
+			var ratio = Math.max((side * side * rowMax) / (newArea * newArea), (newArea * newArea) / (side * side * rowMin));
+			if (ratio > worst) {
+				break;
+			}
+			worst = ratio;
+			row.push(nodes[i]);
+			rowArea = newArea;
+			i++;
+		}
+		var thickness = side > 0 ? rowArea / side : 0;
+		var offset = 0;
+		row.forEach(function (n) {
+			var length = thickness > 0 ? (value(n) * scale) / thickness : 0;
+			if (w >= h) {
+				rects.push({node: n, x: x, y: y + offset, w: thickness, h: length});
+			} else {
+				rects.push({node: n, x: x + offset, y: y, w: length, h: thickness});
+			}
+			offset += length;
+		});
+		if (w >= h) {
+			x += thickness;
+			w -= thickness;
+		} else {
+			y += thickness;
+			h -= thickness;
+		}
+	}
+	return rects;
+}
+
+function render() {
+	var node = stack[stack.length - 1];
+	document.getElementById("summary").textContent =
+		"flash: " + flash(root) + " bytes, RAM: " + ram(root) + " bytes (code " + root.code +
+		", rodata " + root.rodata + ", data " + root.data + ", bss " + root.bss + ")";
+
+	var path = document.getElementById("path");
+	path.textContent = "";
+	stack.forEach(function (n, i) {
+		if (i > 0) {
+			path.appendChild(document.createTextNode(" / "));
+		}
+		if (i === stack.length - 1) {
+			path.appendChild(document.createTextNode(n.name + " (" + value(n) + " bytes)"));
+			return;
+		}
+		var link = document.createElement("a");
+		link.textContent = n.name;
+		link.onclick = function () {
+			stack = stack.slice(0, i + 1);
+			render();
+		};
+		path.appendChild(link);
+	});
+
+	var children = (node.children || []).filter(function (n) { return value(n) > 0; });
+	children.sort(function (a, b) { return value(b) - value(a); });
+
+	var treemap = document.getElementById("treemap");
+	treemap.textContent = "";
+	layout(children, 0, 0, treemap.clientWidth, treemap.clientHeight).forEach(function (r) {
+		var cell = document.createElement("div");
+		cell.className = "cell kind-" + r.node.kind;
+		cell.style.left = r.x + "px";
+		cell.style.top = r.y + "px";
+		cell.style.width = r.w + "px";
+		cell.style.height = r.h + "px";
+		cell.textContent = r.node.name + " (" + value(r.node) + ")";
+		cell.title = r.node.name + " [" + r.node.kind + "]\n" +
+			"code: " + r.node.code + "\nrodata: " + r.node.rodata +
+			"\ndata: " + r.node.data + "\nbss: " + r.node.bss;
+		if (r.node.children && r.node.children.length) {
+			cell.onclick = function () {
+				stack.push(r.node);
+				render();
+			};
+		}
+		treemap.appendChild(cell);
+	});
+
+	var table = document.getElementById("table");
+	table.textContent = "";
+	children.forEach(function (n) {
+		var row = document.createElement("tr");
+		[n.code, n.rodata, n.data, n.bss, flash(n), ram(n), n.name].forEach(function (v) {
+			var cell = document.createElement("td");
+			cell.textContent = v;
+			row.appendChild(cell);
+		});
+		table.appendChild(row);
+	});
+}
+
+document.querySelectorAll("input[name=metric]").forEach(function (input) {
+	input.onchange = function () {
+		metric = input.value;
+		render();
+	};
+});
+window.onresize = render;
+render();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,55 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSizeReport(t *testing.T) {
+	sizes := &programSize{
+		Packages: map[string]packageSize{
+			"main": {Code: 110, Data: 8, files: map[string]map[string]*symbolSize{
+				"/src/main.go": {
+					"main.main": {Kind: "function", Code: 100},
+					"":          {Code: 2},
+				},
+				"": {
+					"main$string": {Kind: "string", Code: 8},
+				},
+			}},
+			"C stack": {BSS: 1024},
+		},
+		Code: 110, Data: 8, BSS: 1024,
+	}
+	tree := newSizeReportTree("example", sizes)
+	expected := &sizeReportNode{Name: "example", Kind: "program", Code: 110, Data: 8, BSS: 1024, Children: []*sizeReportNode{
+		{Name: "C stack", Kind: "package", BSS: 1024},
+		{Name: "main", Kind: "package", Code: 110, Data: 8, Children: []*sizeReportNode{
+			{Name: "main$string", Kind: "string", Code: 8},
+			{Name: "main.go", Kind: "file", Code: 102, Children: []*sizeReportNode{
+				{Name: "(other)", Kind: "other", Code: 2},
+				{Name: "main.main", Kind: "function", Code: 100},
+			}},
+		}},
+	}}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("unexpected size report tree")
+	}
+
+	// Check that the report can be written and contains the data.
+	path := filepath.Join(t.TempDir(), "report.html")
+	err := writeSizeReport(path, "example", sizes)
+	if err != nil {
+		t.Fatal("could not write size report:", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"name":"main.main"`) {
+		t.Error("size report doesn't contain the main.main symbol")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"debug/macho"
 	"debug/pe"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -24,11 +25,11 @@ const sizesDebug = false
 
 // programSize contains size statistics per package of a compiled program.
 type programSize struct {
-	Packages map[string]packageSize
-	Code     uint64
-	ROData   uint64
-	Data     uint64
-	BSS      uint64
+	Packages map[string]packageSize `json:"packages"`
+	Code     uint64                 `json:"code"`
+	ROData   uint64                 `json:"rodata"`
+	Data     uint64                 `json:"data"`
+	BSS      uint64                 `json:"bss"`
 }
 
 // sortedPackageNames returns the list of package names (ProgramSize.Packages)
@@ -55,10 +56,17 @@ func (ps *programSize) RAM() uint64 {
 // packageSize contains the size of a package, calculated from the linked object
 // file.
 type packageSize struct {
-	Code   uint64
-	ROData uint64
-	Data   uint64
-	BSS    uint64
+	Code    uint64                 `json:"code"`
+	ROData  uint64                 `json:"rodata"`
+	Data    uint64                 `json:"data"`
+	BSS     uint64                 `json:"bss"`
+	Symbols map[string]*symbolSize `json:"symbols,omitempty"`
+
+	// Sizes per source file and per symbol within that file, as used in the
+	// HTML size report. Bytes that aren't part of a symbol (such as padding)
+	// are stored under the empty symbol name, and bytes that can't be traced
+	// back to a source file under the empty file name.
+	files map[string]map[string]*symbolSize
 }
 
 // Flash usage in regular microcontrollers.
@@ -71,6 +79,66 @@ func (ps *packageSize) RAM() uint64 {
 	return ps.Data + ps.BSS
 }
 
+// add adds the given number of bytes to the given memory type.
+func (ps *packageSize) add(typ memoryType, size uint64) {
+	switch typ {
+	case memoryCode:
+		ps.Code += size
+	case memoryROData:
+		ps.ROData += size
+	case memoryData:
+		ps.Data += size
+	case memoryBSS:
+		ps.BSS += size
+	}
+}
+
+// symbolSize contains the size of a single symbol (function, global, etc) in a
+// package. A symbol may be spread over multiple packages, for example when a
+// function from one package is inlined in a function from another package.
+type symbolSize struct {
+	Kind   string `json:"kind"`
+	Code   uint64 `json:"code,omitempty"`
+	ROData uint64 `json:"rodata,omitempty"`
+	Data   uint64 `json:"data,omitempty"`
+	BSS    uint64 `json:"bss,omitempty"`
+}
+
+// Flash usage in regular microcontrollers.
+func (ss *symbolSize) Flash() uint64 {
+	return ss.Code + ss.ROData + ss.Data
+}
+
+// Static RAM usage in regular microcontrollers.
+func (ss *symbolSize) RAM() uint64 {
+	return ss.Data + ss.BSS
+}
+
+// add adds the given number of bytes to the given memory type.
+func (ss *symbolSize) add(typ memoryType, size uint64) {
+	switch typ {
+	case memoryCode:
+		ss.Code += size
+	case memoryROData:
+		ss.ROData += size
+	case memoryData:
+		ss.Data += size
+	case memoryBSS:
+		ss.BSS += size
+	}
+}
+
+// sortedSymbolNames returns the list of symbol names in this package sorted
+// alphabetically.
+func (ps *packageSize) sortedSymbolNames() []string {
+	names := make([]string, 0, len(ps.Symbols))
+	for name := range ps.Symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // A mapping of a single chunk of code or data to a file path.
 type addressLine struct {
 	Address    uint64
@@ -80,6 +148,15 @@ type addressLine struct {
 	IsVariable bool   // true if this is a variable (or constant), false if it is code
 }
 
+// A symbol from the symbol table of the input file, used to attribute code and
+// data to individual functions and globals.
+type symbolInfo struct {
+	Name    string
+	Kind    string
+	Address uint64
+	Size    uint64
+}
+
 // Sections defined in the input file. This struct defines them in a
 // filetype-agnostic way but roughly follow the ELF types (.text, .data, .bss,
 // etc).
@@ -120,8 +197,32 @@ var (
 	//   pack:   data created when storing a constant in an interface for example
 	//   string: buffer behind strings
 	packageSymbolRegexp = regexp.MustCompile(`\$(alloc|pack|string)(\.[0-9]+)?$`)
+
+	// Buffers behind strings (like main$string or main$string.5).
+	stringSymbolRegexp = regexp.MustCompile(`\$string(\.[0-9]+)?$`)
 )
 
+// symbolKind returns the kind of symbol, as shown in the size report, based on
+// the symbol name as emitted by the compiler.
+func symbolKind(name string, isFunction bool) string {
+	switch {
+	case strings.HasPrefix(name, "reflect/types.type"), strings.HasPrefix(name, "reflect/types.typeid:"):
+		// Type descriptors (typecodes), also used by the interface lowering
+		// pass.
+		return "type"
+	case strings.HasSuffix(name, "$methodset"), strings.Contains(name, ".$methods."), strings.HasSuffix(name, "$invoke"), strings.HasSuffix(name, ".$typeassert"):
+		// Method sets and the functions that implement interface method
+		// calls and type asserts.
+		return "interface"
+	case stringSymbolRegexp.MatchString(name):
+		return "string"
+	case isFunction:
+		return "function"
+	default:
+		return "global"
+	}
+}
+
 // readProgramSizeFromDWARF reads the source location for each line of code and
 // each variable in the program, as far as this is stored in the DWARF debug
 // information.
@@ -376,6 +477,10 @@ func loadProgramSize(path string, packagePathMap map[string]string) (*programSiz
 	// This stores all chunks of addresses found in the binary.
 	var addresses []addressLine
 
+	// This stores all symbols (functions and globals) found in the binary, if
+	// the file format supports it.
+	var symbols []symbolInfo
+
 	// Load the binary file, which could be in a number of file formats.
 	var sections []memorySection
 	if file, err := elf.NewFile(f); err == nil {
@@ -429,6 +534,17 @@ func loadProgramSize(path string, packagePathMap map[string]string) (*programSiz
 					IsVariable: true,
 				})
 			}
+			address := symbol.Value
+			if file.Machine == elf.EM_ARM && symType == elf.STT_FUNC {
+				// The lowest bit is set for Thumb functions.
+				address &^= 1
+			}
+			symbols = append(symbols, symbolInfo{
+				Name:    symbol.Name,
+				Kind:    symbolKind(symbol.Name, symType == elf.STT_FUNC),
+				Address: address,
+				Size:    symbol.Size,
+			})
 		}
 
 		// Load allocated sections.
@@ -771,45 +887,69 @@ func loadProgramSize(path string, packagePathMap map[string]string) (*programSiz
 		return addresses[i].Address < addresses[j].Address
 	})
 
+	// Sort the symbols by address as well, and remove aliases and other
+	// overlapping symbols so that every byte is attributed to at most one
+	// symbol. When two symbols start at the same address, the largest (and
+	// then the alphabetically first) wins.
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Address != symbols[j].Address {
+			return symbols[i].Address < symbols[j].Address
+		}
+		if symbols[i].Size != symbols[j].Size {
+			return symbols[i].Size > symbols[j].Size
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	var symbolsEnd uint64
+	uniqueSymbols := symbols[:0]
+	for _, symbol := range symbols {
+		if symbol.Address < symbolsEnd {
+			continue
+		}
+		uniqueSymbols = append(uniqueSymbols, symbol)
+		symbolsEnd = symbol.Address + symbol.Size
+	}
+	symbols = uniqueSymbols
+
 	// Now finally determine the binary/RAM size usage per package by going
 	// through each allocated section.
 	sizes := make(map[string]packageSize)
 	for _, section := range sections {
-		switch section.Type {
-		case memoryCode:
-			readSection(section, addresses, func(path string, size uint64, isVariable bool) {
-				field := sizes[path]
-				if isVariable {
-					field.ROData += size
-				} else {
-					field.Code += size
-				}
-				sizes[path] = field
-			}, packagePathMap)
-		case memoryROData:
-			readSection(section, addresses, func(path string, size uint64, isVariable bool) {
-				field := sizes[path]
-				field.ROData += size
-				sizes[path] = field
-			}, packagePathMap)
-		case memoryData:
-			readSection(section, addresses, func(path string, size uint64, isVariable bool) {
-				field := sizes[path]
-				field.Data += size
-				sizes[path] = field
-			}, packagePathMap)
-		case memoryBSS:
-			readSection(section, addresses, func(path string, size uint64, isVariable bool) {
-				field := sizes[path]
-				field.BSS += size
-				sizes[path] = field
-			}, packagePathMap)
-		case memoryStack:
+		if section.Type == memoryStack {
 			// We store the C stack as a pseudo-package.
 			sizes["C stack"] = packageSize{
 				BSS: section.Size,
 			}
+			continue
 		}
+		readSection(section, addresses, func(path, file string, address, size uint64, isVariable bool) {
+			typ := section.Type
+			if typ == memoryCode && isVariable {
+				// Constant data stored in the .text section.
+				typ = memoryROData
+			}
+			field := sizes[path]
+			field.add(typ, size)
+			if field.Symbols == nil && len(symbols) != 0 {
+				field.Symbols = make(map[string]*symbolSize)
+			}
+			addSymbolSizes(field.Symbols, symbols, typ, address, size)
+			if field.files == nil {
+				field.files = make(map[string]map[string]*symbolSize)
+			}
+			if field.files[file] == nil {
+				field.files[file] = make(map[string]*symbolSize)
+			}
+			fileSymbols := field.files[file]
+			covered := addSymbolSizes(fileSymbols, symbols, typ, address, size)
+			if covered != size {
+				if fileSymbols[""] == nil {
+					fileSymbols[""] = &symbolSize{}
+				}
+				fileSymbols[""].add(typ, size-covered)
+			}
+			sizes[path] = field
+		}, packagePathMap)
 	}
 
 	// ...and summarize the results.
@@ -825,9 +965,85 @@ func loadProgramSize(path string, packagePathMap map[string]string) (*programSiz
 	return program, nil
 }
 
+// checkSizeLimits returns an error when the program uses more flash or RAM than
+// the given limits (in bytes), where a limit of zero means there is no limit.
+// The error describes which memory region overflowed, by how much, and which
+// packages use the most of that region. RAM usage includes the C stack, which
+// is listed as the "C stack" pseudo-package.
+func checkSizeLimits(sizes *programSize, flashLimit, ramLimit uint64) error {
+	var msg strings.Builder
+	checkLimit := func(region string, used, limit uint64, packageUsage func(*packageSize) uint64) {
+		if limit == 0 || used <= limit {
+			return
+		}
+		if msg.Len() != 0 {
+			msg.WriteString("\n")
+		}
+		fmt.Fprintf(&msg, "%s overflowed by %d bytes (%d bytes used, %d bytes available)", region, used-limit, used, limit)
+
+		// Show the packages that contribute most to this memory region.
+		names := sizes.sortedPackageNames()
+		sort.SliceStable(names, func(i, j int) bool {
+			pkgI := sizes.Packages[names[i]]
+			pkgJ := sizes.Packages[names[j]]
+			return packageUsage(&pkgI) > packageUsage(&pkgJ)
+		})
+		if len(names) > 5 {
+			names = names[:5]
+		}
+		msg.WriteString("\nlargest packages:")
+		for _, name := range names {
+			pkg := sizes.Packages[name]
+			if packageUsage(&pkg) == 0 {
+				break
+			}
+			fmt.Fprintf(&msg, "\n%9d %s", packageUsage(&pkg), name)
+		}
+	}
+	checkLimit("flash", sizes.Flash(), flashLimit, (*packageSize).Flash)
+	checkLimit("RAM", sizes.RAM(), ramLimit, (*packageSize).RAM)
+	if msg.Len() != 0 {
+		return errors.New(msg.String())
+	}
+	return nil
+}
+
+// addSymbolSizes attributes the memory range starting at address to the symbols
+// that overlap with it. The symbols slice must be sorted by address and must not
+// contain overlapping symbols. Bytes that aren't part of any symbol (such as
+// padding) are not attributed to a symbol. It returns the number of bytes that
+// were attributed to a symbol.
+func addSymbolSizes(sizes map[string]*symbolSize, symbols []symbolInfo, typ memoryType, address, size uint64) (covered uint64) {
+	end := address + size
+	i := sort.Search(len(symbols), func(i int) bool {
+		return symbols[i].Address+symbols[i].Size > address
+	})
+	for ; i < len(symbols) && symbols[i].Address < end; i++ {
+		symbol := symbols[i]
+		start := symbol.Address
+		if start < address {
+			start = address
+		}
+		symbolEnd := symbol.Address + symbol.Size
+		if symbolEnd > end {
+			symbolEnd = end
+		}
+		field := sizes[symbol.Name]
+		if field == nil {
+			field = &symbolSize{Kind: symbol.Kind}
+			sizes[symbol.Name] = field
+		}
+		field.add(typ, symbolEnd-start)
+		covered += symbolEnd - start
+	}
+	return covered
+}
+
 // readSection determines for each byte in this section to which package it
-// belongs. It reports this usage through the addSize callback.
-func readSection(section memorySection, addresses []addressLine, addSize func(string, uint64, bool), packagePathMap map[string]string) {
+// belongs. It reports this usage through the addSize callback, which receives
+// the package path, the source file (if known), the start address and the size
+// of each chunk.
+func readSection(section memorySection, addresses []addressLine, addSize func(path, file string, address, size uint64, isVariable bool), packagePathMap map[string]string) {
 	// The addr variable tracks at which address we are while going through this
 	// section. We start at the beginning.
 	addr := section.Address
@@ -849,9 +1065,9 @@ func readSection(section memorySection, addresses []addressLine, addSize func(st
 			addrAligned := (addr + line.Align - 1) &^ (line.Align - 1)
 			if line.Align > 1 && addrAligned >= line.Address {
 				// It is, assume that's what causes the gap.
-				addSize("(padding)", line.Address-addr, true)
+				addSize("(padding)", "", addr, line.Address-addr, true)
 			} else {
-				addSize("(unknown)", line.Address-addr, false)
+				addSize("(unknown)", "", addr, line.Address-addr, false)
 				if sizesDebug {
 					fmt.Printf("%08x..%08x %5d:  unknown (gap), alignment=%d\n", addr, line.Address, line.Address-addr, line.Align)
 				}
@@ -873,7 +1089,12 @@ func readSection(section memorySection, addresses []addressLine, addSize func(st
 			length = line.Length - (addr - line.Address)
 		}
 		// Finally, mark this chunk of memory as used by the given package.
-		addSize(findPackagePath(line.File, packagePathMap), length, line.IsVariable)
+		file := line.File
+		if packageSymbolRegexp.MatchString(file) || file == "__isr_vector" {
+			// Not a source file but a symbol name, such as main$string.
+			file = ""
+		}
+		addSize(findPackagePath(line.File, packagePathMap), file, line.Address+line.Length-length, length, line.IsVariable)
 		addr = line.Address + line.Length
 	}
 	if addr < sectionEnd {
@@ -882,9 +1103,9 @@ func readSection(section memorySection, addresses []addressLine, addSize func(st
 		if section.Align > 1 && addrAligned >= sectionEnd {
 			// The gap is caused by the section alignment.
 			// For example, if a .rodata section ends with a non-aligned string.
-			addSize("(padding)", sectionEnd-addr, true)
+			addSize("(padding)", "", addr, sectionEnd-addr, true)
 		} else {
-			addSize("(unknown)", sectionEnd-addr, false)
+			addSize("(unknown)", "", addr, sectionEnd-addr, false)
 			if sizesDebug {
 				fmt.Printf("%08x..%08x %5d:  unknown (end), alignment=%d\n", addr, sectionEnd, sectionEnd-addr, section.Align)
 			}
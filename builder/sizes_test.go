@@ -90,3 +90,68 @@ func TestBinarySize(t *testing.T) {
 		})
 	}
 }
+
+// Test whether memory ranges are attributed to the correct symbols.
+func TestSymbolSizes(t *testing.T) {
+	symbols := []symbolInfo{
+		{Name: "main.main", Kind: symbolKind("main.main", true), Address: 0x100, Size: 0x20},
+		{Name: "main$string", Kind: symbolKind("main$string", false), Address: 0x120, Size: 0x08},
+		{Name: "reflect/types.type:named:main.T", Kind: symbolKind("reflect/types.type:named:main.T", false), Address: 0x130, Size: 0x10},
+	}
+	sizes := make(map[string]*symbolSize)
+	addSymbolSizes(sizes, symbols, memoryCode, 0x0f0, 0x18)    // partially before main.main
+	addSymbolSizes(sizes, symbols, memoryCode, 0x108, 0x20)    // main.main and main$string
+	addSymbolSizes(sizes, symbols, memoryROData, 0x128, 0x100) // padding and type
+	expected := map[string]symbolSize{
+		"main.main":                       {Kind: "function", Code: 0x20},
+		"main$string":                     {Kind: "string", Code: 0x08},
+		"reflect/types.type:named:main.T": {Kind: "type", ROData: 0x10},
+	}
+	if len(sizes) != len(expected) {
+		t.Errorf("expected %d symbols, got %d", len(expected), len(sizes))
+	}
+	for name, expectedSize := range expected {
+		size := sizes[name]
+		if size == nil {
+			t.Errorf("symbol %s not found", name)
+			continue
+		}
+		if *size != expectedSize {
+			t.Errorf("symbol %s: expected %+v, got %+v", name, expectedSize, *size)
+		}
+	}
+}
+
+func TestCheckSizeLimits(t *testing.T) {
+	sizes := &programSize{
+		Packages: map[string]packageSize{
+			"main":    {Code: 1000, Data: 100, BSS: 400},
+			"runtime": {Code: 3000, ROData: 500, BSS: 2000},
+			"C stack": {BSS: 2048},
+		},
+		Code: 4000, ROData: 500, Data: 100, BSS: 4448,
+	}
+	if err := checkSizeLimits(sizes, 0, 0); err != nil {
+		t.Errorf("unexpected error without limits: %v", err)
+	}
+	if err := checkSizeLimits(sizes, 4600, 4548); err != nil {
+		t.Errorf("unexpected error with limits that exactly fit: %v", err)
+	}
+	err := checkSizeLimits(sizes, 4000, 8192)
+	expected := "flash overflowed by 600 bytes (4600 bytes used, 4000 bytes available)\n" +
+		"largest packages:\n" +
+		"     3500 runtime\n" +
+		"     1100 main"
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error for flash overflow:\nexpected: %s\nactual:   %v", expected, err)
+	}
+	err = checkSizeLimits(sizes, 0, 4096)
+	expected = "RAM overflowed by 452 bytes (4548 bytes used, 4096 bytes available)\n" +
+		"largest packages:\n" +
+		"     2048 C stack\n" +
+		"     2000 runtime\n" +
+		"      500 main"
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error for RAM overflow:\nexpected: %s\nactual:   %v", expected, err)
+	}
+}
@@ -0,0 +1,96 @@
+package builder
+
+// This file records a timeline of the build (the -trace flag), in the Chrome
+// trace event format. The resulting file can be opened in chrome://tracing or
+// https://ui.perfetto.dev to see which jobs ran in parallel and which jobs took
+// the most time.
+// Format description:
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// buildTrace collects trace events of a single build. A nil *buildTrace is
+// valid and records nothing, so that callers don't need to check whether
+// tracing is enabled.
+type buildTrace struct {
+	lock   sync.Mutex
+	start  time.Time
+	events []traceEvent
+}
+
+// traceEvent is a single complete event ("ph":"X") in the trace file.
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat,omitempty"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`  // start time in microseconds
+	Duration  int64             `json:"dur"` // duration in microseconds
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+func newBuildTrace() *buildTrace {
+	return &buildTrace{
+		start: time.Now(),
+	}
+}
+
+// addSpan records an event that started at the given time and ended now. The
+// lane is shown as a thread in the trace viewer: events in the same lane are
+// shown on the same line.
+func (t *buildTrace) addSpan(name, category string, lane int, start time.Time, args map[string]string) {
+	if t == nil {
+		return
+	}
+	end := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.events = append(t.events, traceEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: start.Sub(t.start).Microseconds(),
+		Duration:  end.Sub(start).Microseconds(),
+		PID:       1,
+		TID:       lane,
+		Args:      args,
+	})
+}
+
+// addJob records the given job, which has just finished running.
+func (t *buildTrace) addJob(job *compileJob) {
+	var args map[string]string
+	if job.cacheResult != "" {
+		args = map[string]string{"cache": job.cacheResult}
+	}
+	if job.err != nil {
+		if args == nil {
+			args = make(map[string]string)
+		}
+		args["error"] = job.err.Error()
+	}
+	t.addSpan(job.description, "job", job.traceLane, job.startTime, args)
+}
+
+// writeFile writes all events recorded so far to the given path.
+func (t *buildTrace) writeFile(path string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	data, err := json.Marshal(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{
+		TraceEvents:     t.events,
+		DisplayTimeUnit: "ms",
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0666)
+}
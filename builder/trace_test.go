@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildTrace(t *testing.T) {
+	// Run a small job graph: two jobs that can run in parallel and a job
+	// that depends on both.
+	cached := dummyCompileJob("cached.o")
+	cached.description = "load cached.o"
+	cached.cacheResult = "hit"
+	jobA := &compileJob{
+		description:  "job A",
+		dependencies: []*compileJob{cached},
+		run: func(job *compileJob) error {
+			job.cacheResult = "miss"
+			return nil
+		},
+	}
+	jobB := &compileJob{
+		description: "job B",
+		run: func(job *compileJob) error {
+			job.traceSpan("step B", job.startTime)
+			return nil
+		},
+	}
+	final := &compileJob{
+		description:  "final",
+		dependencies: []*compileJob{jobA, jobB, dummyCompileJob("other.o")},
+		run: func(*compileJob) error {
+			return nil
+		},
+	}
+	trace := newBuildTrace()
+	err := runJobs(final, make(chan struct{}, 2), trace)
+	if err != nil {
+		t.Fatal("could not run jobs:", err)
+	}
+
+	// Check that all jobs (except for the dummy job without a cache result)
+	// have been recorded.
+	events := make(map[string]traceEvent)
+	for _, event := range trace.events {
+		events[event.Name] = event
+	}
+	if len(events) != len(trace.events) {
+		t.Errorf("duplicate events: %v", trace.events)
+	}
+	for _, name := range []string{"load cached.o", "job A", "job B", "step B", "final"} {
+		event, ok := events[name]
+		if !ok {
+			t.Errorf("missing event %q", name)
+			continue
+		}
+		if event.Phase != "X" || event.Duration < 0 || event.TID < 1 {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}
+	if len(events) != 5 {
+		t.Errorf("expected 5 events, got %d: %v", len(events), trace.events)
+	}
+	if cache := events["load cached.o"].Args["cache"]; cache != "hit" {
+		t.Errorf("expected cache hit for cached job, got %q", cache)
+	}
+	if cache := events["job A"].Args["cache"]; cache != "miss" {
+		t.Errorf("expected cache miss for job A, got %q", cache)
+	}
+	if events["step B"].TID != events["job B"].TID {
+		t.Errorf("step B is not in the same lane as job B: %d != %d", events["step B"].TID, events["job B"].TID)
+	}
+	for _, event := range trace.events {
+		if event.TID > 2 {
+			t.Errorf("expected at most 2 lanes with a semaphore of 2, got lane %d", event.TID)
+		}
+	}
+
+	// Check that the file can be read back.
+	path := filepath.Join(t.TempDir(), "trace.json")
+	err = trace.writeFile(path)
+	if err != nil {
+		t.Fatal("could not write trace:", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("could not read trace:", err)
+	}
+	var file struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		t.Fatal("could not parse trace:", err)
+	}
+	if len(file.TraceEvents) != len(trace.events) {
+		t.Errorf("expected %d events in the trace file, got %d", len(trace.events), len(file.TraceEvents))
+	}
+
+	// A nil trace doesn't record anything.
+	var nilTrace *buildTrace
+	nilTrace.addSpan("nothing", "job", 1, cached.startTime, nil)
+}
@@ -0,0 +1,81 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// WasmBuiltins is a small subset of the musl sources in wasi-libc, for targets
+// that don't link against a libc (like wasip2) but still need the functions
+// that LLVM emits calls to.
+var WasmBuiltins = Library{
+	name: "wasmbuiltins",
+	makeHeaders: func(target, includeDir string) error {
+		if err := os.Mkdir(filepath.Join(includeDir, "bits"), 0o777); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return os.WriteFile(filepath.Join(includeDir, "bits", "alltypes.h"), []byte(wasmAllTypes), 0o666)
+	},
+	cflags: func(target, headerPath string) []string {
+		libcDir := filepath.Join(goenv.Get("TINYGOROOT"), "lib/wasi-libc")
+		return []string{
+			"-Werror",
+			"-Wall",
+			"-std=gnu11",
+			"-nostdlibinc",
+			"-isystem", libcDir + "/libc-top-half/musl/arch/wasm32",
+			"-isystem", libcDir + "/libc-top-half/musl/arch/generic",
+			"-isystem", libcDir + "/libc-top-half/musl/src/internal",
+			"-isystem", libcDir + "/libc-top-half/musl/src/include",
+			"-isystem", libcDir + "/libc-top-half/musl/include",
+			"-isystem", libcDir + "/libc-bottom-half/headers/public",
+			"-I" + headerPath,
+		}
+	},
+	sourceDir: func() string { return filepath.Join(goenv.Get("TINYGOROOT"), "lib/wasi-libc") },
+	librarySources: func(target string) ([]string, error) {
+		return []string{
+			// memory builtins needed for llvm.memcpy.*, llvm.memmove.*, and
+			// llvm.memset.* LLVM intrinsics.
+			"libc-top-half/musl/src/string/memcpy.c",
+			"libc-top-half/musl/src/string/memmove.c",
+			"libc-top-half/musl/src/string/memset.c",
+			"libc-top-half/musl/src/string/strlen.c",
+
+			// exp, exp2, and log are needed for LLVM math builtin functions
+			// like llvm.exp.*.
+			"libc-top-half/musl/src/math/__math_divzero.c",
+			"libc-top-half/musl/src/math/__math_invalid.c",
+			"libc-top-half/musl/src/math/__math_oflow.c",
+			"libc-top-half/musl/src/math/__math_uflow.c",
+			"libc-top-half/musl/src/math/__math_xflow.c",
+			"libc-top-half/musl/src/math/exp.c",
+			"libc-top-half/musl/src/math/exp_data.c",
+			"libc-top-half/musl/src/math/exp2.c",
+			"libc-top-half/musl/src/math/log.c",
+			"libc-top-half/musl/src/math/log_data.c",
+		}, nil
+	},
+}
+
+// alltypes.h for the musl sources above, with the types as wasm32 defines them.
+const wasmAllTypes = `typedef __SIZE_TYPE__ size_t;
+typedef __INT8_TYPE__ int8_t;
+typedef __INT16_TYPE__ int16_t;
+typedef __INT32_TYPE__ int32_t;
+typedef __INT64_TYPE__ int64_t;
+typedef __UINT8_TYPE__ uint8_t;
+typedef __UINT16_TYPE__ uint16_t;
+typedef __UINT32_TYPE__ uint32_t;
+typedef __UINT64_TYPE__ uint64_t;
+typedef __UINTPTR_TYPE__ uintptr_t;
+typedef __INTPTR_TYPE__ intptr_t;
+typedef __INTMAX_TYPE__ intmax_t;
+typedef __UINTMAX_TYPE__ uintmax_t;
+typedef __PTRDIFF_TYPE__ ptrdiff_t;
+typedef __WCHAR_TYPE__ wchar_t;
+
+#define static_assert _Static_assert
+`
@@ -0,0 +1,43 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// makeWasmComponent turns the linked core WebAssembly module into a component
+// targeting the WIT world of the target, using wasm-tools. The file is
+// modified in place.
+func makeWasmComponent(config *compileopts.Config, path string) error {
+	wasmtools := goenv.Get("WASMTOOLS")
+	if _, err := exec.LookPath(wasmtools); err != nil {
+		return fmt.Errorf("could not find wasm-tools (needed to create a component for %s), set the WASMTOOLS environment variable to override: %w", config.WITWorld(), err)
+	}
+
+	// Add the component type information to the module in a custom section.
+	cmd := exec.Command(wasmtools, "component", "embed", "-w", config.WITWorld(), config.WITPackage(), path, "-o", path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if config.Options.PrintCommands != nil {
+		config.Options.PrintCommands(cmd.Path, cmd.Args[1:]...)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wasm-tools component embed failed: %w", err)
+	}
+
+	// Create the component from the module.
+	cmd = exec.Command(wasmtools, "component", "new", path, "-o", path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if config.Options.PrintCommands != nil {
+		config.Options.PrintCommands(cmd.Path, cmd.Args[1:]...)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wasm-tools component new failed: %w", err)
+	}
+	return nil
+}
@@ -79,6 +79,18 @@ func (c *Config) BuildTags() []string {
 	for i := 1; i <= c.GoMinorVersion; i++ {
 		tags = append(tags, fmt.Sprintf("go1.%d", i))
 	}
+	if c.Sanitize() != "" {
+		tags = append(tags, "sanitize."+c.Sanitize())
+	}
+	if c.PGO() == "instrument" {
+		tags = append(tags, "pgo.instrument")
+	}
+	if c.BuildMode() != "default" {
+		tags = append(tags, "tinygo.library")
+	}
+	if c.WasmExceptions() {
+		tags = append(tags, "wasm.exceptions")
+	}
 	tags = append(tags, c.Options.Tags...)
 	return tags
 }
@@ -131,6 +143,43 @@ func (c *Config) Scheduler() string {
 	return "none"
 }
 
+// WasmThreads returns whether this is a WebAssembly target with threads (like
+// wasi-threads), which runs goroutines on multiple threads and links against
+// the threads build of wasi-libc.
+func (c *Config) WasmThreads() bool {
+	for _, tag := range c.Target.BuildTags {
+		if tag == "wasm.threads" {
+			return true
+		}
+	}
+	return false
+}
+
+// WasmExceptions returns whether this is a WebAssembly target with the
+// exception-handling feature enabled (for example using
+// -llvm-features=+exception-handling), which is used to implement recover()
+// instead of the setjmp/longjmp construct used on other architectures.
+func (c *Config) WasmExceptions() bool {
+	if !strings.HasPrefix(c.Triple(), "wasm") {
+		return false
+	}
+	for _, feature := range strings.Split(c.Features(), ",") {
+		if feature == "+exception-handling" {
+			return true
+		}
+	}
+	return false
+}
+
+// WasiLibcSysroot returns the wasi-libc sysroot directory, relative to
+// TINYGOROOT.
+func (c *Config) WasiLibcSysroot() string {
+	if c.WasmThreads() {
+		return "lib/wasi-libc/sysroot-threads"
+	}
+	return "lib/wasi-libc/sysroot"
+}
+
 // Serial returns the serial implementation for this build configuration: uart,
 // usb (meaning USB-CDC), or none.
 func (c *Config) Serial() string {
@@ -165,12 +214,45 @@ func (c *Config) OptLevels() (optLevel, sizeLevel int, inlinerThreshold uint) {
 }
 
 // PanicStrategy returns the panic strategy selected for this target. Valid
-// values are "print" (print the panic value, then exit) or "trap" (issue a trap
-// instruction).
+// values are "print" (print the panic value, then exit), "trap" (issue a trap
+// instruction) or "reset" (store the panic in a reserved RAM area and reset the
+// chip).
 func (c *Config) PanicStrategy() string {
 	return c.Options.PanicStrategy
 }
 
+// Sanitize returns the sanitizer that the program is instrumented with: "" (no
+// sanitizer), "address" (AddressSanitizer) or "undefined" (the minimal runtime
+// of UndefinedBehaviorSanitizer, for C code only).
+func (c *Config) Sanitize() string {
+	return c.Options.Sanitize
+}
+
+// PGO returns the profile-guided optimization mode: "" (disabled),
+// "instrument" (write an LLVM profile when the program exits) or the path of
+// an indexed LLVM profile (.profdata) to optimize the program with.
+func (c *Config) PGO() string {
+	return c.Options.PGO
+}
+
+// BuildMode returns the kind of output file: "default" (an executable),
+// "c-archive" (a static library) or "c-shared" (a shared library). Libraries
+// export the functions marked with //export and have no main function.
+func (c *Config) BuildMode() string {
+	if c.Options.BuildMode != "" {
+		return c.Options.BuildMode
+	}
+	return "default"
+}
+
+// PositionIndependentLibrary returns whether the build mode produces a
+// library that must be built as position independent code. This is not the
+// case for WebAssembly, where -buildmode=c-shared produces a reactor module
+// that is linked like a regular program.
+func (c *Config) PositionIndependentLibrary() bool {
+	return c.BuildMode() != "default" && !strings.HasPrefix(c.Triple(), "wasm")
+}
+
 // AutomaticStackSize returns whether goroutine stack sizes should be determined
 // automatically at compile time, if possible. If it is false, no attempt is
 // made.
@@ -190,6 +272,27 @@ func (c *Config) StackSize() uint64 {
 	return c.Target.DefaultStackSize
 }
 
+// SizeLimits returns the flash and RAM budget of the program in bytes. A limit
+// of zero means there is no limit.
+//
+// The RAM budget covers everything that is reserved in RAM at link time: data,
+// bss and the C stack (the stack of the main goroutine on baremetal targets).
+// The heap is not included, as it uses whatever RAM is left. The flash budget
+// counts from the start of the program: there is no way to specify a flash
+// start address, so to reserve room for a bootloader the limit has to be
+// lowered by the size of the bootloader.
+func (c *Config) SizeLimits() (flash, ram uint64) {
+	flash = c.Target.FlashLimit
+	if c.Options.FlashLimit != 0 {
+		flash = c.Options.FlashLimit
+	}
+	ram = c.Target.RAMLimit
+	if c.Options.RAMLimit != 0 {
+		ram = c.Options.RAMLimit
+	}
+	return flash, ram
+}
+
 // RP2040BootPatch returns whether the RP2040 boot patch should be applied that
 // calculates and patches in the checksum for the 2nd stage bootloader.
 func (c *Config) RP2040BootPatch() bool {
@@ -220,6 +323,10 @@ func (c *Config) LibcPath(name string) (path string, precompiled bool) {
 	if c.ABI() != "" {
 		archname += "-" + c.ABI()
 	}
+	if c.PositionIndependentLibrary() {
+		// Libraries are built as position independent code.
+		archname += "-pic"
+	}
 
 	// Try to load a precompiled library.
 	precompiledDir := filepath.Join(goenv.Get("TINYGOROOT"), "pkg", archname, name)
@@ -242,6 +349,12 @@ func (c *Config) DefaultBinaryExtension() string {
 		// WebAssembly files always have the .wasm file extension.
 		return ".wasm"
 	}
+	switch c.BuildMode() {
+	case "c-archive":
+		return ".a"
+	case "c-shared":
+		return ".so"
+	}
 	if len(parts) >= 3 && parts[2] == "windows" {
 		// Windows uses .exe.
 		return ".exe"
@@ -292,7 +405,9 @@ func (c *Config) CFlags() []string {
 		)
 	case "wasi-libc":
 		root := goenv.Get("TINYGOROOT")
-		cflags = append(cflags, "--sysroot="+root+"/lib/wasi-libc/sysroot")
+		cflags = append(cflags, "--sysroot="+filepath.Join(root, c.WasiLibcSysroot()))
+	case "wasmbuiltins":
+		// There is no libc, only the few builtins that LLVM needs.
 	case "mingw-w64":
 		root := goenv.Get("TINYGOROOT")
 		path, _ := c.LibcPath("mingw-w64")
@@ -332,6 +447,19 @@ func (c *Config) CFlags() []string {
 	if c.ABI() != "" {
 		cflags = append(cflags, "-mabi="+c.ABI())
 	}
+	// Instrument C code for the sanitizer in use. The sanitizer runtimes are
+	// built by TinyGo (see builder/sanitizers.go) and don't support everything
+	// the compiler-rt runtimes support: fake stacks for stack-use-after-return
+	// and the full UBSan runtime.
+	switch c.Sanitize() {
+	case "address":
+		cflags = append(cflags, "-fsanitize=address", "-fsanitize-address-use-after-return=never")
+	case "undefined":
+		cflags = append(cflags, "-fsanitize=undefined", "-fsanitize-minimal-runtime")
+	}
+	if c.PositionIndependentLibrary() {
+		cflags = append(cflags, "-fPIC")
+	}
 	return cflags
 }
 
@@ -352,6 +480,19 @@ func (c *Config) LDFlags() []string {
 	return ldflags
 }
 
+// WITPackage returns the path to the WIT package used to turn the wasm module
+// into a component, or the empty string if the target doesn't use the
+// component model.
+func (c *Config) WITPackage() string {
+	return strings.ReplaceAll(c.Target.WITPackage, "{root}", goenv.Get("TINYGOROOT"))
+}
+
+// WITWorld returns the WIT world the component targets, such as
+// wasi:cli/command.
+func (c *Config) WITWorld() string {
+	return c.Target.WITWorld
+}
+
 // ExtraFiles returns the list of extra files to be built and linked with the
 // executable. This can include extra C and assembly files.
 func (c *Config) ExtraFiles() []string {
@@ -485,6 +626,11 @@ func (c *Config) CodeModel() string {
 // RelocationModel returns the relocation model in use on this platform. Valid
 // values are "static", "pic", "dynamicnopic".
 func (c *Config) RelocationModel() string {
+	if c.PositionIndependentLibrary() {
+		// Libraries may be linked into a shared library or a position
+		// independent executable.
+		return "pic"
+	}
 	if c.Target.RelocationModel != "" {
 		return c.Target.RelocationModel
 	}
@@ -545,4 +691,10 @@ type TestConfig struct {
 	BenchTime         string
 	BenchMem          bool
 	Shuffle           string
+	JSON              bool     // -json: print test results as JSON events, like go test -json
+	Fuzz              string   // -fuzz: regexp of the fuzz test to fuzz
+	FuzzTime          string   // -fuzztime: time or number of iterations (like 100x) to spend fuzzing
+	CoverMode         string   // -cover and -covermode: "" (no coverage), "set" or "count"
+	CoverPackages     []string // -coverpkg: packages to instrument (default: the package under test)
+	CoverProfile      string   // -coverprofile: file to write the coverage profile to
 }
@@ -11,9 +11,12 @@ var (
 	validGCOptions            = []string{"none", "leaking", "conservative", "custom", "precise"}
 	validSchedulerOptions     = []string{"none", "tasks", "asyncify"}
 	validSerialOptions        = []string{"none", "uart", "usb"}
-	validPrintSizeOptions     = []string{"none", "short", "full"}
-	validPanicStrategyOptions = []string{"print", "trap"}
+	validPrintSizeOptions     = []string{"none", "short", "full", "json"}
+	validPanicStrategyOptions = []string{"print", "trap", "reset"}
 	validOptOptions           = []string{"none", "0", "1", "2", "s", "z"}
+	validSanitizeOptions      = []string{"address", "undefined"}
+	validBuildModeOptions     = []string{"default", "c-archive", "c-shared"}
+	validWasmRuntimeOptions   = []string{"emulator", "builtin"}
 )
 
 // Options contains extra options to give to the compiler. These options are
@@ -27,6 +30,10 @@ type Options struct {
 	Opt             string
 	GC              string
 	PanicStrategy   string
+	Sanitize        string // -sanitize flag: address or undefined
+	PGO             string // -pgo flag: instrument, or the path of an LLVM profile
+	BuildMode       string // -buildmode flag: default, c-archive or c-shared
+	WasmRuntime     string // -wasm-runtime flag: emulator, or builtin to run WASI binaries in-process
 	Scheduler       string
 	StackSize       uint64 // goroutine stack size (if none could be automatically determined)
 	Serial          string
@@ -40,8 +47,15 @@ type Options struct {
 	Semaphore       chan struct{}                    `json:"-"` // -p flag controls cap
 	Debug           bool
 	PrintSizes      string
+	SizeReport      string         // path of the HTML size report (tinygo size-report)
+	FlashLimit      uint64         // -size-limit flag, overrides the target flash-limit
+	RAMLimit        uint64         // -size-limit flag, overrides the target ram-limit
 	PrintAllocs     *regexp.Regexp // regexp string
+	PrintChecks     *regexp.Regexp // regexp string
 	PrintStacks     bool
+	Why             string // -why flag: function or global to explain
+	Trace           string // -trace flag: path of the trace file of the build jobs
+	CacheMaxSize    uint64 // TINYGOCACHEMAX: trim the cache to this size after a build (0 means unlimited)
 	Tags            []string
 	GlobalValues    map[string]map[string]string // map[pkgpath]map[varname]value
 	TestConfig      TestConfig
@@ -108,6 +122,24 @@ func (o *Options) Verify() error {
 		}
 	}
 
+	if o.Sanitize != "" {
+		if !isInArray(validSanitizeOptions, o.Sanitize) {
+			return fmt.Errorf("invalid -sanitize=%s: valid values are %s", o.Sanitize, strings.Join(validSanitizeOptions, ", "))
+		}
+	}
+
+	if o.BuildMode != "" {
+		if !isInArray(validBuildModeOptions, o.BuildMode) {
+			return fmt.Errorf("invalid -buildmode=%s: valid values are %s", o.BuildMode, strings.Join(validBuildModeOptions, ", "))
+		}
+	}
+
+	if o.WasmRuntime != "" {
+		if !isInArray(validWasmRuntimeOptions, o.WasmRuntime) {
+			return fmt.Errorf("invalid -wasm-runtime=%s: valid values are %s", o.WasmRuntime, strings.Join(validWasmRuntimeOptions, ", "))
+		}
+	}
+
 	return nil
 }
 
@@ -11,8 +11,11 @@ func TestVerifyOptions(t *testing.T) {
 
 	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, conservative, custom, precise`)
 	expectedSchedulerError := errors.New(`invalid scheduler option 'incorrect': valid values are none, tasks, asyncify`)
-	expectedPrintSizeError := errors.New(`invalid size option 'incorrect': valid values are none, short, full`)
-	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap`)
+	expectedPrintSizeError := errors.New(`invalid size option 'incorrect': valid values are none, short, full, json`)
+	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap, reset`)
+	expectedSanitizeError := errors.New(`invalid -sanitize=incorrect: valid values are address, undefined`)
+	expectedBuildModeError := errors.New(`invalid -buildmode=incorrect: valid values are default, c-archive, c-shared`)
+	expectedWasmRuntimeError := errors.New(`invalid -wasm-runtime=incorrect: valid values are emulator, builtin`)
 
 	testCases := []struct {
 		name          string
@@ -117,6 +120,63 @@ func TestVerifyOptions(t *testing.T) {
 				PanicStrategy: "trap",
 			},
 		},
+		{
+			name: "PanicOptionReset",
+			opts: compileopts.Options{
+				PanicStrategy: "reset",
+			},
+		},
+		{
+			name: "InvalidSanitizeOption",
+			opts: compileopts.Options{
+				Sanitize: "incorrect",
+			},
+			expectedError: expectedSanitizeError,
+		},
+		{
+			name: "SanitizeOptionAddress",
+			opts: compileopts.Options{
+				Sanitize: "address",
+			},
+		},
+		{
+			name: "SanitizeOptionUndefined",
+			opts: compileopts.Options{
+				Sanitize: "undefined",
+			},
+		},
+		{
+			name: "InvalidBuildModeOption",
+			opts: compileopts.Options{
+				BuildMode: "incorrect",
+			},
+			expectedError: expectedBuildModeError,
+		},
+		{
+			name: "BuildModeOptionCArchive",
+			opts: compileopts.Options{
+				BuildMode: "c-archive",
+			},
+		},
+		{
+			name: "BuildModeOptionCShared",
+			opts: compileopts.Options{
+				BuildMode: "c-shared",
+			},
+		},
+		{
+			name: "InvalidWasmRuntimeOption",
+			opts: compileopts.Options{
+				WasmRuntime: "incorrect",
+			},
+			expectedError: expectedWasmRuntimeError,
+		},
+		{
+			name: "WasmRuntimeOptionBuiltin",
+			opts: compileopts.Options{
+				WasmRuntime: "builtin",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
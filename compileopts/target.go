@@ -45,6 +45,8 @@ type TargetSpec struct {
 	ExtraFiles       []string `json:"extra-files,omitempty"`
 	RP2040BootPatch  *bool    `json:"rp2040-boot-patch,omitempty"` // Patch RP2040 2nd stage bootloader checksum
 	Emulator         string   `json:"emulator,omitempty"`
+	WITPackage       string   `json:"wit-package,omitempty"` // WIT package to turn the wasm module into a component
+	WITWorld         string   `json:"wit-world,omitempty"`   // WIT world that the component targets
 	FlashCommand     string   `json:"flash-command,omitempty"`
 	GDB              []string `json:"gdb,omitempty"`
 	PortReset        string   `json:"flash-1200-bps-reset,omitempty"`
@@ -62,6 +64,8 @@ type TargetSpec struct {
 	JLinkDevice      string   `json:"jlink-device,omitempty"`
 	CodeModel        string   `json:"code-model,omitempty"`
 	RelocationModel  string   `json:"relocation-model,omitempty"`
+	FlashLimit       uint64   `json:"flash-limit,omitempty"` // flash budget in bytes, checked after linking
+	RAMLimit         uint64   `json:"ram-limit,omitempty"`   // RAM budget in bytes (data, bss and C stack), checked after linking
 }
 
 // overrideProperties overrides all properties that are set in child into itself using reflection.
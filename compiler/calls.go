@@ -82,6 +82,9 @@ func (b *builder) createCall(fnType llvm.Type, fn llvm.Value, args []llvm.Value,
 // the call resulted in a panic.
 func (b *builder) createInvoke(fnType llvm.Type, fn llvm.Value, args []llvm.Value, name string) llvm.Value {
 	if b.hasDeferFrame() {
+		if b.wasmExceptions() {
+			return b.createWasmInvoke(fnType, fn, args, name)
+		}
 		b.createInvokeCheckpoint()
 	}
 	return b.createCall(fnType, fn, args, name)
@@ -51,10 +51,16 @@ type Config struct {
 
 	// Various compiler options that determine how code is generated.
 	Scheduler          string
+	PanicStrategy      string
 	AutomaticStackSize bool
 	DefaultStackSize   uint64
 	NeedsStackObjects  bool
-	Debug              bool // Whether to emit debug information in the LLVM module.
+	Debug              bool   // Whether to emit debug information in the LLVM module.
+	CoverMode          string // Code coverage instrumentation: "" (disabled), "set" or "count".
+	Fuzz               bool   // Instrument basic blocks for coverage-guided fuzzing.
+	SanitizeAddress    bool   // Mark functions to be instrumented by AddressSanitizer.
+	LibraryExports     bool   // Wrap exported functions for calls from C (-buildmode=c-archive, c-shared).
+	Safepoints         bool   // Let the GC stop the thread at the start of every loop iteration (WebAssembly threads).
 }
 
 // compilerContext contains function-independent data that should still be
@@ -88,20 +94,32 @@ type compilerContext struct {
 	pkg              *types.Package
 	packageDir       string // directory for this package
 	runtimePkg       *types.Package
+	deferStackType   types.Type // result type of ssa:deferstack, once seen
+
+	// Code coverage instrumentation (see coverage.go).
+	coverageCounters   llvm.Value
+	coverageBlocks     []coverageBlock
+	coverageStatements []int // number of statements in each coverage block
+	coverageIndices    map[coverageBlock]int
+
+	// Fuzzing instrumentation (see fuzz.go).
+	fuzzCounters    llvm.Value
+	numFuzzCounters int
 }
 
 // newCompilerContext returns a new compiler context ready for use, most
 // importantly with a newly created LLVM context and module.
 func newCompilerContext(moduleName string, machine llvm.TargetMachine, config *Config, dumpSSA bool) *compilerContext {
 	c := &compilerContext{
-		Config:        config,
-		DumpSSA:       dumpSSA,
-		difiles:       make(map[string]llvm.Metadata),
-		ditypes:       make(map[types.Type]llvm.Metadata),
-		machine:       machine,
-		targetData:    machine.CreateTargetData(),
-		functionInfos: map[*ssa.Function]functionInfo{},
-		astComments:   map[string]*ast.CommentGroup{},
+		Config:          config,
+		DumpSSA:         dumpSSA,
+		difiles:         make(map[string]llvm.Metadata),
+		ditypes:         make(map[types.Type]llvm.Metadata),
+		machine:         machine,
+		targetData:      machine.CreateTargetData(),
+		functionInfos:   map[*ssa.Function]functionInfo{},
+		astComments:     map[string]*ast.CommentGroup{},
+		coverageIndices: map[coverageBlock]int{},
 	}
 
 	c.ctx = llvm.NewContext()
@@ -158,6 +176,7 @@ type builder struct {
 	deferFrame        llvm.Value
 	stackChainAlloca  llvm.Value
 	landingpad        llvm.BasicBlock
+	unwindpad         llvm.BasicBlock // catch-all handler with wasmExceptions
 	difunc            llvm.Metadata
 	dilocals          map[*types.Var]llvm.Metadata
 	initInlinedAt     llvm.Metadata            // fake inlinedAt position
@@ -171,6 +190,10 @@ type builder struct {
 	deferBuiltinFuncs map[ssa.Value]deferBuiltin
 	runDefersBlock    []llvm.BasicBlock
 	afterDefersBlock  []llvm.BasicBlock
+
+	// Whether defer statements in range-over-func loop bodies push deferred
+	// calls onto the defer stack of this function.
+	hasRangeFuncDefers bool
 }
 
 func newBuilder(c *compilerContext, irbuilder llvm.Builder, f *ssa.Function) *builder {
@@ -237,6 +260,10 @@ func NewTargetMachine(config *Config) (llvm.TargetMachine, error) {
 		relocationModel = llvm.RelocDynamicNoPic
 	}
 
+	if strings.HasPrefix(config.Triple, "wasm") && strings.Contains(","+config.Features+",", ",+exception-handling,") {
+		enableWasmExceptions()
+	}
+
 	machine := target.CreateTargetMachine(config.Triple, config.CPU, config.Features, llvm.CodeGenLevelDefault, relocationModel, codeModel)
 	return machine, nil
 }
@@ -310,6 +337,8 @@ func CompilePackage(moduleName string, pkg *loader.Package, ssaPkg *ssa.Package,
 	irbuilder := c.ctx.NewBuilder()
 	defer irbuilder.Dispose()
 	c.createPackage(irbuilder, ssaPkg)
+	c.createCoverageRegistration(ssaPkg)
+	c.createFuzzCountersRegistration(ssaPkg)
 
 	// see: https://reviews.llvm.org/D18355
 	if c.Debug {
@@ -442,6 +471,9 @@ func (c *compilerContext) makeLLVMType(goType types.Type) llvm.Type {
 		}
 		return c.getLLVMType(typ.Underlying())
 	case *types.Pointer:
+		if c.deferStackType != nil && typ == c.deferStackType {
+			return c.getDeferStackType()
+		}
 		if c.hasTypedPointers {
 			ptrTo := c.getLLVMType(typ.Elem())
 			return llvm.PointerType(ptrTo, 0)
@@ -863,6 +895,9 @@ func (c *compilerContext) createPackage(irbuilder llvm.Builder, pkg *ssa.Package
 				continue
 			}
 			b.createFunction()
+			if b.info.libraryExport != "" {
+				b.createLibraryExport()
+			}
 		case *ssa.Type:
 			if types.IsInterface(member.Type()) {
 				// Interfaces don't have concrete methods.
@@ -1178,6 +1213,13 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		b.llvmFn.AddFunctionAttr(noinline)
 	}
 
+	if b.SanitizeAddress {
+		// The instrumentation itself is inserted later, while optimizing the
+		// whole program. It only touches functions with this attribute.
+		sanitize := b.ctx.CreateEnumAttribute(llvm.AttributeKindID("sanitize_address"), 0)
+		b.llvmFn.AddFunctionAttr(sanitize)
+	}
+
 	if b.info.interrupt {
 		// Mark this function as an interrupt.
 		// This is necessary on MCUs that don't push caller saved registers when
@@ -1287,18 +1329,19 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		}
 	}
 
-	if b.fn.Recover != nil {
-		// This function has deferred function calls. Set some things up for
-		// them.
-		b.deferInitFunc()
-	}
-
 	if b.NeedsStackObjects {
 		// Create a dummy alloca that will be used in runtime.trackPointer.
 		// It is necessary to pass a dummy alloca to runtime.trackPointer
 		// because runtime.trackPointer is replaced by an alloca store.
 		b.stackChainAlloca = b.CreateAlloca(b.ctx.Int8Type(), "stackalloc")
 	}
+
+	b.findDeferStack()
+	if b.fn.Recover != nil {
+		// This function has deferred function calls. Set some things up for
+		// them.
+		b.deferInitFunc()
+	}
 }
 
 // createFunction builds the LLVM IR implementation for this function. The
@@ -1308,13 +1351,35 @@ func (b *builder) createFunction() {
 	b.createFunctionStart(false)
 
 	// Fill blocks with instructions.
+	covered := b.isCovered()
 	for _, block := range b.fn.DomPreorder() {
 		if b.DumpSSA {
 			fmt.Printf("%d: %s:\n", block.Index, block.Comment)
 		}
 		b.SetInsertPointAtEnd(b.blockEntries[block])
 		b.currentBlock = block
+		needsCounters := covered || b.Fuzz
+		needsSafepoint := b.Safepoints && b.pkg.Path() != "runtime" && isLoopHeader(block)
 		for _, instr := range block.Instrs {
+			if _, ok := instr.(*ssa.Phi); (needsCounters || needsSafepoint) && !ok {
+				// Update the coverage and fuzzing counters of this block,
+				// after the phi nodes (which must be at the start of the
+				// block).
+				if covered {
+					b.createCoverageCounter(block)
+				}
+				if b.Fuzz {
+					b.createFuzzCounter()
+				}
+				if needsSafepoint {
+					// Let another thread run the GC if it is waiting for
+					// this thread to stop, so that a loop that doesn't call
+					// into the runtime can't delay GC cycles indefinitely.
+					b.createRuntimeCall("gcSafepoint", nil, "")
+				}
+				needsCounters = false
+				needsSafepoint = false
+			}
 			if instr, ok := instr.(*ssa.DebugRef); ok {
 				if !b.Debug {
 					continue
@@ -1398,6 +1463,17 @@ func (b *builder) createFunction() {
 	}
 }
 
+// isLoopHeader returns whether the block is the target of a loop back-edge,
+// that is, a jump from a block that it dominates.
+func isLoopHeader(block *ssa.BasicBlock) bool {
+	for _, pred := range block.Preds {
+		if block.Dominates(pred) {
+			return true
+		}
+	}
+	return false
+}
+
 // posser is an interface that's implemented by both ssa.Value and
 // ssa.Instruction. It is implemented by everything that has a Pos() method,
 // which is all that getPos() needs.
@@ -1499,6 +1575,12 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 		b.createRuntimeInvoke("_panic", []llvm.Value{value}, "")
 		b.CreateUnreachable()
 	case *ssa.Return:
+		if b.hasRangeFuncDefers && instr.Block() != b.fn.Recover && !hasRunDefers(instr.Block()) {
+			// The SSA builder only emits rundefers instructions when the
+			// function itself contains a defer statement, not when all
+			// deferred calls come from range-over-func loop bodies.
+			b.createRunDefersBlock()
+		}
 		if b.hasDeferFrame() {
 			b.createRuntimeCall("destroyDeferFrame", []llvm.Value{b.deferFrame}, "")
 		}
@@ -1516,14 +1598,7 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 			b.CreateRet(retVal)
 		}
 	case *ssa.RunDefers:
-		// Note where we're going to put the rundefers block
-		run := b.insertBasicBlock("rundefers.block")
-		b.CreateBr(run)
-		b.runDefersBlock = append(b.runDefersBlock, run)
-
-		after := b.insertBasicBlock("rundefers.after")
-		b.SetInsertPointAtEnd(after)
-		b.afterDefersBlock = append(b.afterDefersBlock, after)
+		b.createRunDefersBlock()
 	case *ssa.Send:
 		b.createChanSend(instr)
 	case *ssa.Store:
@@ -1770,6 +1845,15 @@ func (b *builder) createBuiltin(argTypes []types.Type, argValues []llvm.Value, c
 	case "ssa:wrapnilchk":
 		// TODO: do an actual nil check?
 		return argValues[0], nil
+	case "ssa:deferstack":
+		// The defer stack of this function, used by range-over-func loop
+		// bodies to push deferred calls onto. It is a pointer to the head of
+		// the linked list of deferred calls.
+		if b.fn.Recover == nil {
+			// None of the loop bodies contain a defer statement.
+			return llvm.ConstNull(b.getDeferStackType()), nil
+		}
+		return b.deferPtr, nil
 
 	// Builtins from the unsafe package.
 	case "Add": // unsafe.Add
@@ -1876,9 +1960,27 @@ func (b *builder) createFunctionCall(instr *ssa.CallCommon) (llvm.Value, error)
 				supportsRecover = 1
 			}
 			return llvm.ConstInt(b.ctx.Int1Type(), supportsRecover, false), nil
+		case name == "runtime.panicStrategy":
+			// These constants are defined in src/runtime/panic.go.
+			panicStrategy := map[string]uint64{
+				"print": 1, // panicStrategyPrint
+				"trap":  2, // panicStrategyTrap
+				"reset": 3, // panicStrategyReset
+			}[b.Config.PanicStrategy]
+			return llvm.ConstInt(b.ctx.Int8Type(), panicStrategy, false), nil
 		case name == "runtime/interrupt.New":
 			return b.createInterruptGlobal(instr)
 		}
+		if origin := fn.Origin(); origin != nil && b.Scheduler == "none" {
+			// The TinyGo implementation of iter.Pull and iter.Pull2 runs the
+			// iterator in a separate goroutine. Report this here, at the call
+			// site, instead of letting the goroutine start inside the iter
+			// package fail later on.
+			switch originName := origin.RelString(nil); originName {
+			case "iter.Pull", "iter.Pull2":
+				b.addError(instr.Pos(), originName+" is not supported with -scheduler=none")
+			}
+		}
 
 		calleeType, callee = b.getFunction(fn)
 		info := b.getFunctionInfo(fn)
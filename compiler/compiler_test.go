@@ -182,14 +182,41 @@ func filterIrrelevantIRLines(lines []string) []string {
 func TestCompilerErrors(t *testing.T) {
 	t.Parallel()
 
+	// Determine Go minor version (e.g. 16 in go1.16.3).
+	_, goMinor, err := goenv.GetGorootVersion()
+	if err != nil {
+		t.Fatal("could not read Go version:", err)
+	}
+
+	tests := []testCase{
+		{"errors.go", "wasi", ""},
+		{"errors-js.go", "wasm", ""},
+	}
+	if goMinor >= 23 {
+		tests = append(tests, testCase{"go1.23-errors.go", "wasm", "none"})
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.file, func(t *testing.T) {
+			t.Parallel()
+			testCompilerErrors(t, tc)
+		})
+	}
+}
+
+// Compile a Go file with errors, and check that the errors match the
+// "// ERROR: " comments in the file.
+func testCompilerErrors(t *testing.T, tc testCase) {
 	// Read expected errors from the test file.
 	var expectedErrors []string
-	errorsFile, err := os.ReadFile("testdata/errors.go")
+	errorsFile, err := os.ReadFile("testdata/" + tc.file)
 	if err != nil {
 		t.Error(err)
 	}
 	errorsFileString := strings.ReplaceAll(string(errorsFile), "\r\n", "\n")
 	for _, line := range strings.Split(errorsFileString, "\n") {
+		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "// ERROR: ") {
 			expectedErrors = append(expectedErrors, strings.TrimPrefix(line, "// ERROR: "))
 		}
@@ -197,22 +224,26 @@ func TestCompilerErrors(t *testing.T) {
 
 	// Compile the Go file with errors.
 	options := &compileopts.Options{
-		Target: "wasm",
+		Target:    tc.target,
+		Scheduler: tc.scheduler,
 	}
-	_, errs := testCompilePackage(t, options, "errors.go")
+	_, errs := testCompilePackage(t, options, tc.file)
 
 	// Check whether the actual errors match the expected errors.
 	expectedErrorsIdx := 0
 	for _, err := range errs {
 		err := err.(types.Error)
 		position := err.Fset.Position(err.Pos)
-		position.Filename = "errors.go" // don't use a full path
+		position.Filename = tc.file // don't use a full path
 		if expectedErrorsIdx >= len(expectedErrors) || expectedErrors[expectedErrorsIdx] != err.Msg {
 			t.Errorf("unexpected compiler error: %s: %s", position.String(), err.Msg)
 			continue
 		}
 		expectedErrorsIdx++
 	}
+	if expectedErrorsIdx != len(expectedErrors) {
+		t.Errorf("expected %d errors, got %d", len(expectedErrors), expectedErrorsIdx)
+	}
 }
 
 // Build a package given a number of compiler options and a file.
@@ -0,0 +1,164 @@
+package compiler
+
+// This file implements code coverage instrumentation (tinygo test -cover).
+// Every basic block in the covered package gets a counter that is incremented
+// (or set) when the block runs. The counters of a package, together with the
+// source range of every counter, are registered with the runtime from the
+// package initializer so that they can be written out at the end of the test.
+//
+// Unlike the go tool, which rewrites the source code to insert counters per
+// statement block, the source ranges here are derived from the positions of
+// the SSA instructions in each basic block. The result is written in the same
+// format as `go test -coverprofile` so that `go tool cover` can read it.
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// coverageBlock is a source range that is instrumented for code coverage. It
+// corresponds to one or more SSA basic blocks with exactly the same range.
+type coverageBlock struct {
+	file      string // import path + file name, as used by go tool cover
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+// isCovered returns whether the function that is being compiled should be
+// instrumented for code coverage. Only functions that appear in the source
+// code of the package are instrumented, not test files or generated wrappers.
+// Instances of generic functions are not instrumented either, as they may be
+// compiled in a different package.
+func (b *builder) isCovered() bool {
+	if b.CoverMode == "" || b.fn.Synthetic != "" || b.fn.Pkg == nil || b.fn.Pkg.Pkg != b.pkg {
+		return false
+	}
+	filename := b.program.Fset.Position(b.fn.Pos()).Filename
+	return filename != "" && !strings.HasSuffix(filename, "_test.go")
+}
+
+// createCoverageCounter inserts code to update the coverage counter of the
+// given basic block at the current insert position. Blocks that don't have
+// any source position (for example, blocks that only jump to another block)
+// are not counted.
+func (b *builder) createCoverageCounter(block *ssa.BasicBlock) {
+	var start, end token.Position
+	lines := make(map[int]struct{})
+	for _, instr := range block.Instrs {
+		pos := instr.Pos()
+		if !pos.IsValid() {
+			continue
+		}
+		position := b.program.Fset.Position(pos)
+		if start.Filename == "" {
+			start, end = position, position
+		} else if position.Filename != start.Filename {
+			continue // shouldn't happen
+		}
+		if position.Line < start.Line || (position.Line == start.Line && position.Column < start.Column) {
+			start = position
+		}
+		if position.Line > end.Line || (position.Line == end.Line && position.Column > end.Column) {
+			end = position
+		}
+		lines[position.Line] = struct{}{}
+	}
+	if start.Filename == "" {
+		return
+	}
+
+	// Find the counter for this source range, or create a new one.
+	key := coverageBlock{
+		file:      path.Join(b.pkg.Path(), filepath.Base(start.Filename)),
+		startLine: start.Line,
+		startCol:  start.Column,
+		endLine:   end.Line,
+		endCol:    end.Column + 1, // the end column is exclusive
+	}
+	index, ok := b.coverageIndices[key]
+	if !ok {
+		index = len(b.coverageBlocks)
+		b.coverageIndices[key] = index
+		b.coverageBlocks = append(b.coverageBlocks, key)
+		b.coverageStatements = append(b.coverageStatements, len(lines))
+	}
+
+	// The counters global is a placeholder until the whole package has been
+	// compiled, at which point the number of counters is known.
+	counterType := b.ctx.Int32Type()
+	placeholderType := llvm.ArrayType(counterType, 0)
+	if b.coverageCounters.IsNil() {
+		b.coverageCounters = llvm.AddGlobal(b.mod, placeholderType, b.pkg.Path()+"$coverage.tmp")
+	}
+	counter := b.CreateGEP(placeholderType, b.coverageCounters, []llvm.Value{
+		llvm.ConstInt(b.ctx.Int32Type(), 0, false),
+		llvm.ConstInt(b.ctx.Int32Type(), uint64(index), false),
+	}, "")
+	value := llvm.ConstInt(counterType, 1, false)
+	if b.CoverMode == "count" {
+		value = b.CreateAdd(b.CreateLoad(counterType, counter, ""), value, "")
+	}
+	b.CreateStore(value, counter)
+}
+
+// createCoverageRegistration creates the global with all coverage counters of
+// this package and registers it with the runtime at the start of the package
+// initializer. It must be called after all functions in the package have been
+// compiled.
+func (c *compilerContext) createCoverageRegistration(ssaPkg *ssa.Package) {
+	if len(c.coverageBlocks) == 0 {
+		return
+	}
+
+	// Replace the placeholder with the real counters global.
+	countersType := llvm.ArrayType(c.ctx.Int32Type(), len(c.coverageBlocks))
+	counters := llvm.AddGlobal(c.mod, countersType, c.pkg.Path()+"$coverage")
+	counters.SetInitializer(llvm.ConstNull(countersType))
+	counters.SetLinkage(llvm.InternalLinkage)
+	c.coverageCounters.ReplaceAllUsesWith(llvm.ConstBitCast(counters, c.coverageCounters.Type()))
+	c.coverageCounters.EraseFromParentAsGlobal()
+	c.coverageCounters = counters
+
+	// Describe the source range of every counter, one line per counter, in
+	// the format used by go tool cover (without the count).
+	var blocks strings.Builder
+	for i, block := range c.coverageBlocks {
+		fmt.Fprintf(&blocks, "%s:%d.%d,%d.%d %d\n", block.file, block.startLine, block.startCol, block.endLine, block.endCol, c.coverageStatements[i])
+	}
+
+	blocksValue := c.createConst(ssa.NewConst(constant.MakeString(blocks.String()), types.Typ[types.String]), token.NoPos)
+	c.createInitRuntimeCall(ssaPkg, "$coverage.register", "registerCoverage", []llvm.Value{
+		llvm.ConstBitCast(counters, c.i8ptrType),
+		llvm.ConstInt(c.uintptrType, uint64(len(c.coverageBlocks)), false),
+		blocksValue,
+	})
+}
+
+// createInitRuntimeCall calls the given runtime function with the given
+// (constant) arguments at the start of the package initializer, before anything
+// in the package can run. The call is made from a separate function without
+// debug information, so that the call to it can be inserted into the package
+// initializer without a debug location.
+func (c *compilerContext) createInitRuntimeCall(ssaPkg *ssa.Package, suffix, fnName string, args []llvm.Value) {
+	irbuilder := c.ctx.NewBuilder()
+	defer irbuilder.Dispose()
+	b := newBuilder(c, irbuilder, ssaPkg.Func("init"))
+	fn := llvm.AddFunction(c.mod, c.pkg.Path()+suffix, llvm.FunctionType(c.ctx.VoidType(), nil, false))
+	fn.SetLinkage(llvm.InternalLinkage)
+	irbuilder.SetInsertPointAtEnd(c.ctx.AddBasicBlock(fn, "entry"))
+	b.createRuntimeCall(fnName, args, "")
+	irbuilder.CreateRetVoid()
+
+	irbuilder.SetInsertPointBefore(b.llvmFn.EntryBasicBlock().FirstInstruction())
+	irbuilder.CreateCall(fn.GlobalValueType(), fn, nil, "")
+}
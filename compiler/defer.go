@@ -12,6 +12,13 @@ package compiler
 //   * On return, runtime.rundefers is called which calls all deferred functions
 //     from the head of the linked list until it has gone through all defer
 //     frames.
+// The body of a range-over-func loop is compiled as a separate (yield)
+// function, but defer statements in this body must run when the enclosing
+// function returns. They are pushed onto the linked list of the enclosing
+// function instead, which is passed to the yield function as a pointer to the
+// head of the list (see ssa:deferstack). The enclosing function knows about
+// these deferred calls in advance, so they can be run in the same way as its
+// own deferred calls.
 
 import (
 	"go/types"
@@ -27,13 +34,13 @@ import (
 func (b *builder) supportsRecover() bool {
 	switch b.archFamily() {
 	case "wasm32":
-		// Probably needs to be implemented using the exception handling
-		// proposal of WebAssembly:
+		// WebAssembly can't jump back to a saved stack pointer and PC like
+		// the setjmp/longjmp construct used on other architectures, so
+		// recover is only supported using the exception handling proposal:
 		// https://github.com/WebAssembly/exception-handling
-		return false
-	case "riscv64", "xtensa":
-		// TODO: add support for these architectures
-		return false
+		// Asyncify can't be used instead, as it can only unwind and rewind
+		// the entire stack of a goroutine, not jump to a given frame.
+		return b.wasmExceptions()
 	default:
 		return true
 	}
@@ -59,9 +66,31 @@ func (b *builder) deferInitFunc() {
 	b.deferExprFuncs = make(map[ssa.Value]int)
 	b.deferBuiltinFuncs = make(map[ssa.Value]deferBuiltin)
 
+	// Register the deferred calls of range-over-func loop bodies first. They
+	// get the first callback numbers, in a fixed order, so that the yield
+	// functions can determine the callback numbers on their own.
+	for _, instr := range rangeFuncDefers(b.fn) {
+		b.allDeferFuncs = append(b.allDeferFuncs, b.getDeferredCall(instr))
+		if builtin, ok := instr.Call.Value.(*ssa.Builtin); ok && !instr.Call.IsInvoke() {
+			b.deferBuiltinFuncs[builtin] = newDeferBuiltin(instr, len(b.allDeferFuncs)-1)
+		}
+		b.hasRangeFuncDefers = true
+	}
+
 	// Create defer list pointer.
 	deferType := llvm.PointerType(b.getLLVMRuntimeType("_defer"), 0)
-	b.deferPtr = b.CreateAlloca(deferType, "deferPtr")
+	if b.hasRangeFuncDefers && b.NeedsStackObjects {
+		// The yield functions push heap allocated defer structs onto the
+		// list, which are then only referenced from the list. Put the list
+		// pointer itself on the heap and track it, so that the GC can find
+		// them.
+		size := llvm.ConstInt(b.uintptrType, b.targetData.TypeAllocSize(deferType), false)
+		alloc := b.createRuntimeCall("alloc", []llvm.Value{size, llvm.ConstNull(b.i8ptrType)}, "deferPtr.alloc")
+		b.trackPointer(alloc)
+		b.deferPtr = b.CreateBitCast(alloc, llvm.PointerType(deferType, 0), "deferPtr")
+	} else {
+		b.deferPtr = b.CreateAlloca(deferType, "deferPtr")
+	}
 	b.CreateStore(llvm.ConstPointerNull(deferType), b.deferPtr)
 
 	if b.hasDeferFrame() {
@@ -79,6 +108,20 @@ func (b *builder) deferInitFunc() {
 		// Create the landing pad block, which is where control transfers after
 		// a panic.
 		b.landingpad = b.ctx.AddBasicBlock(b.llvmFn, "lpad")
+
+		if b.wasmExceptions() {
+			// All invokes unwind to a catch-all handler, which continues at
+			// the landing pad. The personality function is required by LLVM
+			// but never called, as a catch-all handler doesn't need to
+			// inspect the exception.
+			personalityType := llvm.FunctionType(b.ctx.Int32Type(), nil, true)
+			personality := b.mod.NamedFunction("__gxx_wasm_personality_v0")
+			if personality.IsNil() {
+				personality = llvm.AddFunction(b.mod, "__gxx_wasm_personality_v0", personalityType)
+			}
+			b.llvmFn.SetPersonality(personality)
+			b.unwindpad = b.ctx.AddBasicBlock(b.llvmFn, "lpad.dispatch")
+		}
 	}
 }
 
@@ -87,6 +130,16 @@ func (b *builder) deferInitFunc() {
 // still panicking after the defers are run, the panic will be re-raised in
 // destroyDeferFrame.
 func (b *builder) createLandingPad() {
+	if b.wasmExceptions() {
+		// A panic in one of the deferred calls below continues at the
+		// landing pad again, just like with tinygo_longjmp. These calls
+		// need to unwind to a separate catch-all handler though:
+		// WebAssembly only has structured control flow, so the code after
+		// a catch can't jump back into the try block of the same catch.
+		b.createCatchAll(b.unwindpad)
+		b.unwindpad = b.ctx.AddBasicBlock(b.llvmFn, "lpad.dispatch")
+		b.createCatchAll(b.unwindpad)
+	}
 	b.SetInsertPointAtEnd(b.landingpad)
 
 	// Add debug info, if needed.
@@ -195,6 +248,31 @@ sw a2, 4(a1)
 li a0, 0
 1:`
 		constraints = "={a0},{a1},~{a1},~{a2},~{a3},~{a4},~{a5},~{a6},~{a7},~{s0},~{s1},~{s2},~{s3},~{s4},~{s5},~{s6},~{s7},~{s8},~{s9},~{s10},~{s11},~{t0},~{t1},~{t2},~{t3},~{t4},~{t5},~{t6},~{ra},~{f0},~{f1},~{f2},~{f3},~{f4},~{f5},~{f6},~{f7},~{f8},~{f9},~{f10},~{f11},~{f12},~{f13},~{f14},~{f15},~{f16},~{f17},~{f18},~{f19},~{f20},~{f21},~{f22},~{f23},~{f24},~{f25},~{f26},~{f27},~{f28},~{f29},~{f30},~{f31},~{memory}"
+	case "riscv64":
+		// Same as riscv32, but the jump PC is stored at an 8 byte offset
+		// because pointers are 8 bytes in size.
+		asmString = `
+la a2, 1f
+sd a2, 8(a1)
+li a0, 0
+1:`
+		constraints = "={a0},{a1},~{a1},~{a2},~{a3},~{a4},~{a5},~{a6},~{a7},~{s0},~{s1},~{s2},~{s3},~{s4},~{s5},~{s6},~{s7},~{s8},~{s9},~{s10},~{s11},~{t0},~{t1},~{t2},~{t3},~{t4},~{t5},~{t6},~{ra},~{f0},~{f1},~{f2},~{f3},~{f4},~{f5},~{f6},~{f7},~{f8},~{f9},~{f10},~{f11},~{f12},~{f13},~{f14},~{f15},~{f16},~{f17},~{f18},~{f19},~{f20},~{f21},~{f22},~{f23},~{f24},~{f25},~{f26},~{f27},~{f28},~{f29},~{f30},~{f31},~{memory}"
+	case "xtensa":
+		// The return address in a0 is saved as well. With the windowed ABI,
+		// tinygo_longjmp restores the register window of this function by
+		// loading a0 (which includes the window increment in the upper two
+		// bits) and a1 after all other windows have been spilled to the stack.
+		// With the call0 ABI it is simply restored.
+		asmString = `
+movi a4, 1f
+s32i a4, a3, 4
+s32i a0, a3, 8
+movi a2, 0
+1:`
+		constraints = "={a2},{a3},~{a3},~{a4},~{a5},~{a6},~{a7},~{a8},~{a9},~{a10},~{a11},~{a12},~{a13},~{a14},~{a15},~{memory}"
+		if b.hasFeature("+fp") {
+			constraints += ",~{f0},~{f1},~{f2},~{f3},~{f4},~{f5},~{f6},~{f7},~{f8},~{f9},~{f10},~{f11},~{f12},~{f13},~{f14},~{f15}"
+		}
 	default:
 		// This case should have been handled by b.supportsRecover().
 		b.addError(b.fn.Pos(), "unknown architecture for defer: "+b.archFamily())
@@ -247,24 +325,29 @@ func isInLoop(start *ssa.BasicBlock) bool {
 // createDefer emits a single defer instruction, to be run when this function
 // returns.
 func (b *builder) createDefer(instr *ssa.Defer) {
+	// Determine the defer list to push the deferred call onto. This is usually
+	// the defer list of this function, except in the body of a range-over-func
+	// loop where it is the defer list of the enclosing function.
+	deferPtr := b.deferPtr
+	var callback llvm.Value
+	isRangeFuncDefer := instr.DeferStack != nil && isRangeFuncBody(b.fn)
+	if isRangeFuncDefer {
+		deferPtr = b.getValue(instr.DeferStack, getPos(instr))
+		callback = llvm.ConstInt(b.uintptrType, uint64(rangeFuncDeferIndex(instr)), false)
+	} else {
+		callback = b.getDeferCallback(instr)
+	}
+
 	// The pointer to the previous defer struct, which we will replace to
 	// make a linked list.
 	deferType := llvm.PointerType(b.getLLVMRuntimeType("_defer"), 0)
-	next := b.CreateLoad(deferType, b.deferPtr, "defer.next")
+	next := b.CreateLoad(deferType, deferPtr, "defer.next")
 
 	var values []llvm.Value
 	valueTypes := []llvm.Type{b.uintptrType, next.Type()}
 	if instr.Call.IsInvoke() {
 		// Method call on an interface.
 
-		// Get callback type number.
-		methodName := instr.Call.Method.FullName()
-		if _, ok := b.deferInvokeFuncs[methodName]; !ok {
-			b.deferInvokeFuncs[methodName] = len(b.allDeferFuncs)
-			b.allDeferFuncs = append(b.allDeferFuncs, &instr.Call)
-		}
-		callback := llvm.ConstInt(b.uintptrType, uint64(b.deferInvokeFuncs[methodName]), false)
-
 		// Collect all values to be put in the struct (starting with
 		// runtime._defer fields, followed by the call parameters).
 		itf := b.getValue(instr.Call.Value, getPos(instr)) // interface
@@ -278,13 +361,8 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 			valueTypes = append(valueTypes, val.Type())
 		}
 
-	} else if callee, ok := instr.Call.Value.(*ssa.Function); ok {
+	} else if _, ok := instr.Call.Value.(*ssa.Function); ok {
 		// Regular function call.
-		if _, ok := b.deferFuncs[callee]; !ok {
-			b.deferFuncs[callee] = len(b.allDeferFuncs)
-			b.allDeferFuncs = append(b.allDeferFuncs, callee)
-		}
-		callback := llvm.ConstInt(b.uintptrType, uint64(b.deferFuncs[callee]), false)
 
 		// Collect all values to be put in the struct (starting with
 		// runtime._defer fields).
@@ -295,7 +373,7 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 			valueTypes = append(valueTypes, llvmParam.Type())
 		}
 
-	} else if makeClosure, ok := instr.Call.Value.(*ssa.MakeClosure); ok {
+	} else if _, ok := instr.Call.Value.(*ssa.MakeClosure); ok {
 		// Immediately applied function literal with free variables.
 
 		// Extract the context from the closure. We won't need the function
@@ -305,14 +383,6 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 		closure := b.getValue(instr.Call.Value, getPos(instr))
 		context := b.CreateExtractValue(closure, 0, "")
 
-		// Get the callback number.
-		fn := makeClosure.Fn.(*ssa.Function)
-		if _, ok := b.deferClosureFuncs[fn]; !ok {
-			b.deferClosureFuncs[fn] = len(b.allDeferFuncs)
-			b.allDeferFuncs = append(b.allDeferFuncs, makeClosure)
-		}
-		callback := llvm.ConstInt(b.uintptrType, uint64(b.deferClosureFuncs[fn]), false)
-
 		// Collect all values to be put in the struct (starting with
 		// runtime._defer fields, followed by all parameters including the
 		// context pointer).
@@ -325,29 +395,12 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 		values = append(values, context)
 		valueTypes = append(valueTypes, context.Type())
 
-	} else if builtin, ok := instr.Call.Value.(*ssa.Builtin); ok {
-		var argTypes []types.Type
-		var argValues []llvm.Value
-		for _, arg := range instr.Call.Args {
-			argTypes = append(argTypes, arg.Type())
-			argValues = append(argValues, b.getValue(arg, getPos(instr)))
-		}
-
-		if _, ok := b.deferBuiltinFuncs[instr.Call.Value]; !ok {
-			b.deferBuiltinFuncs[instr.Call.Value] = deferBuiltin{
-				callName: builtin.Name(),
-				pos:      builtin.Pos(),
-				argTypes: argTypes,
-				callback: len(b.allDeferFuncs),
-			}
-			b.allDeferFuncs = append(b.allDeferFuncs, instr.Call.Value)
-		}
-		callback := llvm.ConstInt(b.uintptrType, uint64(b.deferBuiltinFuncs[instr.Call.Value].callback), false)
-
+	} else if _, ok := instr.Call.Value.(*ssa.Builtin); ok {
 		// Collect all values to be put in the struct (starting with
 		// runtime._defer fields).
 		values = []llvm.Value{callback, next}
-		for _, param := range argValues {
+		for _, arg := range instr.Call.Args {
+			param := b.getValue(arg, getPos(instr))
 			values = append(values, param)
 			valueTypes = append(valueTypes, param.Type())
 		}
@@ -355,13 +408,6 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 	} else {
 		funcValue := b.getValue(instr.Call.Value, getPos(instr))
 
-		if _, ok := b.deferExprFuncs[instr.Call.Value]; !ok {
-			b.deferExprFuncs[instr.Call.Value] = len(b.allDeferFuncs)
-			b.allDeferFuncs = append(b.allDeferFuncs, &instr.Call)
-		}
-
-		callback := llvm.ConstInt(b.uintptrType, uint64(b.deferExprFuncs[instr.Call.Value]), false)
-
 		// Collect all values to be put in the struct (starting with
 		// runtime._defer fields, followed by all parameters including the
 		// context pointer).
@@ -384,11 +430,12 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 
 	// Put this struct in an allocation.
 	var alloca llvm.Value
-	if !isInLoop(instr.Block()) {
+	if !isInLoop(instr.Block()) && !isRangeFuncDefer {
 		// This can safely use a stack allocation.
 		alloca = llvmutil.CreateEntryBlockAlloca(b.Builder, deferredCallType, "defer.alloca")
 	} else {
-		// This may be hit a variable number of times, so use a heap allocation.
+		// This may be hit a variable number of times, or must outlive this
+		// function (in a range-over-func loop body), so use a heap allocation.
 		size := b.targetData.TypeAllocSize(deferredCallType)
 		sizeValue := llvm.ConstInt(b.uintptrType, size, false)
 		nilPtr := llvm.ConstNull(b.i8ptrType)
@@ -402,7 +449,175 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 
 	// Push it on top of the linked list by replacing deferPtr.
 	allocaCast := b.CreateBitCast(alloca, next.Type(), "defer.alloca.cast")
-	b.CreateStore(allocaCast, b.deferPtr)
+	b.CreateStore(allocaCast, deferPtr)
+}
+
+// getDeferCallback returns the callback number for the given defer
+// instruction, to be stored in the defer struct. Calls to the same function
+// share the same callback number.
+func (b *builder) getDeferCallback(instr *ssa.Defer) llvm.Value {
+	var callback int
+	if instr.Call.IsInvoke() {
+		methodName := instr.Call.Method.FullName()
+		if _, ok := b.deferInvokeFuncs[methodName]; !ok {
+			b.deferInvokeFuncs[methodName] = len(b.allDeferFuncs)
+			b.allDeferFuncs = append(b.allDeferFuncs, &instr.Call)
+		}
+		callback = b.deferInvokeFuncs[methodName]
+	} else if callee, ok := instr.Call.Value.(*ssa.Function); ok {
+		if _, ok := b.deferFuncs[callee]; !ok {
+			b.deferFuncs[callee] = len(b.allDeferFuncs)
+			b.allDeferFuncs = append(b.allDeferFuncs, callee)
+		}
+		callback = b.deferFuncs[callee]
+	} else if makeClosure, ok := instr.Call.Value.(*ssa.MakeClosure); ok {
+		fn := makeClosure.Fn.(*ssa.Function)
+		if _, ok := b.deferClosureFuncs[fn]; !ok {
+			b.deferClosureFuncs[fn] = len(b.allDeferFuncs)
+			b.allDeferFuncs = append(b.allDeferFuncs, makeClosure)
+		}
+		callback = b.deferClosureFuncs[fn]
+	} else if _, ok := instr.Call.Value.(*ssa.Builtin); ok {
+		if _, ok := b.deferBuiltinFuncs[instr.Call.Value]; !ok {
+			b.deferBuiltinFuncs[instr.Call.Value] = newDeferBuiltin(instr, len(b.allDeferFuncs))
+			b.allDeferFuncs = append(b.allDeferFuncs, instr.Call.Value)
+		}
+		callback = b.deferBuiltinFuncs[instr.Call.Value].callback
+	} else {
+		if _, ok := b.deferExprFuncs[instr.Call.Value]; !ok {
+			b.deferExprFuncs[instr.Call.Value] = len(b.allDeferFuncs)
+			b.allDeferFuncs = append(b.allDeferFuncs, &instr.Call)
+		}
+		callback = b.deferExprFuncs[instr.Call.Value]
+	}
+	return llvm.ConstInt(b.uintptrType, uint64(callback), false)
+}
+
+// getDeferredCall returns the value to store in b.allDeferFuncs for the given
+// defer instruction, which is used by createRunDefers to call the deferred
+// function.
+func (b *builder) getDeferredCall(instr *ssa.Defer) interface{} {
+	if instr.Call.IsInvoke() {
+		return &instr.Call
+	}
+	switch value := instr.Call.Value.(type) {
+	case *ssa.Function, *ssa.MakeClosure, *ssa.Builtin:
+		return value
+	default:
+		return &instr.Call
+	}
+}
+
+// newDeferBuiltin returns the information needed to call a deferred builtin
+// function.
+func newDeferBuiltin(instr *ssa.Defer, callback int) deferBuiltin {
+	builtin := instr.Call.Value.(*ssa.Builtin)
+	var argTypes []types.Type
+	for _, arg := range instr.Call.Args {
+		argTypes = append(argTypes, arg.Type())
+	}
+	return deferBuiltin{
+		callName: builtin.Name(),
+		pos:      builtin.Pos(),
+		argTypes: argTypes,
+		callback: callback,
+	}
+}
+
+// isRangeFuncBody returns whether fn is the body of a range-over-func loop,
+// which the SSA builder creates as a synthetic yield function.
+func isRangeFuncBody(fn *ssa.Function) bool {
+	return fn.Synthetic == "range-over-func yield"
+}
+
+// rangeFuncDefers returns all defer statements in range-over-func loop bodies
+// in fn (including nested loops) that push onto the defer stack of fn, in a
+// fixed order.
+func rangeFuncDefers(fn *ssa.Function) []*ssa.Defer {
+	var defers []*ssa.Defer
+	for _, anon := range fn.AnonFuncs {
+		if !isRangeFuncBody(anon) {
+			// A regular closure, which has its own defer stack.
+			continue
+		}
+		for _, block := range anon.Blocks {
+			for _, instr := range block.Instrs {
+				if instr, ok := instr.(*ssa.Defer); ok && instr.DeferStack != nil {
+					defers = append(defers, instr)
+				}
+			}
+		}
+		defers = append(defers, rangeFuncDefers(anon)...)
+	}
+	return defers
+}
+
+// rangeFuncDeferIndex returns the callback number of a defer statement in a
+// range-over-func loop body, as registered by the enclosing function in
+// deferInitFunc.
+func rangeFuncDeferIndex(instr *ssa.Defer) int {
+	fn := instr.Parent()
+	for isRangeFuncBody(fn) {
+		fn = fn.Parent()
+	}
+	for i, d := range rangeFuncDefers(fn) {
+		if d == instr {
+			return i
+		}
+	}
+	panic("defer statement not found in enclosing function")
+}
+
+// findDeferStack records the type of the defer stack if this function calls
+// the ssa:deferstack builtin. This type is a pointer to an opaque type that is
+// internal to the SSA package, so it can only be recognized by its origin. The
+// call is always in the entry block, and range-over-func loop bodies (which
+// use the defer stack through a free variable) are compiled after the
+// function that contains them.
+func (b *builder) findDeferStack() {
+	if b.deferStackType != nil || len(b.fn.Blocks) == 0 {
+		return
+	}
+	for _, instr := range b.fn.Blocks[0].Instrs {
+		call, ok := instr.(*ssa.Call)
+		if !ok {
+			continue
+		}
+		if builtin, ok := call.Call.Value.(*ssa.Builtin); ok && builtin.Name() == "ssa:deferstack" {
+			b.deferStackType = call.Type()
+			return
+		}
+	}
+}
+
+// getDeferStackType returns the LLVM type for the defer stack of a function: a
+// pointer to the head of the linked list of deferred calls.
+func (c *compilerContext) getDeferStackType() llvm.Type {
+	return llvm.PointerType(llvm.PointerType(c.getLLVMRuntimeType("_defer"), 0), 0)
+}
+
+// hasRunDefers returns whether the given block contains a rundefers
+// instruction.
+func hasRunDefers(block *ssa.BasicBlock) bool {
+	for _, instr := range block.Instrs {
+		if _, ok := instr.(*ssa.RunDefers); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// createRunDefersBlock notes where deferred calls need to be run. The code to
+// run them is created at the end of createFunction, once all defer
+// instructions have been created.
+func (b *builder) createRunDefersBlock() {
+	run := b.insertBasicBlock("rundefers.block")
+	b.CreateBr(run)
+	b.runDefersBlock = append(b.runDefersBlock, run)
+
+	after := b.insertBasicBlock("rundefers.after")
+	b.SetInsertPointAtEnd(after)
+	b.afterDefersBlock = append(b.afterDefersBlock, after)
 }
 
 // createRunDefers emits code to run all deferred functions.
@@ -522,7 +737,7 @@ func (b *builder) createRunDefers() {
 				forwardParams = append(forwardParams, llvm.Undef(b.i8ptrType))
 			}
 
-			b.createCall(fnType, fnPtr, forwardParams, "")
+			b.createInvoke(fnType, fnPtr, forwardParams, "")
 
 		case *ssa.Function:
 			// Direct call.
@@ -579,7 +794,7 @@ func (b *builder) createRunDefers() {
 
 			// Call deferred function.
 			fnType, llvmFn := b.getFunction(fn)
-			b.createCall(fnType, llvmFn, forwardParams, "")
+			b.createInvoke(fnType, llvmFn, forwardParams, "")
 		case *ssa.Builtin:
 			db := b.deferBuiltinFuncs[callback]
 
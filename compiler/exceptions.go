@@ -0,0 +1,94 @@
+package compiler
+
+// This file implements panic/recover on WebAssembly using the exception
+// handling proposal. WebAssembly can't jump back to a saved stack pointer and
+// PC like the setjmp/longjmp construct used on other architectures. Instead,
+// every call that may panic in a function with a defer frame is an invoke
+// that unwinds to a catch-all handler, and runtime.tinygo_longjmp throws an
+// exception. The catch-all handler continues at the regular landing pad, so
+// the rest of the defer machinery is the same as on other architectures.
+//
+// The Go bindings of LLVM don't expose the funclet instructions (catchswitch,
+// catchpad, catchret) that are used to catch exceptions, so they're created
+// using the C API directly.
+
+/*
+#include <llvm-c/Core.h>
+
+// Fill in the dispatch block (the unwind destination of all invokes) with a
+// catchswitch with a single catch-all handler, which continues at the landing
+// pad. This is the equivalent of catch (...) in C++.
+static void tinygo_createCatchAll(LLVMBuilderRef builder, LLVMBasicBlockRef dispatch, LLVMBasicBlockRef handler, LLVMBasicBlockRef landingpad, LLVMTypeRef i8ptrType) {
+	LLVMContextRef ctx = LLVMGetTypeContext(i8ptrType);
+	LLVMPositionBuilderAtEnd(builder, dispatch);
+	LLVMValueRef none = LLVMConstNull(LLVMTokenTypeInContext(ctx));
+	LLVMValueRef catchswitch = LLVMBuildCatchSwitch(builder, none, NULL, 1, "catchswitch");
+	LLVMAddHandler(catchswitch, handler);
+	LLVMPositionBuilderAtEnd(builder, handler);
+	LLVMValueRef args[1] = {LLVMConstNull(i8ptrType)};
+	LLVMValueRef catchpad = LLVMBuildCatchPad(builder, catchswitch, args, 1, "catchpad");
+	LLVMBuildCatchRet(builder, catchpad, landingpad);
+}
+*/
+import "C"
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+
+	"tinygo.org/x/go-llvm"
+)
+
+var enableWasmExceptionsOnce sync.Once
+
+// enableWasmExceptions enables the exception handling instructions in the
+// WebAssembly backend of LLVM. There is no target machine option for this, so
+// it is set using a (global) command line flag, like Clang does with
+// -fwasm-exceptions.
+func enableWasmExceptions() {
+	enableWasmExceptionsOnce.Do(func() {
+		llvm.ParseCommandLineOptions([]string{"tinygo", "-wasm-enable-eh"}, "")
+	})
+}
+
+// wasmExceptions returns whether panics are implemented using WebAssembly
+// exception handling, which is the case when the exception-handling target
+// feature is enabled. With -panic=trap there is nothing to recover, so
+// exceptions aren't used either.
+func (c *compilerContext) wasmExceptions() bool {
+	return c.archFamily() == "wasm32" && c.hasFeature("+exception-handling") && c.PanicStrategy != "trap"
+}
+
+// createWasmInvoke creates an invoke instruction that unwinds to the catch-all
+// handler of the current function, and continues in a new basic block
+// otherwise. It is the wasmExceptions equivalent of createInvokeCheckpoint
+// followed by createCall.
+func (b *builder) createWasmInvoke(fnType llvm.Type, fn llvm.Value, args []llvm.Value, name string) llvm.Value {
+	if strings.HasPrefix(fn.Name(), "llvm.") {
+		// Intrinsics can't be invoked (and don't panic).
+		return b.createCall(fnType, fn, args, name)
+	}
+	expanded := make([]llvm.Value, 0, len(args))
+	for _, arg := range args {
+		fragments := b.expandFormalParam(arg)
+		expanded = append(expanded, fragments...)
+	}
+	continueBB := b.insertBasicBlock("invoke.cont")
+	result := b.CreateInvoke(fnType, fn, expanded, continueBB, b.unwindpad, name)
+	b.SetInsertPointAtEnd(continueBB)
+	b.blockExits[b.currentBlock] = continueBB
+	return result
+}
+
+// createCatchAll fills in the given dispatch block (that invokes unwind to)
+// with a catch-all handler, which continues at the landing pad.
+func (b *builder) createCatchAll(dispatch llvm.BasicBlock) {
+	handler := b.ctx.AddBasicBlock(b.llvmFn, "lpad.catch")
+	C.tinygo_createCatchAll(
+		C.LLVMBuilderRef(unsafe.Pointer(b.Builder.C)),
+		C.LLVMBasicBlockRef(unsafe.Pointer(dispatch.C)),
+		C.LLVMBasicBlockRef(unsafe.Pointer(handler.C)),
+		C.LLVMBasicBlockRef(unsafe.Pointer(b.landingpad.C)),
+		C.LLVMTypeRef(unsafe.Pointer(b.i8ptrType.C)))
+}
@@ -0,0 +1,57 @@
+package compiler
+
+// This file implements the instrumentation for coverage-guided fuzzing (tinygo
+// test -fuzz). It works like the inline-8bit-counters mode of LLVM's
+// SanitizerCoverage: every basic block gets an 8-bit counter that is
+// incremented (wrapping around on overflow) every time the block runs. The
+// counters of a package are registered with the runtime from the package
+// initializer, so that the fuzzer in the testing package can find out which
+// inputs reach new code.
+
+import (
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// createFuzzCounter inserts code to increment a new fuzzing counter at the
+// current insert position.
+func (b *builder) createFuzzCounter() {
+	// The counters global is a placeholder until the whole package has been
+	// compiled, at which point the number of counters is known.
+	counterType := b.ctx.Int8Type()
+	placeholderType := llvm.ArrayType(counterType, 0)
+	if b.fuzzCounters.IsNil() {
+		b.fuzzCounters = llvm.AddGlobal(b.mod, placeholderType, b.pkg.Path()+"$fuzz.tmp")
+	}
+	counter := b.CreateGEP(placeholderType, b.fuzzCounters, []llvm.Value{
+		llvm.ConstInt(b.ctx.Int32Type(), 0, false),
+		llvm.ConstInt(b.ctx.Int32Type(), uint64(b.numFuzzCounters), false),
+	}, "")
+	b.numFuzzCounters++
+	value := b.CreateAdd(b.CreateLoad(counterType, counter, ""), llvm.ConstInt(counterType, 1, false), "")
+	b.CreateStore(value, counter)
+}
+
+// createFuzzCountersRegistration creates the global with all fuzzing counters
+// of this package and registers it with the runtime at the start of the
+// package initializer. It must be called after all functions in the package
+// have been compiled.
+func (c *compilerContext) createFuzzCountersRegistration(ssaPkg *ssa.Package) {
+	if c.numFuzzCounters == 0 {
+		return
+	}
+
+	// Replace the placeholder with the real counters global.
+	countersType := llvm.ArrayType(c.ctx.Int8Type(), c.numFuzzCounters)
+	counters := llvm.AddGlobal(c.mod, countersType, c.pkg.Path()+"$fuzz")
+	counters.SetInitializer(llvm.ConstNull(countersType))
+	counters.SetLinkage(llvm.InternalLinkage)
+	c.fuzzCounters.ReplaceAllUsesWith(llvm.ConstBitCast(counters, c.fuzzCounters.Type()))
+	c.fuzzCounters.EraseFromParentAsGlobal()
+	c.fuzzCounters = counters
+
+	c.createInitRuntimeCall(ssaPkg, "$fuzz.register", "registerFuzzCounters", []llvm.Value{
+		llvm.ConstBitCast(counters, c.i8ptrType),
+		llvm.ConstInt(c.uintptrType, uint64(c.numFuzzCounters), false),
+	})
+}
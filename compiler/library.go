@@ -0,0 +1,175 @@
+package compiler
+
+// This file implements the exported functions of a library, in the
+// -buildmode=c-archive and -buildmode=c-shared build modes (including WASI
+// reactor modules). The host program calls them as regular C functions, but
+// they must run inside the Go runtime: on a goroutine with the scheduler
+// running, so that they can block.
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/compiler/llvmutil"
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// checkLibraryExport adds an error if the given exported function cannot be
+// called from C. Only types that have a C equivalent with the same calling
+// convention are allowed (see the header generated by the builder).
+func (c *compilerContext) checkLibraryExport(f *ssa.Function, name string) {
+	pragma := "//export " + name
+	if f.Signature.Recv() != nil {
+		c.addError(f.Pos(), fmt.Sprintf("%s: cannot export a method", pragma))
+		return
+	}
+	if f.Signature.Results().Len() > 1 {
+		c.addError(f.Signature.Results().At(1).Pos(), fmt.Sprintf("%s: too many return values", pragma))
+	} else if f.Signature.Results().Len() == 1 {
+		result := f.Signature.Results().At(0)
+		if !isValidLibraryExportType(result.Type()) {
+			c.addError(result.Pos(), fmt.Sprintf("%s: unsupported result type %s", pragma, result.Type().String()))
+		}
+	}
+	for _, param := range f.Params {
+		if !isValidLibraryExportType(param.Type()) {
+			c.addError(param.Pos(), fmt.Sprintf("%s: unsupported parameter type %s", pragma, param.Type().String()))
+		}
+	}
+}
+
+// Check whether the type can be used in an exported function of a library.
+// Slices, structs and arrays are not allowed because C passes them in memory
+// while TinyGo passes them as separate values, and complex numbers are not
+// allowed because C passes them packed in floating point registers.
+func isValidLibraryExportType(typ types.Type) bool {
+	switch typ := typ.Underlying().(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Complex64, types.Complex128:
+			return false
+		}
+		return true
+	case *types.Pointer, *types.Map, *types.Chan, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// createLibraryExport creates the C function with the export name for an
+// exported function of a library. In Go, it looks like this for an exported
+// function add:
+//
+//	func add(a, b int) int { // the wrapper, with the export name
+//	    args := struct{ a, b, result int }{a: a, b: b}
+//	    runtime.libraryCall(func() { // add$libraryexport
+//	        args.result = main.add(args.a, args.b)
+//	    })
+//	    return args.result
+//	}
+//
+// The arguments and the result are stored on the stack of the host program,
+// which is not scanned by the GC. This is fine: the arguments are owned by the
+// caller, and the result is only stored when the call has finished.
+func (b *builder) createLibraryExport() {
+	name := b.info.libraryExport
+	paramTypes := b.llvmFnType.ParamTypes()
+	paramTypes = paramTypes[:len(paramTypes)-1] // strip context parameter
+	resultType := b.llvmFnType.ReturnType()
+	hasResult := resultType.TypeKind() != llvm.VoidTypeKind
+	argTypes := append([]llvm.Type{}, paramTypes...)
+	if hasResult {
+		argTypes = append(argTypes, resultType)
+	}
+	argsType := b.ctx.StructType(argTypes, false)
+
+	wb := &builder{
+		compilerContext: b.compilerContext,
+		Builder:         b.ctx.NewBuilder(),
+	}
+	defer wb.Dispose()
+	argGEP := func(args llvm.Value, index int) llvm.Value {
+		return wb.CreateInBoundsGEP(argsType, args, []llvm.Value{
+			llvm.ConstInt(b.ctx.Int32Type(), 0, false),
+			llvm.ConstInt(b.ctx.Int32Type(), uint64(index), false),
+		}, "")
+	}
+
+	// Create the function that runs inside the Go runtime. It calls the
+	// exported Go function with the arguments stored in the context parameter.
+	thunkType := llvm.FunctionType(b.ctx.VoidType(), []llvm.Type{b.i8ptrType}, false)
+	thunk := llvm.AddFunction(b.mod, name+"$libraryexport", thunkType)
+	b.addStandardAttributes(thunk)
+	thunk.SetLinkage(llvm.InternalLinkage)
+	thunk.SetUnnamedAddr(true)
+	wb.SetInsertPointAtEnd(b.ctx.AddBasicBlock(thunk, "entry"))
+	args := wb.CreateBitCast(thunk.Param(0), llvm.PointerType(argsType, 0), "args")
+	var params []llvm.Value
+	for i, paramType := range paramTypes {
+		params = append(params, wb.CreateLoad(paramType, argGEP(args, i), ""))
+	}
+	params = append(params, llvm.Undef(b.i8ptrType)) // unused context parameter
+	result := wb.CreateCall(b.llvmFnType, b.llvmFn, params, "")
+	if hasResult {
+		wb.CreateStore(result, argGEP(args, len(paramTypes)))
+	}
+	wb.CreateRetVoid()
+
+	// Create the wrapper that is called by the host program.
+	wrapperType := llvm.FunctionType(resultType, paramTypes, false)
+	wrapper := llvm.AddFunction(b.mod, name, wrapperType)
+	b.addStandardAttributes(wrapper)
+	b.addLibraryExportAttributes(wrapper)
+	if strings.HasPrefix(b.Triple, "wasm") {
+		// Export the wrapper from a WASI reactor module, in the same way as
+		// other exported functions (see createFunction).
+		wrapper.AddFunctionAttr(b.ctx.CreateStringAttribute("wasm-export-name", name))
+		llvmutil.AppendToGlobal(b.mod, "llvm.used", wrapper)
+	}
+	wb.SetInsertPointAtEnd(b.ctx.AddBasicBlock(wrapper, "entry"))
+	args = wb.CreateAlloca(argsType, "args")
+	for i := range paramTypes {
+		wb.CreateStore(wrapper.Param(i), argGEP(args, i))
+	}
+	context := wb.CreateBitCast(args, b.i8ptrType, "")
+	funcValue := wb.createFuncValue(thunk, context, types.NewSignature(nil, nil, nil, false))
+	wb.createRuntimeCall("libraryCall", []llvm.Value{funcValue}, "")
+	if hasResult {
+		wb.CreateRet(wb.CreateLoad(resultType, argGEP(args, len(paramTypes)), ""))
+	} else {
+		wb.CreateRetVoid()
+	}
+}
+
+// addLibraryExportAttributes adds the signext and zeroext attributes that C
+// expects on small integer parameters and results, like Clang does.
+func (b *builder) addLibraryExportAttributes(wrapper llvm.Value) {
+	index := 1
+	for _, param := range b.fn.Params {
+		if kind := extAttributeKind(param.Type()); kind != "" {
+			wrapper.AddAttributeAtIndex(index, b.ctx.CreateEnumAttribute(llvm.AttributeKindID(kind), 0))
+		}
+		index += len(b.expandFormalParamType(b.getLLVMType(param.Type()), "", param.Type()))
+	}
+	if b.fn.Signature.Results().Len() == 1 {
+		if kind := extAttributeKind(b.fn.Signature.Results().At(0).Type()); kind != "" {
+			wrapper.AddAttributeAtIndex(0, b.ctx.CreateEnumAttribute(llvm.AttributeKindID(kind), 0))
+		}
+	}
+}
+
+// extAttributeKind returns the LLVM attribute that extends a value of the
+// given type to a full register, or "" if no extension is needed.
+func extAttributeKind(typ types.Type) string {
+	if basic, ok := typ.Underlying().(*types.Basic); ok {
+		switch basic.Kind() {
+		case types.Bool, types.Uint8, types.Uint16:
+			return "zeroext"
+		case types.Int8, types.Int16:
+			return "signext"
+		}
+	}
+	return ""
+}
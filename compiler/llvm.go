@@ -435,6 +435,17 @@ func (c *compilerContext) archFamily() string {
 	return arch
 }
 
+// hasFeature returns whether the given LLVM target feature (like "+fp") is
+// enabled for the current target.
+func (c *compilerContext) hasFeature(feature string) bool {
+	for _, f := range strings.Split(c.Features, ",") {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
 // isThumb returns whether we're in ARM or in Thumb mode. It panics if the
 // features string is not one for an ARM architecture.
 func (c *compilerContext) isThumb() bool {
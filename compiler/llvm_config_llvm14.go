@@ -0,0 +1,14 @@
+//go:build !byollvm && llvm14
+
+package compiler
+
+// Flags for the C code in this package (exceptions.go), which uses the LLVM C
+// API directly.
+
+/*
+#cgo linux        CPPFLAGS: -I/usr/lib/llvm-14/include
+#cgo darwin,amd64 CPPFLAGS: -I/usr/local/opt/llvm@14/include
+#cgo darwin,arm64 CPPFLAGS: -I/opt/homebrew/opt/llvm@14/include
+#cgo freebsd      CPPFLAGS: -I/usr/local/llvm14/include
+*/
+import "C"
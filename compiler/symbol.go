@@ -23,15 +23,18 @@ import (
 // The linkName value contains a valid link name, even if //go:linkname is not
 // present.
 type functionInfo struct {
-	wasmModule string     // go:wasm-module
-	wasmName   string     // wasm-export-name or wasm-import-name in the IR
-	linkName   string     // go:linkname, go:export - the IR function name
-	section    string     // go:section - object file section name
-	exported   bool       // go:export, CGo
-	interrupt  bool       // go:interrupt
-	nobounds   bool       // go:nobounds
-	variadic   bool       // go:variadic (CGo only)
-	inline     inlineType // go:inline
+	wasmModule    string     // go:wasm-module
+	wasmName      string     // wasm-export-name or wasm-import-name in the IR
+	linkName      string     // go:linkname, go:export - the IR function name
+	libraryExport string     // go:export in -buildmode=c-archive or c-shared, or go:wasmexport - the name of the wrapper
+	section       string     // go:section - object file section name
+	exported      bool       // go:export, CGo
+	wasmExport    bool       // go:wasmexport
+	interrupt     bool       // go:interrupt
+	nobounds      bool       // go:nobounds
+	checkfree     bool       // go:checkfree
+	variadic      bool       // go:variadic (CGo only)
+	inline        inlineType // go:inline
 }
 
 type inlineType int
@@ -191,6 +194,20 @@ func (c *compilerContext) getFunction(fn *ssa.Function) (llvm.Type, llvm.Value)
 		}
 	}
 
+	if info.checkfree {
+		// Checked after optimization, see transform.CheckRuntimeChecks.
+		llvmFn.AddFunctionAttr(c.ctx.CreateStringAttribute("tinygo-checkfree", ""))
+	}
+
+	if c.wasmExceptions() && len(fn.Blocks) == 0 && !strings.HasPrefix(info.linkName, "llvm.") {
+		// Defined functions may panic with wasmExceptions, so they aren't
+		// nounwind. Functions without a Go body (implemented in C or
+		// assembly, or stubs replaced by the compiler like
+		// runtime.trackPointer) can't panic though, so tell LLVM about it.
+		// Intrinsics already have the correct attributes.
+		llvmFn.AddFunctionAttr(c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nounwind"), 0))
+	}
+
 	// External/exported functions may not retain pointer values.
 	// https://golang.org/cmd/cgo/#hdr-Passing_pointers
 	if info.exported {
@@ -243,6 +260,20 @@ func (c *compilerContext) getFunctionInfo(f *ssa.Function) functionInfo {
 	}
 	// Check for //go: pragmas, which may change the link name (among others).
 	c.parsePragmas(&info, f)
+	if (c.LibraryExports || info.wasmExport) && info.exported && f.Blocks != nil && f.Pkg != nil && f.Pkg.Pkg == c.pkg {
+		// Exported functions of a library are called from the host program
+		// through a wrapper with the export name (see library.go). The
+		// function itself is a regular Go function.
+		// Functions exported with //go:wasmexport are wrapped in the same
+		// way in every build mode: the host may call them after main has
+		// returned, when they can only block if they run in a goroutine.
+		if !info.wasmExport {
+			c.checkLibraryExport(f, info.linkName)
+		}
+		info.libraryExport = info.linkName
+		info.linkName = f.RelString(nil)
+		info.exported = false
+	}
 	c.functionInfos[f] = info
 	return info
 }
@@ -296,6 +327,20 @@ func (c *compilerContext) parsePragmas(info *functionInfo, f *ssa.Function) {
 				info.exported = true
 				info.wasmModule = parts[1]
 				info.wasmName = parts[2]
+			case "//go:wasmexport":
+				// Export a WebAssembly function. This is the same as //export
+				// but only allows the types that //go:wasmimport allows.
+				// Proposal: https://github.com/golang/go/issues/65199
+				if len(parts) != 2 {
+					continue
+				}
+				if !c.checkWasmExport(f, comment.Text) {
+					continue
+				}
+				info.linkName = parts[1]
+				info.wasmName = info.linkName
+				info.exported = true
+				info.wasmExport = true
 			case "//go:inline":
 				info.inline = inlineHint
 			case "//go:noinline":
@@ -327,6 +372,15 @@ func (c *compilerContext) parsePragmas(info *functionInfo, f *ssa.Function) {
 				if hasUnsafeImport(f.Pkg.Pkg) {
 					info.nobounds = true
 				}
+			case "//go:checkfree":
+				// Require that all runtime checks (bounds checks, nil checks,
+				// etc) in this function are optimized away. The build fails
+				// if any remain.
+				// go:checkfree also implies go:noinline: the checks are
+				// verified after optimization, and checks that are inlined
+				// into a caller can't be attributed to this function anymore.
+				info.checkfree = true
+				info.inline = inlineNone
 			case "//go:variadic":
 				// The //go:variadic pragma is emitted by the CGo preprocessing
 				// pass for C variadic functions. This includes both explicit
@@ -358,6 +412,37 @@ func (c *compilerContext) checkWasmImport(f *ssa.Function, pragma string) {
 		c.addError(f.Pos(), fmt.Sprintf("can only use //go:wasmimport on declarations"))
 		return
 	}
+	c.checkWasmSignature(f, pragma)
+}
+
+// Check whether this function can be used in //go:wasmexport, and add an error
+// if it can't. It returns whether the function can be exported.
+func (c *compilerContext) checkWasmExport(f *ssa.Function, pragma string) bool {
+	if c.archFamily() != "wasm32" {
+		c.addError(f.Pos(), "//go:wasmexport is only supported on WebAssembly")
+		return false
+	}
+	if c.GOOS == "js" {
+		// The JavaScript runtime calls into Go through its own event handler
+		// (see syscall/js), exported functions can't run on a goroutine.
+		c.addError(f.Pos(), "//go:wasmexport is not supported with GOOS=js")
+		return false
+	}
+	if f.Blocks == nil {
+		c.addError(f.Pos(), "can only use //go:wasmexport on definitions")
+		return false
+	}
+	if f.Signature.Recv() != nil {
+		c.addError(f.Pos(), "cannot use //go:wasmexport on a method")
+		return false
+	}
+	c.checkWasmSignature(f, pragma)
+	return true
+}
+
+// Check whether the parameter and result types of a //go:wasmimport or
+// //go:wasmexport function map directly to WebAssembly types.
+func (c *compilerContext) checkWasmSignature(f *ssa.Function, pragma string) {
 	if f.Signature.Results().Len() > 1 {
 		c.addError(f.Signature.Results().At(1).Pos(), fmt.Sprintf("%s: too many return values", pragma))
 	} else if f.Signature.Results().Len() == 1 {
@@ -438,11 +523,14 @@ func (c *compilerContext) addStandardDeclaredAttributes(llvmFn llvm.Value) {
 // on the architecture. It does not set attributes only set for declared
 // functions, use addStandardDeclaredAttributes for this.
 func (c *compilerContext) addStandardDefinedAttributes(llvmFn llvm.Value) {
-	// TinyGo does not currently raise exceptions, so set the 'nounwind' flag.
+	// TinyGo does not raise exceptions (except for panics with
+	// wasmExceptions), so set the 'nounwind' flag.
 	// This behavior matches Clang when compiling C source files.
 	// It reduces binary size on Linux a little bit on non-x86_64 targets by
 	// eliminating exception tables for these functions.
-	llvmFn.AddFunctionAttr(c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nounwind"), 0))
+	if !c.wasmExceptions() {
+		llvmFn.AddFunctionAttr(c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nounwind"), 0))
+	}
 	if strings.Split(c.Triple, "-")[0] == "x86_64" {
 		// Required by the ABI.
 		if llvmutil.Major() < 15 {
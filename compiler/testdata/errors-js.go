@@ -0,0 +1,8 @@
+package main
+
+// ERROR: //go:wasmexport is not supported with GOOS=js
+//
+//go:wasmexport jsexport
+func jsexport(a int32) int32 {
+	return a
+}
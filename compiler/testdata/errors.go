@@ -41,3 +41,35 @@ func invalidreturn() int
 //
 //go:wasmimport modulename invalidUnsafePointerReturn
 func invalidUnsafePointerReturn() unsafe.Pointer
+
+//go:wasmexport validexport
+func validexport(a int32, b uint64, c float64, d unsafe.Pointer) float32 {
+	return 0
+}
+
+// ERROR: can only use //go:wasmexport on definitions
+//
+//go:wasmexport declaration
+func declaration()
+
+// ERROR: //go:wasmexport invalidexport: unsupported parameter type string
+// ERROR: //go:wasmexport invalidexport: unsupported parameter type *int32
+//
+//go:wasmexport invalidexport
+func invalidexport(a string, b *int32) {
+}
+
+// ERROR: //go:wasmexport invalidexportreturn: unsupported result type int
+//
+//go:wasmexport invalidexportreturn
+func invalidexportreturn() int {
+	return 0
+}
+
+type exportType struct{}
+
+// ERROR: cannot use //go:wasmexport on a method
+//
+//go:wasmexport method
+func (exportType) method() {
+}
@@ -1,3 +1,5 @@
+//go:build go1.20
+
 package main
 
 import "unsafe"
@@ -0,0 +1,29 @@
+//go:build go1.23
+
+package main
+
+import "iter"
+
+func count(yield func(int) bool) {
+	for i := 0; i < 3; i++ {
+		if !yield(i) {
+			return
+		}
+	}
+}
+
+func pairs(yield func(int, string) bool) {
+	yield(1, "one")
+}
+
+func pull() {
+	// ERROR: iter.Pull is not supported with -scheduler=none
+	next, stop := iter.Pull(count)
+	next()
+	stop()
+
+	// ERROR: iter.Pull2 is not supported with -scheduler=none
+	next2, stop2 := iter.Pull2(pairs)
+	next2()
+	stop2()
+}
@@ -0,0 +1,204 @@
+package main
+
+// This file collects the code coverage counters written by test binaries built
+// with -cover, and writes them as a coverage profile that can be read by
+// `go tool cover`.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Marker lines around the coverage counters in the test output. They are
+// written by runtime.coverageFlush.
+const (
+	coverageBeginMarker = "tinygo-coverage-begin"
+	coverageEndMarker   = "tinygo-coverage-end"
+)
+
+// coverageWriter passes through the output of a test binary, except for the
+// coverage counters which are collected instead. It is also used for other
+// data that is written between two marker lines, such as PGO profiles.
+type coverageWriter struct {
+	w           io.Writer
+	beginMarker string
+	endMarker   string
+	line        []byte // current (incomplete) line that isn't written yet
+	inProfile   bool
+	profile     []string // lines of the coverage profile, without the mode line
+}
+
+func newCoverageWriter(w io.Writer) *coverageWriter {
+	return &coverageWriter{w: w, beginMarker: coverageBeginMarker, endMarker: coverageEndMarker}
+}
+
+func (cw *coverageWriter) Write(data []byte) (int, error) {
+	var out []byte
+	for _, c := range data {
+		cw.line = append(cw.line, c)
+		if cw.inProfile {
+			if c == '\n' {
+				line := strings.TrimRight(string(cw.line), "\r\n")
+				if line == cw.endMarker {
+					cw.inProfile = false
+				} else if line != "" {
+					cw.profile = append(cw.profile, line)
+				}
+				cw.line = cw.line[:0]
+			}
+			continue
+		}
+
+		// Only hold back output while it may still be the begin marker, so
+		// that the test output isn't delayed unnecessarily.
+		if c == '\n' && strings.TrimRight(string(cw.line), "\r\n") == cw.beginMarker {
+			cw.inProfile = true
+			cw.line = cw.line[:0]
+			continue
+		}
+		if !bytes.HasPrefix([]byte(cw.beginMarker+"\r\n"), cw.line) && !bytes.HasPrefix([]byte(cw.beginMarker+"\n"), cw.line) {
+			out = append(out, cw.line...)
+			cw.line = cw.line[:0]
+		}
+	}
+	if len(out) != 0 {
+		if _, err := cw.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Flush writes any output that was held back, for example when the test binary
+// exited in the middle of a line.
+func (cw *coverageWriter) Flush() error {
+	if cw.inProfile || len(cw.line) == 0 {
+		return nil
+	}
+	_, err := cw.w.Write(cw.line)
+	cw.line = cw.line[:0]
+	return err
+}
+
+// coverageProfile is a coverage profile in the format of `go tool cover`,
+// possibly merged from the profiles of multiple test binaries.
+type coverageProfile struct {
+	lock   sync.Mutex
+	mode   string
+	blocks map[string]*coverageBlock // key is "file:startLine.startCol,endLine.endCol"
+	order  []string
+}
+
+// coverageBlock is a single line in the coverage profile.
+type coverageBlock struct {
+	statements int
+	count      uint64
+}
+
+func newCoverageProfile(mode string) *coverageProfile {
+	return &coverageProfile{
+		mode:   mode,
+		blocks: make(map[string]*coverageBlock),
+	}
+}
+
+// add merges the given profile lines (as written by the test binary) into the
+// profile. Blocks that are already in the profile are merged: in set mode they
+// are covered if they were covered in either profile, in count mode the counts
+// are added together.
+func (p *coverageProfile) add(lines []string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, line := range lines {
+		// Format: file:startLine.startCol,endLine.endCol numStmts count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid coverage line: %q", line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid coverage line: %q", line)
+		}
+		count, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid coverage line: %q", line)
+		}
+		block := p.blocks[fields[0]]
+		if block == nil {
+			block = &coverageBlock{statements: statements}
+			p.blocks[fields[0]] = block
+			p.order = append(p.order, fields[0])
+		}
+		if p.mode == "set" {
+			if count != 0 {
+				block.count = 1
+			}
+		} else {
+			block.count += count
+		}
+	}
+	return nil
+}
+
+// percent returns the percentage of statements that were covered. It returns
+// false if there are no statements at all.
+func (p *coverageProfile) percent() (float64, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var total, covered int
+	for _, block := range p.blocks {
+		total += block.statements
+		if block.count != 0 {
+			covered += block.statements
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(covered) / float64(total) * 100, true
+}
+
+// summary returns the coverage summary as printed after the test result, like
+// "coverage: 75.0% of statements".
+func (p *coverageProfile) summary() string {
+	percent, ok := p.percent()
+	if !ok {
+		return "coverage: [no statements]"
+	}
+	return fmt.Sprintf("coverage: %.1f%% of statements", percent)
+}
+
+// writeTo writes the profile in the format read by `go tool cover`.
+func (p *coverageProfile) writeTo(w io.Writer) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, err := fmt.Fprintf(w, "mode: %s\n", p.mode); err != nil {
+		return err
+	}
+	for _, key := range p.order {
+		block := p.blocks[key]
+		if _, err := fmt.Fprintf(w, "%s %d %d\n", key, block.statements, block.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the profile to the given path.
+func (p *coverageProfile) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = p.writeTo(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
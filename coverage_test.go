@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCoverageWriter(t *testing.T) {
+	// Test output with coverage counters at the end, as written by the
+	// runtime. Write it in small pieces, to check that markers split over
+	// multiple writes are still found.
+	output := "=== RUN   TestFoo\n--- PASS: TestFoo\nPASS\n" +
+		"tinygo-coverage-begin\r\n" +
+		"example.com/foo/foo.go:3.5,3.8 1 1\r\n" +
+		"example.com/foo/foo.go:4.3,4.12 1 0\r\n" +
+		"example.com/foo/foo.go:6.2,6.10 2 3\r\n" +
+		"tinygo-coverage-end\r\n" +
+		"tinygo-coverage"
+	var buf bytes.Buffer
+	cw := newCoverageWriter(&buf)
+	for i := 0; i < len(output); i += 7 {
+		end := i + 7
+		if end > len(output) {
+			end = len(output)
+		}
+		cw.Write([]byte(output[i:end]))
+	}
+	if buf.String() != "=== RUN   TestFoo\n--- PASS: TestFoo\nPASS\n" {
+		t.Errorf("unexpected output before flush: %q", buf.String())
+	}
+	cw.Flush()
+	if buf.String() != "=== RUN   TestFoo\n--- PASS: TestFoo\nPASS\ntinygo-coverage" {
+		t.Errorf("unexpected output after flush: %q", buf.String())
+	}
+	if len(cw.profile) != 3 || cw.profile[2] != "example.com/foo/foo.go:6.2,6.10 2 3" {
+		t.Fatalf("unexpected profile: %q", cw.profile)
+	}
+
+	// Merge it with a second profile, in set mode.
+	profile := newCoverageProfile("set")
+	if err := profile.add(cw.profile); err != nil {
+		t.Fatal(err)
+	}
+	if summary := profile.summary(); summary != "coverage: 75.0% of statements" {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+	if err := profile.add([]string{"example.com/foo/foo.go:4.3,4.12 1 5", "example.com/bar/bar.go:1.1,2.2 4 0"}); err != nil {
+		t.Fatal(err)
+	}
+	if summary := profile.summary(); summary != "coverage: 50.0% of statements" {
+		t.Errorf("unexpected summary after merging: %s", summary)
+	}
+	var profileBuf strings.Builder
+	profile.writeTo(&profileBuf)
+	expected := "mode: set\n" +
+		"example.com/foo/foo.go:3.5,3.8 1 1\n" +
+		"example.com/foo/foo.go:4.3,4.12 1 1\n" +
+		"example.com/foo/foo.go:6.2,6.10 2 1\n" +
+		"example.com/bar/bar.go:1.1,2.2 4 0\n"
+	if profileBuf.String() != expected {
+		t.Errorf("unexpected profile:\n%s", profileBuf.String())
+	}
+
+	// Invalid lines are reported.
+	if err := profile.add([]string{"garbage"}); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+	if summary := newCoverageProfile("count").summary(); summary != "coverage: [no statements]" {
+		t.Errorf("unexpected summary for an empty profile: %s", summary)
+	}
+}
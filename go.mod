@@ -1,6 +1,6 @@
 module github.com/tinygo-org/tinygo
 
-go 1.18
+go 1.19
 
 require (
 	github.com/aykevl/go-wasm v0.0.2-0.20220616010729-4a0a888aebdc
@@ -14,9 +14,10 @@ require (
 	github.com/mattn/go-colorable v0.1.8
 	github.com/mattn/go-tty v0.0.4
 	github.com/sigurn/crc16 v0.0.0-20211026045750-20ab5afb07e3
+	github.com/tetratelabs/wazero v1.2.1
 	go.bug.st/serial v1.6.0
-	golang.org/x/sys v0.11.0
-	golang.org/x/tools v0.12.0
+	golang.org/x/sys v0.22.0
+	golang.org/x/tools v0.23.0
 	gopkg.in/yaml.v2 v2.4.0
 	tinygo.org/x/go-llvm v0.0.0-20230918183930-9edb6403d0bc
 )
@@ -0,0 +1,26 @@
+package goenv
+
+import (
+	"os"
+	"time"
+)
+
+// Entries in the cache (GOCACHE) that were used more recently than this are
+// not marked as used again, to avoid writing to the file system on every
+// cache hit.
+const cacheMarkInterval = time.Hour
+
+// MarkCacheUsed updates the modification time of the given cache entry, so
+// that it is not removed when trimming the cache. To avoid unnecessary writes,
+// this is only done if it wasn't already marked as used recently.
+func MarkCacheUsed(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(info.ModTime()) < cacheMarkInterval {
+		return
+	}
+	os.Chtimes(path, now, now)
+}
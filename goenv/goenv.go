@@ -25,6 +25,7 @@ var Keys = []string{
 	"GOCACHE",
 	"CGO_ENABLED",
 	"TINYGOROOT",
+	"TINYGOCACHEMAX",
 }
 
 func init() {
@@ -139,6 +140,10 @@ func Get(name string) string {
 		return "1"
 	case "TINYGOROOT":
 		return sourceDir()
+	case "TINYGOCACHEMAX":
+		// Maximum size of the cache directory, for example 2GB. The cache is
+		// not limited in size if this is not set.
+		return os.Getenv("TINYGOCACHEMAX")
 	case "WASMOPT":
 		if path := os.Getenv("WASMOPT"); path != "" {
 			err := wasmOptCheckVersion(path)
@@ -151,6 +156,12 @@ func Get(name string) string {
 		}
 
 		return findWasmOpt()
+	case "WASMTOOLS":
+		// wasm-tools is used to create WebAssembly components (for wasip2).
+		if path := os.Getenv("WASMTOOLS"); path != "" {
+			return path
+		}
+		return "wasm-tools"
 	default:
 		return ""
 	}
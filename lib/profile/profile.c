@@ -0,0 +1,233 @@
+// Minimal LLVM profile runtime for TinyGo (-pgo=instrument).
+//
+// The compiler instruments the program in the same way as Clang does for
+// -fprofile-generate, and this runtime writes the profile counters in the raw
+// profile format that llvm-profdata merges into an indexed profile. Unlike the
+// compiler-rt profile runtime it doesn't need a file system: on baremetal
+// targets the Go runtime writes the profile to the standard output instead.
+
+#include <stddef.h>
+#include <stdint.h>
+
+// Only version 8 of the raw profile format (LLVM 14 to 16) is supported. The
+// upper 32 bits of the version emitted by the compiler contain flags, such as
+// whether the profile is IR level.
+#define RAW_VERSION 8
+#define RAW_VERSION_MASK 0xffffffffULL
+
+// Magic number at the start of a raw profile: "\xfflprofr\x81" on 64-bit
+// targets and "\xfflprofR\x81" on 32-bit targets.
+#define RAW_MAGIC(r) ((uint64_t)255 << 56 | (uint64_t)'l' << 48 | (uint64_t)'p' << 40 | (uint64_t)'r' << 32 | (uint64_t)'o' << 24 | (uint64_t)'f' << 16 | (uint64_t)(r) << 8 | (uint64_t)129)
+
+// Indirect call targets and memcpy sizes. The compiler removes value
+// profiling, but the data records still contain the number of sites per kind.
+#define VALUE_KIND_LAST 1
+
+// Per-function profile data (INSTR_PROF_DATA in InstrProfData.inc). The
+// compiler aligns every record to 8 bytes.
+typedef struct {
+	uint64_t nameRef;
+	uint64_t funcHash;
+	intptr_t counterPtr; // relative to the record
+	void *functionPointer;
+	void *values;
+	uint32_t numCounters;
+	uint16_t numValueSites[VALUE_KIND_LAST + 1];
+} __attribute__((aligned(8))) profileData;
+
+// Emitted by the compiler when the program is instrumented.
+extern const uint64_t __llvm_profile_raw_version __attribute__((weak));
+
+// Referenced by the compiler on targets where the runtime isn't linked in
+// with a linker flag.
+int __llvm_profile_runtime;
+
+#if defined(__wasm__)
+
+// WebAssembly has no __start_ and __stop_ symbols for the profile sections, so
+// the compiler registers every data record (and the names) from a
+// constructor.
+static const profileData *dataFirst, *dataLast;
+static const char *countersFirst, *countersLast;
+static const char *namesFirst;
+static uint64_t namesSize;
+
+void __llvm_profile_register_function(void *d) {
+	const profileData *data = d;
+	const char *counters = (const char *)data + data->counterPtr;
+	const char *countersEnd = counters + data->numCounters * sizeof(uint64_t);
+	if (dataFirst == NULL || data < dataFirst) {
+		dataFirst = data;
+	}
+	if (data + 1 > dataLast) {
+		dataLast = data + 1;
+	}
+	if (countersFirst == NULL || counters < countersFirst) {
+		countersFirst = counters;
+	}
+	if (countersEnd > countersLast) {
+		countersLast = countersEnd;
+	}
+}
+
+void __llvm_profile_register_names_function(void *names, uint64_t size) {
+	namesFirst = names;
+	namesSize = size;
+}
+
+#define DATA_BEGIN dataFirst
+#define DATA_END dataLast
+#define COUNTERS_BEGIN countersFirst
+#define COUNTERS_END countersLast
+#define NAMES_BEGIN namesFirst
+#define NAMES_END (namesFirst + namesSize)
+
+#else
+
+// The linker defines these symbols for the profile sections. They are weak, so
+// that the program still links when nothing was instrumented.
+#define SECTION_SYMBOL extern __attribute__((weak, visibility("hidden")))
+SECTION_SYMBOL const profileData __start___llvm_prf_data[];
+SECTION_SYMBOL const profileData __stop___llvm_prf_data[];
+SECTION_SYMBOL const char __start___llvm_prf_cnts[];
+SECTION_SYMBOL const char __stop___llvm_prf_cnts[];
+SECTION_SYMBOL const char __start___llvm_prf_names[];
+SECTION_SYMBOL const char __stop___llvm_prf_names[];
+
+// The compiler also registers the data records on targets other than Linux,
+// but the sections are used instead.
+void __llvm_profile_register_function(void *d) {
+}
+
+void __llvm_profile_register_names_function(void *names, uint64_t size) {
+}
+
+#define DATA_BEGIN __start___llvm_prf_data
+#define DATA_END __stop___llvm_prf_data
+#define COUNTERS_BEGIN __start___llvm_prf_cnts
+#define COUNTERS_END __stop___llvm_prf_cnts
+#define NAMES_BEGIN __start___llvm_prf_names
+#define NAMES_END __stop___llvm_prf_names
+
+#endif
+
+// Header of a raw profile (INSTR_PROF_RAW_HEADER in InstrProfData.inc).
+typedef struct {
+	uint64_t magic;
+	uint64_t version;
+	uint64_t binaryIdsSize;
+	uint64_t dataSize;
+	uint64_t paddingBytesBeforeCounters;
+	uint64_t countersSize;
+	uint64_t paddingBytesAfterCounters;
+	uint64_t namesSize;
+	uint64_t countersDelta;
+	uint64_t namesDelta;
+	uint64_t valueKindLast;
+} rawHeader;
+
+typedef int (*profileWriter)(void *context, const void *buf, size_t len);
+
+static uint64_t namesPadding(void) {
+	return (8 - (uint64_t)(NAMES_END - NAMES_BEGIN) % 8) % 8;
+}
+
+// Write the raw profile using the given writer. The layout is the header,
+// followed by the data records, the counters and the names (padded to 8
+// bytes). There are no binary IDs and no value profile data.
+static int writeProfile(profileWriter fn, void *context) {
+	if (&__llvm_profile_raw_version == NULL || (__llvm_profile_raw_version & RAW_VERSION_MASK) != RAW_VERSION) {
+		return -1;
+	}
+	rawHeader header = {
+		.magic = sizeof(void *) == 8 ? RAW_MAGIC('r') : RAW_MAGIC('R'),
+		.version = __llvm_profile_raw_version,
+		.dataSize = DATA_END - DATA_BEGIN,
+		.countersSize = (COUNTERS_END - COUNTERS_BEGIN) / sizeof(uint64_t),
+		.namesSize = NAMES_END - NAMES_BEGIN,
+		.countersDelta = (uintptr_t)COUNTERS_BEGIN - (uintptr_t)DATA_BEGIN,
+		.namesDelta = (uintptr_t)NAMES_BEGIN,
+		.valueKindLast = VALUE_KIND_LAST,
+	};
+	static const char padding[8];
+	if (fn(context, &header, sizeof(header)) != 0 ||
+		fn(context, DATA_BEGIN, (DATA_END - DATA_BEGIN) * sizeof(profileData)) != 0 ||
+		fn(context, COUNTERS_BEGIN, COUNTERS_END - COUNTERS_BEGIN) != 0 ||
+		fn(context, NAMES_BEGIN, NAMES_END - NAMES_BEGIN) != 0 ||
+		fn(context, padding, namesPadding()) != 0) {
+		return -1;
+	}
+	return 0;
+}
+
+// Return the size of the raw profile, for __llvm_profile_write_buffer.
+uint64_t __llvm_profile_get_size_for_buffer(void) {
+	return sizeof(rawHeader) +
+		(DATA_END - DATA_BEGIN) * sizeof(profileData) +
+		(COUNTERS_END - COUNTERS_BEGIN) +
+		(NAMES_END - NAMES_BEGIN) + namesPadding();
+}
+
+static int bufferWriter(void *context, const void *buf, size_t len) {
+	char **dst = context;
+	const char *src = buf;
+	for (size_t i = 0; i < len; i++) {
+		(*dst)[i] = src[i];
+	}
+	*dst += len;
+	return 0;
+}
+
+// Write the raw profile to the given buffer, which must be at least
+// __llvm_profile_get_size_for_buffer() bytes.
+int __llvm_profile_write_buffer(char *buffer) {
+	return writeProfile(bufferWriter, &buffer);
+}
+
+#if defined(__linux__) || defined(__wasi__)
+
+// Defined in musl and wasi-libc.
+typedef struct _IO_FILE FILE;
+FILE *fopen(const char *pathname, const char *mode);
+size_t fwrite(const void *ptr, size_t size, size_t nmemb, FILE *stream);
+int fclose(FILE *stream);
+char *getenv(const char *name);
+long write(int fd, const void *buf, size_t count);
+
+static int fileWriter(void *context, const void *buf, size_t len) {
+	return fwrite(buf, 1, len, context) == len ? 0 : -1;
+}
+
+static void printError(const char *s) {
+	size_t len = 0;
+	while (s[len] != 0) {
+		len++;
+	}
+	write(2, s, len);
+}
+
+// Write the raw profile to the file in the LLVM_PROFILE_FILE environment
+// variable, or default.profraw in the current directory. Unlike compiler-rt,
+// patterns such as %p are not expanded.
+int __llvm_profile_write_file(void) {
+	const char *filename = getenv("LLVM_PROFILE_FILE");
+	if (filename == NULL || filename[0] == 0) {
+		filename = "default.profraw";
+	}
+	int err = -1;
+	FILE *f = fopen(filename, "wb");
+	if (f != NULL) {
+		err = writeProfile(fileWriter, f);
+		if (fclose(f) != 0) {
+			err = -1;
+		}
+	}
+	if (err != 0) {
+		printError("LLVM Profile Error: failed to write file \"");
+		printError(filename);
+		printError("\"\n");
+	}
+	return err;
+}
+
+#endif
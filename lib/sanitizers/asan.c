@@ -0,0 +1,586 @@
+// AddressSanitizer runtime for TinyGo (-sanitize=address).
+//
+// This implements the part of the compiler-rt runtime interface that is used by
+// instrumented code: it maps the shadow memory, adds redzones around globals
+// and stack variables, and reports invalid memory accesses. Objects on the Go
+// heap are poisoned and unpoisoned by the TinyGo garbage collector. The C
+// malloc, calloc, realloc and free are replaced by versions that add redzones
+// around every allocation and keep freed memory in a quarantine for a while,
+// on top of the musl allocator. Fake stacks (stack-use-after-return) are not
+// supported.
+
+#include <stdbool.h>
+#include "sanitizers.h"
+
+// Defined in musl. The __libc_* functions are the allocator of musl, which is
+// used by the malloc replacement below.
+void *__libc_malloc(size_t n);
+void __libc_free(void *p);
+void *mmap(void *addr, size_t length, int prot, int flags, int fd, int64_t offset);
+void *memcpy(void *dst, const void *src, size_t n);
+void *memmove(void *dst, const void *src, size_t n);
+void *memset(void *dst, int c, size_t n);
+extern char **environ;
+
+#define PROT_READ           0x1
+#define PROT_WRITE          0x2
+#define MAP_PRIVATE         0x02
+#define MAP_ANONYMOUS       0x20
+#define MAP_NORESERVE       0x4000
+#define MAP_FIXED_NOREPLACE 0x100000
+
+// The shadow memory layout used by LLVM for Linux. Every 8 bytes of memory
+// are described by one shadow byte: 0 means all bytes are addressable, 1-7
+// means only the first n bytes are addressable, and negative values mean none
+// are (the value describes why).
+#if defined(__x86_64__)
+#define SHADOW_OFFSET 0x7fff8000UL
+static const int addressBits[] = {47};
+#elif defined(__aarch64__)
+#define SHADOW_OFFSET (1UL << 36)
+static const int addressBits[] = {48, 47, 42, 39};
+#elif defined(__i386__) || defined(__arm__)
+#define SHADOW_OFFSET (1UL << 29)
+static const int addressBits[] = {32};
+#else
+#error "AddressSanitizer is not supported on this architecture"
+#endif
+
+#define GRANULE 8
+
+#define MEM_TO_SHADOW(addr) ((int8_t *)(((uintptr_t)(addr) >> 3) + SHADOW_OFFSET))
+
+// Shadow values, the same as in compiler-rt.
+#define MAGIC_STACK_LEFT         0xf1
+#define MAGIC_STACK_MID          0xf2
+#define MAGIC_STACK_RIGHT        0xf3
+#define MAGIC_STACK_AFTER_RETURN 0xf5
+#define MAGIC_USER_POISONED      0xf7
+#define MAGIC_STACK_AFTER_SCOPE  0xf8
+#define MAGIC_GLOBAL_REDZONE     0xf9
+#define MAGIC_HEAP_LEFT          0xfa
+#define MAGIC_HEAP_RIGHT         0xfb
+#define MAGIC_HEAP_FREED         0xfd
+#define MAGIC_ALLOCA_LEFT        0xca
+#define MAGIC_ALLOCA_RIGHT       0xcb
+
+#define ALLOCA_REDZONE_SIZE 32
+
+#define EINVAL 22
+#define ENOMEM 12
+
+// The layout of the global descriptions emitted by the compiler.
+struct asan_global {
+	uintptr_t beg;
+	uintptr_t size;
+	uintptr_t size_with_redzone;
+	const char *name;
+	const char *module_name;
+	uintptr_t has_dynamic_init;
+	void *location;
+	uintptr_t odr_indicator;
+};
+
+static bool initialized;
+
+// Top of the main stack, used to clean up stack redzones when returning
+// without running the function epilogues (like longjmp).
+static uintptr_t stackTop;
+
+int __asan_option_detect_stack_use_after_return = 0;
+
+void __asan_init(void) {
+	if (initialized) {
+		return;
+	}
+	initialized = true;
+
+	// Reserve the shadow memory for the whole address space. The pages are
+	// only allocated when they're written to.
+	bool mapped = false;
+	for (size_t i = 0; i < sizeof(addressBits) / sizeof(addressBits[0]); i++) {
+		uintptr_t size = ((uintptr_t)1 << (addressBits[i] - 3)) - 1;
+		void *addr = mmap((void *)SHADOW_OFFSET, size, PROT_READ | PROT_WRITE, MAP_PRIVATE | MAP_ANONYMOUS | MAP_NORESERVE | MAP_FIXED_NOREPLACE, -1, 0);
+		if (addr == (void *)SHADOW_OFFSET) {
+			mapped = true;
+			break;
+		}
+	}
+	if (!mapped) {
+		sanitizer_print("==ERROR: AddressSanitizer: failed to map the shadow memory\n");
+		abort();
+	}
+
+	// The environment is stored at the top of the main stack.
+	stackTop = (uintptr_t)environ;
+}
+
+void __asan_version_mismatch_check_v8(void) {
+}
+
+// Set the shadow of [addr, addr+size) to the given value. The start address
+// must be aligned, the end may be in the middle of a granule (in which case
+// that granule is left unchanged, unless it is already partially poisoned).
+static void poison(uintptr_t addr, uintptr_t size, uint8_t value) {
+	uintptr_t end = addr + size;
+	uintptr_t alignedEnd = end & ~(uintptr_t)(GRANULE - 1);
+	if (alignedEnd > addr) {
+		memset(MEM_TO_SHADOW(addr), value, (alignedEnd - addr) / GRANULE);
+	}
+	if (end != alignedEnd) {
+		int8_t *shadow = MEM_TO_SHADOW(alignedEnd);
+		if (*shadow > 0 && *shadow <= (int8_t)(end - alignedEnd)) {
+			*shadow = value;
+		}
+	}
+}
+
+// Mark [addr, addr+size) as addressable.
+static void unpoison(uintptr_t addr, uintptr_t size) {
+	uintptr_t end = addr + size;
+	addr &= ~(uintptr_t)(GRANULE - 1);
+	uintptr_t alignedEnd = end & ~(uintptr_t)(GRANULE - 1);
+	if (alignedEnd > addr) {
+		memset(MEM_TO_SHADOW(addr), 0, (alignedEnd - addr) / GRANULE);
+	}
+	if (end != alignedEnd) {
+		int8_t *shadow = MEM_TO_SHADOW(alignedEnd);
+		if (*shadow < 0 || *shadow < (int8_t)(end - alignedEnd)) {
+			*shadow = end - alignedEnd;
+		}
+	}
+}
+
+static bool isPoisoned(uintptr_t addr) {
+	int8_t shadow = *MEM_TO_SHADOW(addr);
+	return shadow != 0 && (int8_t)(addr & (GRANULE - 1)) >= shadow;
+}
+
+// Return the first address in [addr, addr+size) that is poisoned, or 0 if
+// all of them are addressable.
+static uintptr_t firstPoisoned(uintptr_t addr, uintptr_t size) {
+	uintptr_t end = addr + size;
+	while (addr < end) {
+		if ((addr & (GRANULE - 1)) == 0 && end - addr >= GRANULE && *MEM_TO_SHADOW(addr) == 0) {
+			addr += GRANULE;
+			continue;
+		}
+		if (isPoisoned(addr)) {
+			return addr;
+		}
+		addr++;
+	}
+	return 0;
+}
+
+static const char *describe(uintptr_t addr) {
+	uint8_t shadow = *MEM_TO_SHADOW(addr);
+	if (shadow > 0 && shadow < GRANULE) {
+		// Partially addressable granule, the next one tells what's beyond.
+		shadow = *MEM_TO_SHADOW(addr + GRANULE);
+	}
+	switch (shadow) {
+	case MAGIC_HEAP_LEFT:
+	case MAGIC_HEAP_RIGHT:
+		return "heap-buffer-overflow";
+	case MAGIC_HEAP_FREED:
+		return "heap-use-after-free";
+	case MAGIC_STACK_LEFT:
+	case MAGIC_STACK_MID:
+	case MAGIC_STACK_RIGHT:
+		return "stack-buffer-overflow";
+	case MAGIC_STACK_AFTER_RETURN:
+		return "stack-use-after-return";
+	case MAGIC_STACK_AFTER_SCOPE:
+		return "stack-use-after-scope";
+	case MAGIC_GLOBAL_REDZONE:
+		return "global-buffer-overflow";
+	case MAGIC_ALLOCA_LEFT:
+	case MAGIC_ALLOCA_RIGHT:
+		return "dynamic-stack-buffer-overflow";
+	case MAGIC_USER_POISONED:
+		return "use-after-poison";
+	default:
+		return "unknown-crash";
+	}
+}
+
+// Report an invalid access and abort. The pc is the address of the
+// instrumented code that did the access.
+__attribute__((noreturn))
+static void report(uintptr_t addr, uintptr_t size, bool isWrite, uintptr_t pc) {
+	uintptr_t bad = firstPoisoned(addr, size);
+	if (bad == 0) {
+		bad = addr;
+	}
+	sanitizer_print("==ERROR: AddressSanitizer: ");
+	sanitizer_print(describe(bad));
+	sanitizer_print(" on address ");
+	sanitizer_print_hex(bad);
+	sanitizer_print("\n");
+	sanitizer_print(isWrite ? "WRITE" : "READ");
+	sanitizer_print(" of size ");
+	sanitizer_print_dec(size);
+	sanitizer_print(" at ");
+	sanitizer_print_hex(addr);
+	sanitizer_print(" from ");
+	sanitizer_print_hex(pc);
+	sanitizer_print("\n");
+	abort();
+}
+
+static void check(uintptr_t addr, uintptr_t size, bool isWrite, uintptr_t pc) {
+	if (size != 0 && firstPoisoned(addr, size) != 0) {
+		report(addr, size, isWrite, pc);
+	}
+}
+
+#define CALLER_PC ((uintptr_t)__builtin_return_address(0))
+
+// Report functions, called by the inline checks.
+#define ASAN_ACCESS(size) \
+	void __asan_report_load##size(uintptr_t addr) { report(addr, size, false, CALLER_PC); } \
+	void __asan_report_store##size(uintptr_t addr) { report(addr, size, true, CALLER_PC); } \
+	void __asan_load##size(uintptr_t addr) { check(addr, size, false, CALLER_PC); } \
+	void __asan_store##size(uintptr_t addr) { check(addr, size, true, CALLER_PC); }
+
+ASAN_ACCESS(1)
+ASAN_ACCESS(2)
+ASAN_ACCESS(4)
+ASAN_ACCESS(8)
+ASAN_ACCESS(16)
+
+void __asan_report_load_n(uintptr_t addr, uintptr_t size) {
+	report(addr, size, false, CALLER_PC);
+}
+
+void __asan_report_store_n(uintptr_t addr, uintptr_t size) {
+	report(addr, size, true, CALLER_PC);
+}
+
+void __asan_loadN(uintptr_t addr, uintptr_t size) {
+	check(addr, size, false, CALLER_PC);
+}
+
+void __asan_storeN(uintptr_t addr, uintptr_t size) {
+	check(addr, size, true, CALLER_PC);
+}
+
+// Instrumented code calls these instead of the memory intrinsics.
+
+void *__asan_memcpy(void *dst, const void *src, size_t n) {
+	check((uintptr_t)src, n, false, CALLER_PC);
+	check((uintptr_t)dst, n, true, CALLER_PC);
+	return memcpy(dst, src, n);
+}
+
+void *__asan_memmove(void *dst, const void *src, size_t n) {
+	check((uintptr_t)src, n, false, CALLER_PC);
+	check((uintptr_t)dst, n, true, CALLER_PC);
+	return memmove(dst, src, n);
+}
+
+void *__asan_memset(void *dst, int c, size_t n) {
+	check((uintptr_t)dst, n, true, CALLER_PC);
+	return memset(dst, c, n);
+}
+
+// Globals are registered by the module constructors of instrumented code.
+
+void __asan_register_globals(struct asan_global *globals, uintptr_t n) {
+	for (uintptr_t i = 0; i < n; i++) {
+		struct asan_global *g = &globals[i];
+		unpoison(g->beg, g->size);
+		uintptr_t redzone = (g->beg + g->size + GRANULE - 1) & ~(uintptr_t)(GRANULE - 1);
+		poison(redzone, g->beg + g->size_with_redzone - redzone, MAGIC_GLOBAL_REDZONE);
+	}
+}
+
+void __asan_unregister_globals(struct asan_global *globals, uintptr_t n) {
+}
+
+void __asan_register_elf_globals(uintptr_t *flag, struct asan_global *start, struct asan_global *stop) {
+	if (*flag) {
+		return;
+	}
+	*flag = 1;
+	__asan_register_globals(start, stop - start);
+}
+
+void __asan_unregister_elf_globals(uintptr_t *flag, struct asan_global *start, struct asan_global *stop) {
+}
+
+void __asan_before_dynamic_init(const char *module_name) {
+}
+
+void __asan_after_dynamic_init(void) {
+}
+
+// Stack redzones. The instrumented code passes the shadow address directly.
+#define ASAN_SET_SHADOW(value) \
+	void __asan_set_shadow_##value(uintptr_t addr, uintptr_t size) { memset((void *)addr, 0x##value, size); }
+
+ASAN_SET_SHADOW(00)
+ASAN_SET_SHADOW(f1)
+ASAN_SET_SHADOW(f2)
+ASAN_SET_SHADOW(f3)
+ASAN_SET_SHADOW(f5)
+ASAN_SET_SHADOW(f8)
+
+void __asan_alloca_poison(uintptr_t addr, uintptr_t size) {
+	uintptr_t partial = addr + size;
+	uintptr_t right = (partial + ALLOCA_REDZONE_SIZE - 1) & ~(uintptr_t)(ALLOCA_REDZONE_SIZE - 1);
+	poison(addr - ALLOCA_REDZONE_SIZE, ALLOCA_REDZONE_SIZE, MAGIC_ALLOCA_LEFT);
+	unpoison(addr, size);
+	uintptr_t alignedPartial = (partial + GRANULE - 1) & ~(uintptr_t)(GRANULE - 1);
+	poison(alignedPartial, right + ALLOCA_REDZONE_SIZE - alignedPartial, MAGIC_ALLOCA_RIGHT);
+}
+
+void __asan_allocas_unpoison(uintptr_t top, uintptr_t bottom) {
+	if (top == 0 || top > bottom) {
+		return;
+	}
+	memset(MEM_TO_SHADOW(top), 0, (bottom - top) / GRANULE);
+}
+
+// Called before a call to a function that doesn't return, like longjmp or a
+// runtime panic. The frames that are skipped don't remove their redzones, so
+// do that here for the main stack.
+void __asan_handle_no_return(void) {
+	uintptr_t sp = (uintptr_t)__builtin_frame_address(0);
+	if (sp < stackTop && stackTop - sp < (256 << 20)) {
+		unpoison(sp, stackTop - sp);
+	}
+}
+
+// Replacement for the C allocator. Every chunk starts with a left redzone that
+// ends with a header, followed by the memory returned to the caller and a
+// right redzone. Freed chunks are poisoned and kept in a quarantine, so that
+// use-after-free is detected until they are evicted from it. Shadow memory is
+// only updated once __asan_init has run, but chunks allocated before that
+// still have a header so that they can be freed.
+
+#define HEAP_LEFT_REDZONE  32
+#define HEAP_RIGHT_REDZONE 16
+#define HEAP_ALIGN         16
+
+#define CHUNK_ALLOCATED 0x61736e41
+#define CHUNK_FREED     0x66726565
+
+// Limits of the quarantine. The oldest chunks are released to musl when either
+// limit is exceeded.
+#define QUARANTINE_CHUNKS 1024
+#define QUARANTINE_BYTES  (4 << 20)
+
+struct chunk_header {
+	void *base;       // start of the allocation from musl
+	size_t size;      // size requested by the caller
+	size_t allocated; // size of the allocation from musl
+	uint32_t state;   // CHUNK_ALLOCATED or CHUNK_FREED
+};
+
+_Static_assert(sizeof(struct chunk_header) <= HEAP_LEFT_REDZONE, "chunk header doesn't fit in the left redzone");
+
+static struct chunk_header *quarantine[QUARANTINE_CHUNKS];
+static size_t quarantineHead;
+static size_t quarantineCount;
+static size_t quarantineBytes;
+static volatile bool quarantineLock;
+
+static struct chunk_header *chunkHeader(void *p) {
+	return (struct chunk_header *)((uintptr_t)p - sizeof(struct chunk_header));
+}
+
+__attribute__((noreturn))
+static void reportFree(const char *kind, void *p, uintptr_t pc) {
+	sanitizer_print("==ERROR: AddressSanitizer: ");
+	sanitizer_print(kind);
+	sanitizer_print(" ");
+	sanitizer_print_hex((uintptr_t)p);
+	sanitizer_print(" from ");
+	sanitizer_print_hex(pc);
+	sanitizer_print("\n");
+	abort();
+}
+
+// Allocate size bytes aligned to align (a power of two, at least HEAP_ALIGN)
+// with redzones around them.
+static void *asanAlloc(size_t size, size_t align) {
+	size_t rounded = (size + GRANULE - 1) & ~(size_t)(GRANULE - 1);
+	size_t allocated = HEAP_LEFT_REDZONE + (align - HEAP_ALIGN) + rounded + HEAP_RIGHT_REDZONE;
+	if (rounded < size || allocated < rounded) {
+		return NULL; // overflow
+	}
+	void *base = __libc_malloc(allocated);
+	if (base == NULL) {
+		return NULL;
+	}
+	uintptr_t user = ((uintptr_t)base + HEAP_LEFT_REDZONE + align - 1) & ~(uintptr_t)(align - 1);
+	struct chunk_header *header = chunkHeader((void *)user);
+	header->base = base;
+	header->size = size;
+	header->allocated = allocated;
+	header->state = CHUNK_ALLOCATED;
+	if (initialized) {
+		poison((uintptr_t)base, user - (uintptr_t)base, MAGIC_HEAP_LEFT);
+		unpoison(user, size);
+		uintptr_t right = user + rounded;
+		poison(right, (uintptr_t)base + allocated - right, MAGIC_HEAP_RIGHT);
+		if (size != rounded) {
+			// The right redzone starts in the middle of the last granule.
+			poison(user + size, rounded - size, MAGIC_HEAP_RIGHT);
+		}
+	}
+	return (void *)user;
+}
+
+// Return the header of a chunk that is passed to free or realloc, and report
+// an error if p wasn't returned by malloc or was already freed.
+static struct chunk_header *checkChunk(void *p, uintptr_t pc) {
+	if ((uintptr_t)p % HEAP_ALIGN != 0) {
+		reportFree("attempting free on address which was not malloc()-ed:", p, pc);
+	}
+	struct chunk_header *header = chunkHeader(p);
+	if (initialized && *MEM_TO_SHADOW(p) == (int8_t)MAGIC_HEAP_FREED) {
+		reportFree("attempting double-free on", p, pc);
+	}
+	if (header->state == CHUNK_FREED) {
+		reportFree("attempting double-free on", p, pc);
+	}
+	if (header->state != CHUNK_ALLOCATED) {
+		reportFree("attempting free on address which was not malloc()-ed:", p, pc);
+	}
+	return header;
+}
+
+// Release a chunk from the quarantine to musl. The shadow is cleared, as the
+// memory may be reused for something other than the C heap.
+static void releaseChunk(struct chunk_header *header) {
+	void *base = header->base;
+	if (initialized) {
+		unpoison((uintptr_t)base, header->allocated);
+	}
+	__libc_free(base);
+}
+
+static void asanFree(void *p, uintptr_t pc) {
+	struct chunk_header *header = checkChunk(p, pc);
+	header->state = CHUNK_FREED;
+	if (!initialized) {
+		__libc_free(header->base);
+		return;
+	}
+	uintptr_t rounded = (header->size + GRANULE - 1) & ~(uintptr_t)(GRANULE - 1);
+	poison((uintptr_t)p, rounded, MAGIC_HEAP_FREED);
+
+	// Put the chunk in the quarantine, and release the oldest chunks if it is
+	// full.
+	while (__atomic_test_and_set(&quarantineLock, __ATOMIC_ACQUIRE)) {
+	}
+	if (quarantineCount == QUARANTINE_CHUNKS) {
+		struct chunk_header *oldest = quarantine[quarantineHead];
+		quarantineHead = (quarantineHead + 1) % QUARANTINE_CHUNKS;
+		quarantineCount--;
+		quarantineBytes -= oldest->allocated;
+		releaseChunk(oldest);
+	}
+	quarantine[(quarantineHead + quarantineCount) % QUARANTINE_CHUNKS] = header;
+	quarantineCount++;
+	quarantineBytes += header->allocated;
+	while (quarantineBytes > QUARANTINE_BYTES && quarantineCount > 1) {
+		struct chunk_header *oldest = quarantine[quarantineHead];
+		quarantineHead = (quarantineHead + 1) % QUARANTINE_CHUNKS;
+		quarantineCount--;
+		quarantineBytes -= oldest->allocated;
+		releaseChunk(oldest);
+	}
+	__atomic_clear(&quarantineLock, __ATOMIC_RELEASE);
+}
+
+void *malloc(size_t size) {
+	return asanAlloc(size, HEAP_ALIGN);
+}
+
+void *calloc(size_t count, size_t size) {
+	if (size != 0 && count > (size_t)-1 / size) {
+		return NULL;
+	}
+	void *p = asanAlloc(count * size, HEAP_ALIGN);
+	if (p != NULL) {
+		memset(p, 0, count * size);
+	}
+	return p;
+}
+
+void *realloc(void *p, size_t size) {
+	if (p == NULL) {
+		return asanAlloc(size, HEAP_ALIGN);
+	}
+	struct chunk_header *header = checkChunk(p, CALLER_PC);
+	void *result = asanAlloc(size, HEAP_ALIGN);
+	if (result == NULL) {
+		return NULL;
+	}
+	memcpy(result, p, header->size < size ? header->size : size);
+	asanFree(p, CALLER_PC);
+	return result;
+}
+
+void free(void *p) {
+	if (p != NULL) {
+		asanFree(p, CALLER_PC);
+	}
+}
+
+void *aligned_alloc(size_t align, size_t size) {
+	if (align == 0 || (align & (align - 1)) != 0) {
+		return NULL;
+	}
+	return asanAlloc(size, align < HEAP_ALIGN ? HEAP_ALIGN : align);
+}
+
+void *memalign(size_t align, size_t size) {
+	return aligned_alloc(align, size);
+}
+
+int posix_memalign(void **result, size_t align, size_t size) {
+	if (align < sizeof(void *) || (align & (align - 1)) != 0) {
+		return EINVAL;
+	}
+	void *p = aligned_alloc(align, size);
+	if (p == NULL) {
+		return ENOMEM;
+	}
+	*result = p;
+	return 0;
+}
+
+size_t malloc_usable_size(void *p) {
+	if (p == NULL) {
+		return 0;
+	}
+	return checkChunk(p, CALLER_PC)->size;
+}
+
+// Public interface, see <sanitizer/asan_interface.h>.
+
+void __asan_poison_memory_region(const volatile void *addr, size_t size) {
+	poison((uintptr_t)addr, size, MAGIC_USER_POISONED);
+}
+
+void __asan_unpoison_memory_region(const volatile void *addr, size_t size) {
+	unpoison((uintptr_t)addr, size);
+}
+
+int __asan_address_is_poisoned(const volatile void *addr) {
+	return isPoisoned((uintptr_t)addr);
+}
+
+void *__asan_region_is_poisoned(void *addr, size_t size) {
+	return (void *)firstPoisoned((uintptr_t)addr, size);
+}
+
+// Used by the TinyGo garbage collector, see src/runtime/asan.go.
+void tinygo_asanPoison(uintptr_t addr, uintptr_t size, uint8_t value) {
+	poison(addr, size, value);
+}
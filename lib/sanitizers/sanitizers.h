@@ -0,0 +1,39 @@
+// Helpers shared by the sanitizer runtimes. They don't use stdio, so that
+// reports can be printed even when the C heap is corrupted.
+
+#pragma once
+
+#include <stddef.h>
+#include <stdint.h>
+
+// Defined in musl.
+long write(int fd, const void *buf, size_t count);
+void abort(void);
+
+static inline void sanitizer_print(const char *s) {
+	size_t len = 0;
+	while (s[len] != 0) {
+		len++;
+	}
+	write(2, s, len);
+}
+
+static inline void sanitizer_print_hex(uintptr_t value) {
+	char buf[2 + sizeof(uintptr_t) * 2];
+	buf[0] = '0';
+	buf[1] = 'x';
+	for (size_t i = 0; i < sizeof(uintptr_t) * 2; i++) {
+		buf[sizeof(buf) - 1 - i] = "0123456789abcdef"[(value >> (i * 4)) & 0xf];
+	}
+	write(2, buf, sizeof(buf));
+}
+
+static inline void sanitizer_print_dec(uintptr_t value) {
+	char buf[20];
+	size_t i = sizeof(buf);
+	do {
+		buf[--i] = '0' + value % 10;
+		value /= 10;
+	} while (value != 0);
+	write(2, buf + i, sizeof(buf) - i);
+}
@@ -0,0 +1,78 @@
+// Minimal UndefinedBehaviorSanitizer runtime for TinyGo (-sanitize=undefined).
+//
+// C code is compiled with -fsanitize-minimal-runtime, so the handlers are only
+// told which check failed. Like the compiler-rt minimal runtime, recoverable
+// errors are reported once per call site and the program continues; the
+// *_abort handlers (and the checks that can't be recovered from) abort.
+
+#include "sanitizers.h"
+
+#define MAX_REPORTED 64
+
+static uintptr_t reported[MAX_REPORTED];
+static int numReported;
+
+// Print a report for the given check, unless the call site has already been
+// reported.
+static void report(const char *kind, uintptr_t pc) {
+	for (int i = 0; i < numReported; i++) {
+		if (reported[i] == pc) {
+			return;
+		}
+	}
+	if (numReported < MAX_REPORTED) {
+		reported[numReported++] = pc;
+	}
+	sanitizer_print("ubsan: ");
+	sanitizer_print(kind);
+	sanitizer_print(" by ");
+	sanitizer_print_hex(pc);
+	sanitizer_print("\n");
+}
+
+#define CALLER_PC ((uintptr_t)__builtin_return_address(0))
+
+#define HANDLER_RECOVER(name, kind) \
+	void __ubsan_handle_##name##_minimal(void) { report(kind, CALLER_PC); }
+
+#define HANDLER_NORECOVER(name, kind) \
+	void __ubsan_handle_##name##_minimal_abort(void) { report(kind, CALLER_PC); abort(); }
+
+#define HANDLER(name, kind) \
+	HANDLER_RECOVER(name, kind) \
+	HANDLER_NORECOVER(name, kind)
+
+HANDLER(type_mismatch, "type-mismatch")
+HANDLER(alignment_assumption, "alignment-assumption")
+HANDLER(add_overflow, "add-overflow")
+HANDLER(sub_overflow, "sub-overflow")
+HANDLER(mul_overflow, "mul-overflow")
+HANDLER(negate_overflow, "negate-overflow")
+HANDLER(divrem_overflow, "divrem-overflow")
+HANDLER(shift_out_of_bounds, "shift-out-of-bounds")
+HANDLER(out_of_bounds, "out-of-bounds")
+HANDLER(vla_bound_not_positive, "vla-bound-not-positive")
+HANDLER(float_cast_overflow, "float-cast-overflow")
+HANDLER(load_invalid_value, "load-invalid-value")
+HANDLER(invalid_builtin, "invalid-builtin")
+HANDLER(invalid_objc_cast, "invalid-objc-cast")
+HANDLER(function_type_mismatch, "function-type-mismatch")
+HANDLER(implicit_conversion, "implicit-conversion")
+HANDLER(nonnull_arg, "nonnull-arg")
+HANDLER(nonnull_return, "nonnull-return")
+HANDLER(nullability_arg, "nullability-arg")
+HANDLER(nullability_return, "nullability-return")
+HANDLER(pointer_overflow, "pointer-overflow")
+HANDLER(cfi_check_fail, "cfi-check-fail")
+
+// These checks can't be recovered from, but the handlers don't have the
+// _abort suffix.
+void __ubsan_handle_builtin_unreachable_minimal(void) {
+	report("builtin-unreachable", CALLER_PC);
+	abort();
+}
+
+void __ubsan_handle_missing_return_minimal(void) {
+	report("missing-return", CALLER_PC);
+	abort();
+}
@@ -74,6 +74,9 @@ func GetCachedGoroot(config *compileopts.Config) (string, error) {
 	cachedGorootName := "goroot-" + hex.EncodeToString(hash[:])
 	cachedgoroot := filepath.Join(goenv.Get("GOCACHE"), cachedGorootName)
 	if _, err := os.Stat(cachedgoroot); err == nil {
+		// Mark it as recently used, so that it isn't removed when the cache
+		// is trimmed.
+		goenv.MarkCacheUsed(cachedgoroot)
 		return cachedgoroot, nil
 	}
 
@@ -237,9 +240,11 @@ func pathsToOverride(goMinor int, needsSyscallPackage bool) map[string]bool {
 		"examples/":             false,
 		"internal/":             true,
 		"internal/bytealg/":     false,
+		"internal/cm/":          false,
 		"internal/fuzz/":        false,
 		"internal/reflectlite/": false,
 		"internal/task/":        false,
+		"internal/wasi/":        false,
 		"machine/":              false,
 		"net/":                  true,
 		"os/":                   true,
@@ -255,6 +260,16 @@ func pathsToOverride(goMinor int, needsSyscallPackage bool) map[string]bool {
 		paths["crypto/internal/boring/sig/"] = false
 	}
 
+	if goMinor >= 23 {
+		// The upstream iter package depends on coroutine support in the
+		// runtime, so use a TinyGo-specific implementation instead.
+		paths["iter/"] = false
+
+		// The upstream unique package (used by net/netip) depends on weak
+		// pointers, which the runtime doesn't support.
+		paths["unique/"] = false
+	}
+
 	if needsSyscallPackage {
 		paths["syscall/"] = true // include syscall/js
 	}
@@ -50,6 +50,7 @@ type PackageJSON struct {
 	Name       string
 	ForTest    string
 	Root       string
+	Standard   bool
 	Module     struct {
 		Path      string
 		Main      bool
@@ -164,6 +165,7 @@ func Load(config *compileopts.Config, inputPkg string, clangHeaders string, type
 				Selections: make(map[*ast.SelectorExpr]*types.Selection),
 			},
 		}
+		initFileVersions(&pkg.info)
 		err := decoder.Decode(&pkg.PackageJSON)
 		if err != nil {
 			if err == io.EOF {
@@ -381,6 +383,19 @@ func (p *Package) Check() error {
 	}
 	checker.Importer = p
 
+	// Set the language version, so that the type checker rejects language
+	// features that aren't supported by the version in go.mod and so that the
+	// SSA builder uses the right semantics (for example, per-iteration loop
+	// variables starting with Go 1.22).
+	if p.Module.GoVersion != "" {
+		checker.GoVersion = "go" + languageVersion(p.Module.GoVersion)
+	} else if minor := p.program.config.GoMinorVersion; minor != 0 {
+		// The version is not known (for example, for packages in GOROOT or
+		// for `tinygo run file.go` outside a module), so use the version of
+		// the Go toolchain.
+		checker.GoVersion = fmt.Sprintf("go1.%d", minor)
+	}
+
 	// Do typechecking of the package.
 	packageName := p.ImportPath
 	if p == p.program.MainPkg() {
@@ -407,6 +422,17 @@ func (p *Package) Check() error {
 	return nil
 }
 
+// languageVersion returns the language version (like "1.22") from a Go version
+// as used in go.mod files (like "1.22.0"). Older versions of go/types don't
+// accept a patch version.
+func languageVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) > 2 {
+		return parts[0] + "." + parts[1]
+	}
+	return version
+}
+
 // parseFiles parses the loaded list of files and returns this list.
 func (p *Package) parseFiles() ([]*ast.File, error) {
 	var files []*ast.File
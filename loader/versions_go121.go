@@ -0,0 +1,11 @@
+//go:build !go1.22
+
+package loader
+
+import "go/types"
+
+// initFileVersions does nothing: types.Info.FileVersions was added in Go 1.22.
+// Language features that depend on the file version (per-iteration loop
+// variables, range-over-func) are not available when TinyGo itself is built
+// with an older Go version.
+func initFileVersions(info *types.Info) {}
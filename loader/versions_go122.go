@@ -0,0 +1,15 @@
+//go:build go1.22
+
+package loader
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// initFileVersions makes sure the type checker records the Go version of each
+// file. The SSA builder needs this information to determine the semantics of
+// loop variables and range-over-func statements.
+func initFileVersions(info *types.Info) {
+	info.FileVersions = make(map[*ast.File]string)
+}
@@ -188,6 +188,15 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 			}
 		}
 
+		if result.Header != "" {
+			// Like the go tool, write the C header of a library next to
+			// it, with the same name.
+			err := moveFile(result.Header, strings.TrimSuffix(outpath, filepath.Ext(outpath))+".h")
+			if err != nil {
+				return err
+			}
+		}
+
 		if err := os.Rename(result.Binary, outpath); err != nil {
 			// Moving failed. Do a file copy.
 			inf, err := os.Open(result.Binary)
@@ -215,9 +224,32 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 	return nil
 }
 
+// SizeReport compiles and links the given package and writes an HTML report of
+// its flash and RAM usage to outpath.
+func SizeReport(pkgName, outpath string, options *compileopts.Options) error {
+	options.SizeReport = outpath
+	config, err := builder.NewConfig(options)
+	if err != nil {
+		return err
+	}
+
+	tmpdir, err := os.MkdirTemp("", "tinygo")
+	if err != nil {
+		return err
+	}
+	if !options.Work {
+		defer os.RemoveAll(tmpdir)
+	}
+
+	_, err = builder.Build(pkgName, "", tmpdir, config)
+	return err
+}
+
 // Test runs the tests in the given package. Returns whether the test passed and
 // possibly an error if the test failed to run.
-func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options, outpath string) (bool, error) {
+// If the test is built with -cover, the coverage counters are merged into the
+// given coverage profile (if it is not nil).
+func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options, outpath string, coverProfile *coverageProfile) (bool, error) {
 	options.TestConfig.CompileTestBinary = true
 	config, err := builder.NewConfig(options)
 	if err != nil {
@@ -228,7 +260,7 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 
 	// Pass test flags to the test binary.
 	var flags []string
-	if testConfig.Verbose {
+	if testConfig.Verbose || testConfig.JSON {
 		flags = append(flags, "-test.v")
 	}
 	if testConfig.Short {
@@ -255,8 +287,14 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 	if testConfig.Shuffle != "" {
 		flags = append(flags, "-test.shuffle="+testConfig.Shuffle)
 	}
+	if testConfig.Fuzz != "" {
+		flags = append(flags, "-test.fuzz="+testConfig.Fuzz)
+		if testConfig.FuzzTime != "" {
+			flags = append(flags, "-test.fuzztime="+testConfig.FuzzTime)
+		}
+	}
 
-	logToStdout := testConfig.Verbose || testConfig.BenchRegexp != ""
+	logToStdout := (testConfig.Verbose || testConfig.BenchRegexp != "" || testConfig.Fuzz != "") && !testConfig.JSON
 
 	var buf bytes.Buffer
 	var output io.Writer = &buf
@@ -265,6 +303,20 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		output = os.Stdout
 	}
 
+	// Convert the test output to JSON events (-json).
+	var testJSON *testJSONWriter
+	if testConfig.JSON {
+		testJSON = newTestJSONWriter(stdout)
+		output = testJSON
+	}
+
+	// Collect the coverage counters from the test output (-cover).
+	var coverage *coverageWriter
+	if testConfig.CoverMode != "" {
+		coverage = newCoverageWriter(output)
+		output = coverage
+	}
+
 	passed := false
 	var duration time.Duration
 	result, err := buildAndRun(pkgName, config, output, flags, nil, 0, func(cmd *exec.Cmd, result builder.BuildResult) error {
@@ -286,6 +338,20 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		// Tests are always run in the package directory.
 		cmd.Dir = result.MainDir
 
+		if testConfig.Fuzz != "" {
+			// Store interesting inputs in the cache, like go test -fuzz.
+			importPath := strings.TrimSuffix(result.ImportPath, ".test")
+			cmd.Args = append(cmd.Args, "-test.fuzzcachedir="+filepath.Join(goenv.Get("GOCACHE"), "fuzz", importPath))
+		}
+
+		if testJSON != nil {
+			testJSON.start(strings.TrimSuffix(result.ImportPath, ".test"))
+			// Like go test -json, include stderr in the output events.
+			if cmd.Stderr == os.Stderr {
+				cmd.Stderr = cmd.Stdout
+			}
+		}
+
 		// wasmtime is the default emulator used for `-target=wasi`. wasmtime
 		// is a WebAssembly runtime CLI with WASI enabled by default. However,
 		// only stdio are allowed by default. For example, while STDOUT routes
@@ -293,7 +359,8 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		// variables from the host. Some tests read testdata files, often from
 		// outside the package directory. Other tests require temporary
 		// writeable directories. We allow this by adding wasmtime flags below.
-		if config.EmulatorName() == "wasmtime" {
+		// The builtin runtime (-wasm-runtime=builtin) accepts the same flags.
+		if emulator := config.EmulatorName(); emulator == "wasmtime" || emulator == "wasmrun" {
 			// At this point, The current working directory is at the package
 			// directory. Ex. $GOROOT/src/compress/flate for compress/flate.
 			// buildAndRun has already added arguments for wasmtime, that allow
@@ -308,15 +375,23 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 			//
 			// Ex. run --dir=.. --dir=../.. --dir=../../..
 			dirs := dirsToModuleRoot(result.MainDir, result.ModuleRoot)
-			args := []string{"run"}
+			var args []string
+			if emulator == "wasmtime" {
+				args = append(args, "run")
+			}
 			for _, d := range dirs[1:] {
 				args = append(args, "--dir="+d)
 			}
 
 			// The below re-organizes the arguments so that the current
-			// directory is added last.
-			args = append(args, cmd.Args[1:]...)
-			cmd.Args = append(cmd.Args[:1:1], args...)
+			// directory is added last. The builtin runtime is started as
+			// `tinygo wasmrun`, so the wasmrun command must stay first.
+			start := 1
+			if emulator == "wasmrun" {
+				start = 2
+			}
+			args = append(args, cmd.Args[start:]...)
+			cmd.Args = append(cmd.Args[:start:start], args...)
 		}
 
 		// Run the test.
@@ -324,10 +399,16 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		err = cmd.Run()
 		duration = time.Since(start)
 		passed = err == nil
+		if coverage != nil {
+			coverage.Flush()
+		}
+		if testJSON != nil {
+			testJSON.Flush()
+		}
 
 		// if verbose or benchmarks, then output is already going to stdout
 		// However, if we failed and weren't printing to stdout, print the output we accumulated.
-		if !passed && !logToStdout {
+		if !passed && !logToStdout && testJSON == nil {
 			buf.WriteTo(stdout)
 		}
 
@@ -345,14 +426,39 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 	if logToStdout {
 		w = os.Stdout
 	}
+	// Print the result of the whole package, or emit it as JSON events.
+	printResult := func(importPath, action, line string) {
+		if testJSON != nil {
+			testJSON.exit(importPath, action, line, duration)
+		} else {
+			fmt.Fprint(w, line)
+		}
+	}
 	if err, ok := err.(loader.NoTestFilesError); ok {
-		fmt.Fprintf(w, "?   \t%s\t[no test files]\n", err.ImportPath)
+		printResult(err.ImportPath, "skip", fmt.Sprintf("?   \t%s\t[no test files]\n", err.ImportPath))
 		// Pretend the test passed - it at least didn't fail.
 		return true, nil
-	} else if passed && !testConfig.CompileOnly {
-		fmt.Fprintf(w, "ok  \t%s\t%.3fs\n", importPath, duration.Seconds())
+	}
+
+	// Merge the coverage counters into the coverage profile, also when the
+	// test failed: like `go test`, the profile should still show what the
+	// (partial) test run covered.
+	summary := ""
+	if coverage != nil && !testConfig.CompileOnly {
+		profile := newCoverageProfile(testConfig.CoverMode)
+		if err := profile.add(coverage.profile); err != nil {
+			return false, err
+		}
+		if coverProfile != nil {
+			coverProfile.add(coverage.profile)
+		}
+		summary = "\t" + profile.summary()
+	}
+
+	if passed && !testConfig.CompileOnly {
+		printResult(importPath, "pass", fmt.Sprintf("ok  \t%s\t%.3fs%s\n", importPath, duration.Seconds(), summary))
 	} else {
-		fmt.Fprintf(w, "FAIL\t%s\t%.3fs\n", importPath, duration.Seconds())
+		printResult(importPath, "fail", fmt.Sprintf("FAIL\t%s\t%.3fs\n", importPath, duration.Seconds()))
 	}
 	return passed, err
 }
@@ -793,9 +899,11 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 	// global variables (built into the binary directly) instead of the
 	// conventional way.
 	needsEnvInVars := config.GOOS() == "js"
+	isBaremetal := false
 	for _, tag := range config.BuildTags() {
 		if tag == "baremetal" {
 			needsEnvInVars = true
+			isBaremetal = true
 		}
 	}
 	var args, env []string
@@ -818,9 +926,10 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 				"runtime": runtimeGlobals,
 			}
 		}
-	} else if config.EmulatorName() == "wasmtime" {
-		// Wasmtime needs some special flags to pass environment variables
-		// and allow reading from the current directory.
+	} else if emulator := config.EmulatorName(); emulator == "wasmtime" || emulator == "wasmrun" {
+		// Wasmtime (and the builtin runtime, which mimics it) needs some
+		// special flags to pass environment variables and allow reading from
+		// the current directory.
 		args = append(args, "--dir=.")
 		for _, v := range environmentVars {
 			args = append(args, "--env", v)
@@ -879,6 +988,14 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 		name = emulator[0]
 		emuArgs := append([]string(nil), emulator[1:]...)
 		args = append(emuArgs, args...)
+		if name == "wasmrun" {
+			// The builtin WebAssembly runtime is part of TinyGo itself.
+			name, err = os.Executable()
+			if err != nil {
+				return result, err
+			}
+			args = append([]string{"wasmrun"}, args...)
+		}
 	}
 	var cmd *exec.Cmd
 	if ctx != nil {
@@ -889,6 +1006,14 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 	cmd.Env = append(cmd.Env, env...)
 	cmd.Env = append(cmd.Env, extraCmdEnv...)
 
+	// Baremetal programs write the profile of -pgo=instrument to the
+	// standard output, from where it is written to a file.
+	var profile *coverageWriter
+	if isBaremetal && config.PGO() == "instrument" {
+		profile = newProfileWriter(stdout)
+		stdout = profile
+	}
+
 	// Configure stdout/stderr. The stdout may go to a buffer, not a real
 	// stdout.
 	cmd.Stdout = stdout
@@ -911,6 +1036,14 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 		config.Options.PrintCommands(cmd.Path, cmd.Args...)
 	}
 	err = run(cmd, result)
+	if profile != nil {
+		profile.Flush()
+		if len(profile.profile) != 0 {
+			if err := writeRawProfile(profile.profile); err != nil {
+				return result, fmt.Errorf("could not write profile: %w", err)
+			}
+		}
+	}
 	if err != nil {
 		if ctx != nil && ctx.Err() == context.DeadlineExceeded {
 			stdout.Write([]byte(fmt.Sprintf("--- timeout of %s exceeded, terminating...\n", timeout)))
@@ -1223,20 +1356,23 @@ func usage(command string) {
 		fmt.Fprintln(os.Stderr, "version:", version)
 		fmt.Fprintf(os.Stderr, "usage: %s <command> [arguments]\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "\ncommands:")
-		fmt.Fprintln(os.Stderr, "  build:   compile packages and dependencies")
-		fmt.Fprintln(os.Stderr, "  run:     compile and run immediately")
-		fmt.Fprintln(os.Stderr, "  test:    test packages")
-		fmt.Fprintln(os.Stderr, "  flash:   compile and flash to the device")
-		fmt.Fprintln(os.Stderr, "  gdb:     run/flash and immediately enter GDB")
-		fmt.Fprintln(os.Stderr, "  lldb:    run/flash and immediately enter LLDB")
-		fmt.Fprintln(os.Stderr, "  monitor: open communication port")
-		fmt.Fprintln(os.Stderr, "  env:     list environment variables used during build")
-		fmt.Fprintln(os.Stderr, "  list:    run go list using the TinyGo root")
-		fmt.Fprintln(os.Stderr, "  clean:   empty cache directory ("+goenv.Get("GOCACHE")+")")
-		fmt.Fprintln(os.Stderr, "  targets: list targets")
-		fmt.Fprintln(os.Stderr, "  info:    show info for specified target")
-		fmt.Fprintln(os.Stderr, "  version: show version")
-		fmt.Fprintln(os.Stderr, "  help:    print this help text")
+		fmt.Fprintln(os.Stderr, "  build:       compile packages and dependencies")
+		fmt.Fprintln(os.Stderr, "  run:         compile and run immediately")
+		fmt.Fprintln(os.Stderr, "  test:        test packages")
+		fmt.Fprintln(os.Stderr, "  flash:       compile and flash to the device")
+		fmt.Fprintln(os.Stderr, "  gdb:         run/flash and immediately enter GDB")
+		fmt.Fprintln(os.Stderr, "  lldb:        run/flash and immediately enter LLDB")
+		fmt.Fprintln(os.Stderr, "  monitor:     open communication port")
+		fmt.Fprintln(os.Stderr, "  env:         list environment variables used during build")
+		fmt.Fprintln(os.Stderr, "  list:        run go list using the TinyGo root")
+		fmt.Fprintln(os.Stderr, "  clean:       empty cache directory ("+goenv.Get("GOCACHE")+")")
+		fmt.Fprintln(os.Stderr, "  cache:       show cache usage (cache stats) or trim the cache (cache trim [size])")
+		fmt.Fprintln(os.Stderr, "  targets:     list targets")
+		fmt.Fprintln(os.Stderr, "  sizediff:    compare flash and RAM usage of two executables")
+		fmt.Fprintln(os.Stderr, "  size-report: write an HTML report of flash and RAM usage")
+		fmt.Fprintln(os.Stderr, "  info:        show info for specified target")
+		fmt.Fprintln(os.Stderr, "  version:     show version")
+		fmt.Fprintln(os.Stderr, "  help:        print this help text")
 
 		if flag.Parsed() {
 			fmt.Fprintln(os.Stderr, "\nflags:")
@@ -1374,6 +1510,48 @@ func parseGoLinkFlag(flagsString string) (map[string]map[string]string, error) {
 	return map[string]map[string]string(globalVarValues), nil
 }
 
+// parseByteSize parses a size in bytes, either as a plain number or with a unit
+// suffix such as 48K or 1MB.
+func parseByteSize(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if s == "" {
+		return 0, errors.New("missing size")
+	}
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K", "M", "G":
+		// Short form (48K instead of 48KB), as used in linker scripts.
+		s += "B"
+	}
+	size, err := bytesize.Parse(s)
+	return uint64(size), err
+}
+
+// parseSizeLimit parses the -size-limit parameter, which is a comma separated
+// list of memory regions with their budget. For example: flash=200K,ram=48K.
+func parseSizeLimit(s string) (flash, ram uint64, err error) {
+	for _, limit := range strings.Split(s, ",") {
+		region, value, ok := strings.Cut(limit, "=")
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid -size-limit=%s: expected region=size", s)
+		}
+		size, err := parseByteSize(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid -size-limit=%s: %w", s, err)
+		}
+		switch region {
+		case "flash":
+			flash = size
+		case "ram":
+			ram = size
+		default:
+			return 0, 0, fmt.Errorf("invalid -size-limit=%s: unknown memory region %#v (valid regions are flash, ram)", s, region)
+		}
+	}
+	return flash, ram, nil
+}
+
 // getListOfPackages returns a standard list of packages for a given list that might
 // include wildards using `go list`.
 // For example [./...] => ["pkg1", "pkg1/pkg12", "pkg2"]
@@ -1413,7 +1591,9 @@ func main() {
 
 	opt := flag.String("opt", "z", "optimization level: 0, 1, 2, s, z")
 	gc := flag.String("gc", "", "garbage collector to use (none, leaking, conservative)")
-	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap)")
+	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap, reset)")
+	sanitize := flag.String("sanitize", "", "instrument the program with a sanitizer on Linux (address, undefined)")
+	pgo := flag.String("pgo", "off", "profile-guided optimization: instrument, off, or the path of an LLVM profile (.profdata)")
 	scheduler := flag.String("scheduler", "", "which scheduler to use (none, tasks, asyncify)")
 	serial := flag.String("serial", "", "which serial output to use (none, uart, usb)")
 	work := flag.Bool("work", false, "print the name of the temporary build directory and do not delete this directory on exit")
@@ -1427,10 +1607,18 @@ func main() {
 		stackSize = uint64(size)
 		return err
 	})
-	printSize := flag.String("size", "", "print sizes (none, short, full)")
+	printSize := flag.String("size", "", "print sizes (none, short, full, json)")
+	var flashLimit, ramLimit uint64
+	flag.Func("size-limit", "fail the build when the program exceeds these flash and RAM budgets (for example: flash=200K,ram=48K)", func(s string) (err error) {
+		flashLimit, ramLimit, err = parseSizeLimit(s)
+		return err
+	})
 	printStacks := flag.Bool("print-stacks", false, "print stack sizes of goroutines")
 	printAllocsString := flag.String("print-allocs", "", "regular expression of functions for which heap allocations should be printed")
+	printChecksString := flag.String("print-checks", "", "regular expression of functions for which runtime checks (bounds checks, nil checks, etc) left after optimization should be printed")
+	why := flag.String("why", "", "print the shortest chain of references that causes the given function or global to be linked in")
 	printCommands := flag.Bool("x", false, "Print commands")
+	trace := flag.String("trace", "", "write a trace of the build jobs to this file (in Chrome trace event format)")
 	parallelism := flag.Int("p", runtime.GOMAXPROCS(0), "the number of build jobs that can run in parallel")
 	nodebug := flag.Bool("no-debug", false, "strip debug information")
 	ocdCommandsString := flag.String("ocd-commands", "", "OpenOCD commands, overriding target spec (can specify multiple separated by commas)")
@@ -1443,6 +1631,7 @@ func main() {
 	cpuprofile := flag.String("cpuprofile", "", "cpuprofile output")
 	monitor := flag.Bool("monitor", false, "enable serial monitor")
 	baudrate := flag.Int("baudrate", 115200, "baudrate of serial monitor")
+	wasmRuntime := flag.String("wasm-runtime", "emulator", "how to run WASI binaries: emulator (the emulator of the target, usually wasmtime) or builtin (in-process, with no external binaries)")
 
 	// Internal flags, that are only intended for TinyGo development.
 	printIR := flag.Bool("internal-printir", false, "print LLVM IR")
@@ -1456,7 +1645,7 @@ func main() {
 	skipDwarf := flag.Bool("internal-nodwarf", false, "internal flag, use -no-debug instead")
 
 	var flagJSON, flagDeps, flagTest bool
-	if command == "help" || command == "list" || command == "info" || command == "build" {
+	if command == "help" || command == "list" || command == "info" || command == "build" || command == "test" {
 		flag.BoolVar(&flagJSON, "json", false, "print data in JSON format")
 	}
 	if command == "help" || command == "list" {
@@ -1464,11 +1653,26 @@ func main() {
 		flag.BoolVar(&flagTest, "test", false, "supply -test flag to go list")
 	}
 	var outpath string
-	if command == "help" || command == "build" || command == "build-library" || command == "test" {
+	if command == "help" || command == "build" || command == "build-library" || command == "test" || command == "size-report" {
 		flag.StringVar(&outpath, "o", "", "output filename")
 	}
 
+	var buildMode string
+	if command == "help" || command == "build" {
+		flag.StringVar(&buildMode, "buildmode", "default", "build mode: default (executable), c-archive or c-shared (library with the //export functions and a C header, or a reactor module on WASI)")
+	}
+
+	var sizeThreshold int64 = -1
+	if command == "help" || command == "sizediff" {
+		flag.Func("threshold", "exit with an error when flash usage grows by more than this many bytes", func(s string) error {
+			size, err := parseByteSize(s)
+			sizeThreshold = int64(size)
+			return err
+		})
+	}
+
 	var testConfig compileopts.TestConfig
+	var cover bool
 	if command == "help" || command == "test" {
 		flag.BoolVar(&testConfig.CompileOnly, "c", false, "compile the test binary but do not run it")
 		flag.BoolVar(&testConfig.Verbose, "v", false, "verbose: print additional output")
@@ -1480,6 +1684,15 @@ func main() {
 		flag.StringVar(&testConfig.BenchTime, "benchtime", "", "run each benchmark for duration `d`")
 		flag.BoolVar(&testConfig.BenchMem, "benchmem", false, "show memory stats for benchmarks")
 		flag.StringVar(&testConfig.Shuffle, "shuffle", "", "shuffle the order the tests and benchmarks run")
+		flag.BoolVar(&cover, "cover", false, "enable code coverage analysis")
+		flag.StringVar(&testConfig.CoverMode, "covermode", "", "code coverage mode: set or count (implies -cover)")
+		flag.Func("coverpkg", "comma separated import paths of packages to cover, may end in /... (implies -cover)", func(s string) error {
+			testConfig.CoverPackages = strings.Split(s, ",")
+			return nil
+		})
+		flag.StringVar(&testConfig.CoverProfile, "coverprofile", "", "write a coverage profile to this file (implies -cover)")
+		flag.StringVar(&testConfig.Fuzz, "fuzz", "", "run the fuzz test matching `regexp` with generated inputs")
+		flag.StringVar(&testConfig.FuzzTime, "fuzztime", "", "time to spend fuzzing, or number of iterations like 1000x (default: until a failure is found)")
 	}
 
 	// Early command processing, before commands are interpreted by the Go flag
@@ -1492,9 +1705,25 @@ func main() {
 			os.Exit(1)
 		}
 		os.Exit(0)
+	case "wasmrun":
+		// Run a WASI binary with the builtin runtime (-wasm-runtime=builtin).
+		os.Exit(runWasm(os.Args[2:]))
 	}
 
 	flag.CommandLine.Parse(os.Args[2:])
+	if command == "test" {
+		testConfig.JSON = flagJSON
+	}
+	if cover || testConfig.CoverMode != "" || len(testConfig.CoverPackages) != 0 || testConfig.CoverProfile != "" {
+		switch testConfig.CoverMode {
+		case "":
+			testConfig.CoverMode = "set"
+		case "set", "count":
+		default:
+			fmt.Fprintf(os.Stderr, "invalid -covermode=%s: must be set or count\n", testConfig.CoverMode)
+			os.Exit(1)
+		}
+	}
 	globalVarValues, err := parseGoLinkFlag(*ldflags)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -1510,11 +1739,35 @@ func main() {
 		}
 	}
 
+	var printChecks *regexp.Regexp
+	if *printChecksString != "" {
+		printChecks, err = regexp.Compile(*printChecksString)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	var ocdCommands []string
 	if *ocdCommandsString != "" {
 		ocdCommands = strings.Split(*ocdCommandsString, ",")
 	}
 
+	var cacheMaxSize uint64
+	if s := goenv.Get("TINYGOCACHEMAX"); s != "" {
+		cacheMaxSize, err = parseByteSize(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid TINYGOCACHEMAX=%s: %s\n", s, err)
+			os.Exit(1)
+		}
+	}
+
+	// Like in Go, -pgo=off disables profile-guided optimization.
+	pgoMode := *pgo
+	if pgoMode == "off" {
+		pgoMode = ""
+	}
+
 	options := &compileopts.Options{
 		GOOS:            goenv.Get("GOOS"),
 		GOARCH:          goenv.Get("GOARCH"),
@@ -1524,6 +1777,10 @@ func main() {
 		Opt:             *opt,
 		GC:              *gc,
 		PanicStrategy:   *panicStrategy,
+		Sanitize:        *sanitize,
+		PGO:             pgoMode,
+		BuildMode:       buildMode,
+		WasmRuntime:     *wasmRuntime,
 		Scheduler:       *scheduler,
 		Serial:          *serial,
 		Work:            *work,
@@ -1535,8 +1792,14 @@ func main() {
 		Semaphore:       make(chan struct{}, *parallelism),
 		Debug:           !*nodebug,
 		PrintSizes:      *printSize,
+		FlashLimit:      flashLimit,
+		RAMLimit:        ramLimit,
 		PrintStacks:     *printStacks,
 		PrintAllocs:     printAllocs,
+		PrintChecks:     printChecks,
+		Why:             *why,
+		Trace:           *trace,
+		CacheMaxSize:    cacheMaxSize,
 		Tags:            []string(tags),
 		TestConfig:      testConfig,
 		GlobalValues:    globalVarValues,
@@ -1589,6 +1852,20 @@ func main() {
 
 		err := Build(pkgName, outpath, options)
 		handleCompilerError(err)
+	case "size-report":
+		pkgName := "."
+		if flag.NArg() == 1 {
+			pkgName = filepath.ToSlash(flag.Arg(0))
+		} else if flag.NArg() > 1 {
+			fmt.Fprintln(os.Stderr, "size-report only accepts a single positional argument: package name, but multiple were specified")
+			usage(command)
+			os.Exit(1)
+		}
+		if outpath == "" {
+			outpath = "size-report.html"
+		}
+		err := SizeReport(pkgName, outpath, options)
+		handleCompilerError(err)
 	case "build-library":
 		// Note: this command is only meant to be used while making a release!
 		if outpath == "" {
@@ -1610,6 +1887,12 @@ func main() {
 			lib = &builder.CompilerRT
 		case "picolibc":
 			lib = &builder.Picolibc
+		case "profile":
+			lib = &builder.Profile
+		case "sanitizers":
+			lib = &builder.Sanitizers
+		case "wasmbuiltins":
+			lib = &builder.WasmBuiltins
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown library: %s\n", name)
 			os.Exit(1)
@@ -1675,6 +1958,15 @@ func main() {
 			fmt.Println("cannot use -o flag with multiple packages")
 			os.Exit(1)
 		}
+		if options.TestConfig.Fuzz != "" && len(explicitPkgNames) > 1 {
+			fmt.Println("cannot use -fuzz flag with multiple packages")
+			os.Exit(1)
+		}
+
+		var coverProfile *coverageProfile
+		if options.TestConfig.CoverProfile != "" {
+			coverProfile = newCoverageProfile(options.TestConfig.CoverMode)
+		}
 
 		fail := make(chan struct{}, 1)
 		var wg sync.WaitGroup
@@ -1715,7 +2007,7 @@ func main() {
 				defer close(buf.done)
 				stdout := (*testStdout)(buf)
 				stderr := (*testStderr)(buf)
-				passed, err := Test(pkgName, stdout, stderr, options, outpath)
+				passed, err := Test(pkgName, stdout, stderr, options, outpath, coverProfile)
 				if err != nil {
 					printCompilerError(func(args ...interface{}) {
 						fmt.Fprintln(stderr, args...)
@@ -1732,10 +2024,33 @@ func main() {
 
 		// Wait for all tests to finish.
 		wg.Wait()
+		if coverProfile != nil {
+			err := coverProfile.writeFile(options.TestConfig.CoverProfile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "could not write coverage profile:", err)
+				os.Exit(1)
+			}
+		}
 		close(fail)
 		if _, fail := <-fail; fail {
 			os.Exit(1)
 		}
+	case "sizediff":
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "expected exactly two executables: old and new")
+			usage(command)
+			os.Exit(1)
+		}
+		diff, err := builder.LoadSizeDiff(flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		diff.Print(os.Stdout, *printSize != "short")
+		if sizeThreshold >= 0 && diff.Flash > sizeThreshold {
+			fmt.Fprintf(os.Stderr, "flash usage grew by %d bytes, which is more than the threshold of %d bytes\n", diff.Flash, sizeThreshold)
+			os.Exit(1)
+		}
 	case "monitor":
 		err := Monitor("", *port, options)
 		handleCompilerError(err)
@@ -1863,6 +2178,45 @@ func main() {
 			fmt.Fprintln(os.Stderr, "cannot clean cache:", err)
 			os.Exit(1)
 		}
+	case "cache":
+		cacheDir := goenv.Get("GOCACHE")
+		switch flag.Arg(0) {
+		case "stats":
+			stats, err := builder.ReadCacheStats(cacheDir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "cannot read cache:", err)
+				os.Exit(1)
+			}
+			stats.Print(os.Stdout, options.CacheMaxSize)
+		case "trim":
+			// Trim to the size given on the command line, or to the
+			// configured maximum size.
+			maxSize := options.CacheMaxSize
+			if flag.NArg() >= 2 {
+				maxSize, err = parseByteSize(flag.Arg(1))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "invalid cache size %s: %s\n", flag.Arg(1), err)
+					os.Exit(1)
+				}
+			} else if maxSize == 0 {
+				fmt.Fprintln(os.Stderr, "no maximum cache size: use 'tinygo cache trim <size>' or set TINYGOCACHEMAX")
+				os.Exit(1)
+			}
+			removed, err := builder.TrimCache(cacheDir, maxSize)
+			var removedSize int64
+			for _, entry := range removed {
+				removedSize += entry.Size
+			}
+			fmt.Printf("removed %d cache entries (%d bytes)\n", len(removed), removedSize)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "cannot trim cache:", err)
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintln(os.Stderr, "expected 'stats' or 'trim' subcommand")
+			usage(command)
+			os.Exit(1)
+		}
 	case "help":
 		command := ""
 		if flag.NArg() >= 1 {
@@ -81,8 +81,8 @@ func TestBuild(t *testing.T) {
 		"zeroalloc.go",
 	}
 
-	// Go 1.21 made some changes to the language, which we can only test when
-	// we're actually on Go 1.21.
+	// Go 1.21 and Go 1.23 made some changes to the language, which we can only
+	// test when we're actually on these Go versions.
 	_, minor, err := goenv.GetGorootVersion()
 	if err != nil {
 		t.Fatal("could not get version:", minor)
@@ -90,6 +90,9 @@ func TestBuild(t *testing.T) {
 	if minor >= 21 {
 		tests = append(tests, "go1.21.go")
 	}
+	if minor >= 23 {
+		tests = append(tests, "go1.23.go", "go1.23-net.go", "go1.23-recover.go", "go1.23-timers.go")
+	}
 
 	if *testTarget != "" {
 		// This makes it possible to run one specific test (instead of all),
@@ -153,11 +156,30 @@ func TestBuild(t *testing.T) {
 		runPlatTests(optionsFromTarget("riscv-qemu", sema), tests, t)
 	})
 
+	t.Run("EmulatedRISCV64", func(t *testing.T) {
+		// Only run the recover test on riscv64: it needs architecture
+		// specific support in the compiler and runtime, unlike most other
+		// tests which are already covered by EmulatedRISCV.
+		t.Parallel()
+		options := optionsFromTarget("riscv64-qemu", sema)
+		emuCheck(t, options)
+		runTest("recover.go", options, t, nil, nil)
+	})
+
 	t.Run("AVR", func(t *testing.T) {
 		t.Parallel()
 		runPlatTests(optionsFromTarget("simavr", sema), tests, t)
 	})
 
+	t.Run("WASIBuiltin", func(t *testing.T) {
+		// Same as WASI, but with the builtin runtime instead of wasmtime so it
+		// doesn't need any external tools.
+		t.Parallel()
+		options := optionsFromTarget("wasi", sema)
+		options.WasmRuntime = "builtin"
+		runPlatTests(options, tests, t)
+	})
+
 	if runtime.GOOS == "linux" {
 		for name, osArch := range supportedLinuxArches {
 			options := optionsFromOSARCH(osArch, sema)
@@ -171,10 +193,29 @@ func TestBuild(t *testing.T) {
 			t.Parallel()
 			runPlatTests(optionsFromTarget("wasm", sema), tests, t)
 		})
+		t.Run("WebAssemblyExceptions", func(t *testing.T) {
+			// Only run the recover test: recover() is only supported on
+			// WebAssembly using the exception handling proposal, which
+			// Node.js supports but asyncify doesn't.
+			t.Parallel()
+			options := optionsFromTarget("wasm", sema)
+			options.LLVMFeatures = "+exception-handling"
+			options.Scheduler = "none"
+			emuCheck(t, options)
+			runTest("recover.go", options, t, nil, nil)
+		})
 		t.Run("WASI", func(t *testing.T) {
 			t.Parallel()
 			runPlatTests(optionsFromTarget("wasi", sema), tests, t)
 		})
+		t.Run("WASIp2", func(t *testing.T) {
+			t.Parallel()
+			runPlatTests(optionsFromTarget("wasip2", sema), tests, t)
+		})
+		t.Run("WASIThreads", func(t *testing.T) {
+			t.Parallel()
+			runPlatTests(optionsFromTarget("wasi-threads", sema), tests, t)
+		})
 	}
 }
 
@@ -186,12 +227,16 @@ func runPlatTests(options compileopts.Options, tests []string, t *testing.T) {
 		t.Fatal("failed to load target spec:", err)
 	}
 
-	isWebAssembly := options.Target == "wasi" || options.Target == "wasm" || (options.Target == "" && options.GOARCH == "wasm")
+	isWebAssembly := options.Target == "wasi" || options.Target == "wasi-threads" || options.Target == "wasip2" || options.Target == "wasm" || (options.Target == "" && options.GOARCH == "wasm")
 
 	for _, name := range tests {
+		if isWebAssembly && name == "go1.23-recover.go" {
+			// The recover() builtin isn't supported yet on WebAssembly.
+			continue
+		}
 		if options.GOOS == "linux" && (options.GOARCH == "arm" || options.GOARCH == "386") {
 			switch name {
-			case "timers.go":
+			case "timers.go", "go1.23-timers.go":
 				// Timer tests do not work because syscall.seek is implemented
 				// as Assembly in mainline Go and causes linker failure
 				continue
@@ -246,18 +291,24 @@ func runPlatTests(options compileopts.Options, tests []string, t *testing.T) {
 			runTest("alias.go", options, t, nil, nil)
 		})
 	}
-	if options.Target == "" || options.Target == "wasi" {
+	if options.Target == "" || options.Target == "wasi" || options.Target == "wasi-threads" || options.Target == "wasip2" {
 		t.Run("filesystem.go", func(t *testing.T) {
 			t.Parallel()
 			runTest("filesystem.go", options, t, nil, nil)
 		})
 	}
-	if options.Target == "" || options.Target == "wasi" || options.Target == "wasm" {
+	if options.Target == "" || options.Target == "wasi" || options.Target == "wasi-threads" || options.Target == "wasip2" || options.Target == "wasm" {
 		t.Run("rand.go", func(t *testing.T) {
 			t.Parallel()
 			runTest("rand.go", options, t, nil, nil)
 		})
 	}
+	t.Run("panichandler.go", func(t *testing.T) {
+		t.Parallel()
+		options := compileopts.Options(options)
+		options.PanicStrategy = "reset"
+		runTest("panichandler.go", options, t, nil, nil)
+	})
 	if !isWebAssembly {
 		// The recover() builtin isn't supported yet on WebAssembly and Windows.
 		t.Run("recover.go", func(t *testing.T) {
@@ -273,7 +324,7 @@ func emuCheck(t *testing.T, options compileopts.Options) {
 	if err != nil {
 		t.Fatal("failed to load target spec:", err)
 	}
-	if spec.Emulator != "" {
+	if spec.Emulator != "" && options.WasmRuntime != "builtin" {
 		emulatorCommand := strings.SplitN(spec.Emulator, " ", 2)[0]
 		_, err := exec.LookPath(emulatorCommand)
 		if err != nil {
@@ -285,6 +336,12 @@ func emuCheck(t *testing.T, options compileopts.Options) {
 			return
 		}
 	}
+	if spec.WITPackage != "" {
+		// Components are created using wasm-tools.
+		if _, err := exec.LookPath(goenv.Get("WASMTOOLS")); err != nil {
+			t.Skipf("wasm-tools not installed: %v", err)
+		}
+	}
 }
 
 func optionsFromTarget(target string, sema chan struct{}) compileopts.Options {
@@ -427,6 +484,11 @@ func TestTest(t *testing.T) {
 			targ{"WASM", optionsFromTarget("wasm", sema)},
 			targ{"WASI", optionsFromTarget("wasi", sema)},
 		)
+
+		// Builtin WebAssembly runtime
+		wasiBuiltin := optionsFromTarget("wasi", sema)
+		wasiBuiltin.WasmRuntime = "builtin"
+		targs = append(targs, targ{"WASIBuiltin", wasiBuiltin})
 	}
 	for _, targ := range targs {
 		targ := targ
@@ -447,7 +509,7 @@ func TestTest(t *testing.T) {
 				defer out.Close()
 
 				opts := targ.opts
-				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/pass", out, out, &opts, "")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/pass", out, out, &opts, "", nil)
 				if err != nil {
 					t.Errorf("test error: %v", err)
 				}
@@ -468,7 +530,7 @@ func TestTest(t *testing.T) {
 				defer out.Close()
 
 				opts := targ.opts
-				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/fail", out, out, &opts, "")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/fail", out, out, &opts, "", nil)
 				if err != nil {
 					t.Errorf("test error: %v", err)
 				}
@@ -495,7 +557,7 @@ func TestTest(t *testing.T) {
 
 				var output bytes.Buffer
 				opts := targ.opts
-				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/nothing", io.MultiWriter(&output, out), out, &opts, "")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/nothing", io.MultiWriter(&output, out), out, &opts, "", nil)
 				if err != nil {
 					t.Errorf("test error: %v", err)
 				}
@@ -507,6 +569,33 @@ func TestTest(t *testing.T) {
 				}
 			})
 
+			t.Run("FailCover", func(t *testing.T) {
+				t.Parallel()
+
+				// The coverage of a failing test must still end up in the
+				// coverage profile.
+
+				var wg sync.WaitGroup
+				defer wg.Wait()
+
+				out := ioLogger(t, &wg)
+				defer out.Close()
+
+				opts := targ.opts
+				opts.TestConfig.CoverMode = "set"
+				profile := newCoverageProfile("set")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/fail", out, out, &opts, "", profile)
+				if err != nil {
+					t.Errorf("test error: %v", err)
+				}
+				if passed {
+					t.Error("test passed")
+				}
+				if percent, ok := profile.percent(); !ok || percent != 100 {
+					t.Errorf("unexpected coverage of the failing test: %s", profile.summary())
+				}
+			})
+
 			t.Run("BuildErr", func(t *testing.T) {
 				t.Parallel()
 
@@ -519,7 +608,7 @@ func TestTest(t *testing.T) {
 				defer out.Close()
 
 				opts := targ.opts
-				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/builderr", out, out, &opts, "")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/builderr", out, out, &opts, "", nil)
 				if err == nil {
 					t.Error("test did not error")
 				}
@@ -587,6 +676,32 @@ func TestGetListOfPackages(t *testing.T) {
 	}
 }
 
+func TestParseSizeLimit(t *testing.T) {
+	tests := []struct {
+		limit      string
+		flash, ram uint64
+		err        bool
+	}{
+		{limit: "flash=200K,ram=48K", flash: 200 * 1024, ram: 48 * 1024},
+		{limit: "ram=1000", ram: 1000},
+		{limit: "flash=1MB", flash: 1024 * 1024},
+		{limit: "rom=16K", err: true},
+		{limit: "flash", err: true},
+		{limit: "flash=", err: true},
+		{limit: "flash=lots", err: true},
+	}
+	for _, tc := range tests {
+		flash, ram, err := parseSizeLimit(tc.limit)
+		if (err != nil) != tc.err {
+			t.Errorf("-size-limit=%s: unexpected error state: %v", tc.limit, err)
+			continue
+		}
+		if flash != tc.flash || ram != tc.ram {
+			t.Errorf("-size-limit=%s: expected flash=%d ram=%d, got flash=%d ram=%d", tc.limit, tc.flash, tc.ram, flash, ram)
+		}
+	}
+}
+
 // This TestMain is necessary because TinyGo may also be invoked to run certain
 // LLVM tools in a separate process. Not capturing these invocations would lead
 // to recursive tests.
@@ -601,6 +716,9 @@ func TestMain(m *testing.M) {
 				os.Exit(1)
 			}
 			os.Exit(0)
+		case "wasmrun":
+			// Run a WASI binary with the builtin runtime.
+			os.Exit(runWasm(os.Args[2:]))
 		}
 	}
 
@@ -13,6 +13,7 @@ import (
 	"os/signal"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mattn/go-tty"
@@ -102,6 +103,9 @@ func Monitor(executable, port string, options *compileopts.Options) error {
 							fmt.Printf("[tinygo: panic at %s]\n", loc.String())
 						}
 					}
+					if record := extractCrashRecord(line); record != nil {
+						printCrashRecordLocations(executable, record)
+					}
 					line = line[:0]
 				} else {
 					line = append(line, c)
@@ -142,6 +146,69 @@ func extractPanicAddress(line []byte) uint64 {
 	return 0
 }
 
+var crashRecordMatch = regexp.MustCompile(`^crash record: pc=(0x[0-9a-f]+|nil) lr=(0x[0-9a-f]+|nil) .* stack=((?:(?:0x[0-9a-f]+|nil),?)*)`)
+
+// crashRecord contains the code addresses of a crash record as printed by the
+// Cortex-M HardFault handler (with -panic=reset) or by runtime.CrashRecord.
+type crashRecord struct {
+	pc    uint64
+	lr    uint64
+	stack []uint64
+}
+
+// Extract the code addresses from a "crash record:" line. It returns nil if
+// the line is not a crash record.
+func extractCrashRecord(line []byte) *crashRecord {
+	matches := crashRecordMatch.FindSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	record := &crashRecord{
+		pc: parseCrashRecordWord(string(matches[1])),
+		lr: parseCrashRecordWord(string(matches[2])),
+	}
+	for _, word := range strings.Split(string(matches[3]), ",") {
+		if word != "" {
+			record.stack = append(record.stack, parseCrashRecordWord(word))
+		}
+	}
+	return record
+}
+
+// Parse a word as printed by the runtime, which prints zero as "nil".
+func parseCrashRecordWord(word string) uint64 {
+	value, _ := strconv.ParseUint(strings.TrimPrefix(word, "0x"), 16, 64)
+	return value
+}
+
+// Print the source locations of the addresses in a crash record. Stack words
+// that don't look like a return address are skipped.
+func printCrashRecordLocations(executable string, record *crashRecord) {
+	if loc, err := addressToLine(executable, record.pc); err == nil && loc.IsValid() {
+		fmt.Printf("[tinygo: crash at %s]\n", loc.String())
+	}
+	if record.lr&1 != 0 {
+		if loc, err := addressToLine(executable, returnAddressToCall(record.lr)); err == nil && loc.IsValid() {
+			fmt.Printf("[tinygo: called from %s]\n", loc.String())
+		}
+	}
+	for _, word := range record.stack {
+		if word&1 == 0 {
+			// Return addresses on Cortex-M always have the Thumb bit set.
+			continue
+		}
+		if loc, err := addressToLine(executable, returnAddressToCall(word)); err == nil && loc.IsValid() {
+			fmt.Printf("[tinygo: stack 0x%x at %s]\n", word, loc.String())
+		}
+	}
+}
+
+// Convert a Thumb return address (as stored in the LR register or on the
+// stack) to an address inside the call instruction.
+func returnAddressToCall(address uint64) uint64 {
+	return (address &^ 1) - 1
+}
+
 // Convert an address in the binary to a source address location.
 func addressToLine(executable string, address uint64) (token.Position, error) {
 	data, err := readDWARF(executable)
@@ -64,3 +64,24 @@ func TestTraceback(t *testing.T) {
 		t.Errorf("expected panic location to be line 6, got line %d", location.Line)
 	}
 }
+
+func TestExtractCrashRecord(t *testing.T) {
+	line := []byte("crash record: pc=0x00001234 lr=0x00000f01 sp=0x20001fc0 psr=0x61000000 cfsr=0x00008200 hfsr=0x40000000 mmfar=0x00000000 bfar=0x00000004 goroutine=0x20000100 stack=nil,0x00000a3d")
+	record := extractCrashRecord(line)
+	if record == nil {
+		t.Fatal("could not extract crash record")
+	}
+	if record.pc != 0x1234 || record.lr != 0xf01 {
+		t.Errorf("unexpected registers: pc=%#x lr=%#x", record.pc, record.lr)
+	}
+	if len(record.stack) != 2 || record.stack[0] != 0 || record.stack[1] != 0xa3d {
+		t.Errorf("unexpected stack: %#x", record.stack)
+	}
+	if address := returnAddressToCall(record.stack[1]); address != 0xa3b {
+		t.Errorf("unexpected call address for return address 0xa3d: %#x", address)
+	}
+
+	if extractCrashRecord([]byte("panic: runtime error at 0x00001234: nil pointer dereference")) != nil {
+		t.Error("extracted crash record from a panic message")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+// This file collects the PGO profile written by baremetal programs built with
+// -pgo=instrument, which have no file system to write it to.
+
+import (
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+)
+
+// Marker lines around the hex encoded raw profile in the program output. They
+// are written by runtime.pgoWrite.
+const (
+	profileBeginMarker = "tinygo-profile-begin"
+	profileEndMarker   = "tinygo-profile-end"
+)
+
+// newProfileWriter returns a writer that passes through the output of a
+// program, except for the raw profile which is collected instead.
+func newProfileWriter(w io.Writer) *coverageWriter {
+	return &coverageWriter{w: w, beginMarker: profileBeginMarker, endMarker: profileEndMarker}
+}
+
+// writeRawProfile decodes the collected profile and writes it to the file in
+// the LLVM_PROFILE_FILE environment variable, or default.profraw. This is the
+// same file the profile runtime writes to on other targets.
+func writeRawProfile(lines []string) error {
+	data, err := hex.DecodeString(strings.Join(lines, ""))
+	if err != nil {
+		return err
+	}
+	filename := os.Getenv("LLVM_PROFILE_FILE")
+	if filename == "" {
+		filename = "default.profraw"
+	}
+	return os.WriteFile(filename, data, 0666)
+}
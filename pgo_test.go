@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileWriter(t *testing.T) {
+	// The profile is written in hex by the runtime, after the program output.
+	var buf bytes.Buffer
+	pw := newProfileWriter(&buf)
+	pw.Write([]byte("hello\r\ntinygo-profile-begin\r\n81726600\r\nff\r\ntinygo-profile-end\r\n"))
+	pw.Flush()
+	if buf.String() != "hello\r\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.profraw")
+	t.Setenv("LLVM_PROFILE_FILE", filename)
+	if err := writeRawProfile(pw.profile); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0x81, 0x72, 0x66, 0x00, 0xff}) {
+		t.Errorf("unexpected profile: %x", data)
+	}
+}
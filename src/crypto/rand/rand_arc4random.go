@@ -1,4 +1,4 @@
-//go:build darwin || tinygo.wasm
+//go:build darwin || (tinygo.wasm && !wasip2)
 
 // This implementation of crypto/rand uses the arc4random_buf function
 // (available on both MacOS and WASI) to generate random numbers.
@@ -0,0 +1,22 @@
+//go:build wasip2
+
+// This implementation of crypto/rand uses the wasi:random/random interface of
+// WASI 0.2, which returns cryptographically secure random data.
+
+package rand
+
+import "internal/wasi/random/random"
+
+func init() {
+	Reader = &reader{}
+}
+
+type reader struct {
+}
+
+func (r *reader) Read(b []byte) (n int, err error) {
+	for n < len(b) {
+		n += copy(b[n:], random.GetRandomBytes(uint64(len(b)-n)))
+	}
+	return len(b), nil
+}
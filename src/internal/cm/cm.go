@@ -0,0 +1,144 @@
+// Package cm contains types and helper functions for the canonical ABI of the
+// WebAssembly component model, as used by the bindings in internal/wasi.
+//
+// See: https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md
+package cm
+
+import "unsafe"
+
+// Resource is a handle to a resource (own<T> or borrow<T>), which is an index
+// in the resource table of the component instance.
+type Resource uint32
+
+// free releases memory that was allocated with cabi_realloc by the host while
+// returning values from an imported function. It is implemented in the
+// runtime.
+//
+//export free
+func free(ptr unsafe.Pointer)
+
+// List is the memory layout of list<T>.
+type List[T any] struct {
+	data *T
+	len  uint32
+}
+
+// ToList returns a List that refers to the elements of s, for passing to an
+// imported function. The slice must stay alive until the call returns.
+func ToList[T any](s []T) List[T] {
+	if len(s) == 0 {
+		return List[T]{}
+	}
+	return List[T]{data: &s[0], len: uint32(len(s))}
+}
+
+// Lower returns the flattened representation of the list: a pointer and a
+// length.
+func (l List[T]) Lower() (unsafe.Pointer, uint32) {
+	return unsafe.Pointer(l.data), l.len
+}
+
+// Len returns the number of elements in the list.
+func (l List[T]) Len() int {
+	return int(l.len)
+}
+
+// Lift returns the list returned by an imported function as a Go slice. The
+// memory was allocated with cabi_realloc, and is handed over to the garbage
+// collector: Lift must be called exactly once for every returned list.
+func (l List[T]) Lift() []T {
+	if l.len == 0 {
+		free(unsafe.Pointer(l.data))
+		return nil
+	}
+	s := unsafe.Slice(l.data, l.len)
+	free(unsafe.Pointer(l.data))
+	return s
+}
+
+// String is the memory layout of string.
+type String List[byte]
+
+// LowerString returns the flattened representation of a string: a pointer and
+// a length. The string must stay alive until the call returns.
+func LowerString(s string) (unsafe.Pointer, uint32) {
+	if len(s) == 0 {
+		return nil, 0
+	}
+	return unsafe.Pointer((*stringHeader)(unsafe.Pointer(&s)).data), uint32(len(s))
+}
+
+// Lift returns the string returned by an imported function as a Go string.
+// Like List.Lift, it must be called exactly once.
+func (s String) Lift() string {
+	b := List[byte](s).Lift()
+	if len(b) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&stringHeader{data: &b[0], len: uintptr(len(b))}))
+}
+
+type stringHeader struct {
+	data *byte
+	len  uintptr
+}
+
+// Option is the memory layout of option<T>.
+type Option[T any] struct {
+	isSome bool
+	value  T
+}
+
+// Some returns an Option with the given value.
+func Some[T any](value T) Option[T] {
+	return Option[T]{isSome: true, value: value}
+}
+
+// Get returns the value and whether there is a value at all (some), or the zero
+// value and false (none).
+func (o *Option[T]) Get() (T, bool) {
+	return o.value, o.isSome
+}
+
+// Result is the memory layout of result<OK, Err>. Shape is the larger of the
+// two types, the zero-length arrays make sure the payload is aligned for both.
+type Result[Shape, OK, Err any] struct {
+	isErr bool
+	_     [0]OK
+	_     [0]Err
+	data  Shape
+}
+
+// IsErr returns whether the result is an error.
+func (r *Result[Shape, OK, Err]) IsErr() bool {
+	return r.isErr
+}
+
+// OK returns the value of the result, or nil if it is an error.
+func (r *Result[Shape, OK, Err]) OK() *OK {
+	if r.isErr {
+		return nil
+	}
+	return (*OK)(unsafe.Pointer(&r.data))
+}
+
+// Err returns the error of the result, or nil if it is not an error.
+func (r *Result[Shape, OK, Err]) Err() *Err {
+	if !r.isErr {
+		return nil
+	}
+	return (*Err)(unsafe.Pointer(&r.data))
+}
+
+// Tuple is the memory layout of tuple<T0, T1>.
+type Tuple[T0, T1 any] struct {
+	F0 T0
+	F1 T1
+}
+
+// Tuple3 is the memory layout of tuple<T0, T1, T2>.
+type Tuple3[T0, T1, T2 any] struct {
+	F0 T0
+	F1 T1
+	F2 T2
+}
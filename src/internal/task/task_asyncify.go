@@ -27,6 +27,9 @@ type state struct {
 	// stackState is the state of the stack while unwound.
 	stackState
 
+	// threadState makes sure the task only runs on one thread at a time.
+	threadState
+
 	launched bool
 }
 
@@ -74,25 +77,18 @@ func (s *state) initialize(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
 //go:linkname runqueuePushBack runtime.runqueuePushBack
 func runqueuePushBack(*Task)
 
-// currentTask is the current running task, or nil if currently in the scheduler.
-var currentTask *Task
-
-// Current returns the current active task.
-func Current() *Task {
-	return currentTask
-}
-
 // Pause suspends the current task and returns to the scheduler.
 // This function may only be called when running on a goroutine stack, not when running on the system stack.
 func Pause() {
 	// This is mildly unsafe but this is also the only place we can do this.
-	if *(*uintptr)(unsafe.Pointer(currentTask.state.asyncifysp)) != stackCanary {
+	t := Current()
+	if *(*uintptr)(unsafe.Pointer(t.state.asyncifysp)) != stackCanary {
 		runtimePanic("stack overflow")
 	}
 
-	currentTask.state.unwind()
+	t.state.unwind()
 
-	*(*uintptr)(unsafe.Pointer(currentTask.state.asyncifysp)) = stackCanary
+	*(*uintptr)(unsafe.Pointer(t.state.asyncifysp)) = stackCanary
 }
 
 //export tinygo_unwind
@@ -102,20 +98,22 @@ func (*stackState) unwind()
 // This may only be called from the scheduler.
 func (t *Task) Resume() {
 	// The current task must be saved and restored because this can nest on WASM with JS.
-	prevTask := currentTask
+	prevTask := Current()
+	t.state.acquire()
 	t.gcData.swap()
-	currentTask = t
+	setCurrent(t)
 	if !t.state.launched {
 		t.state.launch()
 		t.state.launched = true
 	} else {
 		t.state.rewind()
 	}
-	currentTask = prevTask
+	setCurrent(prevTask)
 	t.gcData.swap()
 	if t.state.asyncifysp > t.state.csp {
 		runtimePanic("stack overflow")
 	}
+	t.state.release()
 }
 
 //export tinygo_rewind
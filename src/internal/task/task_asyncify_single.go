@@ -0,0 +1,23 @@
+//go:build scheduler.asyncify && !wasm.threads
+
+package task
+
+// currentTask is the current running task, or nil if currently in the scheduler.
+var currentTask *Task
+
+// Current returns the current active task.
+func Current() *Task {
+	return currentTask
+}
+
+func setCurrent(t *Task) {
+	currentTask = t
+}
+
+// threadState is empty when there is only a single thread: a task can't be
+// resumed while it is still running.
+type threadState struct{}
+
+func (s *threadState) acquire() {}
+
+func (s *threadState) release() {}
@@ -0,0 +1,57 @@
+//go:build scheduler.asyncify && wasm.threads
+
+package task
+
+import "sync/atomic"
+
+// MaxThreads is the maximum number of threads that can run goroutines,
+// including the main thread.
+const MaxThreads = 64
+
+// currentTasks is the current running task of each thread (indexed by thread
+// index), or nil if that thread is currently in the scheduler. This is a
+// regular global instead of thread-local storage so that the garbage collector
+// can find the running tasks of all threads.
+var currentTasks [MaxThreads]*Task
+
+// threadIndex returns the index of the current thread. The main thread has
+// index 0.
+//
+//export tinygo_thread_index
+func threadIndex() uint32
+
+//export tinygo_futex_wait
+func futexWait(addr *uint32, cmp uint32, timeout int64)
+
+//export tinygo_futex_wake
+func futexWake(addr *uint32, count uint32)
+
+// Current returns the current active task.
+func Current() *Task {
+	return currentTasks[threadIndex()]
+}
+
+func setCurrent(t *Task) {
+	currentTasks[threadIndex()] = t
+}
+
+// threadState tracks whether a task is currently running on some thread.
+type threadState struct {
+	// running is 1 while the task runs (or is still unwinding) on a thread.
+	running uint32
+}
+
+// acquire waits until the task is not running on any thread anymore. A task
+// can be scheduled again before it has been fully paused: for example, a
+// blocking channel operation releases the runtime lock before calling Pause,
+// after which another thread may wake it up.
+func (s *threadState) acquire() {
+	for !atomic.CompareAndSwapUint32(&s.running, 0, 1) {
+		futexWait(&s.running, 1, -1)
+	}
+}
+
+func (s *threadState) release() {
+	atomic.StoreUint32(&s.running, 0)
+	futexWake(&s.running, 1)
+}
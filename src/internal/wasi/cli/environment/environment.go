@@ -0,0 +1,53 @@
+// Package environment contains the bindings for the
+// wasi:cli/environment@0.2.0 interface.
+package environment
+
+import (
+	"internal/cm"
+	"unsafe"
+)
+
+// GetEnvironment returns the environment variables as key/value pairs.
+func GetEnvironment() [][2]string {
+	var result cm.List[cm.Tuple[cm.String, cm.String]]
+	wasmimport_GetEnvironment(unsafe.Pointer(&result))
+	list := result.Lift()
+	env := make([][2]string, len(list))
+	for i, kv := range list {
+		env[i] = [2]string{kv.F0.Lift(), kv.F1.Lift()}
+	}
+	return env
+}
+
+//go:wasmimport wasi:cli/environment@0.2.0 get-environment
+func wasmimport_GetEnvironment(result unsafe.Pointer)
+
+// GetArguments returns the command line arguments, including the program
+// name.
+func GetArguments() []string {
+	var result cm.List[cm.String]
+	wasmimport_GetArguments(unsafe.Pointer(&result))
+	list := result.Lift()
+	args := make([]string, len(list))
+	for i, arg := range list {
+		args[i] = arg.Lift()
+	}
+	return args
+}
+
+//go:wasmimport wasi:cli/environment@0.2.0 get-arguments
+func wasmimport_GetArguments(result unsafe.Pointer)
+
+// InitialCWD returns the initial working directory, if there is one.
+func InitialCWD() (string, bool) {
+	var result cm.Option[cm.String]
+	wasmimport_InitialCWD(unsafe.Pointer(&result))
+	cwd, ok := result.Get()
+	if !ok {
+		return "", false
+	}
+	return cwd.Lift(), true
+}
+
+//go:wasmimport wasi:cli/environment@0.2.0 initial-cwd
+func wasmimport_InitialCWD(result unsafe.Pointer)
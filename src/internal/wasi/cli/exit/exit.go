@@ -0,0 +1,15 @@
+// Package exit contains the bindings for the wasi:cli/exit@0.2.0 interface.
+package exit
+
+// Exit exits the current instance, with a status that indicates success or
+// failure. It does not return.
+func Exit(isErr bool) {
+	status := uint32(0)
+	if isErr {
+		status = 1
+	}
+	wasmimport_Exit(status)
+}
+
+//go:wasmimport wasi:cli/exit@0.2.0 exit
+func wasmimport_Exit(status uint32)
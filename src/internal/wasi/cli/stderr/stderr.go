@@ -0,0 +1,12 @@
+// Package stderr contains the bindings for the wasi:cli/stderr@0.2.0 interface.
+package stderr
+
+import "internal/wasi/io/streams"
+
+// GetStderr returns the stderr stream of the program.
+func GetStderr() streams.OutputStream {
+	return streams.OutputStream(wasmimport_GetStderr())
+}
+
+//go:wasmimport wasi:cli/stderr@0.2.0 get-stderr
+func wasmimport_GetStderr() uint32
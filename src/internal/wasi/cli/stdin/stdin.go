@@ -0,0 +1,12 @@
+// Package stdin contains the bindings for the wasi:cli/stdin@0.2.0 interface.
+package stdin
+
+import "internal/wasi/io/streams"
+
+// GetStdin returns the stdin stream of the program.
+func GetStdin() streams.InputStream {
+	return streams.InputStream(wasmimport_GetStdin())
+}
+
+//go:wasmimport wasi:cli/stdin@0.2.0 get-stdin
+func wasmimport_GetStdin() uint32
@@ -0,0 +1,12 @@
+// Package stdout contains the bindings for the wasi:cli/stdout@0.2.0 interface.
+package stdout
+
+import "internal/wasi/io/streams"
+
+// GetStdout returns the stdout stream of the program.
+func GetStdout() streams.OutputStream {
+	return streams.OutputStream(wasmimport_GetStdout())
+}
+
+//go:wasmimport wasi:cli/stdout@0.2.0 get-stdout
+func wasmimport_GetStdout() uint32
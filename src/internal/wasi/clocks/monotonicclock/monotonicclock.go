@@ -0,0 +1,44 @@
+// Package monotonicclock contains the bindings for the
+// wasi:clocks/monotonic-clock@0.2.0 interface.
+package monotonicclock
+
+import "internal/wasi/io/poll"
+
+// Instant is an instant in time, in nanoseconds.
+type Instant uint64
+
+// Duration is a duration of time, in nanoseconds.
+type Duration uint64
+
+// Now reads the current value of the clock.
+func Now() Instant {
+	return Instant(wasmimport_Now())
+}
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 now
+func wasmimport_Now() uint64
+
+// Resolution returns the resolution of the clock.
+func Resolution() Duration {
+	return Duration(wasmimport_Resolution())
+}
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 resolution
+func wasmimport_Resolution() uint64
+
+// SubscribeInstant returns a pollable that is ready at the given instant.
+func SubscribeInstant(when Instant) poll.Pollable {
+	return poll.Pollable(wasmimport_SubscribeInstant(uint64(when)))
+}
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 subscribe-instant
+func wasmimport_SubscribeInstant(when uint64) uint32
+
+// SubscribeDuration returns a pollable that is ready when the given duration
+// has elapsed, starting now.
+func SubscribeDuration(when Duration) poll.Pollable {
+	return poll.Pollable(wasmimport_SubscribeDuration(uint64(when)))
+}
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 subscribe-duration
+func wasmimport_SubscribeDuration(when uint64) uint32
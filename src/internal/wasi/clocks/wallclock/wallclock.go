@@ -0,0 +1,30 @@
+// Package wallclock contains the bindings for the
+// wasi:clocks/wall-clock@0.2.0 interface.
+package wallclock
+
+import "unsafe"
+
+// DateTime represents the record "wasi:clocks/wall-clock@0.2.0#datetime": a
+// time since the Unix epoch.
+type DateTime struct {
+	Seconds     uint64
+	Nanoseconds uint32
+}
+
+// Now reads the current value of the clock.
+func Now() (result DateTime) {
+	wasmimport_Now(unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:clocks/wall-clock@0.2.0 now
+func wasmimport_Now(result unsafe.Pointer)
+
+// Resolution returns the resolution of the clock.
+func Resolution() (result DateTime) {
+	wasmimport_Resolution(unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:clocks/wall-clock@0.2.0 resolution
+func wasmimport_Resolution(result unsafe.Pointer)
@@ -0,0 +1,31 @@
+// Package preopens contains the bindings for the
+// wasi:filesystem/preopens@0.2.0 interface.
+package preopens
+
+import (
+	"internal/cm"
+	"internal/wasi/filesystem/types"
+	"unsafe"
+)
+
+// Preopen is a directory that was made available to the program, and the path
+// under which it is visible.
+type Preopen struct {
+	Descriptor types.Descriptor
+	Path       string
+}
+
+// GetDirectories returns the preopened directories.
+func GetDirectories() []Preopen {
+	var result cm.List[cm.Tuple[types.Descriptor, cm.String]]
+	wasmimport_GetDirectories(unsafe.Pointer(&result))
+	list := result.Lift()
+	dirs := make([]Preopen, len(list))
+	for i, dir := range list {
+		dirs[i] = Preopen{dir.F0, dir.F1.Lift()}
+	}
+	return dirs
+}
+
+//go:wasmimport wasi:filesystem/preopens@0.2.0 get-directories
+func wasmimport_GetDirectories(result unsafe.Pointer)
@@ -0,0 +1,385 @@
+// Package types contains the bindings for the wasi:filesystem/types@0.2.0
+// interface.
+package types
+
+import (
+	"internal/cm"
+	"internal/wasi/clocks/wallclock"
+	"internal/wasi/io/ioerror"
+	"internal/wasi/io/streams"
+	"unsafe"
+)
+
+// FileSize is a size or offset in a file, in bytes.
+type FileSize uint64
+
+// DescriptorType represents the enum "wasi:filesystem/types@0.2.0#descriptor-type".
+type DescriptorType uint8
+
+const (
+	DescriptorTypeUnknown DescriptorType = iota
+	DescriptorTypeBlockDevice
+	DescriptorTypeCharacterDevice
+	DescriptorTypeDirectory
+	DescriptorTypeFIFO
+	DescriptorTypeSymbolicLink
+	DescriptorTypeRegularFile
+	DescriptorTypeSocket
+)
+
+// DescriptorFlags represents the flags "wasi:filesystem/types@0.2.0#descriptor-flags".
+type DescriptorFlags uint8
+
+const (
+	DescriptorFlagsRead DescriptorFlags = 1 << iota
+	DescriptorFlagsWrite
+	DescriptorFlagsFileIntegritySync
+	DescriptorFlagsDataIntegritySync
+	DescriptorFlagsRequestedWriteSync
+	DescriptorFlagsMutateDirectory
+)
+
+// PathFlags represents the flags "wasi:filesystem/types@0.2.0#path-flags".
+type PathFlags uint8
+
+const (
+	PathFlagsSymlinkFollow PathFlags = 1 << iota
+)
+
+// OpenFlags represents the flags "wasi:filesystem/types@0.2.0#open-flags".
+type OpenFlags uint8
+
+const (
+	OpenFlagsCreate OpenFlags = 1 << iota
+	OpenFlagsDirectory
+	OpenFlagsExclusive
+	OpenFlagsTruncate
+)
+
+// DescriptorStat represents the record "wasi:filesystem/types@0.2.0#descriptor-stat".
+type DescriptorStat struct {
+	Type                      DescriptorType
+	LinkCount                 uint64
+	Size                      FileSize
+	DataAccessTimestamp       cm.Option[wallclock.DateTime]
+	DataModificationTimestamp cm.Option[wallclock.DateTime]
+	StatusChangeTimestamp     cm.Option[wallclock.DateTime]
+}
+
+// DirectoryEntry represents the record "wasi:filesystem/types@0.2.0#directory-entry".
+// The name must be lifted exactly once.
+type DirectoryEntry struct {
+	Type DescriptorType
+	Name cm.String
+}
+
+// ErrorCode represents the enum "wasi:filesystem/types@0.2.0#error-code".
+type ErrorCode uint8
+
+const (
+	ErrorCodeAccess ErrorCode = iota
+	ErrorCodeWouldBlock
+	ErrorCodeAlready
+	ErrorCodeBadDescriptor
+	ErrorCodeBusy
+	ErrorCodeDeadlock
+	ErrorCodeQuota
+	ErrorCodeExist
+	ErrorCodeFileTooLarge
+	ErrorCodeIllegalByteSequence
+	ErrorCodeInProgress
+	ErrorCodeInterrupted
+	ErrorCodeInvalid
+	ErrorCodeIO
+	ErrorCodeIsDirectory
+	ErrorCodeLoop
+	ErrorCodeTooManyLinks
+	ErrorCodeMessageSize
+	ErrorCodeNameTooLong
+	ErrorCodeNoDevice
+	ErrorCodeNoEntry
+	ErrorCodeNoLock
+	ErrorCodeInsufficientMemory
+	ErrorCodeInsufficientSpace
+	ErrorCodeNotDirectory
+	ErrorCodeNotEmpty
+	ErrorCodeNotRecoverable
+	ErrorCodeUnsupported
+	ErrorCodeNoTTY
+	ErrorCodeNoSuchDevice
+	ErrorCodeOverflow
+	ErrorCodeNotPermitted
+	ErrorCodePipe
+	ErrorCodeReadOnly
+	ErrorCodeInvalidSeek
+	ErrorCodeTextFileBusy
+	ErrorCodeCrossDevice
+)
+
+// MetadataHashValue represents the record "wasi:filesystem/types@0.2.0#metadata-hash-value".
+type MetadataHashValue struct {
+	Lower uint64
+	Upper uint64
+}
+
+// Descriptor represents the resource "wasi:filesystem/types@0.2.0#descriptor".
+type Descriptor cm.Resource
+
+// ResourceDrop drops the descriptor, which closes the file or directory.
+func (self Descriptor) ResourceDrop() {
+	wasmimport_DescriptorResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [resource-drop]descriptor
+func wasmimport_DescriptorResourceDrop(self uint32)
+
+// ReadViaStream returns a stream for reading from the file, starting at the
+// given offset.
+func (self Descriptor) ReadViaStream(offset FileSize) (result cm.Result[streams.InputStream, streams.InputStream, ErrorCode]) {
+	wasmimport_DescriptorReadViaStream(uint32(self), uint64(offset), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.read-via-stream
+func wasmimport_DescriptorReadViaStream(self uint32, offset uint64, result unsafe.Pointer)
+
+// WriteViaStream returns a stream for writing to the file, starting at the
+// given offset.
+func (self Descriptor) WriteViaStream(offset FileSize) (result cm.Result[streams.OutputStream, streams.OutputStream, ErrorCode]) {
+	wasmimport_DescriptorWriteViaStream(uint32(self), uint64(offset), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.write-via-stream
+func wasmimport_DescriptorWriteViaStream(self uint32, offset uint64, result unsafe.Pointer)
+
+// AppendViaStream returns a stream for appending to the file.
+func (self Descriptor) AppendViaStream() (result cm.Result[streams.OutputStream, streams.OutputStream, ErrorCode]) {
+	wasmimport_DescriptorAppendViaStream(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.append-via-stream
+func wasmimport_DescriptorAppendViaStream(self uint32, result unsafe.Pointer)
+
+// GetFlags returns the flags the descriptor was opened with.
+func (self Descriptor) GetFlags() (result cm.Result[DescriptorFlags, DescriptorFlags, ErrorCode]) {
+	wasmimport_DescriptorGetFlags(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.get-flags
+func wasmimport_DescriptorGetFlags(self uint32, result unsafe.Pointer)
+
+// GetType returns the type of the file or directory.
+func (self Descriptor) GetType() (result cm.Result[DescriptorType, DescriptorType, ErrorCode]) {
+	wasmimport_DescriptorGetType(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.get-type
+func wasmimport_DescriptorGetType(self uint32, result unsafe.Pointer)
+
+// SetSize truncates or extends the file.
+func (self Descriptor) SetSize(size FileSize) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	wasmimport_DescriptorSetSize(uint32(self), uint64(size), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.set-size
+func wasmimport_DescriptorSetSize(self uint32, size uint64, result unsafe.Pointer)
+
+// Read reads up to length bytes at the given offset. The boolean in the
+// result is true when the end of the file was reached.
+func (self Descriptor) Read(length FileSize, offset FileSize) (result cm.Result[cm.Tuple[cm.List[uint8], bool], cm.Tuple[cm.List[uint8], bool], ErrorCode]) {
+	wasmimport_DescriptorRead(uint32(self), uint64(length), uint64(offset), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.read
+func wasmimport_DescriptorRead(self uint32, length uint64, offset uint64, result unsafe.Pointer)
+
+// Write writes the buffer at the given offset, and returns the number of
+// bytes written.
+func (self Descriptor) Write(buffer []uint8, offset FileSize) (result cm.Result[uint64, FileSize, ErrorCode]) {
+	ptr, length := cm.ToList(buffer).Lower()
+	wasmimport_DescriptorWrite(uint32(self), ptr, length, uint64(offset), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.write
+func wasmimport_DescriptorWrite(self uint32, buffer0 unsafe.Pointer, buffer1 uint32, offset uint64, result unsafe.Pointer)
+
+// ReadDirectory returns a stream of the entries in the directory.
+func (self Descriptor) ReadDirectory() (result cm.Result[DirectoryEntryStream, DirectoryEntryStream, ErrorCode]) {
+	wasmimport_DescriptorReadDirectory(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.read-directory
+func wasmimport_DescriptorReadDirectory(self uint32, result unsafe.Pointer)
+
+// Sync synchronizes the data and metadata of the file to disk.
+func (self Descriptor) Sync() (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	wasmimport_DescriptorSync(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.sync
+func wasmimport_DescriptorSync(self uint32, result unsafe.Pointer)
+
+// CreateDirectoryAt creates a directory, relative to this descriptor.
+func (self Descriptor) CreateDirectoryAt(path string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorCreateDirectoryAt(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.create-directory-at
+func wasmimport_DescriptorCreateDirectoryAt(self uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// Stat returns the attributes of the file or directory.
+func (self Descriptor) Stat() (result cm.Result[DescriptorStat, DescriptorStat, ErrorCode]) {
+	wasmimport_DescriptorStat(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.stat
+func wasmimport_DescriptorStat(self uint32, result unsafe.Pointer)
+
+// StatAt returns the attributes of a file or directory, relative to this
+// descriptor.
+func (self Descriptor) StatAt(pathFlags PathFlags, path string) (result cm.Result[DescriptorStat, DescriptorStat, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorStatAt(uint32(self), uint32(pathFlags), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.stat-at
+func wasmimport_DescriptorStatAt(self uint32, pathFlags uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// LinkAt creates a hard link.
+func (self Descriptor) LinkAt(oldPathFlags PathFlags, oldPath string, newDescriptor Descriptor, newPath string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	oldPtr, oldLength := cm.LowerString(oldPath)
+	newPtr, newLength := cm.LowerString(newPath)
+	wasmimport_DescriptorLinkAt(uint32(self), uint32(oldPathFlags), oldPtr, oldLength, uint32(newDescriptor), newPtr, newLength, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.link-at
+func wasmimport_DescriptorLinkAt(self uint32, oldPathFlags uint32, oldPath0 unsafe.Pointer, oldPath1 uint32, newDescriptor uint32, newPath0 unsafe.Pointer, newPath1 uint32, result unsafe.Pointer)
+
+// OpenAt opens a file or directory, relative to this descriptor.
+func (self Descriptor) OpenAt(pathFlags PathFlags, path string, openFlags OpenFlags, flags DescriptorFlags) (result cm.Result[Descriptor, Descriptor, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorOpenAt(uint32(self), uint32(pathFlags), ptr, length, uint32(openFlags), uint32(flags), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.open-at
+func wasmimport_DescriptorOpenAt(self uint32, pathFlags uint32, path0 unsafe.Pointer, path1 uint32, openFlags uint32, flags uint32, result unsafe.Pointer)
+
+// ReadlinkAt reads the contents of a symbolic link, relative to this
+// descriptor.
+func (self Descriptor) ReadlinkAt(path string) (result cm.Result[cm.String, cm.String, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorReadlinkAt(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.readlink-at
+func wasmimport_DescriptorReadlinkAt(self uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// RemoveDirectoryAt removes an empty directory, relative to this descriptor.
+func (self Descriptor) RemoveDirectoryAt(path string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorRemoveDirectoryAt(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.remove-directory-at
+func wasmimport_DescriptorRemoveDirectoryAt(self uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// RenameAt renames a file or directory.
+func (self Descriptor) RenameAt(oldPath string, newDescriptor Descriptor, newPath string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	oldPtr, oldLength := cm.LowerString(oldPath)
+	newPtr, newLength := cm.LowerString(newPath)
+	wasmimport_DescriptorRenameAt(uint32(self), oldPtr, oldLength, uint32(newDescriptor), newPtr, newLength, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.rename-at
+func wasmimport_DescriptorRenameAt(self uint32, oldPath0 unsafe.Pointer, oldPath1 uint32, newDescriptor uint32, newPath0 unsafe.Pointer, newPath1 uint32, result unsafe.Pointer)
+
+// SymlinkAt creates a symbolic link, relative to this descriptor.
+func (self Descriptor) SymlinkAt(oldPath string, newPath string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	oldPtr, oldLength := cm.LowerString(oldPath)
+	newPtr, newLength := cm.LowerString(newPath)
+	wasmimport_DescriptorSymlinkAt(uint32(self), oldPtr, oldLength, newPtr, newLength, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.symlink-at
+func wasmimport_DescriptorSymlinkAt(self uint32, oldPath0 unsafe.Pointer, oldPath1 uint32, newPath0 unsafe.Pointer, newPath1 uint32, result unsafe.Pointer)
+
+// UnlinkFileAt removes a file, relative to this descriptor.
+func (self Descriptor) UnlinkFileAt(path string) (result cm.Result[ErrorCode, struct{}, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorUnlinkFileAt(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.unlink-file-at
+func wasmimport_DescriptorUnlinkFileAt(self uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// MetadataHash returns a hash of the metadata that identifies the file, to be
+// used in place of an inode number.
+func (self Descriptor) MetadataHash() (result cm.Result[MetadataHashValue, MetadataHashValue, ErrorCode]) {
+	wasmimport_DescriptorMetadataHash(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.metadata-hash
+func wasmimport_DescriptorMetadataHash(self uint32, result unsafe.Pointer)
+
+// MetadataHashAt is like MetadataHash, for a path relative to this
+// descriptor.
+func (self Descriptor) MetadataHashAt(pathFlags PathFlags, path string) (result cm.Result[MetadataHashValue, MetadataHashValue, ErrorCode]) {
+	ptr, length := cm.LowerString(path)
+	wasmimport_DescriptorMetadataHashAt(uint32(self), uint32(pathFlags), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]descriptor.metadata-hash-at
+func wasmimport_DescriptorMetadataHashAt(self uint32, pathFlags uint32, path0 unsafe.Pointer, path1 uint32, result unsafe.Pointer)
+
+// DirectoryEntryStream represents the resource "wasi:filesystem/types@0.2.0#directory-entry-stream".
+type DirectoryEntryStream cm.Resource
+
+// ResourceDrop drops the directory entry stream.
+func (self DirectoryEntryStream) ResourceDrop() {
+	wasmimport_DirectoryEntryStreamResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [resource-drop]directory-entry-stream
+func wasmimport_DirectoryEntryStreamResourceDrop(self uint32)
+
+// ReadDirectoryEntry returns the next directory entry, or none at the end of
+// the directory.
+func (self DirectoryEntryStream) ReadDirectoryEntry() (result cm.Result[cm.Option[DirectoryEntry], cm.Option[DirectoryEntry], ErrorCode]) {
+	wasmimport_DirectoryEntryStreamReadDirectoryEntry(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 [method]directory-entry-stream.read-directory-entry
+func wasmimport_DirectoryEntryStreamReadDirectoryEntry(self uint32, result unsafe.Pointer)
+
+// FilesystemErrorCode returns the filesystem error code of a stream error, if
+// it is one.
+func FilesystemErrorCode(err ioerror.Error) (result cm.Option[ErrorCode]) {
+	wasmimport_FilesystemErrorCode(uint32(err), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:filesystem/types@0.2.0 filesystem-error-code
+func wasmimport_FilesystemErrorCode(err uint32, result unsafe.Pointer)
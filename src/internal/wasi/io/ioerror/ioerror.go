@@ -0,0 +1,29 @@
+// Package ioerror contains the bindings for the wasi:io/error@0.2.0 interface.
+package ioerror
+
+import (
+	"internal/cm"
+	"unsafe"
+)
+
+// Error represents the resource "wasi:io/error@0.2.0#error".
+type Error cm.Resource
+
+// ResourceDrop drops the error resource.
+func (self Error) ResourceDrop() {
+	wasmimport_ErrorResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:io/error@0.2.0 [resource-drop]error
+func wasmimport_ErrorResourceDrop(self uint32)
+
+// ToDebugString returns a string that is suitable to assist humans in
+// debugging this error.
+func (self Error) ToDebugString() string {
+	var result cm.String
+	wasmimport_ErrorToDebugString(uint32(self), unsafe.Pointer(&result))
+	return result.Lift()
+}
+
+//go:wasmimport wasi:io/error@0.2.0 [method]error.to-debug-string
+func wasmimport_ErrorToDebugString(self uint32, result unsafe.Pointer)
@@ -0,0 +1,46 @@
+// Package poll contains the bindings for the wasi:io/poll@0.2.0 interface.
+package poll
+
+import (
+	"internal/cm"
+	"unsafe"
+)
+
+// Pollable represents the resource "wasi:io/poll@0.2.0#pollable".
+type Pollable cm.Resource
+
+// ResourceDrop drops the pollable resource.
+func (self Pollable) ResourceDrop() {
+	wasmimport_PollableResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:io/poll@0.2.0 [resource-drop]pollable
+func wasmimport_PollableResourceDrop(self uint32)
+
+// Block blocks until the pollable is ready.
+func (self Pollable) Block() {
+	wasmimport_PollableBlock(uint32(self))
+}
+
+//go:wasmimport wasi:io/poll@0.2.0 [method]pollable.block
+func wasmimport_PollableBlock(self uint32)
+
+// Ready returns whether the pollable is ready, without blocking.
+func (self Pollable) Ready() bool {
+	return wasmimport_PollableReady(uint32(self)) != 0
+}
+
+//go:wasmimport wasi:io/poll@0.2.0 [method]pollable.ready
+func wasmimport_PollableReady(self uint32) uint32
+
+// Poll blocks until at least one of the given pollables is ready, and returns
+// the indices of the pollables that are ready.
+func Poll(in []Pollable) []uint32 {
+	var result cm.List[uint32]
+	ptr, length := cm.ToList(in).Lower()
+	wasmimport_Poll(ptr, length, unsafe.Pointer(&result))
+	return result.Lift()
+}
+
+//go:wasmimport wasi:io/poll@0.2.0 poll
+func wasmimport_Poll(in0 unsafe.Pointer, in1 uint32, result unsafe.Pointer)
@@ -0,0 +1,129 @@
+// Package streams contains the bindings for the wasi:io/streams@0.2.0
+// interface.
+package streams
+
+import (
+	"internal/cm"
+	"internal/wasi/io/ioerror"
+	"internal/wasi/io/poll"
+	"unsafe"
+)
+
+// StreamError represents the variant "wasi:io/streams@0.2.0#stream-error".
+type StreamError struct {
+	tag                 uint8
+	lastOperationFailed ioerror.Error
+}
+
+// Closed returns whether the stream is closed, in which case no more data
+// can be read or written.
+func (self *StreamError) Closed() bool {
+	return self.tag == 1
+}
+
+// LastOperationFailed returns the error of the last operation, or nil if the
+// stream is closed. The error must be dropped by the caller.
+func (self *StreamError) LastOperationFailed() *ioerror.Error {
+	if self.tag != 0 {
+		return nil
+	}
+	return &self.lastOperationFailed
+}
+
+// InputStream represents the resource "wasi:io/streams@0.2.0#input-stream".
+type InputStream cm.Resource
+
+// ResourceDrop drops the input stream.
+func (self InputStream) ResourceDrop() {
+	wasmimport_InputStreamResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [resource-drop]input-stream
+func wasmimport_InputStreamResourceDrop(self uint32)
+
+// Read reads up to len bytes from the stream, without blocking.
+func (self InputStream) Read(len uint64) (result cm.Result[cm.List[uint8], cm.List[uint8], StreamError]) {
+	wasmimport_InputStreamRead(uint32(self), len, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]input-stream.read
+func wasmimport_InputStreamRead(self uint32, len uint64, result unsafe.Pointer)
+
+// BlockingRead reads up to len bytes from the stream, blocking until at least
+// one byte is available or the stream is closed.
+func (self InputStream) BlockingRead(len uint64) (result cm.Result[cm.List[uint8], cm.List[uint8], StreamError]) {
+	wasmimport_InputStreamBlockingRead(uint32(self), len, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]input-stream.blocking-read
+func wasmimport_InputStreamBlockingRead(self uint32, len uint64, result unsafe.Pointer)
+
+// Subscribe returns a pollable that is ready when data can be read.
+func (self InputStream) Subscribe() poll.Pollable {
+	return poll.Pollable(wasmimport_InputStreamSubscribe(uint32(self)))
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]input-stream.subscribe
+func wasmimport_InputStreamSubscribe(self uint32) uint32
+
+// OutputStream represents the resource "wasi:io/streams@0.2.0#output-stream".
+type OutputStream cm.Resource
+
+// ResourceDrop drops the output stream.
+func (self OutputStream) ResourceDrop() {
+	wasmimport_OutputStreamResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [resource-drop]output-stream
+func wasmimport_OutputStreamResourceDrop(self uint32)
+
+// CheckWrite returns the number of bytes that can be written without
+// blocking.
+func (self OutputStream) CheckWrite() (result cm.Result[uint64, uint64, StreamError]) {
+	wasmimport_OutputStreamCheckWrite(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.check-write
+func wasmimport_OutputStreamCheckWrite(self uint32, result unsafe.Pointer)
+
+// Write writes the contents to the stream, without blocking. The length must
+// not exceed the value returned by CheckWrite.
+func (self OutputStream) Write(contents []uint8) (result cm.Result[StreamError, struct{}, StreamError]) {
+	ptr, length := cm.ToList(contents).Lower()
+	wasmimport_OutputStreamWrite(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.write
+func wasmimport_OutputStreamWrite(self uint32, contents0 unsafe.Pointer, contents1 uint32, result unsafe.Pointer)
+
+// BlockingWriteAndFlush writes at most 4096 bytes to the stream and blocks
+// until they have been flushed.
+func (self OutputStream) BlockingWriteAndFlush(contents []uint8) (result cm.Result[StreamError, struct{}, StreamError]) {
+	ptr, length := cm.ToList(contents).Lower()
+	wasmimport_OutputStreamBlockingWriteAndFlush(uint32(self), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.blocking-write-and-flush
+func wasmimport_OutputStreamBlockingWriteAndFlush(self uint32, contents0 unsafe.Pointer, contents1 uint32, result unsafe.Pointer)
+
+// BlockingFlush flushes the stream and blocks until the flush has completed.
+func (self OutputStream) BlockingFlush() (result cm.Result[StreamError, struct{}, StreamError]) {
+	wasmimport_OutputStreamBlockingFlush(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.blocking-flush
+func wasmimport_OutputStreamBlockingFlush(self uint32, result unsafe.Pointer)
+
+// Subscribe returns a pollable that is ready when data can be written.
+func (self OutputStream) Subscribe() poll.Pollable {
+	return poll.Pollable(wasmimport_OutputStreamSubscribe(uint32(self)))
+}
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.subscribe
+func wasmimport_OutputStreamSubscribe(self uint32) uint32
@@ -0,0 +1,26 @@
+// Package random contains the bindings for the wasi:random/random@0.2.0
+// interface, which returns cryptographically secure random data.
+package random
+
+import (
+	"internal/cm"
+	"unsafe"
+)
+
+// GetRandomBytes returns len cryptographically secure random bytes.
+func GetRandomBytes(len uint64) []uint8 {
+	var result cm.List[uint8]
+	wasmimport_GetRandomBytes(len, unsafe.Pointer(&result))
+	return result.Lift()
+}
+
+//go:wasmimport wasi:random/random@0.2.0 get-random-bytes
+func wasmimport_GetRandomBytes(len uint64, result unsafe.Pointer)
+
+// GetRandomU64 returns a cryptographically secure random uint64.
+func GetRandomU64() uint64 {
+	return wasmimport_GetRandomU64()
+}
+
+//go:wasmimport wasi:random/random@0.2.0 get-random-u64
+func wasmimport_GetRandomU64() uint64
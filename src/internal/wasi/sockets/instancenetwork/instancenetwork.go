@@ -0,0 +1,13 @@
+// Package instancenetwork contains the bindings for the
+// wasi:sockets/instance-network@0.2.0 interface.
+package instancenetwork
+
+import "internal/wasi/sockets/network"
+
+// InstanceNetwork returns the default network of the program.
+func InstanceNetwork() network.Network {
+	return network.Network(wasmimport_InstanceNetwork())
+}
+
+//go:wasmimport wasi:sockets/instance-network@0.2.0 instance-network
+func wasmimport_InstanceNetwork() uint32
@@ -0,0 +1,51 @@
+// Package ipnamelookup contains the bindings for the
+// wasi:sockets/ip-name-lookup@0.2.0 interface.
+package ipnamelookup
+
+import (
+	"internal/cm"
+	"internal/wasi/io/poll"
+	"internal/wasi/sockets/network"
+	"unsafe"
+)
+
+// ResolveAddressStream represents the resource "wasi:sockets/ip-name-lookup@0.2.0#resolve-address-stream".
+type ResolveAddressStream cm.Resource
+
+// ResourceDrop drops the stream.
+func (self ResolveAddressStream) ResourceDrop() {
+	wasmimport_ResolveAddressStreamResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:sockets/ip-name-lookup@0.2.0 [resource-drop]resolve-address-stream
+func wasmimport_ResolveAddressStreamResourceDrop(self uint32)
+
+// ResolveNextAddress returns the next address, or none when all addresses have
+// been returned. It returns the would-block error code while the lookup is
+// in progress.
+func (self ResolveAddressStream) ResolveNextAddress() (result cm.Result[cm.Option[network.IPAddress], cm.Option[network.IPAddress], network.ErrorCode]) {
+	wasmimport_ResolveAddressStreamResolveNextAddress(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/ip-name-lookup@0.2.0 [method]resolve-address-stream.resolve-next-address
+func wasmimport_ResolveAddressStreamResolveNextAddress(self uint32, result unsafe.Pointer)
+
+// Subscribe returns a pollable that is ready when the stream is ready for
+// reading.
+func (self ResolveAddressStream) Subscribe() poll.Pollable {
+	return poll.Pollable(wasmimport_ResolveAddressStreamSubscribe(uint32(self)))
+}
+
+//go:wasmimport wasi:sockets/ip-name-lookup@0.2.0 [method]resolve-address-stream.subscribe
+func wasmimport_ResolveAddressStreamSubscribe(self uint32) uint32
+
+// ResolveAddresses starts resolving the IP addresses of the given host name.
+func ResolveAddresses(network_ network.Network, name string) (result cm.Result[ResolveAddressStream, ResolveAddressStream, network.ErrorCode]) {
+	ptr, length := cm.LowerString(name)
+	wasmimport_ResolveAddresses(uint32(network_), ptr, length, unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/ip-name-lookup@0.2.0 resolve-addresses
+func wasmimport_ResolveAddresses(network uint32, name0 unsafe.Pointer, name1 uint32, result unsafe.Pointer)
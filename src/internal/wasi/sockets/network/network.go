@@ -0,0 +1,182 @@
+// Package network contains the bindings for the wasi:sockets/network@0.2.0
+// interface.
+package network
+
+import (
+	"internal/cm"
+	"unsafe"
+)
+
+// Network represents the resource "wasi:sockets/network@0.2.0#network": an
+// opaque handle to the network access of the program.
+type Network cm.Resource
+
+// ResourceDrop drops the network resource.
+func (self Network) ResourceDrop() {
+	wasmimport_NetworkResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:sockets/network@0.2.0 [resource-drop]network
+func wasmimport_NetworkResourceDrop(self uint32)
+
+// ErrorCode represents the enum "wasi:sockets/network@0.2.0#error-code".
+type ErrorCode uint8
+
+const (
+	ErrorCodeUnknown ErrorCode = iota
+	ErrorCodeAccessDenied
+	ErrorCodeNotSupported
+	ErrorCodeInvalidArgument
+	ErrorCodeOutOfMemory
+	ErrorCodeTimeout
+	ErrorCodeConcurrencyConflict
+	ErrorCodeNotInProgress
+	ErrorCodeWouldBlock
+	ErrorCodeInvalidState
+	ErrorCodeNewSocketLimit
+	ErrorCodeAddressNotBindable
+	ErrorCodeAddressInUse
+	ErrorCodeRemoteUnreachable
+	ErrorCodeConnectionRefused
+	ErrorCodeConnectionReset
+	ErrorCodeConnectionAborted
+	ErrorCodeDatagramTooLarge
+	ErrorCodeNameUnresolvable
+	ErrorCodeTemporaryResolverFailure
+	ErrorCodePermanentResolverFailure
+)
+
+var errorCodeStrings = [...]string{
+	"unknown",
+	"access-denied",
+	"not-supported",
+	"invalid-argument",
+	"out-of-memory",
+	"timeout",
+	"concurrency-conflict",
+	"not-in-progress",
+	"would-block",
+	"invalid-state",
+	"new-socket-limit",
+	"address-not-bindable",
+	"address-in-use",
+	"remote-unreachable",
+	"connection-refused",
+	"connection-reset",
+	"connection-aborted",
+	"datagram-too-large",
+	"name-unresolvable",
+	"temporary-resolver-failure",
+	"permanent-resolver-failure",
+}
+
+// String returns the WIT name of the error code.
+func (e ErrorCode) String() string {
+	if int(e) < len(errorCodeStrings) {
+		return errorCodeStrings[e]
+	}
+	return "unknown"
+}
+
+// IPAddressFamily represents the enum "wasi:sockets/network@0.2.0#ip-address-family".
+type IPAddressFamily uint8
+
+const (
+	IPAddressFamilyIPv4 IPAddressFamily = iota
+	IPAddressFamilyIPv6
+)
+
+// IPAddress represents the variant "wasi:sockets/network@0.2.0#ip-address".
+type IPAddress struct {
+	tag  uint8
+	data [8]uint16
+}
+
+// IPv4 returns the IPv4 address, or nil if it is an IPv6 address.
+func (self *IPAddress) IPv4() *[4]uint8 {
+	if self.tag != uint8(IPAddressFamilyIPv4) {
+		return nil
+	}
+	return (*[4]uint8)(unsafe.Pointer(&self.data))
+}
+
+// IPv6 returns the IPv6 address, or nil if it is an IPv4 address.
+func (self *IPAddress) IPv6() *[8]uint16 {
+	if self.tag != uint8(IPAddressFamilyIPv6) {
+		return nil
+	}
+	return &self.data
+}
+
+// IPv4SocketAddress represents the record "wasi:sockets/network@0.2.0#ipv4-socket-address".
+type IPv4SocketAddress struct {
+	Port    uint16
+	Address [4]uint8
+}
+
+// IPv6SocketAddress represents the record "wasi:sockets/network@0.2.0#ipv6-socket-address".
+type IPv6SocketAddress struct {
+	Port     uint16
+	FlowInfo uint32
+	Address  [8]uint16
+	ScopeID  uint32
+}
+
+// IPSocketAddress represents the variant "wasi:sockets/network@0.2.0#ip-socket-address".
+type IPSocketAddress struct {
+	tag  uint8
+	data [7]uint32
+}
+
+// IPSocketAddressIPv4 returns an IPSocketAddress for an IPv4 address.
+func IPSocketAddressIPv4(addr IPv4SocketAddress) (result IPSocketAddress) {
+	result.tag = uint8(IPAddressFamilyIPv4)
+	*(*IPv4SocketAddress)(unsafe.Pointer(&result.data)) = addr
+	return
+}
+
+// IPSocketAddressIPv6 returns an IPSocketAddress for an IPv6 address.
+func IPSocketAddressIPv6(addr IPv6SocketAddress) (result IPSocketAddress) {
+	result.tag = uint8(IPAddressFamilyIPv6)
+	*(*IPv6SocketAddress)(unsafe.Pointer(&result.data)) = addr
+	return
+}
+
+// IPv4 returns the IPv4 socket address, or nil if it is an IPv6 address.
+func (self *IPSocketAddress) IPv4() *IPv4SocketAddress {
+	if self.tag != uint8(IPAddressFamilyIPv4) {
+		return nil
+	}
+	return (*IPv4SocketAddress)(unsafe.Pointer(&self.data))
+}
+
+// IPv6 returns the IPv6 socket address, or nil if it is an IPv4 address.
+func (self *IPSocketAddress) IPv6() *IPv6SocketAddress {
+	if self.tag != uint8(IPAddressFamilyIPv6) {
+		return nil
+	}
+	return (*IPv6SocketAddress)(unsafe.Pointer(&self.data))
+}
+
+// Flat is the flattened representation of an IPSocketAddress, as passed in
+// function parameters: the discriminant followed by 11 joined payload values.
+type Flat [12]uint32
+
+// Lower returns the flattened representation of the address.
+func (self *IPSocketAddress) Lower() (flat Flat) {
+	flat[0] = uint32(self.tag)
+	if v4 := self.IPv4(); v4 != nil {
+		flat[1] = uint32(v4.Port)
+		for i, b := range v4.Address {
+			flat[2+i] = uint32(b)
+		}
+	} else if v6 := self.IPv6(); v6 != nil {
+		flat[1] = uint32(v6.Port)
+		flat[2] = v6.FlowInfo
+		for i, w := range v6.Address {
+			flat[3+i] = uint32(w)
+		}
+		flat[11] = v6.ScopeID
+	}
+	return
+}
@@ -0,0 +1,135 @@
+// Package tcp contains the bindings for the wasi:sockets/tcp@0.2.0 interface.
+package tcp
+
+import (
+	"internal/cm"
+	"internal/wasi/io/poll"
+	"internal/wasi/io/streams"
+	"internal/wasi/sockets/network"
+	"unsafe"
+)
+
+// ShutdownType represents the enum "wasi:sockets/tcp@0.2.0#shutdown-type".
+type ShutdownType uint8
+
+const (
+	ShutdownTypeReceive ShutdownType = iota
+	ShutdownTypeSend
+	ShutdownTypeBoth
+)
+
+// TCPSocket represents the resource "wasi:sockets/tcp@0.2.0#tcp-socket".
+type TCPSocket cm.Resource
+
+// ResourceDrop drops the socket. The streams of the socket must be dropped
+// first.
+func (self TCPSocket) ResourceDrop() {
+	wasmimport_TCPSocketResourceDrop(uint32(self))
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [resource-drop]tcp-socket
+func wasmimport_TCPSocketResourceDrop(self uint32)
+
+// StartBind starts binding the socket to the given local address.
+func (self TCPSocket) StartBind(network_ network.Network, localAddress network.IPSocketAddress) (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	a := localAddress.Lower()
+	wasmimport_TCPSocketStartBind(uint32(self), uint32(network_), a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11], unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.start-bind
+func wasmimport_TCPSocketStartBind(self uint32, network uint32, a0, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11 uint32, result unsafe.Pointer)
+
+// FinishBind finishes binding the socket.
+func (self TCPSocket) FinishBind() (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	wasmimport_TCPSocketFinishBind(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.finish-bind
+func wasmimport_TCPSocketFinishBind(self uint32, result unsafe.Pointer)
+
+// StartConnect starts connecting the socket to the given remote address.
+func (self TCPSocket) StartConnect(network_ network.Network, remoteAddress network.IPSocketAddress) (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	a := remoteAddress.Lower()
+	wasmimport_TCPSocketStartConnect(uint32(self), uint32(network_), a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11], unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.start-connect
+func wasmimport_TCPSocketStartConnect(self uint32, network uint32, a0, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11 uint32, result unsafe.Pointer)
+
+// FinishConnect finishes connecting the socket, and returns the streams for
+// reading from and writing to the connection. It returns the would-block
+// error code while the connection is in progress.
+func (self TCPSocket) FinishConnect() (result cm.Result[cm.Tuple[streams.InputStream, streams.OutputStream], cm.Tuple[streams.InputStream, streams.OutputStream], network.ErrorCode]) {
+	wasmimport_TCPSocketFinishConnect(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.finish-connect
+func wasmimport_TCPSocketFinishConnect(self uint32, result unsafe.Pointer)
+
+// StartListen starts listening on the (bound) socket.
+func (self TCPSocket) StartListen() (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	wasmimport_TCPSocketStartListen(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.start-listen
+func wasmimport_TCPSocketStartListen(self uint32, result unsafe.Pointer)
+
+// FinishListen finishes transitioning the socket to the listening state.
+func (self TCPSocket) FinishListen() (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	wasmimport_TCPSocketFinishListen(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.finish-listen
+func wasmimport_TCPSocketFinishListen(self uint32, result unsafe.Pointer)
+
+// Accept accepts a new connection on a listening socket. It returns the
+// would-block error code when there is no pending connection.
+func (self TCPSocket) Accept() (result cm.Result[cm.Tuple3[TCPSocket, streams.InputStream, streams.OutputStream], cm.Tuple3[TCPSocket, streams.InputStream, streams.OutputStream], network.ErrorCode]) {
+	wasmimport_TCPSocketAccept(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.accept
+func wasmimport_TCPSocketAccept(self uint32, result unsafe.Pointer)
+
+// LocalAddress returns the address the socket is bound to.
+func (self TCPSocket) LocalAddress() (result cm.Result[network.IPSocketAddress, network.IPSocketAddress, network.ErrorCode]) {
+	wasmimport_TCPSocketLocalAddress(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.local-address
+func wasmimport_TCPSocketLocalAddress(self uint32, result unsafe.Pointer)
+
+// RemoteAddress returns the address of the peer of a connected socket.
+func (self TCPSocket) RemoteAddress() (result cm.Result[network.IPSocketAddress, network.IPSocketAddress, network.ErrorCode]) {
+	wasmimport_TCPSocketRemoteAddress(uint32(self), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.remote-address
+func wasmimport_TCPSocketRemoteAddress(self uint32, result unsafe.Pointer)
+
+// Subscribe returns a pollable that is ready when a pending bind, connect or
+// listen operation has finished, or when a connection can be accepted.
+func (self TCPSocket) Subscribe() poll.Pollable {
+	return poll.Pollable(wasmimport_TCPSocketSubscribe(uint32(self)))
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.subscribe
+func wasmimport_TCPSocketSubscribe(self uint32) uint32
+
+// Shutdown shuts down the receiving and/or sending half of the connection.
+func (self TCPSocket) Shutdown(shutdownType ShutdownType) (result cm.Result[network.ErrorCode, struct{}, network.ErrorCode]) {
+	wasmimport_TCPSocketShutdown(uint32(self), uint32(shutdownType), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp@0.2.0 [method]tcp-socket.shutdown
+func wasmimport_TCPSocketShutdown(self uint32, shutdownType uint32, result unsafe.Pointer)
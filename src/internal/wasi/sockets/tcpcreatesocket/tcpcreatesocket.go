@@ -0,0 +1,19 @@
+// Package tcpcreatesocket contains the bindings for the
+// wasi:sockets/tcp-create-socket@0.2.0 interface.
+package tcpcreatesocket
+
+import (
+	"internal/cm"
+	"internal/wasi/sockets/network"
+	"internal/wasi/sockets/tcp"
+	"unsafe"
+)
+
+// CreateTCPSocket creates a new TCP socket for the given address family.
+func CreateTCPSocket(addressFamily network.IPAddressFamily) (result cm.Result[tcp.TCPSocket, tcp.TCPSocket, network.ErrorCode]) {
+	wasmimport_CreateTCPSocket(uint32(addressFamily), unsafe.Pointer(&result))
+	return
+}
+
+//go:wasmimport wasi:sockets/tcp-create-socket@0.2.0 create-tcp-socket
+func wasmimport_CreateTCPSocket(addressFamily uint32, result unsafe.Pointer)
@@ -12,14 +12,46 @@ type Dialer struct {
 	KeepAlive time.Duration
 }
 
-func Dial(network, address string) (Conn, error) {
-	return nil, ErrNotImplemented
+// deadline returns the earliest of the context deadline, d.Deadline and the
+// time d.Timeout from now, or the zero time if none of them is set.
+func (d *Dialer) deadline(ctx context.Context, now time.Time) (earliest time.Time) {
+	if d.Timeout != 0 {
+		earliest = now.Add(d.Timeout)
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		earliest = minNonzeroTime(earliest, dl)
+	}
+	return minNonzeroTime(earliest, d.Deadline)
 }
 
-func Listen(network, address string) (Listener, error) {
-	return nil, ErrNotImplemented
+// minNonzeroTime returns the earlier of two times, ignoring the zero time.
+func minNonzeroTime(a, b time.Time) time.Time {
+	if a.IsZero() {
+		return b
+	}
+	if b.IsZero() || a.Before(b) {
+		return a
+	}
+	return b
 }
 
-func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
-	return nil, ErrNotImplemented
+func isTCPNetwork(net string) bool {
+	switch net {
+	case "tcp", "tcp4", "tcp6":
+		return true
+	}
+	return false
+}
+
+// splitHostPort splits the address into a host and a numeric port.
+func splitHostPort(address string) (host string, port int, err error) {
+	host, service, err := SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, i, ok := dtoi(service)
+	if !ok || i != len(service) || port > 0xffff {
+		return "", 0, &AddrError{Err: "invalid port", Addr: address}
+	}
+	return host, port, nil
 }
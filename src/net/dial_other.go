@@ -0,0 +1,101 @@
+//go:build !wasi && !wasip1 && !wasip2
+
+package net
+
+import (
+	"context"
+	"time"
+)
+
+// Dial connects to the address on the named network, using the network device
+// registered with UseNetdev. Only the "tcp" and "udp" networks (and their
+// IPv4 and IPv6 variants) are supported.
+func Dial(network, address string) (Conn, error) {
+	var d Dialer
+	return d.DialContext(context.Background(), network, address)
+}
+
+// Listen announces on the local network address, using the network device
+// registered with UseNetdev. Only the "tcp" networks are supported.
+func Listen(network, address string) (Listener, error) {
+	if !isTCPNetwork(network) {
+		return nil, &OpError{Op: "listen", Net: network, Err: UnknownNetworkError(network)}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Err: err}
+	}
+	laddr := &TCPAddr{Port: port}
+	if host != "" {
+		if netdev == nil {
+			return nil, &OpError{Op: "listen", Net: network, Err: ErrNotImplemented}
+		}
+		laddr.IP, err = resolveHost(host)
+		if err != nil {
+			return nil, &OpError{Op: "listen", Net: network, Err: err}
+		}
+	}
+	return ListenTCP(network, laddr)
+}
+
+// ListenPacket announces on the local network address, using the network
+// device registered with UseNetdev. Only the "udp" networks are supported.
+func ListenPacket(network, address string) (PacketConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Err: UnknownNetworkError(network)}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Err: err}
+	}
+	laddr := &UDPAddr{Port: port}
+	if host != "" {
+		if netdev == nil {
+			return nil, &OpError{Op: "listen", Net: network, Err: ErrNotImplemented}
+		}
+		laddr.IP, err = resolveHost(host)
+		if err != nil {
+			return nil, &OpError{Op: "listen", Net: network, Err: err}
+		}
+	}
+	return ListenUDP(network, laddr)
+}
+
+// DialContext connects to the address on the named network. The connection
+// attempt is aborted at the earliest of the context deadline, d.Deadline and
+// d.Timeout. Cancelling the context is only noticed before connecting.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Err: err}
+	}
+	deadline := d.deadline(ctx, time.Now())
+	var isUDP bool
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	case "udp", "udp4", "udp6":
+		isUDP = true
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Err: UnknownNetworkError(network)}
+	}
+	if netdev == nil {
+		return nil, &OpError{Op: "dial", Net: network, Err: ErrNotImplemented}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Err: err}
+	}
+	ip, err := resolveHost(host)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Err: err}
+	}
+	if ParseIP(host) != nil {
+		// Only pass host names to the network device.
+		host = ""
+	}
+	if isUDP {
+		return dialUDP(network, host, nil, &UDPAddr{IP: ip, Port: port}, deadline)
+	}
+	return dialTCP(network, host, nil, &TCPAddr{IP: ip, Port: port}, deadline)
+}
@@ -0,0 +1,323 @@
+//go:build wasi || wasip1
+
+// TCP sockets on top of the sock_* functions of WASI preview 1. Preview 1 can't
+// create sockets: the host has to pass them in, for example with
+// `wasmtime run -S tcplisten=127.0.0.1:8080`. Listen returns these pre-opened
+// listening sockets, and FileListener and FileConn wrap a specific file
+// descriptor. Goroutines waiting for a socket are paused until the scheduler
+// sees (using poll_oneoff) that the socket is ready.
+
+package net
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Values from the WASI preview 1 specification.
+const (
+	wasiFiletypeSocketStream = 6
+	wasiFdflagNonblock       = 0x4
+	wasiSdflagWr             = 0x2
+)
+
+// wasiFdstat is the fdstat record returned by fd_fdstat_get.
+type wasiFdstat struct {
+	filetype         uint8
+	flags            uint16
+	rightsBase       uint64
+	rightsInheriting uint64
+}
+
+// wasiIovec is the iovec (and ciovec) record of WASI.
+type wasiIovec struct {
+	buf    unsafe.Pointer
+	bufLen uint32
+}
+
+//go:wasmimport wasi_snapshot_preview1 sock_accept
+func sock_accept(fd uint32, flags uint32, newfd unsafe.Pointer) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_recv
+func sock_recv(fd uint32, riData unsafe.Pointer, riDataLen uint32, riFlags uint32, roDataLen unsafe.Pointer, roFlags unsafe.Pointer) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_send
+func sock_send(fd uint32, siData unsafe.Pointer, siDataLen uint32, siFlags uint32, soDataLen unsafe.Pointer) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_shutdown
+func sock_shutdown(fd uint32, how uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 fd_close
+func fd_close(fd uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 fd_fdstat_get
+func fd_fdstat_get(fd uint32, stat unsafe.Pointer) uint32
+
+//go:wasmimport wasi_snapshot_preview1 fd_fdstat_set_flags
+func fd_fdstat_set_flags(fd uint32, flags uint32) uint32
+
+// runtime_pollWait waits until fd is ready for reading (or writing, if write is
+// set) or until the timeout in nanoseconds has passed, and returns false in the
+// latter case. A negative timeout means there is no timeout.
+//
+//go:linkname runtime_pollWait runtime.netpollWait
+func runtime_pollWait(fd uint32, write bool, timeout int64) bool
+
+// preopenedListeners are the listening sockets passed in by the host that
+// haven't been returned by Listen yet. It is nil until the first call to
+// Listen.
+var preopenedListeners []uint32
+
+func Dial(network, address string) (Conn, error) {
+	return nil, ErrNotImplemented
+}
+
+// Listen returns the next listening socket that was pre-opened by the host.
+// WASI preview 1 has no way to read back the address of a socket, so the
+// network and address are only checked for validity.
+func Listen(network, address string) (Listener, error) {
+	if !isTCPNetwork(network) {
+		return nil, &OpError{Op: "listen", Net: network, Err: errors.New("unknown network " + network)}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Err: err}
+	}
+	laddr := &TCPAddr{IP: ParseIP(host), Port: port}
+
+	if preopenedListeners == nil {
+		preopenedListeners = findPreopenedSockets()
+	}
+	if len(preopenedListeners) == 0 {
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr, Err: errors.New("no pre-opened socket available")}
+	}
+	fd := preopenedListeners[0]
+	preopenedListeners = preopenedListeners[1:]
+	return newSockListener(fd, laddr)
+}
+
+// DialContext is not supported: WASI preview 1 can't create sockets.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
+	return nil, ErrNotImplemented
+}
+
+// FileListener returns a listener for the listening socket f, which usually
+// refers to a socket pre-opened by the host. Unlike on other systems, the
+// file descriptor is not duplicated: the listener takes over the socket, and f
+// must not be used or closed afterwards.
+func FileListener(f *os.File) (Listener, error) {
+	return newSockListener(uint32(f.Fd()), &TCPAddr{})
+}
+
+// FileConn returns a connection for the connected socket f. Like with
+// FileListener, the connection takes over the socket.
+func FileConn(f *os.File) (Conn, error) {
+	fd := uint32(f.Fd())
+	if errno := fd_fdstat_set_flags(fd, wasiFdflagNonblock); errno != 0 {
+		return nil, &OpError{Op: "file", Net: "tcp", Err: syscall.Errno(errno)}
+	}
+	return &sockConn{fd: fd}, nil
+}
+
+// findPreopenedSockets returns all stream sockets among the file descriptors
+// passed in by the host. The file descriptors are numbered consecutively,
+// starting after stdin, stdout and stderr.
+func findPreopenedSockets() []uint32 {
+	fds := []uint32{}
+	for fd := uint32(3); ; fd++ {
+		var stat wasiFdstat
+		if fd_fdstat_get(fd, unsafe.Pointer(&stat)) != 0 {
+			return fds
+		}
+		if stat.filetype == wasiFiletypeSocketStream {
+			fds = append(fds, fd)
+		}
+	}
+}
+
+func newSockListener(fd uint32, laddr *TCPAddr) (*sockListener, error) {
+	// Accept must not block the whole program.
+	if errno := fd_fdstat_set_flags(fd, wasiFdflagNonblock); errno != 0 {
+		return nil, &OpError{Op: "listen", Net: "tcp", Addr: laddr, Err: syscall.Errno(errno)}
+	}
+	return &sockListener{fd: fd, laddr: laddr}, nil
+}
+
+// waitSocket waits until the socket is ready for reading or writing, or until
+// the deadline has passed.
+func waitSocket(fd uint32, write bool, deadline time.Time) error {
+	timeout := int64(-1)
+	if !deadline.IsZero() {
+		timeout = int64(time.Until(deadline))
+		if timeout <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+	}
+	if !runtime_pollWait(fd, write, timeout) {
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// sockConn is a connected TCP socket.
+type sockConn struct {
+	fd            uint32
+	closed        bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func (c *sockConn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for {
+		if c.closed {
+			return 0, c.opError("read", ErrClosed)
+		}
+		iov := wasiIovec{buf: unsafe.Pointer(&b[0]), bufLen: uint32(len(b))}
+		var n uint32
+		var flags uint16
+		errno := syscall.Errno(sock_recv(c.fd, unsafe.Pointer(&iov), 1, 0, unsafe.Pointer(&n), unsafe.Pointer(&flags)))
+		switch errno {
+		case 0:
+			if n == 0 {
+				return 0, io.EOF
+			}
+			return int(n), nil
+		case syscall.EAGAIN:
+			if err := waitSocket(c.fd, false, c.readDeadline); err != nil {
+				return 0, c.opError("read", err)
+			}
+		default:
+			return 0, c.opError("read", errno)
+		}
+	}
+}
+
+func (c *sockConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		if c.closed {
+			return written, c.opError("write", ErrClosed)
+		}
+		iov := wasiIovec{buf: unsafe.Pointer(&b[written]), bufLen: uint32(len(b) - written)}
+		var n uint32
+		errno := syscall.Errno(sock_send(c.fd, unsafe.Pointer(&iov), 1, 0, unsafe.Pointer(&n)))
+		switch errno {
+		case 0:
+			written += int(n)
+		case syscall.EAGAIN:
+			if err := waitSocket(c.fd, true, c.writeDeadline); err != nil {
+				return written, c.opError("write", err)
+			}
+		default:
+			return written, c.opError("write", errno)
+		}
+	}
+	return written, nil
+}
+
+func (c *sockConn) Close() error {
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	c.closed = true
+	if errno := fd_close(c.fd); errno != 0 {
+		return c.opError("close", syscall.Errno(errno))
+	}
+	return nil
+}
+
+// CloseWrite shuts down the writing side of the TCP connection.
+func (c *sockConn) CloseWrite() error {
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	if errno := sock_shutdown(c.fd, wasiSdflagWr); errno != 0 {
+		return c.opError("close", syscall.Errno(errno))
+	}
+	return nil
+}
+
+// WASI preview 1 can't read back socket addresses. Return an empty address
+// instead of nil, as callers (such as net/http) expect an address.
+
+func (c *sockConn) LocalAddr() Addr {
+	return &TCPAddr{}
+}
+
+func (c *sockConn) RemoteAddr() Addr {
+	return &TCPAddr{}
+}
+
+func (c *sockConn) SetDeadline(t time.Time) error {
+	c.readDeadline = t
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *sockConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
+	return nil
+}
+
+func (c *sockConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *sockConn) opError(op string, err error) error {
+	return &OpError{Op: op, Net: "tcp", Err: err}
+}
+
+// sockListener is a TCP socket in the listening state.
+type sockListener struct {
+	fd     uint32
+	laddr  *TCPAddr
+	closed bool
+}
+
+func (l *sockListener) Accept() (Conn, error) {
+	for {
+		if l.closed {
+			return nil, l.opError(ErrClosed)
+		}
+		var fd uint32
+		errno := syscall.Errno(sock_accept(l.fd, wasiFdflagNonblock, unsafe.Pointer(&fd)))
+		switch errno {
+		case 0:
+			return &sockConn{fd: fd}, nil
+		case syscall.EAGAIN:
+			if err := waitSocket(l.fd, false, time.Time{}); err != nil {
+				return nil, l.opError(err)
+			}
+		default:
+			return nil, l.opError(errno)
+		}
+	}
+}
+
+func (l *sockListener) Close() error {
+	if l.closed {
+		return l.opError(ErrClosed)
+	}
+	l.closed = true
+	if errno := fd_close(l.fd); errno != 0 {
+		return l.opError(syscall.Errno(errno))
+	}
+	return nil
+}
+
+func (l *sockListener) Addr() Addr {
+	return l.laddr
+}
+
+func (l *sockListener) opError(err error) error {
+	return &OpError{Op: "accept", Net: "tcp", Addr: l.laddr, Err: err}
+}
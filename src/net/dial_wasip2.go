@@ -0,0 +1,480 @@
+//go:build wasip2
+
+// TCP sockets on top of the wasi:sockets interfaces of WASI 0.2. All calls
+// block the whole program (the host has no way to resume a goroutine when a
+// socket becomes ready), which is good enough for simple clients and servers.
+
+package net
+
+import (
+	"context"
+	"errors"
+	"internal/wasi/io/streams"
+	"internal/wasi/sockets/instancenetwork"
+	"internal/wasi/sockets/ipnamelookup"
+	"internal/wasi/sockets/network"
+	"internal/wasi/sockets/tcp"
+	"internal/wasi/sockets/tcpcreatesocket"
+	"io"
+	"syscall"
+	"time"
+)
+
+// Maximum number of bytes written in a single blocking-write-and-flush call.
+const wasiMaxWrite = 4096
+
+var wasiNetwork network.Network
+
+// instanceNetwork returns the network handle of this instance, which is kept
+// alive for the whole program.
+func instanceNetwork() network.Network {
+	if wasiNetwork == 0 {
+		wasiNetwork = instancenetwork.InstanceNetwork()
+	}
+	return wasiNetwork
+}
+
+func Dial(network, address string) (Conn, error) {
+	return dialTCP(network, address)
+}
+
+func Listen(network, address string) (Listener, error) {
+	return listenTCP(network, address)
+}
+
+// DialContext connects to the address on the named network. The context and
+// the timeouts of the Dialer are currently ignored.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
+	return dialTCP(network, address)
+}
+
+func dialTCP(net, address string) (Conn, error) {
+	if !isTCPNetwork(net) {
+		return nil, &OpError{Op: "dial", Net: net, Err: errors.New("unknown network " + net)}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: net, Err: err}
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	ips, err := lookupIP(net, host)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: net, Err: err}
+	}
+
+	// Try all addresses in order, and return the first error if none of them
+	// could be connected to.
+	var firstErr error
+	for _, ip := range ips {
+		raddr := &TCPAddr{IP: ip, Port: port}
+		c, err := connectTCP(raddr)
+		if err == nil {
+			return c, nil
+		}
+		if firstErr == nil {
+			firstErr = &OpError{Op: "dial", Net: net, Addr: raddr, Err: err}
+		}
+	}
+	return nil, firstErr
+}
+
+func connectTCP(raddr *TCPAddr) (*wasiConn, error) {
+	family, addr := socketAddress(raddr)
+	res := tcpcreatesocket.CreateTCPSocket(family)
+	if res.IsErr() {
+		return nil, socketError(*res.Err())
+	}
+	sock := *res.OK()
+
+	if res := sock.StartConnect(instanceNetwork(), addr); res.IsErr() {
+		sock.ResourceDrop()
+		return nil, socketError(*res.Err())
+	}
+	for {
+		res := sock.FinishConnect()
+		if res.IsErr() {
+			if *res.Err() == network.ErrorCodeWouldBlock {
+				waitSocket(sock)
+				continue
+			}
+			sock.ResourceDrop()
+			return nil, socketError(*res.Err())
+		}
+		streams := res.OK()
+		return &wasiConn{sock: sock, in: streams.F0, out: streams.F1, raddr: raddr}, nil
+	}
+}
+
+func listenTCP(net, address string) (Listener, error) {
+	if !isTCPNetwork(net) {
+		return nil, &OpError{Op: "listen", Net: net, Err: errors.New("unknown network " + net)}
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: net, Err: err}
+	}
+	laddr := &TCPAddr{Port: port}
+	if host == "" {
+		if net == "tcp6" {
+			laddr.IP = IPv6unspecified
+		} else {
+			laddr.IP = IPv4zero
+		}
+	} else {
+		ips, err := lookupIP(net, host)
+		if err != nil {
+			return nil, &OpError{Op: "listen", Net: net, Err: err}
+		}
+		laddr.IP = ips[0]
+	}
+
+	l, err := bindTCP(laddr)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: net, Addr: laddr, Err: err}
+	}
+	return l, nil
+}
+
+func bindTCP(laddr *TCPAddr) (*wasiListener, error) {
+	family, addr := socketAddress(laddr)
+	res := tcpcreatesocket.CreateTCPSocket(family)
+	if res.IsErr() {
+		return nil, socketError(*res.Err())
+	}
+	sock := *res.OK()
+
+	if res := sock.StartBind(instanceNetwork(), addr); res.IsErr() {
+		sock.ResourceDrop()
+		return nil, socketError(*res.Err())
+	}
+	for {
+		res := sock.FinishBind()
+		if res.IsErr() && *res.Err() == network.ErrorCodeWouldBlock {
+			waitSocket(sock)
+			continue
+		}
+		if res.IsErr() {
+			sock.ResourceDrop()
+			return nil, socketError(*res.Err())
+		}
+		break
+	}
+
+	if res := sock.StartListen(); res.IsErr() {
+		sock.ResourceDrop()
+		return nil, socketError(*res.Err())
+	}
+	for {
+		res := sock.FinishListen()
+		if res.IsErr() && *res.Err() == network.ErrorCodeWouldBlock {
+			waitSocket(sock)
+			continue
+		}
+		if res.IsErr() {
+			sock.ResourceDrop()
+			return nil, socketError(*res.Err())
+		}
+		break
+	}
+
+	// Read back the address, to know the port when port 0 was requested.
+	if res := sock.LocalAddress(); !res.IsErr() {
+		laddr = tcpAddress(res.OK())
+	}
+	return &wasiListener{sock: sock, laddr: laddr}, nil
+}
+
+// waitSocket blocks until the socket is ready for the next operation.
+func waitSocket(sock tcp.TCPSocket) {
+	pollable := sock.Subscribe()
+	pollable.Block()
+	pollable.ResourceDrop()
+}
+
+// lookupIP returns the addresses for the host in the order they should be
+// tried, filtered by the address family of the network.
+func lookupIP(net, host string) ([]IP, error) {
+	if ip := ParseIP(host); ip != nil {
+		if !matchFamily(net, ip) {
+			return nil, &AddrError{Err: "no suitable address", Addr: host}
+		}
+		return []IP{ip}, nil
+	}
+
+	res := ipnamelookup.ResolveAddresses(instanceNetwork(), host)
+	if res.IsErr() {
+		return nil, &AddrError{Err: socketError(*res.Err()).Error(), Addr: host}
+	}
+	stream := *res.OK()
+	defer stream.ResourceDrop()
+
+	var ips []IP
+	for {
+		res := stream.ResolveNextAddress()
+		if res.IsErr() {
+			if *res.Err() == network.ErrorCodeWouldBlock {
+				pollable := stream.Subscribe()
+				pollable.Block()
+				pollable.ResourceDrop()
+				continue
+			}
+			return nil, &AddrError{Err: socketError(*res.Err()).Error(), Addr: host}
+		}
+		addr, ok := res.OK().Get()
+		if !ok {
+			break
+		}
+		var ip IP
+		if v4 := addr.IPv4(); v4 != nil {
+			ip = IPv4(v4[0], v4[1], v4[2], v4[3])
+		} else if v6 := addr.IPv6(); v6 != nil {
+			ip = make(IP, IPv6len)
+			for i, v := range v6 {
+				ip[i*2] = byte(v >> 8)
+				ip[i*2+1] = byte(v)
+			}
+		}
+		if matchFamily(net, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &AddrError{Err: "no such host", Addr: host}
+	}
+	return ips, nil
+}
+
+func matchFamily(net string, ip IP) bool {
+	switch net {
+	case "tcp4":
+		return ip.To4() != nil
+	case "tcp6":
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// socketAddress converts a TCPAddr to the form used by wasi:sockets.
+func socketAddress(addr *TCPAddr) (network.IPAddressFamily, network.IPSocketAddress) {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		return network.IPAddressFamilyIPv4, network.IPSocketAddressIPv4(network.IPv4SocketAddress{
+			Port:    uint16(addr.Port),
+			Address: [4]uint8{ip4[0], ip4[1], ip4[2], ip4[3]},
+		})
+	}
+	v6 := network.IPv6SocketAddress{Port: uint16(addr.Port)}
+	ip := addr.IP.To16()
+	for i := range v6.Address {
+		v6.Address[i] = uint16(ip[i*2])<<8 | uint16(ip[i*2+1])
+	}
+	return network.IPAddressFamilyIPv6, network.IPSocketAddressIPv6(v6)
+}
+
+// tcpAddress converts a wasi:sockets address to a TCPAddr.
+func tcpAddress(addr *network.IPSocketAddress) *TCPAddr {
+	if v4 := addr.IPv4(); v4 != nil {
+		a := v4.Address
+		return &TCPAddr{IP: IPv4(a[0], a[1], a[2], a[3]), Port: int(v4.Port)}
+	}
+	v6 := addr.IPv6()
+	ip := make(IP, IPv6len)
+	for i, v := range v6.Address {
+		ip[i*2] = byte(v >> 8)
+		ip[i*2+1] = byte(v)
+	}
+	return &TCPAddr{IP: ip, Port: int(v6.Port)}
+}
+
+// socketErrnos maps wasi:sockets error codes to the closest errno, so that
+// callers can use errors.Is with the usual syscall errors.
+var socketErrnos = [...]syscall.Errno{
+	network.ErrorCodeAccessDenied:       syscall.EACCES,
+	network.ErrorCodeNotSupported:       syscall.ENOTSUP,
+	network.ErrorCodeInvalidArgument:    syscall.EINVAL,
+	network.ErrorCodeOutOfMemory:        syscall.ENOMEM,
+	network.ErrorCodeTimeout:            syscall.ETIMEDOUT,
+	network.ErrorCodeWouldBlock:         syscall.EAGAIN,
+	network.ErrorCodeInvalidState:       syscall.EINVAL,
+	network.ErrorCodeNewSocketLimit:     syscall.EMFILE,
+	network.ErrorCodeAddressNotBindable: syscall.EADDRNOTAVAIL,
+	network.ErrorCodeAddressInUse:       syscall.EADDRINUSE,
+	network.ErrorCodeRemoteUnreachable:  syscall.EHOSTUNREACH,
+	network.ErrorCodeConnectionRefused:  syscall.ECONNREFUSED,
+	network.ErrorCodeConnectionReset:    syscall.ECONNRESET,
+	network.ErrorCodeConnectionAborted:  syscall.ECONNABORTED,
+	network.ErrorCodeDatagramTooLarge:   syscall.EMSGSIZE,
+}
+
+func socketError(code network.ErrorCode) error {
+	if int(code) < len(socketErrnos) && socketErrnos[code] != 0 {
+		return socketErrnos[code]
+	}
+	return errors.New(code.String())
+}
+
+// streamError converts an error from a stream to a Go error. A closed stream
+// is reported as io.EOF.
+func streamError(err *streams.StreamError) error {
+	if err.Closed() {
+		return io.EOF
+	}
+	e := err.LastOperationFailed()
+	msg := e.ToDebugString()
+	e.ResourceDrop()
+	return errors.New(msg)
+}
+
+// wasiConn is a connected TCP socket, with its input and output stream.
+type wasiConn struct {
+	sock   tcp.TCPSocket
+	in     streams.InputStream
+	out    streams.OutputStream
+	raddr  *TCPAddr
+	closed bool
+}
+
+func (c *wasiConn) Read(b []byte) (int, error) {
+	if c.closed {
+		return 0, c.opError("read", ErrClosed)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	res := c.in.BlockingRead(uint64(len(b)))
+	if res.IsErr() {
+		err := streamError(res.Err())
+		if err == io.EOF {
+			return 0, err
+		}
+		return 0, c.opError("read", err)
+	}
+	return copy(b, res.OK().Lift()), nil
+}
+
+func (c *wasiConn) Write(b []byte) (int, error) {
+	if c.closed {
+		return 0, c.opError("write", ErrClosed)
+	}
+	n := 0
+	for n < len(b) {
+		chunk := b[n:]
+		if len(chunk) > wasiMaxWrite {
+			chunk = chunk[:wasiMaxWrite]
+		}
+		res := c.out.BlockingWriteAndFlush(chunk)
+		if res.IsErr() {
+			return n, c.opError("write", streamError(res.Err()))
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+func (c *wasiConn) Close() error {
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	c.closed = true
+	// The streams are children of the socket, so they must be dropped first.
+	c.in.ResourceDrop()
+	c.out.ResourceDrop()
+	c.sock.ResourceDrop()
+	return nil
+}
+
+// CloseWrite shuts down the writing side of the TCP connection.
+func (c *wasiConn) CloseWrite() error {
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	if res := c.sock.Shutdown(tcp.ShutdownTypeSend); res.IsErr() {
+		return c.opError("close", socketError(*res.Err()))
+	}
+	return nil
+}
+
+func (c *wasiConn) LocalAddr() Addr {
+	res := c.sock.LocalAddress()
+	if res.IsErr() {
+		return nil
+	}
+	return tcpAddress(res.OK())
+}
+
+func (c *wasiConn) RemoteAddr() Addr {
+	return c.raddr
+}
+
+// Deadlines are not supported: all operations block until they complete.
+// Clearing a deadline (with the zero time) is allowed.
+
+func (c *wasiConn) SetDeadline(t time.Time) error {
+	return c.setDeadline(t)
+}
+
+func (c *wasiConn) SetReadDeadline(t time.Time) error {
+	return c.setDeadline(t)
+}
+
+func (c *wasiConn) SetWriteDeadline(t time.Time) error {
+	return c.setDeadline(t)
+}
+
+func (c *wasiConn) setDeadline(t time.Time) error {
+	if t.IsZero() {
+		return nil
+	}
+	return c.opError("set", ErrNotImplemented)
+}
+
+func (c *wasiConn) opError(op string, err error) error {
+	return &OpError{Op: op, Net: "tcp", Addr: c.raddr, Err: err}
+}
+
+// wasiListener is a TCP socket in the listening state.
+type wasiListener struct {
+	sock   tcp.TCPSocket
+	laddr  *TCPAddr
+	closed bool
+}
+
+func (l *wasiListener) Accept() (Conn, error) {
+	for {
+		if l.closed {
+			return nil, l.opError(ErrClosed)
+		}
+		res := l.sock.Accept()
+		if res.IsErr() {
+			if *res.Err() == network.ErrorCodeWouldBlock {
+				waitSocket(l.sock)
+				continue
+			}
+			return nil, l.opError(socketError(*res.Err()))
+		}
+		accepted := res.OK()
+		c := &wasiConn{sock: accepted.F0, in: accepted.F1, out: accepted.F2}
+		if res := c.sock.RemoteAddress(); !res.IsErr() {
+			c.raddr = tcpAddress(res.OK())
+		}
+		return c, nil
+	}
+}
+
+func (l *wasiListener) Close() error {
+	if l.closed {
+		return l.opError(ErrClosed)
+	}
+	l.closed = true
+	l.sock.ResourceDrop()
+	return nil
+}
+
+func (l *wasiListener) Addr() Addr {
+	return l.laddr
+}
+
+func (l *wasiListener) opError(err error) error {
+	return &OpError{Op: "accept", Net: "tcp", Addr: l.laddr, Err: err}
+}
@@ -7,4 +7,8 @@ var (
 	errClosed = errors.New("use of closed network connection")
 
 	ErrNotImplemented = errors.New("operation not implemented")
+
+	ErrWriteToConnected = errors.New("use of WriteTo with pre-connected connection")
+
+	errMissingAddress = errors.New("missing address")
 )
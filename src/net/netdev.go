@@ -0,0 +1,224 @@
+package net
+
+// This file lets network drivers (WiFi and Ethernet chips with their own
+// network stack, or a TCP/IP stack written in Go) provide sockets to this
+// package. A driver implements the Netdev interface and registers itself with
+// UseNetdev, after which Dial, Listen, ListenPacket, DialTCP, ListenTCP,
+// DialUDP and ListenUDP create sockets through it.
+
+import (
+	"io"
+	"net/netip"
+	"time"
+)
+
+// Netdev is the interface a network device driver implements to provide
+// sockets. Sockets are identified by a number chosen by the driver.
+//
+// The methods that take a deadline block until they can make progress, or
+// until the deadline has passed in which case they return
+// os.ErrDeadlineExceeded. A zero deadline means there is no deadline. Close
+// must unblock pending calls on the socket.
+type Netdev interface {
+	// GetHostByName looks up the IP address of a host name.
+	GetHostByName(name string) (netip.Addr, error)
+
+	// Addr returns the IP address of the device.
+	Addr() (netip.Addr, error)
+
+	// Socket creates a new socket for the given network, which is either
+	// "tcp" or "udp".
+	Socket(network string) (sockfd int, err error)
+
+	// Bind sets the local address of the socket.
+	Bind(sockfd int, addr netip.AddrPort) error
+
+	// Connect connects the socket to a remote address. The host is the name
+	// addr was resolved from, or empty if an IP address was used directly.
+	// For UDP sockets it only sets the destination of Send.
+	Connect(sockfd int, host string, addr netip.AddrPort, deadline time.Time) error
+
+	// Listen puts a bound TCP socket in the listening state.
+	Listen(sockfd int, backlog int) error
+
+	// Accept waits for an incoming connection on a listening socket and
+	// returns a new socket for it.
+	Accept(sockfd int, deadline time.Time) (newfd int, raddr netip.AddrPort, err error)
+
+	// Send writes data to the socket and returns the number of bytes
+	// written.
+	Send(sockfd int, buf []byte, deadline time.Time) (int, error)
+
+	// Recv reads data from the socket into buf. It returns io.EOF when the
+	// other side has closed the connection.
+	Recv(sockfd int, buf []byte, deadline time.Time) (int, error)
+
+	// SendTo sends a datagram on a UDP socket to the given address.
+	SendTo(sockfd int, buf []byte, addr netip.AddrPort, deadline time.Time) (int, error)
+
+	// RecvFrom receives a datagram on a UDP socket into buf, and returns the
+	// address it was sent from.
+	RecvFrom(sockfd int, buf []byte, deadline time.Time) (int, netip.AddrPort, error)
+
+	// CloseWrite shuts down the sending side of a TCP connection. The other
+	// side receives io.EOF after it has received all data sent before.
+	CloseWrite(sockfd int) error
+
+	// Close closes the socket.
+	Close(sockfd int) error
+}
+
+// netdev is the registered network device, or nil if there is none.
+var netdev Netdev
+
+// UseNetdev registers the network device to use for all sockets. It is meant
+// to be called by network drivers, after the device has been set up.
+func UseNetdev(dev Netdev) {
+	netdev = dev
+}
+
+// listenBacklog is the backlog passed to Netdev.Listen.
+const listenBacklog = 5
+
+// resolveHost returns the IP address of the host, using the network device to
+// look up host names. An empty host refers to the local system.
+func resolveHost(host string) (IP, error) {
+	if host == "" {
+		return IPv4(127, 0, 0, 1), nil
+	}
+	if ip := ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addr, err := netdev.GetHostByName(host)
+	if err != nil {
+		return nil, err
+	}
+	return IP(addr.AsSlice()), nil
+}
+
+// netdevAddrPort converts an IP address and port to the form used by Netdev.
+// IPv4 addresses are always passed as 4-byte addresses, and a nil IP means
+// the unspecified IPv4 address.
+func netdevAddrPort(ip IP, port int) netip.AddrPort {
+	if ip == nil {
+		return netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(port))
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr.Unmap(), uint16(port))
+}
+
+// netdevLocalAddr returns the local address of a socket, using the device
+// address when the socket isn't bound to a specific address.
+func netdevLocalAddr(network string, ip IP, port int) Addr {
+	if ip == nil || ip.IsUnspecified() {
+		if addr, err := netdev.Addr(); err == nil {
+			ip = IP(addr.AsSlice())
+		}
+	}
+	if network == "udp" {
+		return &UDPAddr{IP: ip, Port: port}
+	}
+	return &TCPAddr{IP: ip, Port: port}
+}
+
+// netdevSocket creates a socket and connects it to raddr.
+func netdevSocket(network, host string, laddr, raddr netip.AddrPort, bind bool, deadline time.Time) (int, error) {
+	fd, err := netdev.Socket(network)
+	if err != nil {
+		return -1, err
+	}
+	if bind {
+		if err := netdev.Bind(fd, laddr); err != nil {
+			netdev.Close(fd)
+			return -1, err
+		}
+	}
+	if err := netdev.Connect(fd, host, raddr, deadline); err != nil {
+		netdev.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if c.closed {
+		return 0, c.opError("read", ErrClosed)
+	}
+	n, err := netdev.Recv(c.fd, b, c.readDeadline)
+	if err != nil && err != io.EOF {
+		if c.closed {
+			err = ErrClosed
+		}
+		return n, c.opError("read", err)
+	}
+	return n, err
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		if c.closed {
+			return written, c.opError("write", ErrClosed)
+		}
+		n, err := netdev.Send(c.fd, b[written:], c.writeDeadline)
+		written += n
+		if err != nil {
+			if c.closed {
+				err = ErrClosed
+			}
+			return written, c.opError("write", err)
+		}
+	}
+	return written, nil
+}
+
+func (c *conn) Close() error {
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	c.closed = true
+	if err := netdev.Close(c.fd); err != nil {
+		return c.opError("close", err)
+	}
+	return nil
+}
+
+func (c *conn) LocalAddr() Addr {
+	return c.laddr
+}
+
+func (c *conn) RemoteAddr() Addr {
+	return c.raddr
+}
+
+// Deadlines are passed to the network device on every Send and Recv call, so
+// a new deadline doesn't affect calls that are already in progress.
+
+func (c *conn) SetDeadline(t time.Time) error {
+	if c.closed {
+		return c.opError("set", ErrClosed)
+	}
+	c.readDeadline = t
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	if c.closed {
+		return c.opError("set", ErrClosed)
+	}
+	c.readDeadline = t
+	return nil
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	if c.closed {
+		return c.opError("set", ErrClosed)
+	}
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *conn) opError(op string, err error) error {
+	return &OpError{Op: op, Net: c.net, Source: c.laddr, Addr: c.raddr, Err: err}
+}
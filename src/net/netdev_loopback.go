@@ -0,0 +1,434 @@
+package net
+
+// This file implements an in-memory network device, for testing code that uses
+// this package on systems without network hardware (or in the tests of this
+// package itself).
+
+import (
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// loopbackNetdev is an in-memory network device, where all sockets are
+// connected to each other. Every address is treated as a local address, and
+// "localhost" is the only host name it knows.
+type loopbackNetdev struct {
+	mu       sync.Mutex
+	sockets  map[int]*loopbackSocket
+	tcpPorts map[uint16]*loopbackSocket // listening TCP sockets
+	udpPorts map[uint16]*loopbackSocket // bound UDP sockets
+	nextFd   int
+	nextPort uint16
+}
+
+// loopbackBufferSize is the number of bytes that can be sent on a TCP
+// connection before they have to be received.
+const loopbackBufferSize = 4096
+
+type loopbackSocket struct {
+	network   string
+	laddr     netip.AddrPort
+	raddr     netip.AddrPort
+	bound     bool
+	listening bool
+	closed    bool
+	shutdown  bool               // CloseWrite was called
+	eof       bool               // the peer has closed the connection
+	peer      *loopbackSocket    // the other side of a TCP connection
+	buf       []byte             // received TCP data
+	datagrams []loopbackDatagram // received UDP datagrams
+	pending   []*loopbackSocket  // connections waiting to be accepted
+
+	// changed is closed (and replaced) on every change to the socket.
+	changed chan struct{}
+}
+
+type loopbackDatagram struct {
+	data []byte
+	from netip.AddrPort
+}
+
+var errLoopbackClosed = errors.New("socket closed")
+
+// NewLoopbackNetdev returns a new in-memory network device, where all sockets
+// are connected to each other. It can be registered with UseNetdev to test
+// networking code without network hardware.
+func NewLoopbackNetdev() Netdev {
+	return &loopbackNetdev{
+		sockets:  make(map[int]*loopbackSocket),
+		tcpPorts: make(map[uint16]*loopbackSocket),
+		udpPorts: make(map[uint16]*loopbackSocket),
+		nextFd:   1,
+		nextPort: 49152,
+	}
+}
+
+func (n *loopbackNetdev) GetHostByName(name string) (netip.Addr, error) {
+	if name == "localhost" {
+		return netip.AddrFrom4([4]byte{127, 0, 0, 1}), nil
+	}
+	return netip.Addr{}, errors.New("no such host")
+}
+
+func (n *loopbackNetdev) Addr() (netip.Addr, error) {
+	return netip.AddrFrom4([4]byte{127, 0, 0, 1}), nil
+}
+
+func (n *loopbackNetdev) Socket(network string) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.newSocket(&loopbackSocket{network: network}), nil
+}
+
+// newSocket adds a socket and returns its file descriptor. It must be called
+// with n.mu held.
+func (n *loopbackNetdev) newSocket(s *loopbackSocket) int {
+	s.changed = make(chan struct{})
+	fd := n.nextFd
+	n.nextFd++
+	n.sockets[fd] = s
+	return fd
+}
+
+// socket returns the socket for a file descriptor. It must be called with n.mu
+// held.
+func (n *loopbackNetdev) socket(fd int) (*loopbackSocket, error) {
+	s := n.sockets[fd]
+	if s == nil {
+		return nil, syscall.EBADF
+	}
+	return s, nil
+}
+
+// ports returns the ports in use for the network of the socket.
+func (n *loopbackNetdev) ports(s *loopbackSocket) map[uint16]*loopbackSocket {
+	if s.network == "udp" {
+		return n.udpPorts
+	}
+	return n.tcpPorts
+}
+
+// bind binds the socket to the address, picking a free port if the port is 0.
+// It must be called with n.mu held.
+func (n *loopbackNetdev) bind(s *loopbackSocket, addr netip.AddrPort) error {
+	if s.bound {
+		return syscall.EINVAL
+	}
+	ports := n.ports(s)
+	port := addr.Port()
+	if port == 0 {
+		for ports[n.nextPort] != nil {
+			n.nextPort++
+		}
+		port = n.nextPort
+		n.nextPort++
+	} else if ports[port] != nil {
+		return syscall.EADDRINUSE
+	}
+	if s.network == "udp" {
+		ports[port] = s
+	}
+	s.laddr = netip.AddrPortFrom(addr.Addr(), port)
+	s.bound = true
+	return nil
+}
+
+func (n *loopbackNetdev) Bind(fd int, addr netip.AddrPort) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return err
+	}
+	return n.bind(s, addr)
+}
+
+func (n *loopbackNetdev) Connect(fd int, host string, addr netip.AddrPort, deadline time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return err
+	}
+	// Connecting never blocks, so the deadline only matters if it has already
+	// passed.
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return os.ErrDeadlineExceeded
+	}
+	if !s.bound {
+		if err := n.bind(s, netip.AddrPortFrom(addr.Addr(), 0)); err != nil {
+			return err
+		}
+	}
+	s.raddr = addr
+	if s.network == "udp" {
+		return nil
+	}
+
+	l := n.tcpPorts[addr.Port()]
+	if l == nil || !l.listening {
+		return syscall.ECONNREFUSED
+	}
+	accepted := &loopbackSocket{
+		network: "tcp",
+		laddr:   addr,
+		raddr:   s.laddr,
+		bound:   true,
+		peer:    s,
+	}
+	accepted.changed = make(chan struct{})
+	s.peer = accepted
+	l.pending = append(l.pending, accepted)
+	l.notify()
+	return nil
+}
+
+func (n *loopbackNetdev) Listen(fd int, backlog int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return err
+	}
+	if s.network != "tcp" || !s.bound {
+		return syscall.EINVAL
+	}
+	n.tcpPorts[s.laddr.Port()] = s
+	s.listening = true
+	return nil
+}
+
+func (n *loopbackNetdev) Accept(fd int, deadline time.Time) (int, netip.AddrPort, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return -1, netip.AddrPort{}, err
+	}
+	for {
+		if s.closed {
+			return -1, netip.AddrPort{}, errLoopbackClosed
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return -1, netip.AddrPort{}, os.ErrDeadlineExceeded
+		}
+		if len(s.pending) != 0 {
+			accepted := s.pending[0]
+			s.pending = s.pending[1:]
+			return n.newSocket(accepted), accepted.raddr, nil
+		}
+		if err := n.wait(s, deadline); err != nil {
+			return -1, netip.AddrPort{}, err
+		}
+	}
+}
+
+func (n *loopbackNetdev) Send(fd int, buf []byte, deadline time.Time) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if s.closed {
+			return 0, errLoopbackClosed
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if s.network == "udp" {
+			return n.sendDatagram(s, buf, s.raddr)
+		}
+		if s.peer == nil || s.peer.closed || s.shutdown {
+			return 0, syscall.EPIPE
+		}
+		if space := loopbackBufferSize - len(s.peer.buf); space > 0 {
+			if len(buf) > space {
+				buf = buf[:space]
+			}
+			s.peer.buf = append(s.peer.buf, buf...)
+			s.peer.notify()
+			return len(buf), nil
+		}
+		if err := n.wait(s, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (n *loopbackNetdev) Recv(fd int, buf []byte, deadline time.Time) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if s.closed {
+			return 0, errLoopbackClosed
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if len(s.datagrams) != 0 {
+			count := copy(buf, s.datagrams[0].data)
+			s.datagrams = s.datagrams[1:]
+			return count, nil
+		}
+		if len(s.buf) != 0 {
+			count := copy(buf, s.buf)
+			s.buf = s.buf[count:]
+			if s.peer != nil {
+				// Let the sender know there is space in the buffer.
+				s.peer.notify()
+			}
+			return count, nil
+		}
+		if s.eof {
+			return 0, io.EOF
+		}
+		if err := n.wait(s, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (n *loopbackNetdev) SendTo(fd int, buf []byte, addr netip.AddrPort, deadline time.Time) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return 0, err
+	}
+	if s.closed {
+		return 0, errLoopbackClosed
+	}
+	if s.network != "udp" {
+		return 0, syscall.EINVAL
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return n.sendDatagram(s, buf, addr)
+}
+
+// sendDatagram delivers a datagram from a UDP socket to the socket bound to
+// the port of addr. It must be called with n.mu held.
+func (n *loopbackNetdev) sendDatagram(s *loopbackSocket, buf []byte, addr netip.AddrPort) (int, error) {
+	if !addr.IsValid() {
+		return 0, syscall.EDESTADDRREQ
+	}
+	if !s.bound {
+		if err := n.bind(s, netip.AddrPortFrom(netip.IPv4Unspecified(), 0)); err != nil {
+			return 0, err
+		}
+	}
+	// Datagrams to a port nobody listens on are dropped.
+	if dst := n.udpPorts[addr.Port()]; dst != nil && !dst.closed {
+		from := netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), s.laddr.Port())
+		dst.datagrams = append(dst.datagrams, loopbackDatagram{append([]byte(nil), buf...), from})
+		dst.notify()
+	}
+	return len(buf), nil
+}
+
+func (n *loopbackNetdev) RecvFrom(fd int, buf []byte, deadline time.Time) (int, netip.AddrPort, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return 0, netip.AddrPort{}, err
+	}
+	if s.network != "udp" {
+		return 0, netip.AddrPort{}, syscall.EINVAL
+	}
+	for {
+		if s.closed {
+			return 0, netip.AddrPort{}, errLoopbackClosed
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, netip.AddrPort{}, os.ErrDeadlineExceeded
+		}
+		if len(s.datagrams) != 0 {
+			d := s.datagrams[0]
+			s.datagrams = s.datagrams[1:]
+			return copy(buf, d.data), d.from, nil
+		}
+		if err := n.wait(s, deadline); err != nil {
+			return 0, netip.AddrPort{}, err
+		}
+	}
+}
+
+func (n *loopbackNetdev) CloseWrite(fd int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return err
+	}
+	if s.peer == nil {
+		return syscall.ENOTCONN
+	}
+	s.shutdown = true
+	s.peer.eof = true
+	s.peer.notify()
+	return nil
+}
+
+func (n *loopbackNetdev) Close(fd int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.socket(fd)
+	if err != nil {
+		return err
+	}
+	delete(n.sockets, fd)
+	if ports := n.ports(s); ports[s.laddr.Port()] == s {
+		delete(ports, s.laddr.Port())
+	}
+	s.closed = true
+	s.notify()
+	if s.peer != nil {
+		s.peer.eof = true
+		s.peer.notify()
+	}
+	for _, pending := range s.pending {
+		pending.peer.eof = true
+		pending.peer.notify()
+	}
+	return nil
+}
+
+// wait waits until the socket changes or the deadline has passed. It must be
+// called with n.mu held, which is released while waiting.
+func (n *loopbackNetdev) wait(s *loopbackSocket, deadline time.Time) error {
+	changed := s.changed
+	n.mu.Unlock()
+	defer n.mu.Lock()
+	if deadline.IsZero() {
+		<-changed
+		return nil
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-changed:
+		return nil
+	case <-timer.C:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+// notify wakes up everything waiting for the socket. It must be called with
+// n.mu held.
+func (s *loopbackSocket) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
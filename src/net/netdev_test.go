@@ -0,0 +1,365 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// useLoopbackNetdev registers a new loopback network device for the duration
+// of the test.
+func useLoopbackNetdev(t *testing.T) Netdev {
+	dev := NewLoopbackNetdev()
+	UseNetdev(dev)
+	t.Cleanup(func() {
+		UseNetdev(nil)
+	})
+	return dev
+}
+
+func TestNetdevConn(t *testing.T) {
+	useLoopbackNetdev(t)
+	mp := func() (c1, c2 Conn, stop func(), err error) {
+		ln, err := Listen("tcp", "127.0.0.1:8080")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		c1, err = Dial("tcp", "127.0.0.1:8080")
+		if err != nil {
+			ln.Close()
+			return nil, nil, nil, err
+		}
+		c2, err = ln.Accept()
+		if err != nil {
+			c1.Close()
+			ln.Close()
+			return nil, nil, nil, err
+		}
+		stop = func() {
+			c1.Close()
+			c2.Close()
+			ln.Close()
+		}
+		return c1, c2, stop, nil
+	}
+
+	// This is testConn without PresentTimeout: the deadline is passed to the
+	// network device at the start of a Read or Write, so setting a new deadline
+	// doesn't affect calls that are already in progress.
+	t.Run("BasicIO", func(t *testing.T) { timeoutWrapper(t, mp, testBasicIO) })
+	t.Run("PingPong", func(t *testing.T) { timeoutWrapper(t, mp, testPingPong) })
+	t.Run("RacyRead", func(t *testing.T) { timeoutWrapper(t, mp, testRacyRead) })
+	t.Run("RacyWrite", func(t *testing.T) { timeoutWrapper(t, mp, testRacyWrite) })
+	t.Run("ReadTimeout", func(t *testing.T) { timeoutWrapper(t, mp, testReadTimeout) })
+	t.Run("WriteTimeout", func(t *testing.T) { timeoutWrapper(t, mp, testWriteTimeout) })
+	t.Run("PastTimeout", func(t *testing.T) { timeoutWrapper(t, mp, testPastTimeout) })
+	t.Run("FutureTimeout", func(t *testing.T) { timeoutWrapper(t, mp, testFutureTimeout) })
+	t.Run("CloseTimeout", func(t *testing.T) { timeoutWrapper(t, mp, testCloseTimeout) })
+	t.Run("ConcurrentMethods", func(t *testing.T) { timeoutWrapper(t, mp, testConcurrentMethods) })
+}
+
+func TestNetdevAddrs(t *testing.T) {
+	useLoopbackNetdev(t)
+	ln, err := ListenTCP("tcp", &TCPAddr{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got := ln.Addr().String(); got != ":8080" {
+		t.Errorf("ln.Addr() = %s, want :8080", got)
+	}
+
+	// Host names are resolved by the network device.
+	c, err := Dial("tcp", "localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := c.RemoteAddr().String(); got != "127.0.0.1:8080" {
+		t.Errorf("c.RemoteAddr() = %s, want 127.0.0.1:8080", got)
+	}
+	if got := c.LocalAddr().String(); got != "127.0.0.1:0" {
+		t.Errorf("c.LocalAddr() = %s, want 127.0.0.1:0", got)
+	}
+
+	accepted, err := ln.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accepted.Close()
+	if got := accepted.LocalAddr().String(); got != "127.0.0.1:8080" {
+		t.Errorf("accepted.LocalAddr() = %s, want 127.0.0.1:8080", got)
+	}
+	if got := accepted.RemoteAddr().String(); got != "127.0.0.1:49152" {
+		t.Errorf("accepted.RemoteAddr() = %s, want 127.0.0.1:49152", got)
+	}
+}
+
+func TestNetdevErrors(t *testing.T) {
+	if _, err := Dial("tcp", "127.0.0.1:8080"); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Dial without netdev: got %v, want ErrNotImplemented", err)
+	}
+	if _, err := Listen("tcp", ":8080"); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Listen without netdev: got %v, want ErrNotImplemented", err)
+	}
+
+	useLoopbackNetdev(t)
+	if _, err := Dial("tcp", "127.0.0.1:8080"); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Dial without listener: got %v, want ECONNREFUSED", err)
+	}
+	if _, err := Dial("tcp", "example.com:80"); err == nil {
+		t.Error("Dial to unknown host succeeded")
+	}
+	if _, err := Dial("ip", "127.0.0.1"); err == nil {
+		t.Error("Dial with unknown network succeeded")
+	}
+
+	ln, err := Listen("tcp", ":8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("tcp", ":8080"); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("Listen on used port: got %v, want EADDRINUSE", err)
+	}
+
+	// Closing the listener unblocks Accept.
+	done := make(chan error)
+	go func() {
+		_, err := ln.Accept()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ln.Close()
+	if err := <-done; !errors.Is(err, ErrClosed) {
+		t.Errorf("Accept after Close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestNetdevAcceptDeadline(t *testing.T) {
+	useLoopbackNetdev(t)
+	ln, err := ListenTCP("tcp", &TCPAddr{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Accept returns a timeout error once the deadline has passed.
+	ln.SetDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := ln.Accept(); err != nil {
+		checkForTimeoutError(t, err)
+	} else {
+		t.Error("Accept succeeded without a connection")
+	}
+
+	// A deadline in the past fails immediately, and the zero time disables
+	// the deadline again.
+	ln.SetDeadline(time.Now().Add(-time.Second))
+	if _, err := ln.Accept(); err != nil {
+		checkForTimeoutError(t, err)
+	} else {
+		t.Error("Accept succeeded with a past deadline")
+	}
+	ln.SetDeadline(time.Time{})
+	c, err := Dial("tcp", "127.0.0.1:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted.Close()
+
+	ln.Close()
+	if err := ln.SetDeadline(time.Time{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetDeadline after Close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestNetdevUDP(t *testing.T) {
+	useLoopbackNetdev(t)
+	a, err := DialUDP("udp", &UDPAddr{Port: 9000}, &UDPAddr{IP: IPv4(127, 0, 0, 1), Port: 9001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := DialUDP("udp", &UDPAddr{Port: 9001}, &UDPAddr{IP: IPv4(127, 0, 0, 1), Port: 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	for _, msg := range []string{"ping", "pong"} {
+		if _, err := a.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := make([]byte, 16)
+	for _, want := range []string{"ping", "pong"} {
+		n, err := b.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Errorf("b.Read() = %q, want %q", got, want)
+		}
+	}
+
+	b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := b.Read(buf); err != nil {
+		checkForTimeoutError(t, err)
+	} else {
+		t.Error("b.Read() succeeded without a datagram")
+	}
+}
+
+func TestNetdevUDPListen(t *testing.T) {
+	useLoopbackNetdev(t)
+	server, err := ListenPacket("udp", ":9000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, err := ListenUDP("udp", &UDPAddr{Port: 9001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Send a datagram to the server, and echo it back to the sender.
+	if _, err := client.WriteTo([]byte("ping"), &UDPAddr{IP: IPv4(127, 0, 0, 1), Port: 9000}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, addr, err := server.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "ping" {
+		t.Errorf("server.ReadFrom() = %q, want %q", got, "ping")
+	}
+	if got := addr.String(); got != "127.0.0.1:9001" {
+		t.Errorf("server.ReadFrom() address = %s, want 127.0.0.1:9001", got)
+	}
+	if _, err := server.WriteTo(buf[:n], addr); err != nil {
+		t.Fatal(err)
+	}
+	n, from, err := client.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "ping" || from.Port != 9000 {
+		t.Errorf("client.ReadFromUDP() = %q from %s, want %q from port 9000", got, from, "ping")
+	}
+
+	// Sockets that aren't connected need an address to write to, and
+	// connected sockets can't write to a different address.
+	if _, err := client.Write(buf); !errors.Is(err, syscall.EDESTADDRREQ) {
+		t.Errorf("Write on unconnected socket: got %v, want EDESTADDRREQ", err)
+	}
+	connected, err := DialUDP("udp", nil, &UDPAddr{IP: IPv4(127, 0, 0, 1), Port: 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connected.Close()
+	if _, err := connected.WriteTo(buf, addr); !errors.Is(err, ErrWriteToConnected) {
+		t.Errorf("WriteTo on connected socket: got %v, want ErrWriteToConnected", err)
+	}
+
+	server.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, _, err := server.ReadFrom(buf); err != nil {
+		checkForTimeoutError(t, err)
+	} else {
+		t.Error("server.ReadFrom() succeeded without a datagram")
+	}
+}
+
+func TestNetdevCloseWrite(t *testing.T) {
+	useLoopbackNetdev(t)
+	ln, err := ListenTCP("tcp", &TCPAddr{Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := DialTCP("tcp", nil, &TCPAddr{IP: IPv4(127, 0, 0, 1), Port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	accepted, err := ln.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accepted.Close()
+
+	// The other side reads the data written before CloseWrite, then io.EOF,
+	// and can still write back.
+	if _, err := c.Write([]byte("request")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("more")); err == nil {
+		t.Error("Write after CloseWrite succeeded")
+	}
+	data, err := io.ReadAll(accepted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "request" {
+		t.Errorf("ReadAll() = %q, want %q", data, "request")
+	}
+	if _, err := accepted.Write([]byte("response")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, err := c.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "response" {
+		t.Errorf("Read() = %q, want %q", buf[:n], "response")
+	}
+}
+
+func TestNetdevDialDeadline(t *testing.T) {
+	useLoopbackNetdev(t)
+	ln, err := Listen("tcp", ":8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The earliest of the context deadline, Deadline and Timeout is passed to
+	// the network device.
+	past := time.Now().Add(-time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	for _, d := range []*Dialer{
+		{Deadline: past},
+		{Deadline: time.Now().Add(time.Hour), Timeout: -time.Second},
+	} {
+		if _, err := d.DialContext(ctx, "tcp", "127.0.0.1:8080"); err != nil {
+			checkForTimeoutError(t, err)
+		} else {
+			t.Errorf("DialContext with %+v succeeded", d)
+		}
+	}
+	d := &Dialer{Timeout: time.Hour}
+	c, err := d.DialContext(ctx, "tcp", "127.0.0.1:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	// A context that is already done fails without connecting.
+	cancel()
+	if _, err := d.DialContext(ctx, "tcp", "127.0.0.1:8080"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DialContext with cancelled context: got %v, want context.Canceled", err)
+	}
+}
@@ -3,6 +3,7 @@ package net
 import (
 	"internal/itoa"
 	"net/netip"
+	"time"
 )
 
 // TCPAddr represents the address of a TCP end point.
@@ -54,12 +55,165 @@ func (a *TCPAddr) opAddr() Addr {
 	return a
 }
 
+// TCPAddrFromAddrPort returns addr as a TCPAddr. If addr.IsValid() is false,
+// then the returned TCPAddr will contain a nil IP field, indicating an
+// address family-agnostic unspecified address.
+func TCPAddrFromAddrPort(addr netip.AddrPort) *TCPAddr {
+	return &TCPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
 // TCPConn is an implementation of the Conn interface for TCP network
 // connections.
 type TCPConn struct {
 	conn
 }
 
+// CloseWrite shuts down the writing side of the TCP connection. The other side
+// reads io.EOF once it has received all data written before.
 func (c *TCPConn) CloseWrite() error {
-	return &OpError{"close", "", nil, nil, ErrNotImplemented}
+	if c.closed {
+		return c.opError("close", ErrClosed)
+	}
+	if err := netdev.CloseWrite(c.fd); err != nil {
+		return c.opError("close", err)
+	}
+	return nil
+}
+
+// DialTCP connects to raddr using the registered network device. If laddr is
+// not nil, it is used as the local address of the connection.
+func DialTCP(network string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
+	}
+	return dialTCP(network, "", laddr, raddr, time.Time{})
+}
+
+func dialTCP(network, host string, laddr, raddr *TCPAddr, deadline time.Time) (*TCPConn, error) {
+	if netdev == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: ErrNotImplemented}
+	}
+	var localIP IP
+	var localPort int
+	if laddr != nil {
+		localIP, localPort = laddr.IP, laddr.Port
+	}
+	fd, err := netdevSocket("tcp", host, netdevAddrPort(localIP, localPort), netdevAddrPort(raddr.IP, raddr.Port), laddr != nil, deadline)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr, Err: err}
+	}
+	return &TCPConn{conn{
+		fd:    fd,
+		net:   network,
+		laddr: netdevLocalAddr("tcp", localIP, localPort),
+		raddr: raddr,
+	}}, nil
+}
+
+// TCPListener is a TCP network listener.
+type TCPListener struct {
+	fd       int
+	net      string
+	laddr    *TCPAddr
+	closed   bool
+	deadline time.Time
+}
+
+// ListenTCP listens for incoming connections on laddr using the registered
+// network device. If laddr is nil or its IP is nil, it listens on all
+// addresses of the device.
+func ListenTCP(network string, laddr *TCPAddr) (*TCPListener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if laddr == nil {
+		laddr = &TCPAddr{}
+	}
+	if netdev == nil {
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr, Err: ErrNotImplemented}
+	}
+	fd, err := netdev.Socket("tcp")
+	if err == nil {
+		err = netdev.Bind(fd, netdevAddrPort(laddr.IP, laddr.Port))
+		if err == nil {
+			err = netdev.Listen(fd, listenBacklog)
+		}
+		if err != nil {
+			netdev.Close(fd)
+		}
+	}
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr, Err: err}
+	}
+	return &TCPListener{fd: fd, net: network, laddr: laddr}, nil
+}
+
+// Accept implements the Accept method in the Listener interface; it waits for
+// the next call and returns a generic Conn.
+func (l *TCPListener) Accept() (Conn, error) {
+	return l.AcceptTCP()
+}
+
+// AcceptTCP accepts the next incoming call and returns the new connection.
+func (l *TCPListener) AcceptTCP() (*TCPConn, error) {
+	if l.closed {
+		return nil, l.opError("accept", ErrClosed)
+	}
+	fd, raddr, err := netdev.Accept(l.fd, l.deadline)
+	if err != nil {
+		if l.closed {
+			err = ErrClosed
+		}
+		return nil, l.opError("accept", err)
+	}
+	return &TCPConn{conn{
+		fd:    fd,
+		net:   l.net,
+		laddr: netdevLocalAddr("tcp", l.laddr.IP, l.laddr.Port),
+		raddr: TCPAddrFromAddrPort(raddr),
+	}}, nil
+}
+
+// Close stops listening on the TCP address. Already accepted connections are
+// not closed.
+func (l *TCPListener) Close() error {
+	if l.closed {
+		return l.opError("close", ErrClosed)
+	}
+	l.closed = true
+	if err := netdev.Close(l.fd); err != nil {
+		return l.opError("close", err)
+	}
+	return nil
+}
+
+// Addr returns the listener's network address, a *TCPAddr.
+func (l *TCPListener) Addr() Addr {
+	return l.laddr
+}
+
+// SetDeadline sets the deadline associated with the listener.
+// A zero time value disables the deadline. Like the deadlines of a
+// connection, it only affects calls to Accept that start after it is set.
+func (l *TCPListener) SetDeadline(t time.Time) error {
+	if l.closed {
+		return l.opError("set", ErrClosed)
+	}
+	l.deadline = t
+	return nil
+}
+
+func (l *TCPListener) opError(op string, err error) error {
+	return &OpError{Op: op, Net: l.net, Addr: l.laddr, Err: err}
 }
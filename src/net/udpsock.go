@@ -3,6 +3,8 @@ package net
 import (
 	"internal/itoa"
 	"net/netip"
+	"syscall"
+	"time"
 )
 
 // UDPAddr represents the address of a UDP end point.
@@ -53,3 +55,144 @@ func (a *UDPAddr) opAddr() Addr {
 	}
 	return a
 }
+
+// UDPAddrFromAddrPort returns addr as a UDPAddr. If addr.IsValid() is false,
+// then the returned UDPAddr will contain a nil IP field, indicating an
+// address family-agnostic unspecified address.
+func UDPAddrFromAddrPort(addr netip.AddrPort) *UDPAddr {
+	return &UDPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
+// UDPConn is the implementation of the Conn and PacketConn interfaces for UDP
+// network connections. Every Write or WriteTo sends a single datagram, and
+// every Read or ReadFrom receives a single datagram.
+type UDPConn struct {
+	conn
+}
+
+// ReadFromUDP acts like ReadFrom but returns a UDPAddr.
+func (c *UDPConn) ReadFromUDP(b []byte) (n int, addr *UDPAddr, err error) {
+	if c.closed {
+		return 0, nil, c.opError("read", ErrClosed)
+	}
+	n, from, err := netdev.RecvFrom(c.fd, b, c.readDeadline)
+	if err != nil {
+		if c.closed {
+			err = ErrClosed
+		}
+		return n, nil, c.opError("read", err)
+	}
+	return n, UDPAddrFromAddrPort(from), nil
+}
+
+// ReadFrom implements the PacketConn ReadFrom method.
+func (c *UDPConn) ReadFrom(b []byte) (int, Addr, error) {
+	n, addr, err := c.ReadFromUDP(b)
+	if addr == nil {
+		return n, nil, err
+	}
+	return n, addr, err
+}
+
+// WriteToUDP acts like WriteTo but takes a UDPAddr.
+func (c *UDPConn) WriteToUDP(b []byte, addr *UDPAddr) (int, error) {
+	if c.closed {
+		return 0, c.opError("write", ErrClosed)
+	}
+	if c.raddr != nil {
+		return 0, &OpError{Op: "write", Net: c.net, Source: c.laddr, Addr: addr.opAddr(), Err: ErrWriteToConnected}
+	}
+	if addr == nil {
+		return 0, &OpError{Op: "write", Net: c.net, Source: c.laddr, Addr: nil, Err: errMissingAddress}
+	}
+	n, err := netdev.SendTo(c.fd, b, netdevAddrPort(addr.IP, addr.Port), c.writeDeadline)
+	if err != nil {
+		if c.closed {
+			err = ErrClosed
+		}
+		return n, &OpError{Op: "write", Net: c.net, Source: c.laddr, Addr: addr, Err: err}
+	}
+	return n, nil
+}
+
+// WriteTo implements the PacketConn WriteTo method.
+func (c *UDPConn) WriteTo(b []byte, addr Addr) (int, error) {
+	a, ok := addr.(*UDPAddr)
+	if !ok {
+		return 0, &OpError{Op: "write", Net: c.net, Source: c.laddr, Addr: addr, Err: syscall.EINVAL}
+	}
+	return c.WriteToUDP(b, a)
+}
+
+// DialUDP creates a UDP socket that sends to and receives from raddr, using
+// the registered network device. If laddr is not nil, it is used as the local
+// address.
+func DialUDP(network string, laddr, raddr *UDPAddr) (*UDPConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
+	}
+	return dialUDP(network, "", laddr, raddr, time.Time{})
+}
+
+func dialUDP(network, host string, laddr, raddr *UDPAddr, deadline time.Time) (*UDPConn, error) {
+	if netdev == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: ErrNotImplemented}
+	}
+	var localIP IP
+	var localPort int
+	if laddr != nil {
+		localIP, localPort = laddr.IP, laddr.Port
+	}
+	fd, err := netdevSocket("udp", host, netdevAddrPort(localIP, localPort), netdevAddrPort(raddr.IP, raddr.Port), laddr != nil, deadline)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr, Err: err}
+	}
+	return &UDPConn{conn{
+		fd:    fd,
+		net:   network,
+		laddr: netdevLocalAddr("udp", localIP, localPort),
+		raddr: raddr,
+	}}, nil
+}
+
+// ListenUDP creates a UDP socket bound to laddr using the registered network
+// device. It is not connected to a remote address: use ReadFrom and WriteTo
+// to receive and send datagrams. If laddr is nil or its IP is nil, it listens
+// on all addresses of the device.
+func ListenUDP(network string, laddr *UDPAddr) (*UDPConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if laddr == nil {
+		laddr = &UDPAddr{}
+	}
+	if netdev == nil {
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr, Err: ErrNotImplemented}
+	}
+	fd, err := netdev.Socket("udp")
+	if err == nil {
+		err = netdev.Bind(fd, netdevAddrPort(laddr.IP, laddr.Port))
+		if err != nil {
+			netdev.Close(fd)
+		}
+	}
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Addr: laddr, Err: err}
+	}
+	return &UDPConn{conn{
+		fd:    fd,
+		net:   network,
+		laddr: netdevLocalAddr("udp", laddr.IP, laddr.Port),
+	}}, nil
+}
@@ -1,4 +1,4 @@
-//go:build !wasi && !wasip1
+//go:build !wasi && !wasip1 && !wasip2
 
 package os_test
 
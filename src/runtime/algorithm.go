@@ -15,6 +15,12 @@ func rand_fastrand64() uint64 {
 	return fastrand64()
 }
 
+// This function is used by math/rand, math/rand/v2, and hash/maphash since Go
+// 1.22.
+func rand() uint64 {
+	return fastrand64()
+}
+
 // This function is used by hash/maphash.
 func fastrand() uint32 {
 	xorshift32State = xorshift32(xorshift32State)
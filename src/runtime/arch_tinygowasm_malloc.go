@@ -2,12 +2,17 @@
 
 package runtime
 
-import "unsafe"
+import (
+	"runtime/interrupt"
+	"unsafe"
+)
 
 // The below functions override the default allocator of wasi-libc. This ensures
 // code linked from other languages can allocate memory without colliding with
 // our GC allocations.
 
+// The allocs map is protected with interrupt.Disable, as these functions may be
+// called from multiple threads when using WebAssembly threads.
 var allocs = make(map[uintptr][]byte)
 
 //export malloc
@@ -15,9 +20,11 @@ func libc_malloc(size uintptr) unsafe.Pointer {
 	if size == 0 {
 		return nil
 	}
+	mask := interrupt.Disable()
 	buf := make([]byte, size)
 	ptr := unsafe.Pointer(&buf[0])
 	allocs[uintptr(ptr)] = buf
+	interrupt.Restore(mask)
 	return ptr
 }
 
@@ -26,11 +33,13 @@ func libc_free(ptr unsafe.Pointer) {
 	if ptr == nil {
 		return
 	}
+	mask := interrupt.Disable()
 	if _, ok := allocs[uintptr(ptr)]; ok {
 		delete(allocs, uintptr(ptr))
 	} else {
 		panic("free: invalid pointer")
 	}
+	interrupt.Restore(mask)
 }
 
 //export calloc
@@ -48,6 +57,7 @@ func libc_realloc(oldPtr unsafe.Pointer, size uintptr) unsafe.Pointer {
 
 	// It's hard to optimize this to expand the current buffer with our GC, but
 	// it is theoretically possible. For now, just always allocate fresh.
+	mask := interrupt.Disable()
 	buf := make([]byte, size)
 
 	if oldPtr != nil {
@@ -61,5 +71,6 @@ func libc_realloc(oldPtr unsafe.Pointer, size uintptr) unsafe.Pointer {
 
 	ptr := unsafe.Pointer(&buf[0])
 	allocs[uintptr(ptr)] = buf
+	interrupt.Restore(mask)
 	return ptr
 }
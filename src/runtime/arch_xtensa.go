@@ -7,7 +7,7 @@ const GOARCH = "arm" // xtensa pretends to be arm
 // The bitness of the CPU (e.g. 8, 32, 64).
 const TargetBits = 32
 
-const deferExtraRegs = 0
+const deferExtraRegs = 1 // the return address (a0) also needs to be stored
 
 const callInstSize = 3 // "callx0 someFunction" (and similar) is 3 bytes
 
@@ -0,0 +1,34 @@
+//go:build sanitize.address
+
+package runtime
+
+// This file tells AddressSanitizer (-sanitize=address) which parts of the heap
+// may be accessed. The runtime itself is not instrumented, so it can still
+// access the whole heap (for example, while scanning it).
+
+import "unsafe"
+
+// Shadow memory values, the same as the ones used by compiler-rt. They're used
+// to describe the invalid access in the report.
+const (
+	asanHeapRedzone = 0xfa
+	asanHeapFree    = 0xfd
+)
+
+//export tinygo_asanPoison
+func asanPoison(addr, size uintptr, value uint8)
+
+//export __asan_unpoison_memory_region
+func asanUnpoison(addr unsafe.Pointer, size uintptr)
+
+// asanAllocated marks a new heap object of the given size at ptr as accessible.
+// The rest of the allocated blocks (from start to end) are a redzone.
+func asanAllocated(start, ptr unsafe.Pointer, size, end uintptr) {
+	asanPoison(uintptr(start), end-uintptr(start), asanHeapRedzone)
+	asanUnpoison(ptr, size)
+}
+
+// asanFreed marks the given heap memory as freed.
+func asanFreed(addr, size uintptr) {
+	asanPoison(addr, size, asanHeapFree)
+}
@@ -0,0 +1,11 @@
+//go:build !sanitize.address
+
+package runtime
+
+import "unsafe"
+
+// Stubs for when AddressSanitizer is not in use, see asan.go.
+
+func asanAllocated(start, ptr unsafe.Pointer, size, end uintptr) {}
+
+func asanFreed(addr, size uintptr) {}
@@ -61,6 +61,8 @@ func runtime_putchar(c byte) {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	coverageFlush()
+	pgoWriteProfile()
 	exit(code)
 }
 
@@ -0,0 +1,64 @@
+package runtime
+
+// Code coverage support (tinygo test -cover). The compiler inserts a counter in
+// every basic block of the covered packages, and registers the counters of
+// each package from its package initializer.
+
+import "unsafe"
+
+type coveragePackage struct {
+	counters    unsafe.Pointer // array of uint32 counters
+	numCounters uintptr
+	blocks      string // one line per counter: "file:startLine.startCol,endLine.endCol numStmts"
+}
+
+var coveragePackages []coveragePackage
+
+// coverageFlushed is set once the coverage counters have been written, so
+// that they are only written once even if the program exits (via os.Exit for
+// example) after the testing package already flushed them.
+var coverageFlushed bool
+
+// registerCoverage is called by the compiler from the package initializer of
+// every covered package.
+func registerCoverage(counters unsafe.Pointer, numCounters uintptr, blocks string) {
+	coveragePackages = append(coveragePackages, coveragePackage{counters, numCounters, blocks})
+}
+
+// coverageFlush writes all coverage counters to the standard output, between
+// two marker lines. The tinygo test command removes them from the test output
+// and writes them to the coverage profile. Using the standard output means this
+// works everywhere the test output can be read: on the host, in WebAssembly
+// runtimes and in emulators (via semihosting).
+//
+// It is called from the testing package at the end of a test run, and from
+// every exit path of the runtime so that a test that exits early (os.Exit or a
+// failing test) still reports its coverage.
+//
+//go:linkname coverageFlush testing.runtime_coverageFlush
+func coverageFlush() {
+	if len(coveragePackages) == 0 || coverageFlushed {
+		return
+	}
+	coverageFlushed = true
+	printstring("tinygo-coverage-begin\n")
+	for _, pkg := range coveragePackages {
+		blocks := pkg.blocks
+		for i := uintptr(0); i < pkg.numCounters; i++ {
+			// Print the block description, followed by the count.
+			end := 0
+			for end < len(blocks) && blocks[end] != '\n' {
+				end++
+			}
+			printstring(blocks[:end])
+			printspace()
+			printuint32(*(*uint32)(unsafe.Add(pkg.counters, i*4)))
+			printnl()
+			if end < len(blocks) {
+				end++ // skip newline
+			}
+			blocks = blocks[end:]
+		}
+	}
+	printstring("tinygo-coverage-end\n")
+}
@@ -0,0 +1,23 @@
+package runtime
+
+// Coverage-guided fuzzing support (tinygo test -fuzz). The compiler inserts an
+// 8-bit counter in every basic block of the fuzzed packages, and registers the
+// counters of each package from its package initializer.
+
+import "unsafe"
+
+var fuzzCounterRegions [][]uint8
+
+// registerFuzzCounters is called by the compiler from the package initializer
+// of every package that is instrumented for fuzzing.
+func registerFuzzCounters(counters unsafe.Pointer, numCounters uintptr) {
+	fuzzCounterRegions = append(fuzzCounterRegions, unsafe.Slice((*uint8)(counters), numCounters))
+}
+
+// fuzzCounters returns the fuzzing counters of all instrumented packages. The
+// fuzzer resets them before running an input, and inspects them afterwards.
+//
+//go:linkname fuzzCounters testing.runtime_fuzzCounters
+func fuzzCounters() [][]uint8 {
+	return fuzzCounterRegions
+}
@@ -201,6 +201,7 @@ func initHeap() {
 	// Set all block states to 'free'.
 	metadataSize := heapEnd - uintptr(metadataStart)
 	memzero(unsafe.Pointer(metadataStart), metadataSize)
+	asanFreed(heapStart, uintptr(metadataStart)-heapStart)
 }
 
 // setHeapEnd is called to expand the heap. The heap can only grow, not shrink.
@@ -232,6 +233,9 @@ func setHeapEnd(newHeapEnd uintptr) {
 	if gcAsserts && uintptr(metadataStart) < uintptr(oldMetadataStart)+oldMetadataSize {
 		runtimePanic("gc: heap did not grow enough at once")
 	}
+
+	// The old metadata and the new memory are now free heap blocks.
+	asanFreed(uintptr(oldMetadataStart), uintptr(metadataStart)-uintptr(oldMetadataStart))
 }
 
 // calculateHeapAddresses initializes variables such as metadataStart and
@@ -281,6 +285,7 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 		runtimePanicAt(returnAddress(0), "heap alloc in interrupt")
 	}
 
+	gcLock()
 	gcTotalAlloc += uint64(size)
 	gcMallocs++
 
@@ -374,6 +379,8 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 				size -= add
 			}
 			memzero(pointer, size)
+			asanAllocated(thisAlloc.pointer(), pointer, size, nextAlloc.address())
+			gcUnlock()
 			return pointer
 		}
 	}
@@ -384,6 +391,7 @@ func realloc(ptr unsafe.Pointer, size uintptr) unsafe.Pointer {
 		return alloc(size, nil)
 	}
 
+	gcLock()
 	ptrAddress := uintptr(ptr)
 	endOfTailAddress := blockFromAddr(ptrAddress).findNext().address()
 
@@ -391,12 +399,14 @@ func realloc(ptr unsafe.Pointer, size uintptr) unsafe.Pointer {
 	// ptr, because we align to full blocks of size bytesPerBlock
 	oldSize := endOfTailAddress - ptrAddress
 	if size <= oldSize {
+		gcUnlock()
 		return ptr
 	}
 
 	newAlloc := alloc(size, nil)
 	memcpy(newAlloc, ptr, oldSize)
 	free(ptr)
+	gcUnlock()
 
 	return newAlloc
 }
@@ -407,7 +417,9 @@ func free(ptr unsafe.Pointer) {
 
 // GC performs a garbage collection cycle.
 func GC() {
+	gcLock()
 	runGC()
+	gcUnlock()
 }
 
 // runGC performs a garbage colleciton cycle. It is the internal implementation
@@ -418,6 +430,10 @@ func runGC() (freeBytes uintptr) {
 		println("running collection cycle...")
 	}
 
+	// Wait until other threads (if any) have stopped, so that they don't
+	// modify the heap while it is being collected.
+	stopTheWorld()
+
 	// Mark phase: mark all reachable objects, recursively.
 	markStack()
 	findGlobals(markRoots)
@@ -624,6 +640,7 @@ func sweep() (freeBytes uintptr) {
 		case blockStateHead:
 			// Unmarked head. Free it, including all tail blocks following it.
 			block.markFree()
+			asanFreed(block.address(), bytesPerBlock)
 			freeCurrentObject = true
 			gcFrees++
 			freeBytes += bytesPerBlock
@@ -632,6 +649,7 @@ func sweep() (freeBytes uintptr) {
 				// This is a tail object following an unmarked head.
 				// Free it now.
 				block.markFree()
+				asanFreed(block.address(), bytesPerBlock)
 				freeBytes += bytesPerBlock
 			}
 		case blockStateMark:
@@ -673,6 +691,7 @@ func dumpHeap() {
 // The returned memory statistics are up to date as of the
 // call to ReadMemStats. This would not do GC implicitly for you.
 func ReadMemStats(m *MemStats) {
+	gcLock()
 	m.HeapIdle = 0
 	m.HeapInuse = 0
 	for block := gcBlock(0); block < endBlock; block++ {
@@ -690,6 +709,7 @@ func ReadMemStats(m *MemStats) {
 	m.Mallocs = gcMallocs
 	m.Frees = gcFrees
 	m.Sys = uint64(heapEnd - heapStart)
+	gcUnlock()
 }
 
 func SetFinalizer(obj interface{}, finalizer interface{}) {
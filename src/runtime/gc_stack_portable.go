@@ -45,7 +45,15 @@ func markStack() {
 	// live.
 	volatile.LoadUint32((*uint32)(unsafe.Pointer(&stackChainStart)))
 
-	if task.OnSystemStack() {
+	if hasThreads {
+		// Only the system stack of the main thread needs to be scanned. The
+		// system stacks of other threads are allocated by pthread_create using
+		// malloc so they are part of the heap, and running goroutines are
+		// reachable through internal/task.currentTasks.
+		if sp := mainThreadStackPointer(); sp != 0 {
+			markRoots(sp, stackTop)
+		}
+	} else if task.OnSystemStack() {
 		markRoots(getCurrentStackPointer(), stackTop)
 	}
 }
@@ -1,4 +1,4 @@
-//go:build !baremetal
+//go:build !baremetal && !(wasm.threads && scheduler.asyncify)
 
 package interrupt
 
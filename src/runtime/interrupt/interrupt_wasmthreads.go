@@ -0,0 +1,44 @@
+//go:build wasm.threads && scheduler.asyncify
+
+package interrupt
+
+import _ "unsafe"
+
+// There are no interrupts when using WebAssembly threads, but goroutines run in
+// parallel on multiple threads. Therefore, a critical section takes the global
+// runtime lock, which protects all scheduler and channel state.
+
+// State represents the previous global interrupt state.
+type State uintptr
+
+// Disable enters a critical section, by taking the global runtime lock. It can
+// be used in a critical section like this:
+//
+//	state := interrupt.Disable()
+//	// critical section
+//	interrupt.Restore(state)
+//
+// Critical sections can be nested. Make sure to call Restore in the same order
+// as you called Disable (this happens naturally with the pattern above).
+func Disable() (state State) {
+	lockRuntime()
+	return 0
+}
+
+// Restore leaves the critical section entered with Disable. The runtime lock is
+// only released when leaving the outermost critical section.
+func Restore(state State) {
+	unlockRuntime()
+}
+
+// In returns whether the system is currently in an interrupt.
+func In() bool {
+	// There are no interrupts, so it can't be in one.
+	return false
+}
+
+//go:linkname lockRuntime runtime.lockRuntime
+func lockRuntime()
+
+//go:linkname unlockRuntime runtime.unlockRuntime
+func unlockRuntime()
@@ -0,0 +1,43 @@
+//go:build tinygo.library || tinygo.wasm
+
+package runtime
+
+// This file implements calls from the host program into exported Go functions
+// in the library build modes (-buildmode=c-archive and -buildmode=c-shared).
+// The compiler wraps every exported function of the library so that it is
+// called through libraryCall. On WebAssembly, functions exported with
+// //go:wasmexport are wrapped in the same way in every build mode.
+
+// Number of calls into Go that are currently running.
+var libraryDepth int
+
+// libraryCall runs fn, which is an exported function called by the host
+// program. The first call runs in a new goroutine while the scheduler runs
+// until the call has returned, so that the function may block (for example on
+// a channel operation). Other goroutines continue to run during the next call.
+// Nested calls (when Go calls a C function that calls back into Go) run
+// directly on the calling goroutine.
+//
+// The runtime can only run on one thread at a time. If the host program calls
+// into Go from several threads, the calls are serialized: a call from another
+// thread waits until the running call has returned. This means that a call
+// that blocks until a call from another thread has made progress will
+// deadlock.
+//
+//go:noinline
+func libraryCall(fn func()) {
+	if libraryOwner() && libraryDepth != 0 {
+		fn()
+		return
+	}
+	libraryLock()
+	libraryDepth++
+
+	// The host program may call into Go at any stack depth, so the top of the
+	// system stack that is scanned by the GC must be updated on every call.
+	stackTop = getCurrentStackPointer()
+	runLibraryCall(fn)
+
+	libraryDepth--
+	libraryUnlock()
+}
@@ -0,0 +1,18 @@
+//go:build tinygo.wasm
+
+package runtime
+
+// A WebAssembly instance runs on a single thread of the host, so calls into Go
+// through exported functions can't happen in parallel and need no lock. With
+// wasi-threads, every thread is a separate instance whose exports are not
+// called by the host.
+
+func libraryOwner() bool {
+	return true
+}
+
+func libraryLock() {
+}
+
+func libraryUnlock() {
+}
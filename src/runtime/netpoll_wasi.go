@@ -0,0 +1,177 @@
+//go:build tinygo.wasm && (wasi || wasip1)
+
+package runtime
+
+// This file lets goroutines wait for a WASI socket (or any other file
+// descriptor) to become ready, for the net package. Waiting goroutines are
+// paused, and the scheduler includes their file descriptors in the
+// poll_oneoff call it already makes when there is nothing else to do.
+//
+// Without a scheduler, and with WebAssembly threads, the calling thread simply
+// blocks in poll_oneoff.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// pollWaiter is a goroutine waiting for a file descriptor.
+type pollWaiter struct {
+	next     *pollWaiter
+	task     *task.Task
+	fd       uint32
+	write    bool
+	deadline timeUnit // 0 when there is no deadline
+	ready    bool
+}
+
+var (
+	// pollWaiters is the list of goroutines waiting for a file descriptor.
+	pollWaiters *pollWaiter
+
+	// Buffers for poll_oneoff, reused to avoid heap allocations in the
+	// scheduler.
+	pollSubscriptions []__wasi_subscription_t
+	pollEvents        []__wasi_event_t
+)
+
+// netpollWait waits until the file descriptor is ready for reading (or writing
+// if write is set), or until the timeout in nanoseconds has passed. A negative
+// timeout means there is no timeout. It returns false if the timeout has
+// passed.
+//
+//go:linkname netpollWait net.runtime_pollWait
+func netpollWait(fd uint32, write bool, timeout int64) bool {
+	if !hasScheduler || hasThreads {
+		return netpollBlock(fd, write, timeout)
+	}
+	w := &pollWaiter{
+		task:  task.Current(),
+		fd:    fd,
+		write: write,
+	}
+	if timeout >= 0 {
+		w.deadline = ticks() + nanosecondsToTicks(timeout)
+		if w.deadline == 0 {
+			w.deadline = 1
+		}
+	}
+	w.next = pollWaiters
+	pollWaiters = w
+	task.Pause()
+	return w.ready
+}
+
+// netpollBlock waits for the file descriptor while blocking the current
+// thread. With threads, the thread counts as stopped while it waits so that
+// the GC can run on other threads in the meantime.
+func netpollBlock(fd uint32, write bool, timeout int64) bool {
+	var subscriptions [2]__wasi_subscription_t
+	var events [2]__wasi_event_t
+	var nevents uint32
+	setFDSubscription(&subscriptions[0], fd, write)
+	n := uint32(1)
+	if timeout >= 0 {
+		setClockSubscription(&subscriptions[1], uint64(nanosecondsToTicks(timeout)))
+		n++
+	}
+	enterBlockingCall()
+	poll_oneoff(&subscriptions[0], &events[0], n, &nevents)
+	exitBlockingCall()
+	for _, event := range events[:nevents] {
+		if event.eventType != __wasi_eventtype_t_clock {
+			return true
+		}
+	}
+	return false
+}
+
+// netpoll waits until one of the waiting file descriptors is ready or until
+// the timeout has passed, and adds the goroutines that can continue to the
+// runqueue. A negative timeout means there is no timeout, in which case there
+// must be at least one waiting goroutine.
+func netpoll(timeout timeUnit) {
+	// Shorten the timeout to the earliest deadline.
+	now := ticks()
+	for w := pollWaiters; w != nil; w = w.next {
+		if w.deadline == 0 {
+			continue
+		}
+		left := w.deadline - now
+		if left < 0 {
+			left = 0
+		}
+		if timeout < 0 || left < timeout {
+			timeout = left
+		}
+	}
+
+	// Subscribe to every waiting file descriptor, and to the clock.
+	pollSubscriptions = pollSubscriptions[:0]
+	for w := pollWaiters; w != nil; w = w.next {
+		var s __wasi_subscription_t
+		setFDSubscription(&s, w.fd, w.write)
+		s.userData = uint64(uintptr(unsafe.Pointer(w)))
+		pollSubscriptions = append(pollSubscriptions, s)
+	}
+	if timeout >= 0 {
+		var s __wasi_subscription_t
+		setClockSubscription(&s, uint64(timeout))
+		pollSubscriptions = append(pollSubscriptions, s)
+	}
+	if cap(pollEvents) < len(pollSubscriptions) {
+		pollEvents = make([]__wasi_event_t, cap(pollSubscriptions))
+	}
+	var nevents uint32
+	poll_oneoff(&pollSubscriptions[0], &pollEvents[0], uint32(len(pollSubscriptions)), &nevents)
+
+	// Mark the goroutines with a ready file descriptor.
+	for _, event := range pollEvents[:nevents] {
+		if event.eventType == __wasi_eventtype_t_clock {
+			continue
+		}
+		// An error (such as a closed socket) is also reported as ready: the
+		// goroutine will see the error on its next call.
+		(*pollWaiter)(unsafe.Pointer(uintptr(event.userData))).ready = true
+	}
+
+	// Wake up the ready goroutines, and those with an expired deadline.
+	now = ticks()
+	for p := &pollWaiters; *p != nil; {
+		w := *p
+		if w.ready || (w.deadline != 0 && now >= w.deadline) {
+			*p = w.next
+			w.next = nil
+			scheduleLogTask("  poll:", w.task)
+			runqueue.Push(w.task)
+			continue
+		}
+		p = &w.next
+	}
+}
+
+func setFDSubscription(s *__wasi_subscription_t, fd uint32, write bool) {
+	s.u.tag = __wasi_eventtype_t_fd_read
+	if write {
+		s.u.tag = __wasi_eventtype_t_fd_write
+	}
+	// The subscription_fd_readwrite record only contains the file descriptor,
+	// at the same place as the clock id.
+	s.u.u = __wasi_subscription_clock_t{id: fd}
+}
+
+func setClockSubscription(s *__wasi_subscription_t, timeout uint64) {
+	s.u.tag = __wasi_eventtype_t_clock
+	s.u.u = __wasi_subscription_clock_t{
+		id:        0,
+		timeout:   timeout,
+		precision: timePrecisionNanoseconds,
+	}
+}
+
+func waitForEvents() {
+	if pollWaiters == nil {
+		runtimePanic("deadlocked: no event source")
+	}
+	netpoll(-1)
+}
@@ -1,4 +1,4 @@
-//go:build linux && !baremetal && !nintendoswitch && !wasi
+//go:build linux && !baremetal && !nintendoswitch && !wasi && !wasip2
 
 package runtime
 
@@ -87,6 +87,13 @@ func findGlobals(found func(start, end uintptr)) {
 		PF_W    = 0x2 // program flag: write access
 	)
 
+	// The ELF header is at the start of the first loadable segment. Use it to
+	// calculate the load bias, which is zero for static executables but not
+	// for shared libraries (-buildmode=c-shared) or position independent
+	// executables that link in a -buildmode=c-archive library.
+	var loadBias uintptr
+	foundLoad := false
+
 	headerPtr := unsafe.Pointer(uintptr(unsafe.Pointer(&ehdr_start)) + ehdr_start.phoff)
 	for i := 0; i < int(ehdr_start.phnum); i++ {
 		// Look for a writable segment and scan its contents.
@@ -94,18 +101,23 @@ func findGlobals(found func(start, end uintptr)) {
 		// the alternative would be to put elfProgramHeader in separate files
 		// which is IMHO a lot uglier. If only the ELF spec was consistent
 		// between 32-bit and 64-bit...
+		var _type, flags uint32
+		var offset, vaddr, memsz uintptr
 		if TargetBits == 64 {
 			header := (*elfProgramHeader64)(headerPtr)
-			if header._type == PT_LOAD && header.flags&PF_W != 0 {
-				start := header.vaddr
-				end := start + header.memsz
-				found(start, end)
-			}
+			_type, flags, offset, vaddr, memsz = header._type, header.flags, header.offset, header.vaddr, header.memsz
 		} else {
 			header := (*elfProgramHeader32)(headerPtr)
-			if header._type == PT_LOAD && header.flags&PF_W != 0 {
-				start := header.vaddr
-				end := start + header.memsz
+			_type, flags, offset, vaddr, memsz = header._type, header.flags, header.offset, header.vaddr, header.memsz
+		}
+		if _type == PT_LOAD {
+			if !foundLoad {
+				loadBias = uintptr(unsafe.Pointer(&ehdr_start)) - (vaddr - offset)
+				foundLoad = true
+			}
+			if flags&PF_W != 0 {
+				start := loadBias + vaddr
+				end := start + memsz
 				found(start, end)
 			}
 		}
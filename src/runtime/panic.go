@@ -11,16 +11,21 @@ import (
 //export llvm.trap
 func trap()
 
-// Inline assembly stub. It is essentially C longjmp but modified a bit for the
-// purposes of TinyGo. It restores the stack pointer and jumps to the given pc.
-//
-//export tinygo_longjmp
-func tinygo_longjmp(frame *deferFrame)
-
 // Compiler intrinsic.
 // Returns whether recover is supported on the current architecture.
 func supportsRecover() bool
 
+// Compiler intrinsic.
+// Returns the strategy used when a panic is not recovered (-panic flag). The
+// values are defined below.
+func panicStrategy() uint8
+
+const (
+	panicStrategyPrint = 1
+	panicStrategyTrap  = 2
+	panicStrategyReset = 3
+)
+
 // DeferFrame is a stack allocated object that stores information for the
 // current "defer frame", which is used in functions that use the `defer`
 // keyword.
@@ -46,6 +51,9 @@ func _panic(message interface{}) {
 			// unreachable
 		}
 	}
+	if panicHandler != nil || panicStrategy() == panicStrategyReset {
+		handleUnrecoveredPanic(panicValueString(message), uintptr(returnAddress(0)))
+	}
 	printstring("panic: ")
 	printitf(message)
 	printnl()
@@ -60,6 +68,9 @@ func runtimePanic(msg string) {
 }
 
 func runtimePanicAt(addr unsafe.Pointer, msg string) {
+	if panicHandler != nil || panicStrategy() == panicStrategyReset {
+		handleUnrecoveredPanic(msg, uintptr(addr))
+	}
 	if hasReturnAddr {
 		printstring("panic: runtime error at ")
 		printptr(uintptr(addr) - callInstSize)
@@ -0,0 +1,21 @@
+//go:build wasm.exceptions
+
+package runtime
+
+import "unsafe"
+
+// WebAssembly can't jump to a given stack pointer and pc, so panics are
+// implemented using the exception handling proposal instead. The compiler
+// catches the exception in the function of the defer frame (in any function
+// with a defer frame, actually, but the frame that is thrown is always the
+// most recent one) and continues at its landing pad, which is what
+// tinygo_longjmp does on other architectures.
+func tinygo_longjmp(frame *deferFrame) {
+	wasm_throw(0, unsafe.Pointer(frame))
+}
+
+// Throw an exception with the C++ exception tag (__cpp_exception), which is
+// the only tag supported by LLVM.
+//
+//export llvm.wasm.throw
+func wasm_throw(tag int32, obj unsafe.Pointer)
@@ -0,0 +1,126 @@
+package runtime
+
+// This file implements the user-installable panic handler and the panic record
+// that survives a reset when compiling with -panic=reset.
+
+// PanicRecord describes an unrecovered panic.
+type PanicRecord struct {
+	// Message is the panic value converted to a string. It is truncated when
+	// it was restored using LastPanic.
+	Message string
+
+	// PC is the address of the instruction that caused the panic, or 0 if it
+	// isn't known on this architecture.
+	PC uintptr
+}
+
+// Size of the message stored in the panic record. Longer messages are
+// truncated.
+const panicRecordMessageSize = 96
+
+// Magic value to detect whether the panic record contains valid data. RAM
+// contents are undefined after a power-on reset, so a checksum is also stored.
+const panicRecordMagic = 0x706e6963 // "pnic"
+
+// storedPanicRecord is the panic record as stored in RAM that is not cleared
+// on startup. It must not contain pointers, as it is not scanned by the GC.
+type storedPanicRecord struct {
+	magic    uint32
+	length   uint32
+	pc       uintptr
+	checksum uintptr
+	message  [panicRecordMessageSize]byte
+}
+
+var panicHandler func(PanicRecord)
+
+// SetPanicHandler installs a function that is called when a panic is not
+// recovered, before the panic is handled according to the -panic flag. It can
+// be used to log the panic to flash storage, for example.
+//
+// The handler is called at most once: a panic inside the handler is handled as
+// if there was no handler installed. If the handler returns, the program
+// continues with the usual panic strategy (print or reset). Pass nil to remove
+// a previously installed handler.
+//
+// The handler is never called with -panic=trap: in that case, panics are
+// replaced with a trap instruction at compile time.
+func SetPanicHandler(handler func(PanicRecord)) {
+	panicHandler = handler
+}
+
+// LastPanic returns the panic that caused the last reset, when the program was
+// compiled with -panic=reset. The record is kept across resets until it is
+// cleared using ClearLastPanic.
+func LastPanic() (record PanicRecord, ok bool) {
+	stored := &panicRecordStorage
+	if stored.magic != panicRecordMagic || stored.length > panicRecordMessageSize || stored.checksum != stored.computeChecksum() {
+		return PanicRecord{}, false
+	}
+	return PanicRecord{
+		Message: string(stored.message[:stored.length]),
+		PC:      stored.pc,
+	}, true
+}
+
+// ClearLastPanic clears the panic record returned by LastPanic.
+func ClearLastPanic() {
+	panicRecordStorage = storedPanicRecord{}
+}
+
+// computeChecksum returns a simple checksum over the panic record, to check
+// whether the record is valid after a reset.
+func (r *storedPanicRecord) computeChecksum() uintptr {
+	checksum := ^(uintptr(r.magic) ^ uintptr(r.length) ^ r.pc)
+	for _, c := range r.message[:r.length] {
+		checksum = checksum*31 + uintptr(c)
+	}
+	return checksum
+}
+
+// handleUnrecoveredPanic is called for every panic that is not recovered, when
+// a panic handler has been installed or when compiling with -panic=reset. The
+// pc is the return address of the call that caused the panic.
+//
+// It does not return when compiling with -panic=reset.
+func handleUnrecoveredPanic(msg string, pc uintptr) {
+	if hasReturnAddr && pc != 0 {
+		pc -= callInstSize
+	}
+	if panicStrategy() == panicStrategyReset {
+		// Store the panic before calling the handler, in case the handler
+		// itself crashes.
+		stored := &panicRecordStorage
+		stored.length = uint32(copy(stored.message[:], msg))
+		stored.pc = pc
+		stored.magic = panicRecordMagic
+		stored.checksum = stored.computeChecksum()
+	}
+	if handler := panicHandler; handler != nil {
+		panicHandler = nil // don't recurse when the handler panics
+		handler(PanicRecord{Message: msg, PC: pc})
+	}
+	if panicStrategy() == panicStrategyReset {
+		printstring("panic: ")
+		printstring(msg)
+		printnl()
+		resetAfterPanic()
+	}
+}
+
+// panicValueString converts a panic value to a string, without depending on
+// packages like fmt.
+func panicValueString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case error:
+		return value.Error()
+	case stringer:
+		return value.String()
+	case nil:
+		return "nil"
+	default:
+		return "(unknown panic value)"
+	}
+}
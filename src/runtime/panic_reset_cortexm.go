@@ -0,0 +1,9 @@
+//go:build cortexm
+
+package runtime
+
+// The panic record is stored in the .noinit section, which is not cleared on
+// startup and therefore survives a warm reset.
+//
+//go:section .noinit
+var panicRecordStorage storedPanicRecord
@@ -0,0 +1,26 @@
+//go:build nrf
+
+package runtime
+
+import (
+	"device/arm"
+	"device/nrf"
+)
+
+// resetAfterPanic resets the chip through the watchdog after a panic with
+// -panic=reset. The watchdog reset keeps the contents of RAM intact.
+func resetAfterPanic() {
+	if nrf.WDT.RUNSTATUS.Get() != 0 {
+		// The application has already started the watchdog, so it can't be
+		// reconfigured and may take a long time to expire. Reset the chip
+		// through the SCB instead.
+		arm.SystemReset()
+	}
+	// Start the watchdog with the shortest timeout the hardware allows (15
+	// ticks of the 32.768kHz clock), and wait for it to expire.
+	nrf.WDT.CRV.Set(15)
+	nrf.WDT.TASKS_START.Set(1)
+	for {
+		arm.Asm("wfi")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !cortexm
+
+package runtime
+
+// There is no RAM area that survives a reset on this target, so the panic
+// record is only available until the program exits.
+var panicRecordStorage storedPanicRecord
+
+// resetAfterPanic is called after a panic with -panic=reset. Resetting the chip
+// is not supported on this target, so it aborts instead.
+func resetAfterPanic() {
+	abort()
+}
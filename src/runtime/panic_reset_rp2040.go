@@ -0,0 +1,20 @@
+//go:build rp2040
+
+package runtime
+
+import (
+	"device/arm"
+	"device/rp"
+)
+
+// resetAfterPanic resets the chip through the watchdog after a panic with
+// -panic=reset. The watchdog reset keeps the contents of RAM intact.
+func resetAfterPanic() {
+	// Reset everything apart from the oscillators, like the machine package
+	// does when the watchdog expires.
+	rp.PSM.WDSEL.Set(0x0001ffff &^ (rp.PSM_WDSEL_ROSC | rp.PSM_WDSEL_XOSC))
+	rp.WATCHDOG.CTRL.SetBits(rp.WATCHDOG_CTRL_TRIGGER)
+	for {
+		arm.Asm("wfi")
+	}
+}
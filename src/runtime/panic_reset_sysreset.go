@@ -0,0 +1,13 @@
+//go:build cortexm && !rp2040 && !nrf
+
+package runtime
+
+import "device/arm"
+
+// resetAfterPanic resets the chip after a panic with -panic=reset. There is no
+// support for the watchdog of this chip in the runtime, so it requests a
+// system reset through the SCB instead. Like a watchdog reset, this keeps the
+// contents of RAM intact.
+func resetAfterPanic() {
+	arm.SystemReset()
+}
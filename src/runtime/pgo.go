@@ -0,0 +1,22 @@
+//go:build pgo.instrument
+
+package runtime
+
+// Profile-guided optimization support (-pgo=instrument). The compiler inserts
+// LLVM profile counters in all functions, and the profile runtime in
+// lib/profile writes them out in the raw LLVM profile format when the program
+// exits. The raw profiles can be merged into an indexed profile with
+// llvm-profdata, which can then be passed to -pgo.
+
+var pgoWritten bool
+
+// pgoWriteProfile writes the profile when the program exits, either by
+// returning from main or by calling os.Exit. The profile is only written once,
+// even when both happen.
+func pgoWriteProfile() {
+	if pgoWritten {
+		return
+	}
+	pgoWritten = true
+	pgoWrite()
+}
@@ -0,0 +1,29 @@
+//go:build pgo.instrument && baremetal
+
+package runtime
+
+//export __llvm_profile_get_size_for_buffer
+func llvmProfileGetSizeForBuffer() uint64
+
+//export __llvm_profile_write_buffer
+func llvmProfileWriteBuffer(buf *byte) int32
+
+// pgoWrite writes the profile to the standard output in hexadecimal, between
+// two marker lines, because there is no file system. The tinygo run and test
+// commands remove them from the output and write the profile to a file.
+func pgoWrite() {
+	buf := make([]byte, llvmProfileGetSizeForBuffer())
+	if len(buf) == 0 || llvmProfileWriteBuffer(&buf[0]) != 0 {
+		printstring("failed to write profile\n")
+		return
+	}
+	printstring("tinygo-profile-begin\n")
+	for i, b := range buf {
+		putchar("0123456789abcdef"[b>>4])
+		putchar("0123456789abcdef"[b&0xf])
+		if i%32 == 31 || i == len(buf)-1 {
+			printnl()
+		}
+	}
+	printstring("tinygo-profile-end\n")
+}
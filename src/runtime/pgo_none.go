@@ -0,0 +1,8 @@
+//go:build !pgo.instrument
+
+package runtime
+
+// Stub for when the program is not instrumented for profile-guided
+// optimization, see pgo.go.
+
+func pgoWriteProfile() {}
@@ -0,0 +1,13 @@
+//go:build pgo.instrument && !baremetal
+
+package runtime
+
+// Write the profile to a file, see lib/profile/profile.c. Errors are printed
+// by the profile runtime.
+//
+//export __llvm_profile_write_file
+func llvmProfileWriteFile() int32
+
+func pgoWrite() {
+	llvmProfileWriteFile()
+}
@@ -16,8 +16,11 @@ func initAll()
 func callMain()
 
 func GOMAXPROCS(n int) int {
-	// Note: setting GOMAXPROCS is ignored.
-	return 1
+	// Note: setting GOMAXPROCS is ignored, except with WebAssembly threads
+	// where it can increase the number of threads running goroutines. Every
+	// GC cycle waits for all threads to stop, which a goroutine blocked in a
+	// host call delays until the call returns (see scheduler_threads.go).
+	return gomaxprocs(n)
 }
 
 func GOROOT() string {
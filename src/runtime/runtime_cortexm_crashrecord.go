@@ -0,0 +1,193 @@
+//go:build cortexm
+
+package runtime
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// CrashRecord describes a HardFault that happened before the last reset. It is
+// only stored when compiling with -panic=reset. It can be printed in a format
+// that `tinygo monitor` understands, so that the addresses are converted to
+// source locations.
+type CrashRecord struct {
+	CFSR  uint32 // Configurable Fault Status Register (0 on Cortex-M0)
+	HFSR  uint32 // HardFault Status Register (0 on Cortex-M0)
+	MMFAR uint32 // MemManage Fault Address Register (0 if not valid)
+	BFAR  uint32 // BusFault Address Register (0 if not valid)
+
+	// Registers stacked on exception entry.
+	PC  uintptr
+	LR  uintptr
+	SP  uintptr
+	PSR uintptr
+
+	// Goroutine is the address of the goroutine (task) that was running when
+	// the fault happened.
+	Goroutine uintptr
+
+	// Stack is a snapshot of the stack just above the exception frame. It is
+	// empty when the stack pointer was invalid, for example after a stack
+	// overflow.
+	Stack []uintptr
+}
+
+// Number of stack words stored in a crash record.
+const crashRecordStackSize = 16
+
+// Magic value to detect whether the crash record contains valid data.
+const crashRecordMagic = 0x63727368 // "crsh"
+
+// storedCrashRecord is the crash record as stored in RAM that survives a warm
+// reset. It must not contain pointers, as it is not scanned by the GC.
+type storedCrashRecord struct {
+	magic     uint32
+	cfsr      uint32
+	hfsr      uint32
+	mmfar     uint32
+	bfar      uint32
+	pc        uintptr
+	lr        uintptr
+	sp        uintptr
+	psr       uintptr
+	goroutine uintptr
+	stackLen  uintptr
+	stack     [crashRecordStackSize]uintptr
+	checksum  uintptr
+}
+
+//go:section .noinit
+var crashRecordStorage storedCrashRecord
+
+// LastCrash returns the HardFault that caused the last reset, when the program
+// was compiled with -panic=reset. The record is kept across resets until it is
+// cleared using ClearLastCrash.
+func LastCrash() (record CrashRecord, ok bool) {
+	stored := &crashRecordStorage
+	if stored.magic != crashRecordMagic || stored.stackLen > crashRecordStackSize || stored.checksum != stored.computeChecksum() {
+		return CrashRecord{}, false
+	}
+	stack := make([]uintptr, stored.stackLen)
+	copy(stack, stored.stack[:])
+	return CrashRecord{
+		CFSR:      stored.cfsr,
+		HFSR:      stored.hfsr,
+		MMFAR:     stored.mmfar,
+		BFAR:      stored.bfar,
+		PC:        stored.pc,
+		LR:        stored.lr,
+		SP:        stored.sp,
+		PSR:       stored.psr,
+		Goroutine: stored.goroutine,
+		Stack:     stack,
+	}, true
+}
+
+// ClearLastCrash clears the crash record returned by LastCrash.
+func ClearLastCrash() {
+	crashRecordStorage = storedCrashRecord{}
+}
+
+// Print prints the crash record as a single line, in a format that is
+// recognized by `tinygo monitor`.
+func (r *CrashRecord) Print() {
+	printCrashRecord(r.CFSR, r.HFSR, r.MMFAR, r.BFAR, r.PC, r.LR, r.SP, r.PSR, r.Goroutine, r.Stack)
+}
+
+func printCrashRecord(cfsr, hfsr, mmfar, bfar uint32, pc, lr, sp, psr, goroutine uintptr, stack []uintptr) {
+	printstring("crash record:")
+	printstring(" pc=")
+	printptr(pc)
+	printstring(" lr=")
+	printptr(lr)
+	printstring(" sp=")
+	printptr(sp)
+	printstring(" psr=")
+	printptr(psr)
+	printstring(" cfsr=")
+	printptr(uintptr(cfsr))
+	printstring(" hfsr=")
+	printptr(uintptr(hfsr))
+	printstring(" mmfar=")
+	printptr(uintptr(mmfar))
+	printstring(" bfar=")
+	printptr(uintptr(bfar))
+	printstring(" goroutine=")
+	printptr(goroutine)
+	printstring(" stack=")
+	for i, word := range stack {
+		if i != 0 {
+			putchar(',')
+		}
+		printptr(word)
+	}
+	printnl()
+}
+
+// computeChecksum returns a simple checksum over the crash record, to check
+// whether the record is valid after a reset.
+func (r *storedCrashRecord) computeChecksum() uintptr {
+	checksum := ^uintptr(r.magic)
+	for _, word := range [...]uintptr{uintptr(r.cfsr), uintptr(r.hfsr), uintptr(r.mmfar), uintptr(r.bfar), r.pc, r.lr, r.sp, r.psr, r.goroutine, r.stackLen} {
+		checksum = checksum*31 + word
+	}
+	for _, word := range r.stack[:r.stackLen] {
+		checksum = checksum*31 + word
+	}
+	return checksum
+}
+
+// recordCrash is called at the start of the HardFault handler. With
+// -panic=reset it stores a crash record, otherwise it does nothing. It must be
+// called before anything else in the handler: the handler runs on a fresh stack
+// at the top of the system stack, so the print calls in the handler would
+// overwrite the stack contents that are stored in the record when the fault
+// happened on the system stack. Goroutine stacks are on the heap and aren't
+// affected.
+//
+// The stack pointer is only accessed when spValid is set and it points into
+// RAM.
+func recordCrash(sp *interruptStack, spValid bool) {
+	if panicStrategy() != panicStrategyReset {
+		return
+	}
+
+	stored := &crashRecordStorage
+	*stored = storedCrashRecord{}
+	stored.cfsr, stored.hfsr, stored.mmfar, stored.bfar = readFaultRegisters()
+	stored.sp = uintptr(unsafe.Pointer(sp))
+	stored.goroutine = uintptr(unsafe.Pointer(task.Current()))
+	if spValid && uintptr(unsafe.Pointer(sp)) >= 0x20000000 {
+		// The same check as in the HardFault handler: the stack pointer may
+		// not point into RAM after a stack overflow.
+		stored.pc = sp.PC
+		stored.lr = sp.LR
+		stored.psr = sp.PSR
+		stack := uintptr(unsafe.Pointer(sp)) + unsafe.Sizeof(*sp)
+		stackEnd := heapEnd // goroutine stacks are allocated on the heap
+		if stack < stackTop {
+			stackEnd = stackTop // system stack
+		}
+		for stored.stackLen < crashRecordStackSize && stack < stackEnd {
+			stored.stack[stored.stackLen] = *(*uintptr)(unsafe.Pointer(stack))
+			stored.stackLen++
+			stack += unsafe.Sizeof(uintptr(0))
+		}
+	}
+	stored.magic = crashRecordMagic
+	stored.checksum = stored.computeChecksum()
+}
+
+// handleCrashRecord is called at the end of the HardFault handler. With
+// -panic=reset it prints the crash record stored by recordCrash and resets the
+// chip. Otherwise it does nothing and the HardFault handler continues as
+// usual.
+func handleCrashRecord() {
+	if panicStrategy() != panicStrategyReset {
+		return
+	}
+	stored := &crashRecordStorage
+	printCrashRecord(stored.cfsr, stored.hfsr, stored.mmfar, stored.bfar, stored.pc, stored.lr, stored.sp, stored.psr, stored.goroutine, stored.stack[:stored.stackLen])
+	resetAfterPanic()
+}
@@ -19,6 +19,7 @@ import (
 //
 //export handleHardFault
 func handleHardFault(sp *interruptStack) {
+	recordCrash(sp, true)
 	print("fatal error: ")
 	if uintptr(unsafe.Pointer(sp)) < 0x20000000 {
 		print("stack overflow")
@@ -36,5 +37,12 @@ func handleHardFault(sp *interruptStack) {
 		print(" pc=", sp.PC)
 	}
 	println()
+	handleCrashRecord()
 	abort()
 }
+
+// readFaultRegisters returns the fault status and address registers, for the
+// crash record. Cortex-M0 doesn't have these registers so they're all zero.
+func readFaultRegisters() (cfsr, hfsr, mmfar, bfar uint32) {
+	return 0, 0, 0, 0
+}
@@ -22,6 +22,7 @@ const (
 func handleHardFault(sp *interruptStack) {
 	fault := GetFaultStatus()
 	spValid := !fault.Bus().ImpreciseDataBusError()
+	recordCrash(sp, spValid)
 
 	print("fatal error: ")
 	if spValid && uintptr(unsafe.Pointer(sp)) < 0x20000000 {
@@ -102,9 +103,23 @@ func handleHardFault(sp *interruptStack) {
 		}
 	}
 	println()
+	handleCrashRecord()
 	abort()
 }
 
+// readFaultRegisters returns the fault status and address registers, for the
+// crash record. The address registers are zero if they are not valid.
+func readFaultRegisters() (cfsr, hfsr, mmfar, bfar uint32) {
+	fault := GetFaultStatus()
+	if addr, ok := fault.Mem().Address(); ok {
+		mmfar = uint32(addr)
+	}
+	if addr, ok := fault.Bus().Address(); ok {
+		bfar = uint32(addr)
+	}
+	return uint32(fault), arm.SCB.HFSR.Get(), mmfar, bfar
+}
+
 // Descriptions are sourced from the K66 SVD and
 // http://infocenter.arm.com/help/index.jsp?topic=/com.arm.doc.dui0552a/Cihcfefj.html
 
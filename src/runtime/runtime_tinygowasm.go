@@ -1,8 +1,9 @@
-//go:build tinygo.wasm
+//go:build tinygo.wasm && !wasip2
 
 package runtime
 
 import (
+	"runtime/interrupt"
 	"unsafe"
 )
 
@@ -37,6 +38,8 @@ var (
 )
 
 func putchar(c byte) {
+	// Protect the buffer when running on multiple threads.
+	mask := interrupt.Disable()
 	putcharBuffer[putcharPosition] = c
 	putcharPosition++
 
@@ -45,6 +48,7 @@ func putchar(c byte) {
 		fd_write(stdout, &putcharIOVec, 1, &putcharNWritten)
 		putcharPosition = 0
 	}
+	interrupt.Restore(mask)
 }
 
 func getchar() byte {
@@ -72,6 +76,8 @@ func abort() {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	coverageFlush()
+	pgoWriteProfile()
 	proc_exit(uint32(code))
 }
 
@@ -0,0 +1,119 @@
+//go:build wasip2
+
+package runtime
+
+import (
+	"internal/cm"
+	"internal/wasi/cli/exit"
+	"internal/wasi/cli/stdin"
+	"internal/wasi/cli/stdout"
+	"internal/wasi/clocks/wallclock"
+	"internal/wasi/io/streams"
+)
+
+const (
+	putcharBufferSize = 120
+	getcharBufferSize = 64
+)
+
+// Using global variables to avoid heap allocation.
+var (
+	putcharBuffer        = [putcharBufferSize]byte{}
+	putcharPosition uint = 0
+	putcharStdout   streams.OutputStream
+
+	getcharBuffer   = [getcharBufferSize]byte{}
+	getcharPosition int
+	getcharLength   int
+	getcharStdin    streams.InputStream
+)
+
+func putchar(c byte) {
+	putcharBuffer[putcharPosition] = c
+	putcharPosition++
+
+	if c == '\n' || putcharPosition >= putcharBufferSize {
+		if putcharStdout == 0 {
+			putcharStdout = stdout.GetStdout()
+		}
+		putcharStdout.BlockingWriteAndFlush(putcharBuffer[:putcharPosition])
+		putcharPosition = 0
+	}
+}
+
+// getchar returns the next byte from stdin, blocking until one is available.
+// It returns 0 once stdin is closed or cannot be read.
+func getchar() byte {
+	if getcharPosition == getcharLength && !fillGetcharBuffer(true) {
+		return 0
+	}
+	c := getcharBuffer[getcharPosition]
+	getcharPosition++
+	return c
+}
+
+// buffered returns the number of bytes that can be read from stdin without
+// blocking.
+func buffered() int {
+	if getcharPosition == getcharLength {
+		fillGetcharBuffer(false)
+	}
+	return getcharLength - getcharPosition
+}
+
+// fillGetcharBuffer reads the next chunk of stdin into the empty getchar
+// buffer. It returns false if no bytes were read.
+func fillGetcharBuffer(blocking bool) bool {
+	if getcharStdin == 0 {
+		getcharStdin = stdin.GetStdin()
+	}
+	var result cm.Result[cm.List[uint8], cm.List[uint8], streams.StreamError]
+	if blocking {
+		result = getcharStdin.BlockingRead(getcharBufferSize)
+	} else {
+		result = getcharStdin.Read(getcharBufferSize)
+	}
+	if err := result.Err(); err != nil {
+		if ioErr := err.LastOperationFailed(); ioErr != nil {
+			ioErr.ResourceDrop()
+		}
+		return false
+	}
+	getcharPosition = 0
+	getcharLength = copy(getcharBuffer[:], result.OK().Lift())
+	return getcharLength != 0
+}
+
+//go:linkname now time.now
+func now() (sec int64, nsec int32, mono int64) {
+	now := wallclock.Now()
+	sec = int64(now.Seconds)
+	nsec = int32(now.Nanoseconds)
+	mono = nanotime()
+	return
+}
+
+// Abort executes the wasm 'unreachable' instruction.
+func abort() {
+	trap()
+}
+
+//go:linkname syscall_Exit syscall.Exit
+func syscall_Exit(code int) {
+	coverageFlush()
+	pgoWriteProfile()
+	// WASI 0.2 only supports exiting with success or failure, not with a
+	// specific exit code.
+	exit.Exit(code != 0)
+}
+
+// TinyGo does not yet support any form of parallelism on WebAssembly, so these
+// can be left empty.
+
+//go:linkname procPin sync/atomic.runtime_procPin
+func procPin() {
+}
+
+//go:linkname procUnpin sync/atomic.runtime_procUnpin
+func procUnpin() {
+}
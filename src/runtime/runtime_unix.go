@@ -1,4 +1,4 @@
-//go:build (darwin || (linux && !baremetal && !wasi)) && !nintendoswitch
+//go:build (darwin || (linux && !baremetal && !wasi && !wasip2)) && !nintendoswitch
 
 package runtime
 
@@ -64,26 +64,6 @@ type timespec struct {
 
 var stackTop uintptr
 
-// Entry point for Go. Initialize all packages and call main.main().
-//
-//export main
-func main(argc int32, argv *unsafe.Pointer) int {
-	preinit()
-
-	// Store argc and argv for later use.
-	main_argc = argc
-	main_argv = argv
-
-	// Obtain the initial stack pointer right before calling the run() function.
-	// The run function has been moved to a separate (non-inlined) function so
-	// that the correct stack pointer is read.
-	stackTop = getCurrentStackPointer()
-	runMain()
-
-	// For libc compatibility.
-	return 0
-}
-
 var (
 	main_argc int32
 	main_argv *unsafe.Pointer
@@ -112,13 +92,6 @@ func os_runtime_args() []string {
 	return args
 }
 
-// Must be a separate function to get the correct stack pointer.
-//
-//go:noinline
-func runMain() {
-	run()
-}
-
 //go:extern environ
 var environ *unsafe.Pointer
 
@@ -197,6 +170,8 @@ func now() (sec int64, nsec int32, mono int64) {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	coverageFlush()
+	pgoWriteProfile()
 	exit(code)
 }
 
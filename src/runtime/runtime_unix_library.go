@@ -0,0 +1,48 @@
+//go:build (darwin || (linux && !baremetal && !wasi && !wasip2)) && !nintendoswitch && tinygo.library
+
+package runtime
+
+import "sync/atomic"
+
+// Entry point for Go in the library build modes (-buildmode=c-archive and
+// -buildmode=c-shared). The builder registers this function as a constructor,
+// so that the heap and all packages are initialized when the library is
+// loaded. The host program doesn't pass command line arguments to a library, so
+// os.Args is empty.
+//
+//export tinygo_libraryInit
+func libraryInit() {
+	preinit()
+	initHeap()
+	libraryCall(initAll)
+}
+
+// libraryThread is the thread running the outermost call into Go (see
+// libraryCall), or 0 if there is none. It is also the lock that serializes
+// calls from different threads of the host program.
+var libraryThread uintptr
+
+//export pthread_self
+func pthread_self() uintptr
+
+//export sched_yield
+func sched_yield() int32
+
+// libraryOwner returns whether the current thread is running a call into Go.
+func libraryOwner() bool {
+	return atomic.LoadUintptr(&libraryThread) == pthread_self()
+}
+
+// libraryLock waits until no other thread is running a call into Go, and
+// makes the current thread the owner of the runtime.
+func libraryLock() {
+	self := pthread_self()
+	for !atomic.CompareAndSwapUintptr(&libraryThread, 0, self) {
+		sched_yield()
+	}
+}
+
+// libraryUnlock lets other threads call into Go again.
+func libraryUnlock() {
+	atomic.StoreUintptr(&libraryThread, 0)
+}
@@ -0,0 +1,32 @@
+//go:build (darwin || (linux && !baremetal && !wasi && !wasip2)) && !nintendoswitch && !tinygo.library
+
+package runtime
+
+import "unsafe"
+
+// Entry point for Go. Initialize all packages and call main.main().
+//
+//export main
+func main(argc int32, argv *unsafe.Pointer) int {
+	preinit()
+
+	// Store argc and argv for later use.
+	main_argc = argc
+	main_argv = argv
+
+	// Obtain the initial stack pointer right before calling the run() function.
+	// The run function has been moved to a separate (non-inlined) function so
+	// that the correct stack pointer is read.
+	stackTop = getCurrentStackPointer()
+	runMain()
+
+	// For libc compatibility.
+	return 0
+}
+
+// Must be a separate function to get the correct stack pointer.
+//
+//go:noinline
+func runMain() {
+	run()
+}
@@ -0,0 +1,53 @@
+//go:build wasip2
+
+package runtime
+
+import (
+	"internal/wasi/cli/environment"
+	"internal/wasi/clocks/monotonicclock"
+	"unsafe"
+)
+
+type timeUnit int64
+
+//export wasi:cli/run@0.2.0#run
+func __wasi_cli_run_run() uint32 {
+	// These need to be initialized early so that the heap can be initialized.
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+
+	// Calls into //go:wasmexport functions while main runs are nested calls,
+	// see _start for WASI preview 1.
+	libraryDepth++
+	run()
+	libraryDepth--
+	return 0
+}
+
+var args []string
+
+//go:linkname os_runtime_args os.runtime_args
+func os_runtime_args() []string {
+	if args == nil {
+		args = environment.GetArguments()
+	}
+	return args
+}
+
+func ticksToNanoseconds(ticks timeUnit) int64 {
+	return int64(ticks)
+}
+
+func nanosecondsToTicks(ns int64) timeUnit {
+	return timeUnit(ns)
+}
+
+func sleepTicks(d timeUnit) {
+	p := monotonicclock.SubscribeDuration(monotonicclock.Duration(d))
+	p.Block()
+	p.ResourceDrop()
+}
+
+func ticks() timeUnit {
+	return timeUnit(monotonicclock.Now())
+}
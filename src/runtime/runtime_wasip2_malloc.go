@@ -0,0 +1,14 @@
+//go:build wasip2 && !custommalloc
+
+package runtime
+
+import "unsafe"
+
+// cabi_realloc is called by the host to allocate memory for values (like
+// strings and lists) that are returned from imported functions. They are
+// allocated the same way as with malloc, so they must be freed after use.
+//
+//export cabi_realloc
+func cabi_realloc(ptr unsafe.Pointer, oldSize, align, newSize uintptr) unsafe.Pointer {
+	return libc_realloc(ptr, newSize)
+}
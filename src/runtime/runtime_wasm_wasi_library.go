@@ -0,0 +1,20 @@
+//go:build tinygo.wasm && (wasi || wasip1) && tinygo.library
+
+package runtime
+
+import "unsafe"
+
+// Entry point of a WASI reactor module (-buildmode=c-shared). The host calls
+// _initialize once after instantiating the module, which initializes the heap
+// and all packages. After that, the host may call the exported functions as
+// often as it wants: the runtime (including other goroutines) stays alive
+// between calls.
+//
+//export _initialize
+func _initialize() {
+	// These need to be initialized early so that the heap can be initialized.
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+	initHeap()
+	libraryCall(initAll)
+}
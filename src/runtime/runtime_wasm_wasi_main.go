@@ -0,0 +1,20 @@
+//go:build tinygo.wasm && (wasi || wasip1) && !tinygo.library
+
+package runtime
+
+import "unsafe"
+
+//export _start
+func _start() {
+	// These need to be initialized early so that the heap can be initialized.
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+
+	// While main runs, calls into //go:wasmexport functions can only come
+	// from a host function called by Go, so they run on the calling
+	// goroutine. Once main has returned, the host may still call them: they
+	// then run in a new goroutine like in a reactor module.
+	libraryDepth++
+	run()
+	libraryDepth--
+}
@@ -177,6 +177,7 @@ func now() (sec int64, nsec int32, mono int64) {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	coverageFlush()
 	libc_exit(code)
 }
 
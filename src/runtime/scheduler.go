@@ -78,6 +78,7 @@ func Goexit() {
 // Add this task to the end of the run queue.
 func runqueuePushBack(t *task.Task) {
 	runqueue.Push(t)
+	notifyWorkers()
 }
 
 // Add this task to the sleep queue, assuming its state is set to sleeping.
@@ -90,6 +91,7 @@ func addSleepTask(t *task.Task, duration timeUnit) {
 	}
 	t.Data = uint64(duration)
 	now := ticks()
+	mask := interrupt.Disable()
 	if sleepQueue == nil {
 		scheduleLog("  -> sleep new queue")
 
@@ -114,6 +116,7 @@ func addSleepTask(t *task.Task, duration timeUnit) {
 	}
 	t.Next = *q
 	*q = t
+	interrupt.Restore(mask)
 }
 
 // addTimer adds the given timer node to the timer queue. It must not be in the
@@ -23,6 +23,24 @@ func run() {
 	go func() {
 		initAll()
 		callMain()
+		coverageFlush()
+		pgoWriteProfile()
+		schedulerDone = true
+	}()
+	if hasThreads {
+		schedulerThreads()
+		return
+	}
+	scheduler()
+}
+
+// runLibraryCall runs fn in a new goroutine and runs the scheduler until fn
+// has returned. It is used for calls into exported functions in the library
+// build modes.
+func runLibraryCall(fn func()) {
+	schedulerDone = false
+	go func() {
+		fn()
 		schedulerDone = true
 	}()
 	scheduler()
@@ -23,6 +23,14 @@ func run() {
 	initHeap()
 	initAll()
 	callMain()
+	coverageFlush()
+	pgoWriteProfile()
+}
+
+// runLibraryCall runs fn, which is a call into an exported function in the
+// library build modes. Without a scheduler, it runs directly.
+func runLibraryCall(fn func()) {
+	fn()
 }
 
 const hasScheduler = false
@@ -0,0 +1,30 @@
+//go:build !(scheduler.asyncify && wasm.threads)
+
+package runtime
+
+// Goroutines run on a single thread (if there is a scheduler at all). See
+// scheduler_threads.go for WebAssembly with threads.
+
+const hasThreads = false
+
+func gcLock() {}
+
+func gcUnlock() {}
+
+func stopTheWorld() {}
+
+func enterBlockingCall() {}
+
+func exitBlockingCall() {}
+
+func mainThreadStackPointer() uintptr {
+	return 0
+}
+
+func notifyWorkers() {}
+
+func gomaxprocs(n int) int {
+	return 1
+}
+
+func schedulerThreads() {}
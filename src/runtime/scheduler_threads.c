@@ -0,0 +1,46 @@
+//go:build scheduler.asyncify && wasm.threads
+
+// Thread support for WebAssembly with threads, see scheduler_threads.go.
+// Threads are created using pthread_create from the threads build of
+// wasi-libc, which uses the wasi-threads thread-spawn API.
+
+#include <pthread.h>
+#include <stdint.h>
+
+void __wasi_init_tp(void);
+void tinygo_worker_main(uint32_t index);
+
+// Initialize the thread pointer of the main thread. This is normally done by
+// the _start function of wasi-libc, which isn't used by TinyGo.
+void tinygo_init_threads(void) {
+    __wasi_init_tp();
+}
+
+static void *tinygo_worker_start(void *arg) {
+    tinygo_worker_main((uint32_t)(uintptr_t)arg);
+    return NULL;
+}
+
+// Start a new worker thread with the given index. It returns 0 on success, or
+// an error number.
+int tinygo_spawn_worker(uint32_t index, size_t stackSize) {
+    pthread_attr_t attr;
+    pthread_t thread;
+    pthread_attr_init(&attr);
+    pthread_attr_setstacksize(&attr, stackSize);
+    pthread_attr_setdetachstate(&attr, PTHREAD_CREATE_DETACHED);
+    int result = pthread_create(&thread, &attr, tinygo_worker_start, (void *)(uintptr_t)index);
+    pthread_attr_destroy(&attr);
+    return result;
+}
+
+// Wait until *addr is changed from cmp and tinygo_futex_wake is called, or
+// until the timeout (in nanoseconds, or -1 for no timeout) has passed.
+void tinygo_futex_wait(uint32_t *addr, uint32_t cmp, int64_t timeout) {
+    __builtin_wasm_memory_atomic_wait32((int *)addr, (int)cmp, timeout);
+}
+
+// Wake up at most count threads waiting on addr.
+void tinygo_futex_wake(uint32_t *addr, uint32_t count) {
+    __builtin_wasm_memory_atomic_notify((int *)addr, count);
+}
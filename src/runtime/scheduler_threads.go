@@ -0,0 +1,363 @@
+//go:build scheduler.asyncify && wasm.threads
+
+package runtime
+
+// This file implements goroutines on multiple threads for WebAssembly with
+// threads (-target=wasi-threads). Goroutines are still paused and resumed
+// using asyncify, but several worker threads take goroutines from the same
+// runqueue. Every thread is a separate WebAssembly instance (started with the
+// wasi-threads thread-spawn API) that shares linear memory with the others.
+// Asyncify keeps its state in WebAssembly globals, which are separate for each
+// instance, so a goroutine that paused on one thread can be resumed on another.
+//
+// All runtime state (the runqueue, timers, channels, the heap) is protected by
+// a single recursive lock, which is what interrupt.Disable takes on this
+// target.
+//
+// The garbage collector stops all other threads before marking the heap. A
+// thread counts as stopped while it holds or waits for the runtime lock, or
+// while it waits for new goroutines to run. Blocking calls made by the runtime
+// itself (such as waiting for a socket) stop the thread with
+// enterBlockingCall. The compiler also inserts a call to gcSafepoint at the
+// start of every loop iteration outside the runtime, so that a goroutine in a
+// loop that doesn't call into the runtime stops as well. A goroutine that is
+// blocked in a host call outside the runtime still delays every GC cycle until
+// the call returns.
+//
+// Threads are only supported on WASI hosts (-target=wasi-threads). Browsers
+// and Node.js (-target=wasm) are not supported: threads would have to be
+// started as Web Workers from JavaScript.
+
+import (
+	"internal/task"
+	"runtime/interrupt"
+	"sync/atomic"
+)
+
+const hasThreads = true
+
+// defaultWorkers is the number of threads that run goroutines, including the
+// main thread. It can be increased at runtime with runtime.GOMAXPROCS.
+const defaultWorkers = 4
+
+// workerStackSize is the size of the system stack of a worker thread. This
+// stack is only used by the scheduler, goroutines have their own stack.
+const workerStackSize = 64 * 1024
+
+var (
+	// runtimeLock is 0 when unlocked, 1 when locked, and 2 when locked while
+	// other threads may be waiting for it.
+	runtimeLock uint32
+
+	// runtimeLockOwner is the thread index plus one of the thread holding the
+	// runtime lock, or 0 if it isn't locked.
+	runtimeLockOwner uint32
+
+	// runtimeLockDepth is the number of nested lockRuntime calls of the
+	// thread holding the runtime lock.
+	runtimeLockDepth uint32
+
+	// runningThreads is the number of threads that are not stopped (see
+	// above). It starts at 1 for the main thread.
+	runningThreads uint32 = 1
+
+	// numWorkers is the number of worker threads including the main thread.
+	numWorkers uint32 = 1
+
+	// idleWorkers is the number of worker threads waiting for work.
+	idleWorkers uint32
+
+	// workGeneration is incremented every time a goroutine is added to the
+	// runqueue, which wakes up an idle worker.
+	workGeneration uint32
+
+	// stopRequested is 1 while a thread waits in stopTheWorld for the other
+	// threads to stop.
+	stopRequested uint32
+
+	// mainStackPointer is the stack pointer of the main thread while it is
+	// stopped on the system stack, so that another thread running the GC can
+	// scan it. It is 0 otherwise.
+	mainStackPointer uintptr
+)
+
+//export tinygo_thread_index
+func threadIndex() uint32
+
+//export tinygo_set_thread_index
+func setThreadIndex(index uint32)
+
+//export tinygo_futex_wait
+func futexWait(addr *uint32, cmp uint32, timeout int64)
+
+//export tinygo_futex_wake
+func futexWake(addr *uint32, count uint32)
+
+//export tinygo_init_threads
+func initThreads()
+
+//export tinygo_spawn_worker
+func spawnWorker(index uint32, stackSize uintptr) int32
+
+// lockRuntime takes the runtime lock. It is the implementation of
+// interrupt.Disable on this target, and can be nested.
+func lockRuntime() {
+	self := threadIndex() + 1
+	if atomic.LoadUint32(&runtimeLockOwner) == self {
+		runtimeLockDepth++
+		return
+	}
+	stopThread()
+	acquireRuntimeLock()
+	atomic.StoreUint32(&runtimeLockOwner, self)
+	runtimeLockDepth = 1
+}
+
+// unlockRuntime releases the runtime lock taken by the matching lockRuntime
+// call. It is the implementation of interrupt.Restore on this target.
+func unlockRuntime() {
+	runtimeLockDepth--
+	if runtimeLockDepth != 0 {
+		return
+	}
+	// Count this thread as running before releasing the lock, so that a GC
+	// cycle can't start without waiting for this thread.
+	startThread()
+	atomic.StoreUint32(&runtimeLockOwner, 0)
+	releaseRuntimeLock()
+}
+
+func acquireRuntimeLock() {
+	if atomic.CompareAndSwapUint32(&runtimeLock, 0, 1) {
+		return
+	}
+	for atomic.SwapUint32(&runtimeLock, 2) != 0 {
+		futexWait(&runtimeLock, 2, -1)
+	}
+}
+
+func releaseRuntimeLock() {
+	if atomic.SwapUint32(&runtimeLock, 0) == 2 {
+		futexWake(&runtimeLock, 1)
+	}
+}
+
+// stopThread marks the current thread as stopped, meaning that another thread
+// may run the GC until this thread calls startThread. The compiler treats
+// calls to this function like heap allocations: all pointers on the stack
+// have been stored in stack slots before the thread is stopped.
+//
+//go:noinline
+func stopThread() {
+	if threadIndex() == 0 && task.OnSystemStack() {
+		mainStackPointer = getCurrentStackPointer()
+	}
+	if atomic.AddUint32(&runningThreads, ^uint32(0)) == 0 {
+		// Wake up the thread that is waiting in stopTheWorld.
+		futexWake(&runningThreads, 1)
+	}
+}
+
+// startThread marks the current thread as running again. It must only be
+// called while holding the runtime lock, which means that no GC cycle is in
+// progress.
+func startThread() {
+	if threadIndex() == 0 {
+		mainStackPointer = 0
+	}
+	atomic.AddUint32(&runningThreads, 1)
+}
+
+// enterBlockingCall marks the current thread as stopped before a system call
+// that may block for a long time, so that it doesn't delay GC cycles. The
+// system call must not access the heap, and the runtime lock must not be held.
+func enterBlockingCall() {
+	stopThread()
+}
+
+// exitBlockingCall marks the current thread as running again after a blocking
+// system call. It waits until a GC cycle that is in progress has finished.
+func exitBlockingCall() {
+	acquireRuntimeLock()
+	startThread()
+	releaseRuntimeLock()
+}
+
+// stopTheWorld waits until all other threads are stopped. It must be called
+// with the runtime lock held, the other threads continue once it is released.
+func stopTheWorld() {
+	atomic.StoreUint32(&stopRequested, 1)
+	for {
+		n := atomic.LoadUint32(&runningThreads)
+		if n == 0 {
+			atomic.StoreUint32(&stopRequested, 0)
+			return
+		}
+		futexWait(&runningThreads, n, -1)
+	}
+}
+
+// gcSafepoint is called by the compiler at the start of every loop iteration.
+// If another thread is waiting in stopTheWorld, it stops the current thread
+// until the GC cycle has finished.
+func gcSafepoint() {
+	if atomic.LoadUint32(&stopRequested) == 0 {
+		return
+	}
+	if atomic.LoadUint32(&runtimeLockOwner) == threadIndex()+1 {
+		// This thread holds the runtime lock, so it already counts as
+		// stopped.
+		return
+	}
+	stopThread()
+	exitBlockingCall()
+}
+
+// mainThreadStackPointer returns the stack pointer of the system stack of the
+// main thread if it needs to be scanned by the GC, or 0 if it doesn't.
+func mainThreadStackPointer() uintptr {
+	if threadIndex() == 0 {
+		if task.OnSystemStack() {
+			return getCurrentStackPointer()
+		}
+		return 0
+	}
+	return mainStackPointer
+}
+
+// gcLock and gcUnlock protect the heap: they take the runtime lock.
+func gcLock() {
+	lockRuntime()
+}
+
+func gcUnlock() {
+	unlockRuntime()
+}
+
+// notifyWorkers wakes up an idle worker, after a goroutine was added to the
+// runqueue.
+func notifyWorkers() {
+	atomic.AddUint32(&workGeneration, 1)
+	if atomic.LoadUint32(&idleWorkers) != 0 {
+		futexWake(&workGeneration, 1)
+	}
+}
+
+// gomaxprocs starts new worker threads until there are n workers in total and
+// returns the previous number of workers. The number of workers can't be
+// decreased.
+func gomaxprocs(n int) int {
+	mask := interrupt.Disable()
+	prev := int(numWorkers)
+	if n > task.MaxThreads {
+		n = task.MaxThreads
+	}
+	for int(numWorkers) < n {
+		// The new thread is running until it stops in the scheduler.
+		atomic.AddUint32(&runningThreads, 1)
+		if spawnWorker(numWorkers, workerStackSize) != 0 {
+			atomic.AddUint32(&runningThreads, ^uint32(0))
+			break
+		}
+		numWorkers++
+	}
+	interrupt.Restore(mask)
+	return prev
+}
+
+// schedulerThreads starts the worker threads and runs the scheduler on the
+// main thread. It does not return: the process exits once the main goroutine
+// has returned.
+func schedulerThreads() {
+	initThreads()
+	gomaxprocs(defaultWorkers)
+	workerLoop()
+}
+
+//export tinygo_worker_main
+func workerMain(index uint32) {
+	setThreadIndex(index)
+	workerLoop()
+}
+
+// workerLoop is the scheduler of a single worker thread. It is similar to
+// scheduler(), except that it holds the runtime lock while looking at the
+// scheduler queues and waits for other workers to add goroutines to the
+// runqueue when there is nothing to do.
+func workerLoop() {
+	lockRuntime()
+	for !schedulerDone {
+		var now timeUnit
+		if sleepQueue != nil || timerQueue != nil {
+			now = ticks()
+		}
+
+		// Add a task that is done sleeping to the end of the runqueue.
+		if sleepQueue != nil && now-sleepQueueBaseTime >= timeUnit(sleepQueue.Data) {
+			t := sleepQueue
+			scheduleLogTask("  awake:", t)
+			sleepQueueBaseTime += timeUnit(t.Data)
+			sleepQueue = t.Next
+			t.Next = nil
+			runqueuePushBack(t)
+		}
+
+		// Check for an expired timer to trigger.
+		if timerQueue != nil && now >= timerQueue.whenTicks() {
+			scheduleLog("--- timer awoke")
+			tn := timerQueue
+			timerQueue = tn.next
+			tn.next = nil
+			tn.callback(tn)
+		}
+
+		t := runqueue.Pop()
+		if t == nil {
+			timeout := int64(-1)
+			if sleepQueue != nil || timerQueue != nil {
+				var timeLeft timeUnit
+				if sleepQueue != nil {
+					timeLeft = timeUnit(sleepQueue.Data) - (now - sleepQueueBaseTime)
+				}
+				if timerQueue != nil {
+					timeLeftForTimer := timerQueue.whenTicks() - now
+					if sleepQueue == nil || timeLeftForTimer < timeLeft {
+						timeLeft = timeLeftForTimer
+					}
+				}
+				timeout = ticksToNanoseconds(timeLeft)
+			} else if atomic.LoadUint32(&idleWorkers)+1 == numWorkers {
+				// All other workers are idle as well, and there are no
+				// sleeping goroutines or timers that could create more work.
+				waitForEvents()
+			}
+			waitForWork(timeout)
+			continue
+		}
+
+		// Run the given task, without holding the runtime lock.
+		unlockRuntime()
+		scheduleLogTask("  run:", t)
+		t.Resume()
+		lockRuntime()
+	}
+	unlockRuntime()
+
+	// The main goroutine has returned. Exit the process, which stops all
+	// other threads.
+	proc_exit(0)
+}
+
+// waitForWork waits until a goroutine is added to the runqueue, or until the
+// timeout (in nanoseconds, -1 for no timeout) has passed. It must be called
+// with the runtime lock held, and the thread stays stopped while waiting.
+func waitForWork(timeout int64) {
+	generation := atomic.LoadUint32(&workGeneration)
+	atomic.AddUint32(&idleWorkers, 1)
+	atomic.StoreUint32(&runtimeLockOwner, 0)
+	releaseRuntimeLock()
+	futexWait(&workGeneration, generation, timeout)
+	acquireRuntimeLock()
+	atomic.StoreUint32(&runtimeLockOwner, threadIndex()+1)
+	atomic.AddUint32(&idleWorkers, ^uint32(0))
+}
@@ -0,0 +1,103 @@
+//go:build go1.23
+
+package runtime
+
+// Timer support for Go 1.23 and above. Since Go 1.23, the runtime allocates
+// the time.Timer and time.Ticker structs itself.
+
+import "unsafe"
+
+type timer struct {
+	when   int64
+	period int64
+	f      func(any, uintptr, int64)
+	arg    any
+}
+
+// timeTimer is allocated by newTimer. The first two fields must match the
+// layout of time.Timer and time.Ticker.
+type timeTimer struct {
+	c    unsafe.Pointer // <-chan time.Time
+	init bool
+	timer
+
+	// isChan is the channel passed to newTimer, or nil if this is not a
+	// channel timer (for example in time.AfterFunc).
+	isChan *channel
+}
+
+//go:linkname newTimer time.newTimer
+func newTimer(when, period int64, f func(any, uintptr, int64), arg any, c unsafe.Pointer) *timeTimer {
+	tim := &timeTimer{
+		init: true,
+		timer: timer{
+			when:   when,
+			period: period,
+			f:      f,
+			arg:    arg,
+		},
+		isChan: (*channel)(c),
+	}
+	addTimer(&timerNode{
+		timer:    &tim.timer,
+		callback: timerCallback,
+	})
+	scheduleLog("adding timer")
+	return tim
+}
+
+// timerCallback is called when a timer expires. See time_go122.go for why this
+// is a callback.
+func timerCallback(tn *timerNode) {
+	// Run timer function (implemented in the time package), with the delay in
+	// nanoseconds since the timer should have fired. The seq parameter is not
+	// used in the time package so is left zero.
+	delay := ticksToNanoseconds(ticks()) - tn.timer.when
+	if delay < 0 {
+		delay = 0
+	}
+	tn.timer.f(tn.timer.arg, 0, delay)
+
+	// If this is a periodic timer (a ticker), re-add it to the queue.
+	if tn.timer.period != 0 {
+		tn.timer.when += tn.timer.period
+		addTimer(tn)
+	}
+}
+
+//go:linkname stopTimer time.stopTimer
+func stopTimer(tim *timeTimer) bool {
+	removed := removeTimer(&tim.timer)
+	if tim.drain() {
+		removed = true
+	}
+	return removed
+}
+
+//go:linkname resetTimer time.resetTimer
+func resetTimer(tim *timeTimer, when, period int64) bool {
+	removed := removeTimer(&tim.timer)
+	if tim.drain() {
+		removed = true
+	}
+	tim.when = when
+	tim.period = period
+	addTimer(&timerNode{
+		timer:    &tim.timer,
+		callback: timerCallback,
+	})
+	return removed
+}
+
+// drain removes a stale value from the channel of the timer, and returns
+// whether there was one. Since Go 1.23, no value sent before a call to Stop or
+// Reset can be received afterwards, and a value that wasn't received yet counts
+// as the timer not having fired.
+func (tim *timeTimer) drain() bool {
+	if tim.isChan == nil {
+		return false
+	}
+	var value [3]uint64 // large enough for a time.Time
+	received, _ := tim.isChan.tryRecv(unsafe.Pointer(&value))
+	return received
+}
@@ -40,21 +40,27 @@ func (c *Cond) trySignal() bool {
 }
 
 func (c *Cond) Signal() {
+	state := lock()
 	c.trySignal()
+	unlock(state)
 }
 
 func (c *Cond) Broadcast() {
 	// Signal everything.
+	state := lock()
 	for c.trySignal() {
 	}
+	unlock(state)
 }
 
 func (c *Cond) Wait() {
 	// Add an earlySignal frame to the stack so we can be signalled while unlocking.
+	state := lock()
 	early := earlySignal{
 		next: c.unlocking,
 	}
 	c.unlocking = &early
+	unlock(state)
 
 	// Temporarily unlock L.
 	c.L.Unlock()
@@ -63,7 +69,9 @@ func (c *Cond) Wait() {
 	defer c.L.Lock()
 
 	// If we were signaled while unlocking, immediately complete.
+	state = lock()
 	if early.signaled {
+		unlock(state)
 		return
 	}
 
@@ -80,5 +88,6 @@ func (c *Cond) Wait() {
 
 	// Wait for a signal.
 	c.blocked.Push(task.Current())
+	unlock(state)
 	task.Pause()
 }
@@ -0,0 +1,14 @@
+//go:build !(scheduler.asyncify && wasm.threads)
+
+package sync
+
+import "runtime/interrupt"
+
+// Goroutines only switch when they block and the primitives in this package
+// can't be used in interrupts, so their state doesn't need to be protected.
+
+func lock() interrupt.State {
+	return 0
+}
+
+func unlock(state interrupt.State) {}
@@ -0,0 +1,17 @@
+//go:build scheduler.asyncify && wasm.threads
+
+package sync
+
+import "runtime/interrupt"
+
+// With WebAssembly threads, goroutines run in parallel on multiple threads.
+// The state of the synchronization primitives in this package is protected by
+// the runtime lock, which interrupt.Disable takes on this target.
+
+func lock() interrupt.State {
+	return interrupt.Disable()
+}
+
+func unlock(state interrupt.State) {
+	interrupt.Restore(state)
+}
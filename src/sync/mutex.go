@@ -14,18 +14,23 @@ type Mutex struct {
 func scheduleTask(*task.Task)
 
 func (m *Mutex) Lock() {
+	state := lock()
 	if m.locked {
 		// Push self onto stack of blocked tasks, and wait to be resumed.
 		m.blocked.Push(task.Current())
+		unlock(state)
 		task.Pause()
 		return
 	}
 
 	m.locked = true
+	unlock(state)
 }
 
 func (m *Mutex) Unlock() {
+	state := lock()
 	if !m.locked {
+		unlock(state)
 		panic("sync: unlock of unlocked Mutex")
 	}
 
@@ -35,6 +40,7 @@ func (m *Mutex) Unlock() {
 	} else {
 		m.locked = false
 	}
+	unlock(state)
 }
 
 type RWMutex struct {
@@ -58,29 +64,35 @@ const (
 )
 
 func (rw *RWMutex) Lock() {
+	state := lock()
 	if rw.state == 0 {
 		// The mutex is completely unlocked.
 		// Lock without waiting.
 		rw.state = rwMutexStateWLocked
+		unlock(state)
 		return
 	}
 
 	// Wait for the lock to be released.
 	rw.waitingWriters.Push(task.Current())
+	unlock(state)
 	task.Pause()
 }
 
 func (rw *RWMutex) Unlock() {
+	state := lock()
 	switch rw.state {
 	case rwMutexStateWLocked:
 		// This is correct.
 
 	case rwMutexStateUnlocked:
 		// The mutex is already unlocked.
+		unlock(state)
 		panic("sync: unlock of unlocked RWMutex")
 
 	default:
 		// The mutex is read-locked instead of write-locked.
+		unlock(state)
 		panic("sync: write-unlock of read-locked RWMutex")
 	}
 
@@ -95,32 +107,40 @@ func (rw *RWMutex) Unlock() {
 		// Nothing is waiting for the lock.
 		rw.state = rwMutexStateUnlocked
 	}
+	unlock(state)
 }
 
 func (rw *RWMutex) RLock() {
+	state := lock()
 	if rw.state == rwMutexStateWLocked {
 		// Wait for the write lock to be released.
 		rw.waitingReaders.Push(task.Current())
+		unlock(state)
 		task.Pause()
 		return
 	}
 
 	if rw.state == rwMutexMaxReaders {
+		unlock(state)
 		panic("sync: too many readers on RWMutex")
 	}
 
 	// Increase the reader count.
 	rw.state++
+	unlock(state)
 }
 
 func (rw *RWMutex) RUnlock() {
+	state := lock()
 	switch rw.state {
 	case rwMutexStateUnlocked:
 		// The mutex is already unlocked.
+		unlock(state)
 		panic("sync: unlock of unlocked RWMutex")
 
 	case rwMutexStateWLocked:
 		// The mutex is write-locked instead of read-locked.
+		unlock(state)
 		panic("sync: read-unlock of write-locked RWMutex")
 	}
 
@@ -131,6 +151,7 @@ func (rw *RWMutex) RUnlock() {
 		// Try to unblock a writer.
 		rw.maybeUnblockWriter()
 	}
+	unlock(state)
 }
 
 func (rw *RWMutex) maybeUnblockReaders() bool {
@@ -8,11 +8,14 @@ type Pool struct {
 
 // Get returns an item in the pool, or the value of calling Pool.New() if there are no items.
 func (p *Pool) Get() interface{} {
+	state := lock()
 	if len(p.items) > 0 {
 		x := p.items[len(p.items)-1]
 		p.items = p.items[:len(p.items)-1]
+		unlock(state)
 		return x
 	}
+	unlock(state)
 	if p.New == nil {
 		return nil
 	}
@@ -21,5 +24,7 @@ func (p *Pool) Get() interface{} {
 
 // Put adds a value back into the pool.
 func (p *Pool) Put(x interface{}) {
+	state := lock()
 	p.items = append(p.items, x)
+	unlock(state)
 }
@@ -1,4 +1,4 @@
-//go:build !wasi && !wasip1 && !darwin
+//go:build !wasi && !wasip1 && !wasip2 && !darwin
 
 package syscall
 
@@ -0,0 +1,13 @@
+//go:build wasi || wasip1
+
+package syscall
+
+// errno is a thread-local variable in wasi-libc, but TinyGo doesn't support
+// threads on WebAssembly so it's just a global here.
+//
+//go:extern errno
+var libcErrno uintptr
+
+func getErrno() error {
+	return Errno(libcErrno)
+}
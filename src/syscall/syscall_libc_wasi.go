@@ -1,4 +1,4 @@
-//go:build wasi || wasip1
+//go:build wasi || wasip1 || wasip2
 
 package syscall
 
@@ -118,13 +118,6 @@ const (
 	PATH_MAX = 4096
 )
 
-//go:extern errno
-var libcErrno uintptr
-
-func getErrno() error {
-	return Errno(libcErrno)
-}
-
 func (e Errno) Is(target error) bool {
 	switch target.Error() {
 	case "permission denied":
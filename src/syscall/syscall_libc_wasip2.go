@@ -0,0 +1,813 @@
+//go:build wasip2
+
+// There is no libc for WASI Preview 2 (yet). Instead of writing a separate
+// syscall package, this file implements the libc functions used by the wasip1
+// version in Go, on top of the WASI 0.2 interfaces. This keeps the rest of the
+// syscall and os packages the same for wasip1 and wasip2.
+
+package syscall
+
+import (
+	"internal/wasi/cli/environment"
+	"internal/wasi/cli/stderr"
+	"internal/wasi/cli/stdin"
+	"internal/wasi/cli/stdout"
+	"internal/wasi/filesystem/preopens"
+	"internal/wasi/filesystem/types"
+	"internal/wasi/io/streams"
+	"unsafe"
+)
+
+// Like errno in wasi-libc, but a regular Go global.
+var libcErrno uintptr
+
+func getErrno() error {
+	return Errno(libcErrno)
+}
+
+// wasiFile is an open file descriptor: either a stdio stream or a filesystem
+// descriptor. Duplicated file descriptors share the same wasiFile, like they
+// share the same open file description on POSIX systems.
+type wasiFile struct {
+	d      types.Descriptor
+	in     streams.InputStream
+	out    streams.OutputStream
+	stream bool // stdin, stdout, or stderr
+	append bool
+	offset int64
+	refs   int
+}
+
+// A directory that was made available to the program (for example using the
+// --dir flag in wasmtime).
+type wasiPreopen struct {
+	d    types.Descriptor
+	path string
+}
+
+var (
+	wasiFiles    = make(map[int32]*wasiFile)
+	wasiNextFD   = int32(3)
+	wasiPreopens []wasiPreopen
+	wasiCWD      = "/"
+	wasiEnv      = make(map[string][]byte) // NUL terminated values, for getenv
+)
+
+func init() {
+	wasiFiles[Stdin] = &wasiFile{in: stdin.GetStdin(), stream: true, refs: 1}
+	wasiFiles[Stdout] = &wasiFile{out: stdout.GetStdout(), stream: true, refs: 1}
+	wasiFiles[Stderr] = &wasiFile{out: stderr.GetStderr(), stream: true, refs: 1}
+
+	for _, dir := range preopens.GetDirectories() {
+		path := dir.Path
+		if path == "." || path == "" {
+			path = "/"
+		}
+		wasiPreopens = append(wasiPreopens, wasiPreopen{dir.Descriptor, cleanPath(path)})
+	}
+	if cwd, ok := environment.InitialCWD(); ok {
+		wasiCWD = cleanPath(cwd)
+	}
+	for _, kv := range environment.GetEnvironment() {
+		wasiEnv[kv[0]] = cstring(kv[1])
+	}
+}
+
+// cleanPath returns an absolute path without ".", ".." and duplicate slashes.
+func cleanPath(path string) string {
+	if len(path) == 0 || path[0] != '/' {
+		path = wasiCWD + "/" + path
+	}
+	var parts []string
+	for len(path) != 0 {
+		i := 0
+		for i < len(path) && path[i] != '/' {
+			i++
+		}
+		switch part := path[:i]; part {
+		case "", ".":
+		case "..":
+			if len(parts) != 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, part)
+		}
+		if i == len(path) {
+			break
+		}
+		path = path[i+1:]
+	}
+	clean := ""
+	for _, part := range parts {
+		clean += "/" + part
+	}
+	if clean == "" {
+		return "/"
+	}
+	return clean
+}
+
+// resolvePath returns the preopened directory that contains the given path,
+// and the path relative to that directory.
+func resolvePath(path string) (types.Descriptor, string, Errno) {
+	path = cleanPath(path)
+	best := -1
+	for i, dir := range wasiPreopens {
+		if !hasPathPrefix(path, dir.path) {
+			continue
+		}
+		if best < 0 || len(dir.path) > len(wasiPreopens[best].path) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, "", ENOENT
+	}
+	rel := path[len(wasiPreopens[best].path):]
+	for len(rel) != 0 && rel[0] == '/' {
+		rel = rel[1:]
+	}
+	if rel == "" {
+		rel = "."
+	}
+	return wasiPreopens[best].d, rel, 0
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return len(path) >= len(prefix) && path[:len(prefix)] == prefix && (len(path) == len(prefix) || path[len(prefix)] == '/')
+}
+
+// goString returns a copy of the given C string.
+func goString(s *byte) string {
+	length := libc_strlen(unsafe.Pointer(s))
+	return string(unsafe.Slice(s, length))
+}
+
+// fail sets errno and returns -1, like most libc functions do on failure.
+func fail(err Errno) int32 {
+	libcErrno = uintptr(err)
+	return -1
+}
+
+var errorCodeErrnos = [...]Errno{
+	types.ErrorCodeAccess:              EACCES,
+	types.ErrorCodeWouldBlock:          EAGAIN,
+	types.ErrorCodeAlready:             EALREADY,
+	types.ErrorCodeBadDescriptor:       EBADF,
+	types.ErrorCodeBusy:                EBUSY,
+	types.ErrorCodeDeadlock:            EDEADLK,
+	types.ErrorCodeQuota:               EDQUOT,
+	types.ErrorCodeExist:               EEXIST,
+	types.ErrorCodeFileTooLarge:        EFBIG,
+	types.ErrorCodeIllegalByteSequence: EILSEQ,
+	types.ErrorCodeInProgress:          EINPROGRESS,
+	types.ErrorCodeInterrupted:         EINTR,
+	types.ErrorCodeInvalid:             EINVAL,
+	types.ErrorCodeIO:                  EIO,
+	types.ErrorCodeIsDirectory:         EISDIR,
+	types.ErrorCodeLoop:                ELOOP,
+	types.ErrorCodeTooManyLinks:        EMLINK,
+	types.ErrorCodeMessageSize:         EMSGSIZE,
+	types.ErrorCodeNameTooLong:         ENAMETOOLONG,
+	types.ErrorCodeNoDevice:            ENODEV,
+	types.ErrorCodeNoEntry:             ENOENT,
+	types.ErrorCodeNoLock:              ENOLCK,
+	types.ErrorCodeInsufficientMemory:  ENOMEM,
+	types.ErrorCodeInsufficientSpace:   ENOSPC,
+	types.ErrorCodeNotDirectory:        ENOTDIR,
+	types.ErrorCodeNotEmpty:            ENOTEMPTY,
+	types.ErrorCodeNotRecoverable:      EIO,
+	types.ErrorCodeUnsupported:         ENOTSUP,
+	types.ErrorCodeNoTTY:               ENOTTY,
+	types.ErrorCodeNoSuchDevice:        ENXIO,
+	types.ErrorCodeOverflow:            EOVERFLOW,
+	types.ErrorCodeNotPermitted:        EPERM,
+	types.ErrorCodePipe:                EPIPE,
+	types.ErrorCodeReadOnly:            EROFS,
+	types.ErrorCodeInvalidSeek:         ESPIPE,
+	types.ErrorCodeTextFileBusy:        EBUSY,
+	types.ErrorCodeCrossDevice:         EXDEV,
+}
+
+// errorCodeErrno converts a filesystem error code to an errno value.
+func errorCodeErrno(code *types.ErrorCode) Errno {
+	if int(*code) < len(errorCodeErrnos) {
+		return errorCodeErrnos[*code]
+	}
+	return EIO
+}
+
+// streamErrno converts a stream error to an errno value.
+func streamErrno(err *streams.StreamError) Errno {
+	if ioErr := err.LastOperationFailed(); ioErr != nil {
+		errno := EIO
+		if code, ok := types.FilesystemErrorCode(*ioErr).Get(); ok {
+			errno = errorCodeErrno(&code)
+		}
+		ioErr.ResourceDrop()
+		return errno
+	}
+	return EPIPE
+}
+
+// ssize_t write(int fd, const void *buf, size_t count);
+//
+//export write
+func wasip2_write(fd int32, buf *byte, count uint) int {
+	f := wasiFiles[fd]
+	if f == nil {
+		return int(fail(EBADF))
+	}
+	if f.append {
+		stat := f.d.Stat()
+		if err := stat.Err(); err != nil {
+			return int(fail(errorCodeErrno(err)))
+		}
+		f.offset = int64(stat.OK().Size)
+	}
+	n := wasip2_pwrite(fd, buf, count, f.offset)
+	if n > 0 && !f.stream {
+		f.offset += int64(n)
+	}
+	return n
+}
+
+// ssize_t pwrite(int fd, const void *buf, size_t count, off_t offset);
+//
+//export pwrite
+func wasip2_pwrite(fd int32, buf *byte, count uint, offset int64) int {
+	f := wasiFiles[fd]
+	if f == nil {
+		return int(fail(EBADF))
+	}
+	p := unsafe.Slice(buf, count)
+	if f.stream {
+		if f.out == 0 {
+			return int(fail(EBADF))
+		}
+		// Streams accept at most 4096 bytes per blocking write.
+		for written := 0; written < len(p); {
+			chunk := p[written:]
+			if len(chunk) > 4096 {
+				chunk = chunk[:4096]
+			}
+			result := f.out.BlockingWriteAndFlush(chunk)
+			if err := result.Err(); err != nil {
+				return int(fail(streamErrno(err)))
+			}
+			written += len(chunk)
+		}
+		return len(p)
+	}
+	result := f.d.Write(p, types.FileSize(offset))
+	if err := result.Err(); err != nil {
+		return int(fail(errorCodeErrno(err)))
+	}
+	return int(*result.OK())
+}
+
+// ssize_t read(int fd, void *buf, size_t count);
+//
+//export read
+func wasip2_read(fd int32, buf *byte, count uint) int {
+	f := wasiFiles[fd]
+	if f == nil {
+		return int(fail(EBADF))
+	}
+	n := wasip2_pread(fd, buf, count, f.offset)
+	if n > 0 && !f.stream {
+		f.offset += int64(n)
+	}
+	return n
+}
+
+// ssize_t pread(int fd, void *buf, size_t count, off_t offset);
+//
+//export pread
+func wasip2_pread(fd int32, buf *byte, count uint, offset int64) int {
+	f := wasiFiles[fd]
+	if f == nil {
+		return int(fail(EBADF))
+	}
+	p := unsafe.Slice(buf, count)
+	if f.stream {
+		if f.in == 0 {
+			return int(fail(EBADF))
+		}
+		result := f.in.BlockingRead(uint64(count))
+		if err := result.Err(); err != nil {
+			if err.Closed() {
+				return 0 // EOF
+			}
+			return int(fail(streamErrno(err)))
+		}
+		return copy(p, result.OK().Lift())
+	}
+	result := f.d.Read(types.FileSize(count), types.FileSize(offset))
+	if err := result.Err(); err != nil {
+		return int(fail(errorCodeErrno(err)))
+	}
+	return copy(p, result.OK().F0.Lift())
+}
+
+// off_t lseek(int fd, off_t offset, int whence);
+//
+//export lseek
+func wasip2_lseek(fd int32, offset int64, whence int) int64 {
+	f := wasiFiles[fd]
+	if f == nil {
+		return int64(fail(EBADF))
+	}
+	if f.stream {
+		return int64(fail(ESPIPE))
+	}
+	switch whence {
+	case 0: // SEEK_SET
+	case 1: // SEEK_CUR
+		offset += f.offset
+	case 2: // SEEK_END
+		stat := f.d.Stat()
+		if err := stat.Err(); err != nil {
+			return int64(fail(errorCodeErrno(err)))
+		}
+		offset += int64(stat.OK().Size)
+	default:
+		return int64(fail(EINVAL))
+	}
+	if offset < 0 {
+		return int64(fail(EINVAL))
+	}
+	f.offset = offset
+	return offset
+}
+
+// int close(int fd);
+//
+//export close
+func wasip2_close(fd int32) int32 {
+	f := wasiFiles[fd]
+	if f == nil {
+		return fail(EBADF)
+	}
+	delete(wasiFiles, fd)
+	f.refs--
+	if f.refs == 0 && !f.stream {
+		f.d.ResourceDrop()
+	}
+	return 0
+}
+
+// int dup(int fd);
+//
+//export dup
+func wasip2_dup(fd int32) int32 {
+	f := wasiFiles[fd]
+	if f == nil {
+		return fail(EBADF)
+	}
+	f.refs++
+	newfd := wasiNextFD
+	wasiNextFD++
+	wasiFiles[newfd] = f
+	return newfd
+}
+
+// int open(const char *pathname, int flags, mode_t mode);
+//
+//export open
+func wasip2_open(pathname *byte, flags int32, mode uint32) int32 {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return fail(errno)
+	}
+
+	var openFlags types.OpenFlags
+	if flags&O_CREAT != 0 {
+		openFlags |= types.OpenFlagsCreate
+	}
+	if flags&O_DIRECTORY != 0 {
+		openFlags |= types.OpenFlagsDirectory
+	}
+	if flags&O_EXCL != 0 {
+		openFlags |= types.OpenFlagsExclusive
+	}
+	if flags&O_TRUNC != 0 {
+		openFlags |= types.OpenFlagsTruncate
+	}
+	var descriptorFlags types.DescriptorFlags
+	if flags&O_RDONLY != 0 {
+		descriptorFlags |= types.DescriptorFlagsRead
+	}
+	if flags&O_WRONLY != 0 {
+		descriptorFlags |= types.DescriptorFlagsWrite
+	}
+	if flags&O_SYNC != 0 {
+		descriptorFlags |= types.DescriptorFlagsFileIntegritySync
+	}
+	if flags&O_DSYNC != 0 {
+		descriptorFlags |= types.DescriptorFlagsDataIntegritySync
+	}
+
+	result := dir.OpenAt(types.PathFlagsSymlinkFollow, rel, openFlags, descriptorFlags)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	fd := wasiNextFD
+	wasiNextFD++
+	wasiFiles[fd] = &wasiFile{
+		d:      *result.OK(),
+		append: flags&O_APPEND != 0,
+		refs:   1,
+	}
+	return fd
+}
+
+// int fsync(int fd);
+//
+//export fsync
+func wasip2_fsync(fd int32) int32 {
+	f := wasiFiles[fd]
+	if f == nil {
+		return fail(EBADF)
+	}
+	if f.stream {
+		return fail(EINVAL)
+	}
+	result := f.d.Sync()
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// int chdir(const char *pathname);
+//
+//export chdir
+func wasip2_chdir(pathname *byte) int32 {
+	path := cleanPath(goString(pathname))
+	dir, rel, errno := resolvePath(path)
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.StatAt(types.PathFlagsSymlinkFollow, rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	if result.OK().Type != types.DescriptorTypeDirectory {
+		return fail(ENOTDIR)
+	}
+	wasiCWD = path
+	return 0
+}
+
+// char *getcwd(char *buf, size_t size)
+//
+//export getcwd
+func wasip2_getcwd(buf *byte, size uint) *byte {
+	if uint(len(wasiCWD)) >= size {
+		libcErrno = uintptr(ERANGE)
+		return nil
+	}
+	p := unsafe.Slice(buf, size)
+	n := copy(p, wasiCWD)
+	p[n] = 0
+	return buf
+}
+
+// int mkdir(const char *pathname, mode_t mode);
+//
+//export mkdir
+func wasip2_mkdir(pathname *byte, mode uint32) int32 {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.CreateDirectoryAt(rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// int rmdir(const char *pathname);
+//
+//export rmdir
+func wasip2_rmdir(pathname *byte) int32 {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.RemoveDirectoryAt(rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// int unlink(const char *pathname);
+//
+//export unlink
+func wasip2_unlink(pathname *byte) int32 {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.UnlinkFileAt(rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// int rename(const char *from, *to);
+//
+//export rename
+func wasip2_rename(from, to *byte) int32 {
+	fromDir, fromRel, errno := resolvePath(goString(from))
+	if errno != 0 {
+		return fail(errno)
+	}
+	toDir, toRel, errno := resolvePath(goString(to))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := fromDir.RenameAt(fromRel, toDir, toRel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// int symlink(const char *from, *to);
+//
+//export symlink
+func wasip2_symlink(from, to *byte) int32 {
+	dir, rel, errno := resolvePath(goString(to))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.SymlinkAt(goString(from), rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	return 0
+}
+
+// ssize_t readlink(const char *path, void *buf, size_t count);
+//
+//export readlink
+func wasip2_readlink(pathname *byte, buf *byte, count uint) int {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return int(fail(errno))
+	}
+	result := dir.ReadlinkAt(rel)
+	if err := result.Err(); err != nil {
+		return int(fail(errorCodeErrno(err)))
+	}
+	return copy(unsafe.Slice(buf, count), result.OK().Lift())
+}
+
+// int chmod(const char *pathname, mode_t mode);
+//
+//export chmod
+func wasip2_chmod(pathname *byte, mode uint32) int32 {
+	// There are no file permissions in WASI.
+	var stat Stat_t
+	return wasip2_stat(pathname, unsafe.Pointer(&stat))
+}
+
+// int stat(const char *path, struct stat * buf);
+//
+//export stat
+func wasip2_stat(pathname *byte, ptr unsafe.Pointer) int32 {
+	return statAt(pathname, types.PathFlagsSymlinkFollow, (*Stat_t)(ptr))
+}
+
+// int lstat(const char *path, struct stat * buf);
+//
+//export lstat
+func wasip2_lstat(pathname *byte, ptr unsafe.Pointer) int32 {
+	return statAt(pathname, 0, (*Stat_t)(ptr))
+}
+
+func statAt(pathname *byte, pathFlags types.PathFlags, st *Stat_t) int32 {
+	dir, rel, errno := resolvePath(goString(pathname))
+	if errno != 0 {
+		return fail(errno)
+	}
+	result := dir.StatAt(pathFlags, rel)
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	setStat(st, result.OK())
+	if hash := dir.MetadataHashAt(pathFlags, rel); !hash.IsErr() {
+		st.Ino = hash.OK().Lower
+	}
+	return 0
+}
+
+// int fstat(int fd, struct stat * buf);
+//
+//export fstat
+func wasip2_fstat(fd int32, ptr unsafe.Pointer) int32 {
+	f := wasiFiles[fd]
+	if f == nil {
+		return fail(EBADF)
+	}
+	st := (*Stat_t)(ptr)
+	if f.stream {
+		*st = Stat_t{Mode: S_IFCHR}
+		return 0
+	}
+	result := f.d.Stat()
+	if err := result.Err(); err != nil {
+		return fail(errorCodeErrno(err))
+	}
+	setStat(st, result.OK())
+	if hash := f.d.MetadataHash(); !hash.IsErr() {
+		st.Ino = hash.OK().Lower
+	}
+	return 0
+}
+
+func setStat(st *Stat_t, stat *types.DescriptorStat) {
+	*st = Stat_t{
+		Nlink: stat.LinkCount,
+		Size:  int64(stat.Size),
+	}
+	switch stat.Type {
+	case types.DescriptorTypeBlockDevice:
+		st.Mode = S_IFBLK
+	case types.DescriptorTypeCharacterDevice:
+		st.Mode = S_IFCHR
+	case types.DescriptorTypeDirectory:
+		st.Mode = S_IFDIR
+	case types.DescriptorTypeFIFO:
+		st.Mode = S_IFIFO
+	case types.DescriptorTypeSymbolicLink:
+		st.Mode = S_IFLNK
+	case types.DescriptorTypeRegularFile:
+		st.Mode = S_IFREG
+	case types.DescriptorTypeSocket:
+		st.Mode = S_IFSOCK
+	}
+	if t, ok := stat.DataAccessTimestamp.Get(); ok {
+		st.Atim = Timespec{Sec: int32(t.Seconds), Nsec: int64(t.Nanoseconds)}
+	}
+	if t, ok := stat.DataModificationTimestamp.Get(); ok {
+		st.Mtim = Timespec{Sec: int32(t.Seconds), Nsec: int64(t.Nanoseconds)}
+	}
+	if t, ok := stat.StatusChangeTimestamp.Get(); ok {
+		st.Ctim = Timespec{Sec: int32(t.Seconds), Nsec: int64(t.Nanoseconds)}
+	}
+}
+
+// wasiDir is the DIR* returned by fdopendir.
+type wasiDir struct {
+	d      types.Descriptor
+	stream types.DirectoryEntryStream
+	dirent []byte // the last entry returned by readdir
+}
+
+// Keep directories alive while they're only referenced as uintptr.
+var wasiDirs = make(map[*wasiDir]struct{})
+
+// DIR *fdopendir(int);
+//
+//export fdopendir
+func wasip2_fdopendir(fd int32) unsafe.Pointer {
+	f := wasiFiles[fd]
+	if f == nil || f.stream {
+		libcErrno = uintptr(EBADF)
+		return nil
+	}
+	result := f.d.ReadDirectory()
+	if err := result.Err(); err != nil {
+		libcErrno = uintptr(errorCodeErrno(err))
+		return nil
+	}
+	dir := &wasiDir{d: f.d, stream: *result.OK()}
+	wasiDirs[dir] = struct{}{}
+	return unsafe.Pointer(dir)
+}
+
+// int fdclosedir(DIR *);
+//
+//export fdclosedir
+func wasip2_fdclosedir(ptr unsafe.Pointer) int32 {
+	dir := (*wasiDir)(ptr)
+	if _, ok := wasiDirs[dir]; !ok {
+		return fail(EBADF)
+	}
+	delete(wasiDirs, dir)
+	dir.stream.ResourceDrop()
+	return 0
+}
+
+// struct dirent *readdir(DIR *);
+//
+//export readdir
+func wasip2_readdir(ptr unsafe.Pointer) *Dirent {
+	dir := (*wasiDir)(ptr)
+	result := dir.stream.ReadDirectoryEntry()
+	if err := result.Err(); err != nil {
+		libcErrno = uintptr(errorCodeErrno(err))
+		return nil
+	}
+	entry, ok := result.OK().Get()
+	if !ok {
+		return nil // end of directory
+	}
+	name := entry.Name.Lift()
+
+	// struct dirent is followed by the NUL terminated name, see Dirent.Name.
+	dir.dirent = make([]byte, 9+len(name)+1)
+	dirent := (*Dirent)(unsafe.Pointer(&dir.dirent[0]))
+	copy(dir.dirent[9:], name)
+	if hash := dir.d.MetadataHashAt(0, name); !hash.IsErr() {
+		dirent.Ino = hash.OK().Lower
+	}
+	switch entry.Type {
+	case types.DescriptorTypeBlockDevice:
+		dirent.Type = DT_BLK
+	case types.DescriptorTypeCharacterDevice:
+		dirent.Type = DT_CHR
+	case types.DescriptorTypeDirectory:
+		dirent.Type = DT_DIR
+	case types.DescriptorTypeFIFO:
+		dirent.Type = DT_FIFO
+	case types.DescriptorTypeSymbolicLink:
+		dirent.Type = DT_LNK
+	case types.DescriptorTypeRegularFile:
+		dirent.Type = DT_REG
+	default:
+		dirent.Type = DT_UNKNOWN
+	}
+	return dirent
+}
+
+// void *mmap(void *addr, size_t length, int prot, int flags, int fd, off_t offset);
+//
+//export mmap
+func wasip2_mmap(addr unsafe.Pointer, length uintptr, prot, flags, fd int32, offset uintptr) unsafe.Pointer {
+	libcErrno = uintptr(ENOSYS)
+	return unsafe.Pointer(^uintptr(0))
+}
+
+// int munmap(void *addr, size_t length);
+//
+//export munmap
+func wasip2_munmap(addr unsafe.Pointer, length uintptr) int32 {
+	return fail(ENOSYS)
+}
+
+// int mprotect(void *addr, size_t len, int prot);
+//
+//export mprotect
+func wasip2_mprotect(addr unsafe.Pointer, len uintptr, prot int32) int32 {
+	return fail(ENOSYS)
+}
+
+// char *getenv(const char *name);
+//
+//export getenv
+func wasip2_getenv(name *byte) *byte {
+	value, ok := wasiEnv[goString(name)]
+	if !ok {
+		return nil
+	}
+	return &value[0]
+}
+
+// int setenv(const char *name, const char *val, int replace);
+//
+//export setenv
+func wasip2_setenv(name, val *byte, replace int32) int32 {
+	key := goString(name)
+	if _, ok := wasiEnv[key]; ok && replace == 0 {
+		return 0
+	}
+	wasiEnv[key] = cstring(goString(val))
+	return 0
+}
+
+// int unsetenv(const char *name);
+//
+//export unsetenv
+func wasip2_unsetenv(name *byte) int32 {
+	delete(wasiEnv, goString(name))
+	return 0
+}
+
+func Environ() []string {
+	envs := make([]string, 0, len(wasiEnv))
+	for key, value := range wasiEnv {
+		envs = append(envs, key+"="+string(value[:len(value)-1]))
+	}
+	return envs
+}
@@ -1,12 +1,33 @@
 package testing
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// Fuzzing flags.
+var (
+	matchFuzz    *string
+	fuzzDuration benchTimeFlag
+	fuzzCacheDir *string
+)
+
+func initFuzzFlags() {
+	matchFuzz = flag.String("test.fuzz", "", "run the fuzz test matching `regexp`")
+	flag.Var(&fuzzDuration, "test.fuzztime", "time to spend fuzzing; default is to run indefinitely")
+	fuzzCacheDir = flag.String("test.fuzzcachedir", "", "directory where interesting fuzzing inputs are stored")
+}
+
 // InternalFuzzTarget is an internal type but exported because it is
 // cross-package; it is part of the implementation of the "go test" command.
 type InternalFuzzTarget struct {
@@ -62,6 +83,9 @@ type corpusEntry = struct {
 // a no-op if called after or within the fuzz target, and args must match the
 // arguments for the fuzz target.
 func (f *F) Add(args ...interface{}) {
+	if f.inFuzzFn || f.fuzzCalled {
+		return
+	}
 	var values []interface{}
 	for i := range args {
 		if t := reflect.TypeOf(args[i]); !supportedTypes[t] {
@@ -106,6 +130,9 @@ var supportedTypes = map[reflect.Type]bool{
 // float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64.
 // More types may be supported in the future.
 //
+// TinyGo doesn't support reflect.Value.Call, so ff may only have a single
+// argument after *T.
+//
 // ff must not call any *F methods, e.g. (*F).Log, (*F).Error, (*F).Skip. Use
 // the corresponding *T method instead. The only *F methods that are allowed in
 // the (*F).Fuzz function are (*F).Failed and (*F).Name.
@@ -120,11 +147,109 @@ var supportedTypes = map[reflect.Type]bool{
 // (set with -fuzztime), or the test process is interrupted by a signal. F.Fuzz
 // should be called exactly once, unless F.Skip or F.Fail is called beforehand.
 func (f *F) Fuzz(ff interface{}) {
-	f.failed = true
-	f.result.N = 0
-	f.result.T = 0
-	f.result.Error = errors.New("operation not implemented")
-	return
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+
+	fn, err := newFuzzFunc(ff)
+	if err != nil {
+		f.Error(err)
+		return
+	}
+	for _, entry := range f.corpus {
+		if err := fn.checkValues(entry.Values); err != nil {
+			f.Errorf("%s: %v", entry.Path, err)
+			return
+		}
+	}
+
+	// Add the seed corpus entries from testdata.
+	entries, err := readCorpusDir(filepath.Join("testdata", "fuzz", f.name), fn)
+	if err != nil {
+		f.Error(err)
+		return
+	}
+	corpus := append(f.corpus, entries...)
+
+	f.inFuzzFn = true
+	defer func() {
+		f.inFuzzFn = false
+	}()
+
+	if f.fuzzContext.mode == fuzzCoordinator {
+		start := time.Now()
+		f.fuzz(fn, corpus)
+		f.result.T = time.Since(start)
+		return
+	}
+
+	// Run every entry of the seed corpus as a subtest, like go test does when
+	// not fuzzing.
+	for _, entry := range corpus {
+		entry := entry
+		f.runSubtest(f.testContext, filepath.Base(entry.Path), func(t *T) {
+			fn.call(t, entry.Values[0])
+		})
+	}
+}
+
+// fuzzFunc is the function passed to F.Fuzz, with a single argument that is
+// passed as an interface.
+type fuzzFunc struct {
+	zero interface{} // zero value of the argument
+	call func(t *T, value interface{})
+}
+
+// newFuzzFunc converts the function passed to F.Fuzz to a fuzzFunc. As
+// reflect.Value.Call is not supported, this is done with a type switch over
+// all supported function types.
+func newFuzzFunc(ff interface{}) (fuzzFunc, error) {
+	switch ff := ff.(type) {
+	case func(*T, []byte):
+		return fuzzFunc{[]byte{}, func(t *T, v interface{}) { ff(t, v.([]byte)) }}, nil
+	case func(*T, string):
+		return fuzzFunc{"", func(t *T, v interface{}) { ff(t, v.(string)) }}, nil
+	case func(*T, bool):
+		return fuzzFunc{false, func(t *T, v interface{}) { ff(t, v.(bool)) }}, nil
+	case func(*T, byte):
+		return fuzzFunc{byte(0), func(t *T, v interface{}) { ff(t, v.(byte)) }}, nil
+	case func(*T, rune):
+		return fuzzFunc{rune(0), func(t *T, v interface{}) { ff(t, v.(rune)) }}, nil
+	case func(*T, float32):
+		return fuzzFunc{float32(0), func(t *T, v interface{}) { ff(t, v.(float32)) }}, nil
+	case func(*T, float64):
+		return fuzzFunc{float64(0), func(t *T, v interface{}) { ff(t, v.(float64)) }}, nil
+	case func(*T, int):
+		return fuzzFunc{int(0), func(t *T, v interface{}) { ff(t, v.(int)) }}, nil
+	case func(*T, int8):
+		return fuzzFunc{int8(0), func(t *T, v interface{}) { ff(t, v.(int8)) }}, nil
+	case func(*T, int16):
+		return fuzzFunc{int16(0), func(t *T, v interface{}) { ff(t, v.(int16)) }}, nil
+	case func(*T, int64):
+		return fuzzFunc{int64(0), func(t *T, v interface{}) { ff(t, v.(int64)) }}, nil
+	case func(*T, uint):
+		return fuzzFunc{uint(0), func(t *T, v interface{}) { ff(t, v.(uint)) }}, nil
+	case func(*T, uint16):
+		return fuzzFunc{uint16(0), func(t *T, v interface{}) { ff(t, v.(uint16)) }}, nil
+	case func(*T, uint32):
+		return fuzzFunc{uint32(0), func(t *T, v interface{}) { ff(t, v.(uint32)) }}, nil
+	case func(*T, uint64):
+		return fuzzFunc{uint64(0), func(t *T, v interface{}) { ff(t, v.(uint64)) }}, nil
+	}
+	return fuzzFunc{}, fmt.Errorf("testing: unsupported fuzz function type %T: TinyGo only supports a *testing.T argument followed by a single argument of a supported type", ff)
+}
+
+// checkValues checks whether the values of a corpus entry can be passed to the
+// fuzz function.
+func (fn fuzzFunc) checkValues(values []interface{}) error {
+	if len(values) != 1 {
+		return fmt.Errorf("wrong number of values in corpus entry: %d, want 1", len(values))
+	}
+	if got, want := reflect.TypeOf(values[0]), reflect.TypeOf(fn.zero); got != want {
+		return fmt.Errorf("mismatched types in corpus entry: %v, want %v", got, want)
+	}
+	return nil
 }
 
 // fuzzContext holds fields common to all fuzz tests.
@@ -135,9 +260,333 @@ type fuzzContext struct {
 
 type fuzzMode uint8
 
+const (
+	seedCorpusOnly  fuzzMode = iota // run the seed corpus as regular tests
+	fuzzCoordinator                 // generate new inputs (-test.fuzz)
+)
+
 // fuzzResult contains the results of a fuzz run.
 type fuzzResult struct {
 	N     int           // The number of iterations.
 	T     time.Duration // The total time taken.
 	Error error         // Error is the error from the failing input
 }
+
+// runFuzzTests runs the fuzz tests with only their seed corpus, as regular
+// tests.
+func runFuzzTests(deps testDeps, fuzzTargets []InternalFuzzTarget) (ran, ok bool) {
+	ok = true
+	if len(fuzzTargets) == 0 {
+		return false, true
+	}
+
+	ctx := newTestContext(newMatcher(deps.MatchString, flagRunRegexp, "-test.run", flagSkipRegexp))
+	fctx := &fuzzContext{deps: deps, mode: seedCorpusOnly}
+	root := &T{
+		common: common{
+			output: &logger{logToStdout: flagVerbose},
+		},
+		context: ctx,
+	}
+	for i := 0; i < flagCount; i++ {
+		tRunner(root, func(root *T) {
+			for _, target := range fuzzTargets {
+				ok = root.runFuzzTarget(ctx, fctx, target) && ok
+			}
+		})
+	}
+	return root.ran, ok
+}
+
+// runFuzzing runs the fuzz test matching -test.fuzz with generated inputs. It
+// returns false if the fuzz test failed.
+func runFuzzing(deps testDeps, fuzzTargets []InternalFuzzTarget) (ok bool) {
+	m := newMatcher(deps.MatchString, *matchFuzz, "-test.fuzz", flagSkipRegexp)
+	var matched []InternalFuzzTarget
+	var names []string
+	for _, target := range fuzzTargets {
+		if _, ok, _ := m.fullName(nil, target.Name); ok {
+			matched = append(matched, target)
+			names = append(names, target.Name)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Fprintln(os.Stderr, "testing: warning: no fuzz tests to fuzz")
+		return true
+	}
+	if len(matched) > 1 {
+		fmt.Fprintf(os.Stderr, "testing: will not fuzz, -fuzz matches more than one fuzz test: %v\n", names)
+		return false
+	}
+
+	ctx := newTestContext(m)
+	fctx := &fuzzContext{deps: deps, mode: fuzzCoordinator}
+	root := &T{
+		common: common{
+			output: &logger{logToStdout: flagVerbose},
+		},
+		context: ctx,
+	}
+	tRunner(root, func(root *T) {
+		ok = root.runFuzzTarget(ctx, fctx, matched[0])
+	})
+	return ok
+}
+
+// runFuzzTarget runs the given fuzz test as a subtest of c, and returns whether
+// it succeeded.
+func (c *common) runFuzzTarget(context *testContext, fctx *fuzzContext, target InternalFuzzTarget) bool {
+	c.hasSub = true
+	testName, ok, _ := context.match.fullName(c, target.Name)
+	if !ok {
+		return true
+	}
+
+	f := &F{
+		common: common{
+			output: &logger{logToStdout: flagVerbose},
+			name:   testName,
+			parent: c,
+			level:  c.level + 1,
+		},
+		fuzzContext: fctx,
+		testContext: context,
+	}
+	if c.level > 0 {
+		f.indent = f.indent + "    "
+	}
+	if flagVerbose {
+		fmt.Fprintf(c.output, "=== RUN   %s\n", f.name)
+	}
+
+	fRunner(f, target.Fn)
+	return !f.failed
+}
+
+func fRunner(f *F, fn func(f *F)) {
+	defer func() {
+		f.runCleanup()
+	}()
+
+	// Run the fuzz test.
+	f.start = time.Now()
+	fn(f)
+	f.duration += time.Since(f.start)
+
+	f.report() // Report after all seed corpus entries have run.
+	if !f.hasSub {
+		f.setRan()
+	}
+}
+
+// The corpus file format, shared with the go command: a version line followed
+// by one Go expression per value, like []byte("abc") or int(5).
+const corpusVersion1 = "go test fuzz v1"
+
+// readCorpusDir reads all corpus files in the given directory. It returns no
+// entries if the directory doesn't exist.
+func readCorpusDir(dir string, fn fuzzFunc) ([]corpusEntry, error) {
+	if isBaremetal || dir == "" {
+		// There is no file system.
+		return nil, nil
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var entries []corpusEntry
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values, err := unmarshalCorpusFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if err := fn.checkValues(values); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		entries = append(entries, corpusEntry{Path: path, Data: data, Values: values})
+	}
+	return entries, nil
+}
+
+// marshalCorpusFile encodes the given values in the corpus file format.
+func marshalCorpusFile(values ...interface{}) []byte {
+	b := bytes.NewBufferString(corpusVersion1 + "\n")
+	for _, value := range values {
+		switch v := value.(type) {
+		case int, int8, int16, int64, uint, uint16, uint32, uint64, bool:
+			fmt.Fprintf(b, "%T(%v)\n", v, v)
+		case float32:
+			if math.IsNaN(float64(v)) && math.Float32bits(v) != math.Float32bits(float32(math.NaN())) {
+				// Keep the exact bits of unusual NaN values.
+				fmt.Fprintf(b, "math.Float32frombits(0x%x)\n", math.Float32bits(v))
+			} else {
+				fmt.Fprintf(b, "float32(%v)\n", v)
+			}
+		case float64:
+			if math.IsNaN(v) && math.Float64bits(v) != math.Float64bits(math.NaN()) {
+				fmt.Fprintf(b, "math.Float64frombits(0x%x)\n", math.Float64bits(v))
+			} else {
+				fmt.Fprintf(b, "float64(%v)\n", v)
+			}
+		case string:
+			fmt.Fprintf(b, "string(%q)\n", v)
+		case rune:
+			// Not every int32 can be written as a rune literal.
+			if utf8.ValidRune(v) {
+				fmt.Fprintf(b, "rune(%q)\n", v)
+			} else {
+				fmt.Fprintf(b, "int32(%v)\n", v)
+			}
+		case byte:
+			fmt.Fprintf(b, "byte(%q)\n", v)
+		case []byte:
+			fmt.Fprintf(b, "[]byte(%q)\n", v)
+		default:
+			panic(fmt.Sprintf("testing: unsupported type in corpus entry: %T", v))
+		}
+	}
+	return b.Bytes()
+}
+
+// unmarshalCorpusFile decodes the values in a corpus file.
+func unmarshalCorpusFile(data []byte) ([]interface{}, error) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != corpusVersion1 {
+		return nil, fmt.Errorf("must include version and be in the format %q", corpusVersion1)
+	}
+	var values []interface{}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		value, err := parseCorpusValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %v", line, err)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil, errors.New("must include at least one value")
+	}
+	return values, nil
+}
+
+// parseCorpusValue parses a single value in a corpus file, which is a
+// conversion of a literal to one of the supported types.
+func parseCorpusValue(line string) (interface{}, error) {
+	open := strings.IndexByte(line, '(')
+	if open < 0 || !strings.HasSuffix(line, ")") {
+		return nil, errors.New("expected a type conversion")
+	}
+	typ := line[:open]
+	arg := strings.TrimSpace(line[open+1 : len(line)-1])
+	switch typ {
+	case "[]byte", "string":
+		s, err := strconv.Unquote(arg)
+		if err != nil {
+			return nil, err
+		}
+		if typ == "string" {
+			return s, nil
+		}
+		return []byte(s), nil
+	case "bool":
+		switch arg {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid bool: %s", arg)
+	case "byte", "uint8", "rune", "int32":
+		var n int64
+		if strings.HasPrefix(arg, "'") {
+			s, err := strconv.Unquote(arg)
+			if err != nil {
+				return nil, err
+			}
+			r, _ := utf8.DecodeRuneInString(s)
+			n = int64(r)
+		} else {
+			var err error
+			n, err = strconv.ParseInt(arg, 0, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if typ == "byte" || typ == "uint8" {
+			if n < 0 || n > math.MaxUint8 {
+				return nil, fmt.Errorf("%s out of range for byte", arg)
+			}
+			return byte(n), nil
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%s out of range for rune", arg)
+		}
+		return rune(n), nil
+	case "int", "int8", "int16", "int64":
+		bitSize := map[string]int{"int": strconv.IntSize, "int8": 8, "int16": 16, "int64": 64}[typ]
+		n, err := strconv.ParseInt(arg, 0, bitSize)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "int":
+			return int(n), nil
+		case "int8":
+			return int8(n), nil
+		case "int16":
+			return int16(n), nil
+		default:
+			return n, nil
+		}
+	case "uint", "uint16", "uint32", "uint64":
+		bitSize := map[string]int{"uint": strconv.IntSize, "uint16": 16, "uint32": 32, "uint64": 64}[typ]
+		n, err := strconv.ParseUint(arg, 0, bitSize)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "uint":
+			return uint(n), nil
+		case "uint16":
+			return uint16(n), nil
+		case "uint32":
+			return uint32(n), nil
+		default:
+			return n, nil
+		}
+	case "float32":
+		n, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, err
+		}
+		return float32(n), nil
+	case "float64":
+		return strconv.ParseFloat(arg, 64)
+	case "math.Float32frombits":
+		bits, err := strconv.ParseUint(arg, 0, 32)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(uint32(bits)), nil
+	case "math.Float64frombits":
+		bits, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
@@ -0,0 +1,410 @@
+//go:build linux && !baremetal
+
+package testing
+
+// This file implements the fuzzing engine used with -test.fuzz. It runs in the
+// same process as the tests: inputs from the corpus are mutated, and inputs
+// that reach new code are added to the corpus. Which code was reached is found
+// using the 8-bit counters that the compiler inserts in every basic block of
+// the fuzzed packages (see runtime/fuzz.go), similar to libFuzzer with LLVM
+// SanitizerCoverage.
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Maximum length of generated []byte and string inputs.
+const maxFuzzInputLen = 64 << 10
+
+// How often a status line is printed while fuzzing.
+const fuzzStatusInterval = 3 * time.Second
+
+// runtime_fuzzCounters returns the fuzzing counters of all instrumented
+// packages. It is implemented in the runtime.
+func runtime_fuzzCounters() [][]uint8
+
+// fuzzer holds the state of a running fuzz test.
+type fuzzer struct {
+	f        *F
+	fn       fuzzFunc
+	rand     *rand.Rand
+	corpus   []interface{}
+	counters [][]uint8
+	seen     [][]uint8   // counter buckets that were seen before
+	current  interface{} // input that is currently running
+}
+
+// fuzz runs the fuzz function with generated inputs until it fails, or until
+// the time or number of iterations set with -test.fuzztime is reached.
+func (f *F) fuzz(fn fuzzFunc, corpus []corpusEntry) {
+	cacheDir := ""
+	if *fuzzCacheDir != "" {
+		cacheDir = filepath.Join(*fuzzCacheDir, f.name)
+	}
+	cached, err := readCorpusDir(cacheDir, fn)
+	if err != nil {
+		f.Error(err)
+		return
+	}
+	corpus = append(corpus, cached...)
+	if len(corpus) == 0 {
+		corpus = append(corpus, corpusEntry{Path: "zero", Values: []interface{}{fn.zero}})
+	}
+
+	fz := &fuzzer{
+		f:        f,
+		fn:       fn,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		counters: runtime_fuzzCounters(),
+	}
+	for _, region := range fz.counters {
+		fz.seen = append(fz.seen, make([]uint8, len(region)))
+	}
+	if len(fz.counters) == 0 {
+		fmt.Fprintln(os.Stderr, "testing: warning: the test binary is not instrumented for fuzzing, inputs are generated without coverage guidance")
+	}
+
+	// A panic can't always be recovered (for example, an index out of range),
+	// so write the input that caused it from a panic handler before the
+	// program exits.
+	runtime.SetPanicHandler(fz.crashed)
+	defer runtime.SetPanicHandler(nil)
+
+	// Run the corpus first, to find out which code it already reaches.
+	start := time.Now()
+	fmt.Printf("fuzz: elapsed: 0s, gathering baseline coverage: 0/%d completed\n", len(corpus))
+	for _, entry := range corpus {
+		value := entry.Values[0]
+		if t := fz.run(value); t.Failed() {
+			fz.fail(t, value)
+			return
+		}
+		fz.hasNewCoverage()
+		fz.corpus = append(fz.corpus, value)
+	}
+	fmt.Printf("fuzz: elapsed: %s, gathering baseline coverage: %d/%d completed, now fuzzing\n", fuzzElapsed(start), len(corpus), len(corpus))
+
+	// Mutate inputs from the corpus, and keep those that reach new code.
+	interesting := 0
+	lastStatus := time.Now()
+	printStatus := func() {
+		execs := f.result.N
+		rate := float64(execs) / time.Since(start).Seconds()
+		fmt.Printf("fuzz: elapsed: %s, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n", fuzzElapsed(start), execs, rate, interesting, len(fz.corpus))
+	}
+	for {
+		if fuzzDuration.n > 0 && f.result.N >= fuzzDuration.n {
+			break
+		}
+		if fuzzDuration.d > 0 && time.Since(start) >= fuzzDuration.d {
+			break
+		}
+		value := fz.mutate(fz.corpus[fz.rand.Intn(len(fz.corpus))])
+		f.result.N++
+		if t := fz.run(value); t.Failed() {
+			printStatus()
+			fz.fail(t, value)
+			return
+		}
+		if fz.hasNewCoverage() {
+			fz.corpus = append(fz.corpus, value)
+			interesting++
+			if cacheDir != "" {
+				writeCorpusFile(cacheDir, value)
+			}
+		}
+		if time.Since(lastStatus) >= fuzzStatusInterval {
+			printStatus()
+			lastStatus = time.Now()
+		}
+	}
+	printStatus()
+}
+
+// run runs the fuzz function with a single input, and returns the test it ran
+// in.
+func (fz *fuzzer) run(value interface{}) *T {
+	for _, region := range fz.counters {
+		for i := range region {
+			region[i] = 0
+		}
+	}
+	t := &T{
+		common: common{
+			output: &logger{},
+			name:   fz.f.name,
+			parent: &fz.f.common,
+			level:  fz.f.level + 1,
+			indent: "    ",
+		},
+		context: fz.f.testContext,
+	}
+	fz.current = value
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("panic: %v", r)
+			}
+			t.runCleanup()
+		}()
+		t.start = time.Now()
+		fz.fn.call(t, value)
+		t.duration = time.Since(t.start)
+	}()
+	fz.current = nil
+	return t
+}
+
+// hasNewCoverage returns whether the last input reached new code, or ran a
+// block of code a number of times that wasn't seen before. Like libFuzzer,
+// the counters are put in buckets (1, 2, 3, 4-7, 8-15, 16-31, 32-127, 128+)
+// so that loops don't make every input interesting.
+func (fz *fuzzer) hasNewCoverage() bool {
+	found := false
+	for i, region := range fz.counters {
+		seen := fz.seen[i]
+		for j, count := range region {
+			if count == 0 {
+				continue
+			}
+			var bucket uint8
+			switch {
+			case count >= 128:
+				bucket = 1 << 7
+			case count >= 32:
+				bucket = 1 << 6
+			case count >= 16:
+				bucket = 1 << 5
+			case count >= 8:
+				bucket = 1 << 4
+			case count >= 4:
+				bucket = 1 << 3
+			default:
+				bucket = 1 << (count - 1)
+			}
+			if seen[j]&bucket == 0 {
+				seen[j] |= bucket
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// fail reports a failing input, and writes it to the seed corpus in testdata
+// so that it is run with the regular tests from now on.
+func (fz *fuzzer) fail(t *T, value interface{}) {
+	f := fz.f
+	f.result.Error = fmt.Errorf("fuzz function failed")
+	t.report()
+	path, err := writeCorpusFile(filepath.Join("testdata", "fuzz", f.name), value)
+	if err != nil {
+		fmt.Fprintf(f.output, "    could not write failing input: %v\n", err)
+		return
+	}
+	fmt.Fprintf(f.output, "    Failing input written to %s\n", path)
+	fmt.Fprintf(f.output, "    To re-run:\n    tinygo test -run=%s/%s\n", f.name, filepath.Base(path))
+}
+
+// crashed is called from the panic handler when an input caused a panic that
+// could not be recovered. The program exits after it returns.
+func (fz *fuzzer) crashed(record runtime.PanicRecord) {
+	if fz.current == nil {
+		return
+	}
+	f := fz.f
+	fmt.Printf("--- FAIL: %s (%s)\n", f.name, fmtDuration(time.Since(f.start)))
+	path, err := writeCorpusFile(filepath.Join("testdata", "fuzz", f.name), fz.current)
+	if err != nil {
+		fmt.Printf("    could not write failing input: %v\n", err)
+		return
+	}
+	fmt.Printf("    Failing input written to %s\n", path)
+	fmt.Printf("    To re-run:\n    tinygo test -run=%s/%s\n", f.name, filepath.Base(path))
+}
+
+// writeCorpusFile writes the value as a new corpus file in the given
+// directory. The file is named after the hash of its contents, like the go
+// command does.
+func writeCorpusFile(dir string, value interface{}) (string, error) {
+	data := marshalCorpusFile(value)
+	path := filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256(data))[:16])
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// fuzzElapsed formats the time since start in whole seconds.
+func fuzzElapsed(start time.Time) string {
+	return time.Since(start).Round(time.Second).String()
+}
+
+// mutate returns a randomly changed copy of the given value.
+func (fz *fuzzer) mutate(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return fz.mutateBytes(append([]byte(nil), v...))
+	case string:
+		return string(fz.mutateBytes([]byte(v)))
+	case bool:
+		return !v
+	case byte:
+		return byte(fz.mutateUint(uint64(v), 8))
+	case rune:
+		return rune(fz.mutateInt(int64(v), 32))
+	case int:
+		return int(fz.mutateInt(int64(v), strconv.IntSize))
+	case int8:
+		return int8(fz.mutateInt(int64(v), 8))
+	case int16:
+		return int16(fz.mutateInt(int64(v), 16))
+	case int64:
+		return fz.mutateInt(v, 64)
+	case uint:
+		return uint(fz.mutateUint(uint64(v), strconv.IntSize))
+	case uint16:
+		return uint16(fz.mutateUint(uint64(v), 16))
+	case uint32:
+		return uint32(fz.mutateUint(uint64(v), 32))
+	case uint64:
+		return fz.mutateUint(v, 64)
+	case float32:
+		return float32(fz.mutateFloat(float64(v)))
+	case float64:
+		return fz.mutateFloat(v)
+	}
+	return value
+}
+
+// mutateInt mutates a signed integer of the given size in bits.
+func (fz *fuzzer) mutateInt(v int64, size int) int64 {
+	switch fz.rand.Intn(4) {
+	case 0:
+		v += int64(fz.rand.Intn(33) - 16)
+	case 1:
+		v = int64(fz.rand.Uint64())
+	case 2:
+		v ^= 1 << fz.rand.Intn(size)
+	default:
+		interesting := []int64{0, 1, -1, math.MinInt64 >> (64 - size), math.MaxInt64 >> (64 - size)}
+		v = interesting[fz.rand.Intn(len(interesting))]
+	}
+	// Sign-extend, so that the value fits in the given size.
+	return v << (64 - size) >> (64 - size)
+}
+
+// mutateUint mutates an unsigned integer of the given size in bits.
+func (fz *fuzzer) mutateUint(v uint64, size int) uint64 {
+	switch fz.rand.Intn(4) {
+	case 0:
+		v += uint64(fz.rand.Intn(33) - 16)
+	case 1:
+		v = fz.rand.Uint64()
+	case 2:
+		v ^= 1 << fz.rand.Intn(size)
+	default:
+		interesting := []uint64{0, 1, math.MaxUint64 >> (64 - size), math.MaxUint64 >> (65 - size)}
+		v = interesting[fz.rand.Intn(len(interesting))]
+	}
+	return v & (math.MaxUint64 >> (64 - size))
+}
+
+// mutateFloat mutates a floating point number.
+func (fz *fuzzer) mutateFloat(v float64) float64 {
+	switch fz.rand.Intn(4) {
+	case 0:
+		v += float64(fz.rand.Intn(33) - 16)
+	case 1:
+		v *= fz.rand.NormFloat64()
+	case 2:
+		v = math.Float64frombits(math.Float64bits(v) ^ 1<<fz.rand.Intn(64))
+	default:
+		interesting := []float64{0, math.Copysign(0, -1), 1, -1, math.Inf(1), math.Inf(-1), math.NaN(), math.MaxFloat64, math.SmallestNonzeroFloat64}
+		v = interesting[fz.rand.Intn(len(interesting))]
+	}
+	return v
+}
+
+// mutateBytes applies a few random mutations to b, and returns the result.
+func (fz *fuzzer) mutateBytes(b []byte) []byte {
+	for n := 1 + fz.rand.Intn(4); n > 0; n-- {
+		if len(b) == 0 {
+			// Only insertions make sense.
+			b = fz.insertBytes(b)
+			continue
+		}
+		pos := fz.rand.Intn(len(b))
+		switch fz.rand.Intn(8) {
+		case 0:
+			// Flip a bit.
+			b[pos] ^= 1 << fz.rand.Intn(8)
+		case 1:
+			// Set a random byte.
+			b[pos] = byte(fz.rand.Intn(256))
+		case 2:
+			// Set an interesting byte.
+			interesting := []byte{0, 1, '0', ' ', 0x7f, 0x80, 0xff}
+			b[pos] = interesting[fz.rand.Intn(len(interesting))]
+		case 3:
+			b = fz.insertBytes(b)
+		case 4:
+			// Remove a range of bytes.
+			end := pos + 1 + fz.rand.Intn(len(b)-pos)
+			b = append(b[:pos], b[end:]...)
+		case 5:
+			// Duplicate a range of bytes.
+			end := pos + 1 + fz.rand.Intn(len(b)-pos)
+			chunk := append([]byte(nil), b[pos:end]...)
+			b = fz.insertAt(b, fz.rand.Intn(len(b)+1), chunk)
+		case 6:
+			// Swap two bytes.
+			other := fz.rand.Intn(len(b))
+			b[pos], b[other] = b[other], b[pos]
+		default:
+			// Insert a range of bytes from another corpus entry.
+			var other []byte
+			switch v := fz.corpus[fz.rand.Intn(len(fz.corpus))].(type) {
+			case []byte:
+				other = v
+			case string:
+				other = []byte(v)
+			}
+			if len(other) != 0 {
+				start := fz.rand.Intn(len(other))
+				end := start + 1 + fz.rand.Intn(len(other)-start)
+				b = fz.insertAt(b, pos, other[start:end])
+			}
+		}
+	}
+	if len(b) > maxFuzzInputLen {
+		b = b[:maxFuzzInputLen]
+	}
+	return b
+}
+
+// insertBytes inserts a few random bytes at a random position in b.
+func (fz *fuzzer) insertBytes(b []byte) []byte {
+	chunk := make([]byte, 1+fz.rand.Intn(8))
+	fz.rand.Read(chunk)
+	return fz.insertAt(b, fz.rand.Intn(len(b)+1), chunk)
+}
+
+// insertAt inserts chunk into b at the given position.
+func (fz *fuzzer) insertAt(b []byte, pos int, chunk []byte) []byte {
+	result := make([]byte, 0, len(b)+len(chunk))
+	result = append(result, b[:pos]...)
+	result = append(result, chunk...)
+	return append(result, b[pos:]...)
+}
@@ -0,0 +1,8 @@
+//go:build !linux || baremetal
+
+package testing
+
+// fuzz is called with -test.fuzz. Fuzzing is only supported on Linux hosts.
+func (f *F) fuzz(fn fuzzFunc, corpus []corpusEntry) {
+	f.Error("testing: fuzzing is only supported on Linux")
+}
@@ -0,0 +1,77 @@
+package testing
+
+import (
+	"math"
+	"reflect"
+)
+
+func TestCorpusFile(t *T) {
+	// Values must survive a round trip through the corpus file format.
+	for _, value := range []interface{}{
+		[]byte("a\x00\xff"),
+		"héllo\n",
+		true,
+		byte(0x80),
+		rune('é'),
+		rune(-5),
+		int(-3),
+		int8(math.MinInt8),
+		int16(300),
+		int64(math.MinInt64),
+		uint(7),
+		uint16(math.MaxUint16),
+		uint32(1 << 31),
+		uint64(math.MaxUint64),
+		float32(1.5),
+		float64(-0.1),
+		math.Inf(1),
+		float32(math.Inf(-1)),
+	} {
+		data := marshalCorpusFile(value)
+		values, err := unmarshalCorpusFile(data)
+		if err != nil {
+			t.Errorf("could not parse %q: %v", data, err)
+			continue
+		}
+		if len(values) != 1 || !reflect.DeepEqual(values[0], value) {
+			t.Errorf("round trip of %#v through %q resulted in %#v", value, data, values)
+		}
+	}
+
+	// Files written by the go command must be readable.
+	values, err := unmarshalCorpusFile([]byte("go test fuzz v1\n[]byte(\"\\x00abc\")\nuint8('\\n')\nmath.Float64frombits(0x7ff8000000000001)\n"))
+	if err != nil {
+		t.Fatal("could not parse corpus file:", err)
+	}
+	if len(values) != 3 || string(values[0].([]byte)) != "\x00abc" || values[1].(byte) != '\n' || math.Float64bits(values[2].(float64)) != 0x7ff8000000000001 {
+		t.Errorf("unexpected values: %#v", values)
+	}
+
+	for _, data := range []string{
+		"",
+		"go test fuzz v2\nint(1)\n",
+		"go test fuzz v1\n",
+		"go test fuzz v1\nint(abc)\n",
+		"go test fuzz v1\ncomplex128(1)\n",
+	} {
+		if _, err := unmarshalCorpusFile([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+// FuzzParseCorpusValue also checks that the seed corpus in
+// testdata/fuzz/FuzzParseCorpusValue is read.
+func FuzzParseCorpusValue(f *F) {
+	f.Add("int(5)")
+	f.Add("[]byte(\"abc\")")
+	f.Fuzz(func(t *T, line string) {
+		value, err := parseCorpusValue(line)
+		if err != nil {
+			return
+		}
+		if _, err := unmarshalCorpusFile(marshalCorpusFile(value)); err != nil {
+			t.Errorf("could not parse value of %q after writing it: %v", line, err)
+		}
+	})
+}
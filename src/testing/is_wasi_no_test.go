@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 //
-//go:build !wasi && !wasip1
+//go:build !wasi && !wasip1 && !wasip2
 
 package testing_test
 
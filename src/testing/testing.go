@@ -52,6 +52,7 @@ func Init() {
 	flag.IntVar(&flagCount, "test.count", 1, "run each test or benchmark `count` times")
 
 	initBenchmarkFlags()
+	initFuzzFlags()
 }
 
 // common holds the elements common between T and B and
@@ -436,8 +437,14 @@ func tRunner(t *T, fn func(t *T)) {
 // Run runs f as a subtest of t called name. It waits until the subtest is finished
 // and returns whether the subtest succeeded.
 func (t *T) Run(name string, f func(t *T)) bool {
-	t.hasSub = true
-	testName, ok, _ := t.context.match.fullName(&t.common, name)
+	return t.runSubtest(t.context, name, f)
+}
+
+// runSubtest runs f as a subtest of c called name, and returns whether the
+// subtest succeeded. It is used for subtests of tests and of fuzz tests.
+func (c *common) runSubtest(context *testContext, name string, f func(t *T)) bool {
+	c.hasSub = true
+	testName, ok, _ := context.match.fullName(c, name)
 	if !ok {
 		return true
 	}
@@ -447,16 +454,16 @@ func (t *T) Run(name string, f func(t *T)) bool {
 		common: common{
 			output: &logger{logToStdout: flagVerbose},
 			name:   testName,
-			parent: &t.common,
-			level:  t.level + 1,
+			parent: c,
+			level:  c.level + 1,
 		},
-		context: t.context,
+		context: context,
 	}
-	if t.level > 0 {
+	if c.level > 0 {
 		sub.indent = sub.indent + "    "
 	}
 	if flagVerbose {
-		fmt.Fprintf(t.output, "=== RUN   %s\n", sub.name)
+		fmt.Fprintf(c.output, "=== RUN   %s\n", sub.name)
 	}
 
 	tRunner(&sub, f)
@@ -481,6 +488,8 @@ type M struct {
 	Tests      []InternalTest
 	Benchmarks []InternalBenchmark
 
+	fuzzTargets []InternalFuzzTarget
+
 	deps testDeps
 
 	// value to pass to os.Exit, the outer test func main
@@ -531,19 +540,32 @@ func (m *M) Run() (code int) {
 	}
 
 	testRan, testOk := runTests(m.deps.MatchString, m.Tests)
-	if !testRan && *matchBenchmarks == "" {
+	fuzzTargetsRan, fuzzTargetsOk := runFuzzTests(m.deps, m.fuzzTargets)
+	if !testRan && !fuzzTargetsRan && *matchBenchmarks == "" && *matchFuzz == "" {
 		fmt.Fprintln(os.Stderr, "testing: warning: no tests to run")
 	}
-	if !testOk || !runBenchmarks(m.deps.MatchString, m.Benchmarks) {
+	ok := testOk && fuzzTargetsOk
+	if *matchFuzz != "" {
+		// Benchmarks are not run while fuzzing.
+		ok = ok && runFuzzing(m.deps, m.fuzzTargets)
+	} else {
+		ok = ok && runBenchmarks(m.deps.MatchString, m.Benchmarks)
+	}
+	if !ok {
 		fmt.Println("FAIL")
 		m.exitCode = 1
 	} else {
 		fmt.Println("PASS")
 		m.exitCode = 0
 	}
+	runtime_coverageFlush()
 	return
 }
 
+// runtime_coverageFlush writes the code coverage counters to the output, when
+// the test was built with -cover. It is implemented in the runtime.
+func runtime_coverageFlush()
+
 func runTests(matchString func(pat, str string) (bool, error), tests []InternalTest) (ran, ok bool) {
 	ok = true
 
@@ -567,19 +589,19 @@ func runTests(matchString func(pat, str string) (bool, error), tests []InternalT
 	return t.ran, ok
 }
 
-func (t *T) report() {
-	dstr := fmtDuration(t.duration)
-	format := t.indent + "--- %s: %s (%s)\n"
-	if t.Failed() {
-		if t.parent != nil {
-			t.parent.failed = true
+func (c *common) report() {
+	dstr := fmtDuration(c.duration)
+	format := c.indent + "--- %s: %s (%s)\n"
+	if c.Failed() {
+		if c.parent != nil {
+			c.parent.failed = true
 		}
-		t.flushToParent(t.name, format, "FAIL", t.name, dstr)
+		c.flushToParent(c.name, format, "FAIL", c.name, dstr)
 	} else if flagVerbose {
-		if t.Skipped() {
-			t.flushToParent(t.name, format, "SKIP", t.name, dstr)
+		if c.Skipped() {
+			c.flushToParent(c.name, format, "SKIP", c.name, dstr)
 		} else {
-			t.flushToParent(t.name, format, "PASS", t.name, dstr)
+			c.flushToParent(c.name, format, "PASS", c.name, dstr)
 		}
 	}
 }
@@ -610,9 +632,10 @@ type InternalExample struct {
 func MainStart(deps interface{}, tests []InternalTest, benchmarks []InternalBenchmark, fuzzTargets []InternalFuzzTarget, examples []InternalExample) *M {
 	Init()
 	return &M{
-		Tests:      tests,
-		Benchmarks: benchmarks,
-		deps:       deps.(testDeps),
+		Tests:       tests,
+		Benchmarks:  benchmarks,
+		fuzzTargets: fuzzTargets,
+		deps:        deps.(testDeps),
 	}
 }
 
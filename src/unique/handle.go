@@ -0,0 +1,41 @@
+// Package unique lets clients deduplicate comparable values, so that they can
+// be compared by comparing a small handle.
+//
+// This is a TinyGo specific implementation of the package. The upstream
+// implementation depends on weak pointers and a concurrent hash trie, which
+// need runtime support that TinyGo doesn't have. Instead, all values are kept
+// in a single map and are never freed.
+package unique
+
+import "sync"
+
+// Handle is a globally unique identity for some value of type T.
+//
+// Two handles compare equal exactly if the two values used to create the
+// handles would have also compared equal.
+type Handle[T comparable] struct {
+	value *T
+}
+
+// Value returns a shallow copy of the T value that produced the Handle.
+func (h Handle[T]) Value() T {
+	return *h.value
+}
+
+var (
+	mutex   sync.Mutex
+	handles = map[any]any{}
+)
+
+// Make returns a globally unique handle for a value of type T.
+func Make[T comparable](value T) Handle[T] {
+	mutex.Lock()
+	defer mutex.Unlock()
+	ptr, ok := handles[value].(*T)
+	if !ok {
+		ptr = new(T)
+		*ptr = value
+		handles[value] = ptr
+	}
+	return Handle[T]{ptr}
+}
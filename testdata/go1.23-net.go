@@ -0,0 +1,24 @@
+package main
+
+// Since Go 1.23, net/netip depends on the unique package, so this checks that
+// the net package can be imported.
+
+import (
+	"net"
+	"net/netip"
+	"unique"
+)
+
+func main() {
+	addr := netip.MustParseAddr("192.168.1.1")
+	println("addr:", addr.String())
+	println("same addr:", addr == netip.MustParseAddr("192.168.1.1"))
+	println("other addr:", addr == netip.MustParseAddr("192.168.1.2"))
+	println("ip:", net.ParseIP("10.0.0.1").String())
+
+	h1 := unique.Make("foo")
+	h2 := unique.Make("foo")
+	h3 := unique.Make("bar")
+	println("handles equal:", h1 == h2, h1 == h3)
+	println("handle value:", h3.Value())
+}
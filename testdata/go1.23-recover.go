@@ -0,0 +1,75 @@
+//go:build go1.23
+
+package main
+
+func main() {
+	// A panic in the loop body runs the deferred calls of the loop body and of
+	// the iterator.
+	panicInBody()
+
+	// A panic in the iterator runs the deferred calls of the loop body.
+	panicInIterator()
+
+	// The loop body can recover from a panic.
+	println("recoverInBody:", recoverInBody())
+}
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		defer println("count: iterator done")
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func panics(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+		panic("iterator panic")
+	}
+}
+
+func panicInBody() {
+	defer func() {
+		println("panicInBody: recovered:", recover().(string))
+	}()
+	for v := range count(5) {
+		defer println("panicInBody: loop defer", v)
+		if v == 2 {
+			panic("body panic")
+		}
+	}
+	println("panicInBody: unreachable")
+}
+
+func panicInIterator() {
+	defer func() {
+		println("panicInIterator: recovered:", recover().(string))
+	}()
+	for v := range panics(2) {
+		defer println("panicInIterator: loop defer", v)
+	}
+	println("panicInIterator: unreachable")
+}
+
+func recoverInBody() (result int) {
+	for v := range count(3) {
+		defer func() {
+			if r := recover(); r != nil {
+				println("recoverInBody: recovered:", r.(string))
+				result = v
+			}
+		}()
+		if v == 1 {
+			panic("panic at 1")
+		}
+	}
+	return -1
+}
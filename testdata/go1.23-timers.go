@@ -0,0 +1,51 @@
+package main
+
+// Timer behavior that changed in Go 1.23: a stopped or reset timer never
+// delivers a stale value afterwards.
+
+import "time"
+
+func main() {
+	// A timer that fired but wasn't received from.
+	timer := time.NewTimer(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	println("stop after firing:", timer.Stop())
+	select {
+	case <-timer.C:
+		println("fail: received stale value after Stop")
+	default:
+		println("no stale value after Stop")
+	}
+
+	// Reset of a timer that fired but wasn't received from.
+	timer.Reset(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	println("reset after firing:", timer.Reset(100*time.Millisecond))
+	start := time.Now()
+	<-timer.C
+	if time.Since(start) < 50*time.Millisecond {
+		println("fail: received stale value after Reset")
+	} else {
+		println("no stale value after Reset")
+	}
+
+	// Ticker.Reset changes the period.
+	ticker := time.NewTicker(time.Hour)
+	ticker.Reset(10 * time.Millisecond)
+	<-ticker.C
+	<-ticker.C
+	ticker.Stop()
+	println("ticker reset")
+
+	// AfterFunc, stopped before it fires.
+	done := make(chan bool)
+	t := time.AfterFunc(10*time.Millisecond, func() {
+		done <- true
+	})
+	println("stop before firing:", t.Stop())
+	time.AfterFunc(10*time.Millisecond, func() {
+		done <- true
+	})
+	<-done
+	println("AfterFunc called")
+}
@@ -0,0 +1,148 @@
+//go:build go1.23
+
+package main
+
+import (
+	"iter"
+	"maps"
+	"slices"
+)
+
+func main() {
+	// Range over an integer.
+	for i := range 3 {
+		println("range int:", i)
+	}
+	var n uint8 = 2
+	for i := range n {
+		println("range uint8:", i)
+	}
+
+	// Loop variables are per-iteration.
+	var funcs []func() int
+	for i := 0; i < 3; i++ {
+		funcs = append(funcs, func() int { return i })
+	}
+	for _, f := range funcs {
+		println("loopvar:", f())
+	}
+
+	// Range over a function.
+	for v := range count(5) {
+		if v == 3 {
+			break
+		}
+		println("count:", v)
+	}
+	for k, v := range pairs([]string{"a", "b", "c"}) {
+		if k == 1 {
+			continue
+		}
+		println("pairs:", k, v)
+	}
+	for range count(2) {
+		println("no variables")
+	}
+	println("find:", find(count(10), 7))
+	println("find:", find(count(10), 12))
+
+	// Nested loops with a labelled break.
+outer:
+	for i := range count(3) {
+		for j := range count(3) {
+			if j > i {
+				continue outer
+			}
+			if i == 2 {
+				break outer
+			}
+			println("nested:", i, j)
+		}
+	}
+
+	// Defer inside a loop body runs when the function returns, not at the end
+	// of the iteration.
+	deferInLoop()
+
+	// The iterator is stopped when the loop body returns early.
+	for v := range cleanup(count(5)) {
+		if v == 1 {
+			break
+		}
+	}
+
+	// The iter package.
+	seq := iter.Seq[int](count(3))
+	for v := range seq {
+		println("iter.Seq:", v)
+	}
+	next, stop := iter.Pull(seq)
+	for {
+		v, ok := next()
+		if !ok {
+			break
+		}
+		println("iter.Pull:", v)
+	}
+	stop()
+	next2, stop2 := iter.Pull2(iter.Seq2[int, string](pairs([]string{"x", "y"})))
+	k, v, ok := next2()
+	println("iter.Pull2:", k, v, ok)
+	stop2()
+	k, v, ok = next2()
+	println("iter.Pull2 after stop:", k, v, ok)
+
+	// Standard library iterators.
+	for i, s := range slices.All([]string{"p", "q"}) {
+		println("slices.All:", i, s)
+	}
+	m := map[string]int{"one": 1, "two": 2, "three": 3}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		println("maps.Keys:", key, m[key])
+	}
+}
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func pairs(s []string) func(func(int, string) bool) {
+	return func(yield func(int, string) bool) {
+		for i, v := range s {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+func find(seq func(func(int) bool), needle int) bool {
+	for v := range seq {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func deferInLoop() {
+	defer println("deferInLoop: function defer")
+	for v := range count(3) {
+		defer println("deferInLoop: loop defer", v)
+		println("deferInLoop: loop body", v)
+	}
+	println("deferInLoop: after loop")
+}
+
+func cleanup(seq func(func(int) bool)) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		defer println("cleanup: iterator stopped")
+		seq(yield)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// This program is compiled with -panic=reset. The panic handler checks the
+// panic record and exits before the chip is reset.
+
+import (
+	"os"
+	"runtime"
+)
+
+func main() {
+	if _, ok := runtime.LastPanic(); ok {
+		println("unexpected panic record at startup")
+	}
+	runtime.SetPanicHandler(func(record runtime.PanicRecord) {
+		println("handler:", record.Message)
+
+		// The record is stored before the handler is called.
+		stored, ok := runtime.LastPanic()
+		println("stored:", ok, stored.Message == record.Message, stored.PC == record.PC)
+
+		runtime.ClearLastPanic()
+		_, ok = runtime.LastPanic()
+		println("cleared:", !ok)
+		os.Exit(0)
+	})
+	println("before panic")
+	panic("something went wrong")
+}
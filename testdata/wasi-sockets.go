@@ -0,0 +1,62 @@
+package main
+
+// Server for TestWASISockets: it accepts a connection on a socket pre-opened by
+// the host, while another goroutine runs the GC.
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"time"
+)
+
+var sink []byte
+
+func main() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		println("listen:", err.Error())
+		return
+	}
+	println("listening")
+
+	// Run the GC while the main goroutine waits in Accept. The client only
+	// connects after it has seen "gc ok".
+	go func() {
+		for i := 0; i < 10; i++ {
+			sink = make([]byte, 1024)
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		println("gc ok")
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		println("accept:", err.Error())
+		return
+	}
+
+	// The client doesn't send anything until it has seen the result of this
+	// read, so it must time out.
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, err = conn.Read(buf)
+	println("read timeout:", errors.Is(err, os.ErrDeadlineExceeded))
+
+	conn.SetReadDeadline(time.Time{})
+	n, err := conn.Read(buf)
+	if err != nil {
+		println("read:", err.Error())
+		return
+	}
+	println("read:", string(buf[:n]))
+	conn.Write([]byte("echo: " + string(buf[:n])))
+
+	_, err = conn.Read(buf)
+	println("eof:", err == io.EOF)
+	conn.Close()
+	ln.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+// Exported functions that block. They are called by the host after
+// _initialize (in a reactor module) or after main has returned (in a command
+// module), see wasmexport_test.go.
+
+import "time"
+
+var values = make(chan int32)
+
+func init() {
+	go func() {
+		for i := int32(1); ; i++ {
+			values <- i
+		}
+	}()
+}
+
+// Receive the next value from the goroutine started in init.
+//
+//go:wasmexport next
+func next() int32 {
+	return <-values
+}
+
+// Sleep, to check that timers work across calls.
+//
+//go:wasmexport sleep
+func sleep(ms int32) int32 {
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	return ms
+}
+
+func main() {
+	println("main:", next())
+}
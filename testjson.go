@@ -0,0 +1,193 @@
+package main
+
+// This file converts the verbose output of a test binary into the stream of
+// JSON events printed by `go test -json` (see `go doc test2json`). The output
+// is parsed on the host, so it works the same for test binaries that run
+// natively, in an emulator like qemu or simavr, or in a WebAssembly runtime.
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// testEvent is a single event in the JSON output, in the same format as
+// written by test2json.
+type testEvent struct {
+	Time    *time.Time `json:",omitempty"`
+	Action  string
+	Package string   `json:",omitempty"`
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"`
+	Output  *string  `json:",omitempty"`
+}
+
+// testJSONWriter converts the output of a test binary that was run with
+// -test.v into JSON events, and writes them to the underlying writer.
+type testJSONWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	pkg     string
+	started bool
+	line    []byte   // current (incomplete) line
+	running []string // tests that were started but didn't finish, innermost last
+	last    string   // test that finished most recently
+	err     error    // first write error
+}
+
+func newTestJSONWriter(w io.Writer) *testJSONWriter {
+	return &testJSONWriter{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// start emits the start event of the given package. All following events
+// belong to this package.
+func (tw *testJSONWriter) start(pkg string) {
+	tw.pkg = pkg
+	if !tw.started {
+		tw.started = true
+		tw.emit(testEvent{Action: "start"})
+	}
+}
+
+func (tw *testJSONWriter) Write(data []byte) (int, error) {
+	for _, c := range data {
+		tw.line = append(tw.line, c)
+		if c == '\n' {
+			tw.handleLine(string(tw.line))
+			tw.line = tw.line[:0]
+		}
+	}
+	if tw.err != nil {
+		return 0, tw.err
+	}
+	return len(data), nil
+}
+
+// Flush emits the last line of output if it didn't end in a newline, for
+// example because the test binary crashed.
+func (tw *testJSONWriter) Flush() error {
+	if len(tw.line) != 0 {
+		tw.handleLine(string(tw.line))
+		tw.line = tw.line[:0]
+	}
+	return tw.err
+}
+
+// exit emits the result of the whole package: the given summary line (like
+// "ok  \tpkg\t0.123s") as output, followed by a pass, fail or skip event. Tests
+// that are still running when the package fails (for example because of a
+// panic) are reported as failed.
+func (tw *testJSONWriter) exit(pkg, action, summary string, elapsed time.Duration) error {
+	tw.Flush()
+	tw.start(pkg)
+	if action == "fail" {
+		for i := len(tw.running) - 1; i >= 0; i-- {
+			tw.emit(testEvent{Action: "fail", Test: tw.running[i]})
+		}
+		tw.running = nil
+	}
+	tw.emitOutput("", summary)
+	seconds := elapsed.Seconds()
+	tw.emit(testEvent{Action: action, Elapsed: &seconds})
+	return tw.err
+}
+
+// handleLine converts a single line of test output (including the newline, if
+// there is one) to events.
+func (tw *testJSONWriter) handleLine(line string) {
+	// Serial output from emulators often uses CRLF line endings.
+	if strings.HasSuffix(line, "\r\n") {
+		line = line[:len(line)-2] + "\n"
+	}
+	text := strings.TrimRight(line, "\n")
+	trimmed := strings.TrimLeft(text, " ")
+
+	// Lines like "=== RUN   TestFoo" when a test starts or continues.
+	if strings.HasPrefix(trimmed, "=== ") {
+		for _, marker := range []struct{ prefix, action string }{
+			{"=== RUN   ", "run"},
+			{"=== PAUSE ", "pause"},
+			{"=== CONT  ", "cont"},
+		} {
+			if !strings.HasPrefix(trimmed, marker.prefix) {
+				continue
+			}
+			name := strings.TrimSpace(trimmed[len(marker.prefix):])
+			if marker.action == "run" {
+				tw.emit(testEvent{Action: "run", Test: name})
+				tw.running = append(tw.running, name)
+			}
+			tw.emitOutput(name, line)
+			if marker.action != "run" {
+				tw.emit(testEvent{Action: marker.action, Test: name})
+			}
+			return
+		}
+	}
+
+	// Lines like "--- PASS: TestFoo (0.01s)" when a test has finished.
+	if strings.HasPrefix(trimmed, "--- ") {
+		for _, marker := range []struct{ prefix, action string }{
+			{"--- PASS: ", "pass"},
+			{"--- FAIL: ", "fail"},
+			{"--- SKIP: ", "skip"},
+			{"--- BENCH: ", "bench"},
+		} {
+			if !strings.HasPrefix(trimmed, marker.prefix) {
+				continue
+			}
+			name := trimmed[len(marker.prefix):]
+			var elapsed *float64
+			if index := strings.LastIndex(name, " ("); index >= 0 && strings.HasSuffix(name, "s)") {
+				seconds, err := strconv.ParseFloat(name[index+2:len(name)-2], 64)
+				if err == nil {
+					elapsed = &seconds
+				}
+				name = name[:index]
+			}
+			tw.emitOutput(name, line)
+			tw.emit(testEvent{Action: marker.action, Test: name, Elapsed: elapsed})
+			for i := len(tw.running) - 1; i >= 0; i-- {
+				if tw.running[i] == name {
+					tw.running = append(tw.running[:i], tw.running[i+1:]...)
+					break
+				}
+			}
+			tw.last = name
+			return
+		}
+	}
+
+	// Any other output belongs to the innermost running test. Indented lines
+	// after a test has finished are its log output, which is printed after
+	// the result line. Everything else (like the final PASS or FAIL) belongs
+	// to the package.
+	test := ""
+	if len(tw.running) != 0 {
+		test = tw.running[len(tw.running)-1]
+	} else if strings.HasPrefix(text, " ") {
+		test = tw.last
+	}
+	tw.emitOutput(test, line)
+}
+
+// emitOutput emits an output event for the given test, or for the package if
+// the test name is empty.
+func (tw *testJSONWriter) emitOutput(test, output string) {
+	tw.emit(testEvent{Action: "output", Test: test, Output: &output})
+}
+
+func (tw *testJSONWriter) emit(event testEvent) {
+	if tw.err != nil {
+		return
+	}
+	now := time.Now()
+	event.Time = &now
+	event.Package = tw.pkg
+	tw.err = tw.enc.Encode(&event)
+}
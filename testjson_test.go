@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw := newTestJSONWriter(&buf)
+	tw.start("example.com/pkg")
+
+	// Emulators may use CRLF line endings and split the output in arbitrary
+	// places, so write it byte by byte.
+	output := "=== RUN   TestFoo\r\n" +
+		"some output\r\n" +
+		"=== RUN   TestFoo/sub\r\n" +
+		"    --- PASS: TestFoo/sub (0.00s)\r\n" +
+		"--- PASS: TestFoo (0.01s)\r\n" +
+		"=== RUN   TestBar\r\n" +
+		"--- FAIL: TestBar (0.00s)\r\n" +
+		"    bar_test.go:10: failed\r\n" +
+		"=== RUN   TestSkip\r\n" +
+		"--- SKIP: TestSkip (0.00s)\r\n" +
+		"FAIL\r\n"
+	for i := 0; i < len(output); i++ {
+		tw.Write([]byte{output[i]})
+	}
+	err := tw.exit("example.com/pkg", "fail", "FAIL\texample.com/pkg\t0.500s\n", 500*time.Millisecond)
+	if err != nil {
+		t.Fatal("could not write events:", err)
+	}
+
+	type event struct {
+		Action  string
+		Package string
+		Test    string
+		Elapsed *float64
+		Output  string
+	}
+	expected := []event{
+		{Action: "start"},
+		{Action: "run", Test: "TestFoo"},
+		{Action: "output", Test: "TestFoo", Output: "=== RUN   TestFoo\n"},
+		{Action: "output", Test: "TestFoo", Output: "some output\n"},
+		{Action: "run", Test: "TestFoo/sub"},
+		{Action: "output", Test: "TestFoo/sub", Output: "=== RUN   TestFoo/sub\n"},
+		{Action: "output", Test: "TestFoo/sub", Output: "    --- PASS: TestFoo/sub (0.00s)\n"},
+		{Action: "pass", Test: "TestFoo/sub"},
+		{Action: "output", Test: "TestFoo", Output: "--- PASS: TestFoo (0.01s)\n"},
+		{Action: "pass", Test: "TestFoo"},
+		{Action: "run", Test: "TestBar"},
+		{Action: "output", Test: "TestBar", Output: "=== RUN   TestBar\n"},
+		{Action: "output", Test: "TestBar", Output: "--- FAIL: TestBar (0.00s)\n"},
+		{Action: "fail", Test: "TestBar"},
+		{Action: "output", Test: "TestBar", Output: "    bar_test.go:10: failed\n"},
+		{Action: "run", Test: "TestSkip"},
+		{Action: "output", Test: "TestSkip", Output: "=== RUN   TestSkip\n"},
+		{Action: "output", Test: "TestSkip", Output: "--- SKIP: TestSkip (0.00s)\n"},
+		{Action: "skip", Test: "TestSkip"},
+		{Action: "output", Output: "FAIL\n"},
+		{Action: "output", Output: "FAIL\texample.com/pkg\t0.500s\n"},
+		{Action: "fail"},
+	}
+
+	dec := json.NewDecoder(&buf)
+	for i := 0; ; i++ {
+		var e event
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			if i != len(expected) {
+				t.Errorf("expected %d events, got %d", len(expected), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal("could not decode event:", err)
+		}
+		if e.Package != "example.com/pkg" {
+			t.Errorf("event %d: unexpected package %q", i, e.Package)
+		}
+		switch e.Action {
+		case "pass", "fail", "skip":
+			if e.Elapsed == nil {
+				t.Errorf("event %d: missing elapsed time", i)
+			}
+		}
+		e.Package = ""
+		e.Elapsed = nil
+		if i >= len(expected) {
+			t.Errorf("unexpected event %d: %+v", i, e)
+			continue
+		}
+		if e != expected[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, expected[i], e)
+		}
+	}
+}
+
+func TestTestJSONWriterCrash(t *testing.T) {
+	// A test binary that crashes in the middle of a test, without a newline
+	// at the end of the output.
+	var buf bytes.Buffer
+	tw := newTestJSONWriter(&buf)
+	tw.start("example.com/pkg")
+	tw.Write([]byte("=== RUN   TestCrash\npanic: oops"))
+	tw.exit("example.com/pkg", "fail", "FAIL\texample.com/pkg\t0.000s\n", 0)
+
+	var actions []string
+	dec := json.NewDecoder(&buf)
+	for {
+		var e testEvent
+		if err := dec.Decode(&e); err != nil {
+			break
+		}
+		action := e.Action + ":" + e.Test
+		if e.Output != nil {
+			action += ":" + *e.Output
+		}
+		actions = append(actions, action)
+	}
+	expected := []string{
+		"start:",
+		"run:TestCrash",
+		"output:TestCrash:=== RUN   TestCrash\n",
+		"output:TestCrash:panic: oops",
+		"fail:TestCrash",
+		"output::FAIL\texample.com/pkg\t0.000s\n",
+		"fail:",
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected events %q, got %q", expected, actions)
+	}
+	for i := range actions {
+		if actions[i] != expected[i] {
+			t.Errorf("event %d: expected %q, got %q", i, expected[i], actions[i])
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package fail
+
+// Double returns twice the given value. It is only here so that the failing
+// test has some code to cover.
+func Double(n int) int {
+	return n * 2
+}
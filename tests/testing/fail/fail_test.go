@@ -1,7 +1,14 @@
 package fail_test
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/tinygo-org/tinygo/tests/testing/fail"
+)
 
 func TestFail(t *testing.T) {
+	if fail.Double(2) != 4 {
+		t.Error("unexpected result")
+	}
 	t.Error("fail")
 }
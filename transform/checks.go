@@ -0,0 +1,70 @@
+package transform
+
+// This file reports runtime checks (bounds checks, nil checks, etc) that are
+// still present after optimization. This is useful to find out which checks
+// couldn't be eliminated by the optimizer in performance sensitive code, like
+// the -d=ssa/check_bce flag of the gc toolchain.
+
+import (
+	"go/token"
+	"regexp"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// runtimeCheckFunctions maps the runtime functions that are called when a
+// runtime check fails (as inserted by compiler/asserts.go) to a description of
+// the check.
+var runtimeCheckFunctions = map[string]string{
+	"runtime.lookupPanic":              "bounds check",
+	"runtime.slicePanic":               "slice bounds check",
+	"runtime.sliceToArrayPointerPanic": "slice to array pointer check",
+	"runtime.unsafeSlicePanic":         "unsafe.Slice/String check",
+	"runtime.chanMakePanic":            "channel size check",
+	"runtime.negativeShiftPanic":       "negative shift check",
+	"runtime.divideByZeroPanic":        "divide by zero check",
+	"runtime.nilPanic":                 "nil check",
+}
+
+// CheckRuntimeChecks looks for runtime checks that are still present in the
+// module. It should be run after all optimizations.
+//
+// If printChecks is non-nil, every check in a function that matches the regexp
+// is reported to the logger. Additionally, an error is returned for every check
+// in a function marked with //go:checkfree. The compiler doesn't inline these
+// functions, so that their checks can't end up in a different function.
+func CheckRuntimeChecks(mod llvm.Module, printChecks *regexp.Regexp, logger func(token.Position, string)) []error {
+	var errs []error
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() {
+			continue
+		}
+		checkFree := !fn.GetStringAttributeAtIndex(-1, "tinygo-checkfree").IsNil()
+		logChecks := printChecks != nil && printChecks.MatchString(fn.Name())
+		if !checkFree && !logChecks {
+			continue
+		}
+		for bb := fn.EntryBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				if inst.IsACallInst().IsNil() {
+					continue
+				}
+				callee := inst.CalledValue()
+				if callee.IsAFunction().IsNil() {
+					continue
+				}
+				check, ok := runtimeCheckFunctions[callee.Name()]
+				if !ok {
+					continue
+				}
+				if logChecks {
+					logger(getPosition(inst), "found "+check+" in "+fn.Name())
+				}
+				if checkFree {
+					errs = append(errs, errorAt(inst, check+" not eliminated in //go:checkfree function "+fn.Name()))
+				}
+			}
+		}
+	}
+	return errs
+}
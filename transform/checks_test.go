@@ -0,0 +1,82 @@
+package transform_test
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+func TestCheckRuntimeChecks(t *testing.T) {
+	t.Parallel()
+
+	mod := compileGoFileForTesting(t, "./testdata/checks.go")
+
+	// Report all checks. Nothing is optimized, so the check in the
+	// //go:checkfree function is also reported as an error.
+	var testOutputs []allocsTestOutput
+	errs := transform.CheckRuntimeChecks(mod, regexp.MustCompile("^main\\."), func(pos token.Position, msg string) {
+		testOutputs = append(testOutputs, allocsTestOutput{
+			filename: filepath.Base(pos.Filename),
+			line:     pos.Line,
+			msg:      msg,
+		})
+	})
+	sort.Slice(testOutputs, func(i, j int) bool {
+		return testOutputs[i].line < testOutputs[j].line
+	})
+	testOutput := ""
+	for _, out := range testOutputs {
+		testOutput += out.String() + "\n"
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "bounds check not eliminated in //go:checkfree function main.first") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	// Load expected test output (the OUT: lines).
+	testInput, err := os.ReadFile("./testdata/checks.go")
+	if err != nil {
+		t.Fatal("could not read test input:", err)
+	}
+	var expectedTestOutput string
+	for i, line := range strings.Split(strings.ReplaceAll(string(testInput), "\r\n", "\n"), "\n") {
+		if idx := strings.Index(line, " // OUT: "); idx > 0 {
+			msg := line[idx+len(" // OUT: "):]
+			expectedTestOutput += "checks.go:" + strconv.Itoa(i+1) + ": " + msg + "\n"
+		}
+	}
+
+	if testOutput != expectedTestOutput {
+		t.Errorf("output does not match expected output:\n%s", testOutput)
+	}
+}
+
+// Test that checks in a //go:checkfree function are still found after
+// optimization, when the function would otherwise have been inlined.
+func TestCheckRuntimeChecksInline(t *testing.T) {
+	t.Parallel()
+
+	mod := compileGoFileForTesting(t, "./testdata/checks-inline.go")
+
+	// Optimize the module, including the inliner.
+	builder := llvm.NewPassManagerBuilder()
+	defer builder.Dispose()
+	builder.SetOptLevel(2)
+	builder.UseInlinerWithThreshold(225)
+	pm := llvm.NewPassManager()
+	defer pm.Dispose()
+	builder.Populate(pm)
+	pm.Run(mod)
+
+	errs := transform.CheckRuntimeChecks(mod, nil, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "bounds check not eliminated in //go:checkfree function main.checkedLookup") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !byollvm && llvm14
+
+package transform
+
+// Flags for the C++ files in this package (sanitizer.cpp and pgo.cpp), which
+// use the LLVM C++ API.
+
+/*
+#cgo linux        CPPFLAGS: -I/usr/lib/llvm-14/include
+#cgo darwin,amd64 CPPFLAGS: -I/usr/local/opt/llvm@14/include
+#cgo darwin,arm64 CPPFLAGS: -I/opt/homebrew/opt/llvm@14/include
+#cgo freebsd      CPPFLAGS: -I/usr/local/llvm14/include
+#cgo              CXXFLAGS: -std=c++14
+*/
+import "C"
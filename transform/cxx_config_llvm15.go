@@ -0,0 +1,15 @@
+//go:build !byollvm && llvm15
+
+package transform
+
+// Flags for the C++ files in this package (sanitizer.cpp and pgo.cpp), which
+// use the LLVM C++ API.
+
+/*
+#cgo linux        CPPFLAGS: -I/usr/lib/llvm-15/include
+#cgo darwin,amd64 CPPFLAGS: -I/usr/local/opt/llvm@15/include
+#cgo darwin,arm64 CPPFLAGS: -I/opt/homebrew/opt/llvm@15/include
+#cgo freebsd      CPPFLAGS: -I/usr/local/llvm15/include
+#cgo              CXXFLAGS: -std=c++14
+*/
+import "C"
@@ -1,5 +1,8 @@
 package transform
 
+// #include <llvm-c/Core.h>
+import "C"
+
 import (
 	"tinygo.org/x/go-llvm"
 )
@@ -50,7 +53,7 @@ func MakeGCStackSlots(mod llvm.Module) bool {
 		done := false
 		for bb := fn.FirstBasicBlock(); !bb.IsNil() && !done; bb = llvm.NextBasicBlock(bb) {
 			for call := bb.FirstInstruction(); !call.IsNil() && !done; call = llvm.NextInstruction(call) {
-				if call.IsACallInst().IsNil() {
+				if call.IsACallInst().IsNil() && call.IsAInvokeInst().IsNil() {
 					continue // only looking at calls
 				}
 				called := call.CalledValue()
@@ -75,6 +78,13 @@ func MakeGCStackSlots(mod llvm.Module) bool {
 	// a heap allocation (and thus which functions do not).
 	markParentFunctions(allocatingFunctions, alloc)
 
+	// With WebAssembly threads, another thread may run the GC while this
+	// thread is stopped (see scheduler_threads.go). Functions that may stop
+	// the current thread need stack slots just like allocating functions.
+	if stopThread := mod.NamedFunction("runtime.stopThread"); !stopThread.IsNil() {
+		markParentFunctions(allocatingFunctions, stopThread)
+	}
+
 	// Also trace all functions that call a function pointer.
 	for fn := range funcsWithFPCall {
 		// Assume that functions that call a function pointer do a heap
@@ -100,6 +110,7 @@ func MakeGCStackSlots(mod llvm.Module) bool {
 	stackChainStart.SetInitializer(llvm.ConstNull(stackChainStartType))
 
 	// Iterate until runtime.trackPointer has no uses left.
+	stackObjects := map[llvm.Value]llvm.Value{} // stack object for each function
 	for use := trackPointer.FirstUse(); !use.IsNil(); use = trackPointer.FirstUse() {
 		// Pick the first use of runtime.trackPointer.
 		call := use.User()
@@ -267,21 +278,50 @@ func MakeGCStackSlots(mod llvm.Module) bool {
 			builder.SetInsertPointBefore(ret)
 			builder.CreateStore(parent, stackChainStart)
 		}
+
+		stackObjects[fn] = stackObjectCast
+	}
+
+	// A caught exception (a recovered panic with WebAssembly exception
+	// handling) skips the returns of the functions in between, so the stack
+	// chain needs to be restored to what it was in the function that caught
+	// the exception.
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		var catchrets []llvm.Value
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			if terminator := bb.LastInstruction(); !terminator.IsNil() && terminator.InstructionOpcode() == llvm.Opcode(C.LLVMCatchRet) {
+				catchrets = append(catchrets, terminator)
+			}
+		}
+		if len(catchrets) == 0 {
+			continue
+		}
+		chain, ok := stackObjects[fn]
+		if !ok {
+			// This function doesn't have a stack object, so restore the
+			// chain as it was at function entry.
+			builder.SetInsertPointBefore(fn.EntryBasicBlock().FirstInstruction())
+			chain = builder.CreateLoad(stackChainStartType, stackChainStart, "")
+		}
+		for _, catchret := range catchrets {
+			builder.SetInsertPointBefore(catchret)
+			builder.CreateStore(chain, stackChainStart)
+		}
 	}
 
 	return true
 }
 
 // markParentFunctions traverses all parent function calls (recursively) and
-// adds them to the set of marked functions. It only considers function calls:
-// any other uses of such a function is ignored.
+// adds them to the set of marked functions. It only considers function calls
+// (and invokes): any other uses of such a function is ignored.
 func markParentFunctions(marked map[llvm.Value]struct{}, fn llvm.Value) {
 	worklist := []llvm.Value{fn}
 	for len(worklist) != 0 {
 		fn := worklist[len(worklist)-1]
 		worklist = worklist[:len(worklist)-1]
 		for _, use := range getUses(fn) {
-			if use.IsACallInst().IsNil() || use.CalledValue() != fn {
+			if (use.IsACallInst().IsNil() && use.IsAInvokeInst().IsNil()) || use.CalledValue() != fn {
 				// Not the parent function.
 				continue
 			}
@@ -84,6 +84,10 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 		}
 	}
 
+	// Functions called through an interface, for -why=. These have to be found
+	// before the interface method thunks are inlined.
+	var interfaceCalls InterfaceCalls
+
 	if optLevel > 0 {
 		// Run some preparatory passes for the Go optimizer.
 		goPasses := llvm.NewPassManager()
@@ -104,6 +108,9 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 		if err != nil {
 			return []error{err}
 		}
+		if config.Options.Why != "" {
+			interfaceCalls = FindInterfaceCalls(mod)
+		}
 
 		errs := LowerInterrupts(mod)
 		if len(errs) > 0 {
@@ -128,6 +135,9 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 		if err != nil {
 			return []error{err}
 		}
+		if config.Options.Why != "" {
+			interfaceCalls = FindInterfaceCalls(mod)
+		}
 		errs := LowerInterrupts(mod)
 		if len(errs) > 0 {
 			return errs
@@ -160,6 +170,20 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 		return []error{errors.New("optimizations caused a verification failure")}
 	}
 
+	// Profile-guided optimization (-pgo). The profile counters are inserted
+	// (or the profile is read) after the TinyGo-specific passes, but before
+	// the LLVM passes that use the profile: mainly the inliner and block
+	// placement.
+	switch pgo := config.PGO(); pgo {
+	case "":
+	case "instrument":
+		PGOInstrument(mod)
+	default:
+		if err := PGOUse(mod, pgo); err != nil {
+			return []error{err}
+		}
+	}
+
 	// After TinyGo-specific transforms have finished, undo exporting these functions.
 	for _, name := range functionsUsedInTransforms {
 		fn := mod.NamedFunction(name)
@@ -187,6 +211,30 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 	builder.Populate(modPasses)
 	modPasses.Run(mod)
 
+	// Report runtime checks that remain after optimization (-print-checks and
+	// //go:checkfree).
+	errs := CheckRuntimeChecks(mod, config.Options.PrintChecks, func(pos token.Position, msg string) {
+		fmt.Fprintln(os.Stderr, pos.String()+": "+msg)
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// Explain why a given function or global is part of the program (-why=).
+	if config.Options.Why != "" {
+		printReferenceChain(os.Stdout, config.Options.Why, ReferenceChain(mod, config.Options.Why, interfaceCalls))
+	}
+
+	if config.PGO() == "instrument" {
+		PGOLowerInstrumentation(mod)
+	}
+
+	if config.Sanitize() == "address" {
+		// Like Clang, insert the checks after optimizing so that only the
+		// memory accesses that remain are checked.
+		AddressSanitizer(mod)
+	}
+
 	hasGCPass := MakeGCStackSlots(mod)
 	if hasGCPass {
 		if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
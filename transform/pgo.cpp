@@ -0,0 +1,101 @@
+#include <llvm/IR/DiagnosticInfo.h>
+#include <llvm/IR/DiagnosticPrinter.h>
+#include <llvm/IR/Instructions.h>
+#include <llvm/IR/LLVMContext.h>
+#include <llvm/IR/Module.h>
+#include <llvm/IR/PassManager.h>
+#include <llvm/Passes/PassBuilder.h>
+#include <llvm/Support/raw_ostream.h>
+#include <llvm/Transforms/Instrumentation.h>
+#include <llvm/Transforms/Instrumentation/InstrProfiling.h>
+#include <llvm/Transforms/Instrumentation/PGOInstrumentation.h>
+#include <string.h>
+
+using namespace llvm;
+
+// This file runs the IR-level profile-guided optimization passes over a
+// module, in the same way as Clang does for -fprofile-generate and
+// -fprofile-use. They aren't available in the LLVM C API.
+
+// Run a single module pass with all analyses registered.
+template <typename PassT>
+static void runModulePass(Module *M, PassT &&Pass) {
+	LoopAnalysisManager LAM;
+	FunctionAnalysisManager FAM;
+	CGSCCAnalysisManager CGAM;
+	ModuleAnalysisManager MAM;
+	PassBuilder PB;
+	PB.registerModuleAnalyses(MAM);
+	PB.registerCGSCCAnalyses(CGAM);
+	PB.registerFunctionAnalyses(FAM);
+	PB.registerLoopAnalyses(LAM);
+	PB.crossRegisterProxies(LAM, FAM, CGAM, MAM);
+
+	ModulePassManager MPM;
+	MPM.addPass(std::move(Pass));
+	MPM.run(*M, MAM);
+}
+
+// Collect the error messages of the profile use pass, instead of letting the
+// default diagnostic handler exit the process. Warnings (such as for functions
+// that changed since the profile was created) are ignored, like Go does for
+// stale profiles.
+struct ProfileErrorHandler : public DiagnosticHandler {
+	std::string Errors;
+
+	bool handleDiagnostics(const DiagnosticInfo &DI) override {
+		if (DI.getSeverity() == DS_Error) {
+			raw_string_ostream OS(Errors);
+			if (!Errors.empty()) {
+				OS << "\n";
+			}
+			DiagnosticPrinterRawOStream DP(OS);
+			DI.print(DP);
+		}
+		return true;
+	}
+};
+
+extern "C" {
+
+void tinygo_pgoInstrument(LLVMModuleRef mod) {
+	Module *M = unwrap(mod);
+	runModulePass(M, PGOInstrumentationGen());
+}
+
+void tinygo_pgoLowerInstrumentation(LLVMModuleRef mod) {
+	Module *M = unwrap(mod);
+
+	// Value profiling (of indirect call targets and memcpy sizes) is not
+	// supported by the TinyGo profile runtime, so remove it before the
+	// counters are lowered.
+	if (Function *Fn = M->getFunction("llvm.instrprof.value.profile")) {
+		while (!Fn->use_empty()) {
+			cast<Instruction>(Fn->user_back())->eraseFromParent();
+		}
+	}
+
+	InstrProfOptions Options;
+	Options.DoCounterPromotion = true;
+	runModulePass(M, InstrProfiling(Options));
+}
+
+char *tinygo_pgoUse(LLVMModuleRef mod, const char *filename) {
+	Module *M = unwrap(mod);
+	LLVMContext &Ctx = M->getContext();
+
+	std::unique_ptr<DiagnosticHandler> OldHandler = Ctx.getDiagnosticHandler();
+	auto Handler = std::make_unique<ProfileErrorHandler>();
+	ProfileErrorHandler *Errors = Handler.get();
+	Ctx.setDiagnosticHandler(std::move(Handler));
+	runModulePass(M, PGOInstrumentationUse(filename));
+	std::string Message = Errors->Errors;
+	Ctx.setDiagnosticHandler(std::move(OldHandler));
+
+	if (Message.empty()) {
+		return nullptr;
+	}
+	return strdup(Message.c_str());
+}
+
+} // extern "C"
@@ -0,0 +1,54 @@
+package transform
+
+// This file implements profile-guided optimization (the -pgo flag), using the
+// IR-level instrumentation of LLVM.
+
+/*
+#cgo CXXFLAGS: -fno-rtti
+#include <stdlib.h>
+#include <llvm-c/Types.h>
+void tinygo_pgoInstrument(LLVMModuleRef mod);
+void tinygo_pgoLowerInstrumentation(LLVMModuleRef mod);
+char *tinygo_pgoUse(LLVMModuleRef mod, const char *filename);
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// PGOInstrument inserts profile counters in all functions of the module
+// (-pgo=instrument). They are lowered to plain loads and stores by
+// PGOLowerInstrumentation, which should run after the module has been
+// optimized.
+//
+// The instrumentation must be inserted at the same point in the optimization
+// pipeline as PGOUse is run, so that the control flow graphs match.
+func PGOInstrument(mod llvm.Module) {
+	C.tinygo_pgoInstrument(C.LLVMModuleRef(unsafe.Pointer(mod.C)))
+}
+
+// PGOLowerInstrumentation lowers the profile counters inserted by
+// PGOInstrument and adds the per-function profile data that is written by the
+// profile runtime in lib/profile.
+func PGOLowerInstrumentation(mod llvm.Module) {
+	C.tinygo_pgoLowerInstrumentation(C.LLVMModuleRef(unsafe.Pointer(mod.C)))
+}
+
+// PGOUse reads an indexed LLVM profile (as created by llvm-profdata from the
+// profiles written by a -pgo=instrument build) and annotates all functions
+// with branch weights and entry counts, which are used by the inliner and for
+// block placement.
+func PGOUse(mod llvm.Module, filename string) error {
+	cfilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cfilename))
+	cerr := C.tinygo_pgoUse(C.LLVMModuleRef(unsafe.Pointer(mod.C)), cfilename)
+	if cerr != nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return errors.New(C.GoString(cerr))
+	}
+	return nil
+}
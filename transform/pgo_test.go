@@ -0,0 +1,83 @@
+package transform_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+// createPGOModule creates a module with a single function that contains a
+// branch.
+func createPGOModule(ctx llvm.Context) llvm.Module {
+	mod := ctx.NewModule("pgo")
+	mod.SetTarget("x86_64-unknown-linux-musl")
+	mod.SetDataLayout("e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128")
+	builder := ctx.NewBuilder()
+	defer builder.Dispose()
+
+	i32 := ctx.Int32Type()
+	fn := llvm.AddFunction(mod, "main.classify", llvm.FunctionType(i32, []llvm.Type{i32}, false))
+	entry := ctx.AddBasicBlock(fn, "entry")
+	small := ctx.AddBasicBlock(fn, "small")
+	large := ctx.AddBasicBlock(fn, "large")
+	builder.SetInsertPointAtEnd(entry)
+	cmp := builder.CreateICmp(llvm.IntSLT, fn.Param(0), llvm.ConstInt(i32, 10, false), "")
+	builder.CreateCondBr(cmp, small, large)
+	builder.SetInsertPointAtEnd(small)
+	builder.CreateRet(llvm.ConstInt(i32, 1, false))
+	builder.SetInsertPointAtEnd(large)
+	builder.CreateRet(llvm.ConstInt(i32, 2, false))
+	return mod
+}
+
+func TestPGOInstrument(t *testing.T) {
+	t.Parallel()
+
+	// The IR is created using the API instead of being read from a file,
+	// because the textual IR differs between LLVM versions.
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	mod := createPGOModule(ctx)
+	defer mod.Dispose()
+
+	transform.PGOInstrument(mod)
+	transform.PGOLowerInstrumentation(mod)
+	if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
+		t.Fatal("IR verification failed")
+	}
+
+	// The profile runtime reads the raw profile version and the profile
+	// sections.
+	if mod.NamedGlobal("__llvm_profile_raw_version").IsNil() {
+		t.Error("expected __llvm_profile_raw_version to be defined")
+	}
+	sections := map[string]bool{}
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		sections[global.Section()] = true
+	}
+	for _, section := range []string{"__llvm_prf_cnts", "__llvm_prf_data", "__llvm_prf_names"} {
+		if !sections[section] {
+			t.Errorf("expected a global in section %s", section)
+		}
+	}
+	if fn := mod.NamedFunction("llvm.instrprof.increment"); !fn.IsNil() && !fn.FirstUse().IsNil() {
+		t.Error("profile counters were not lowered")
+	}
+}
+
+func TestPGOUseError(t *testing.T) {
+	t.Parallel()
+
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	mod := createPGOModule(ctx)
+	defer mod.Dispose()
+
+	// Errors while reading the profile must be returned, not printed.
+	err := transform.PGOUse(mod, filepath.Join(t.TempDir(), "missing.profdata"))
+	if err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+}
@@ -0,0 +1,46 @@
+#include <llvm/IR/Module.h>
+#include <llvm/IR/PassManager.h>
+#include <llvm/Passes/PassBuilder.h>
+#include <llvm/Transforms/Instrumentation/AddressSanitizer.h>
+
+using namespace llvm;
+
+// This file runs the AddressSanitizer instrumentation pass over a module, in
+// the same way as Clang does for -fsanitize=address. It isn't available in the
+// LLVM C API.
+
+extern "C" {
+
+void tinygo_addressSanitizer(LLVMModuleRef mod) {
+	Module *M = unwrap(mod);
+
+	LoopAnalysisManager LAM;
+	FunctionAnalysisManager FAM;
+	CGSCCAnalysisManager CGAM;
+	ModuleAnalysisManager MAM;
+	PassBuilder PB;
+	PB.registerModuleAnalyses(MAM);
+	PB.registerCGSCCAnalyses(CGAM);
+	PB.registerFunctionAnalyses(FAM);
+	PB.registerLoopAnalyses(LAM);
+	PB.crossRegisterProxies(LAM, FAM, CGAM, MAM);
+
+	// Fake stacks (used to detect stack-use-after-return) are not supported by
+	// the TinyGo sanitizer runtime.
+	AddressSanitizerOptions Opts;
+	Opts.UseAfterScope = true;
+	Opts.UseAfterReturn = AsanDetectStackUseAfterReturnMode::Never;
+
+	ModulePassManager MPM;
+#if LLVM_VERSION_MAJOR >= 15
+	MPM.addPass(AddressSanitizerPass(Opts, /*UseGlobalGC=*/false, /*UseOdrIndicator=*/false, AsanDtorKind::None));
+#else
+	// The module pass also instruments all functions (the separate function
+	// pass would instrument them a second time).
+	MPM.addPass(RequireAnalysisPass<ASanGlobalsMetadataAnalysis, Module>());
+	MPM.addPass(ModuleAddressSanitizerPass(Opts, /*UseGlobalGC=*/false, /*UseOdrIndicator=*/false, AsanDtorKind::None));
+#endif
+	MPM.run(*M, MAM);
+}
+
+} // extern "C"
@@ -0,0 +1,24 @@
+package transform
+
+// This file implements the -sanitize=address instrumentation of Go code (and
+// the C code in CGo headers, which is compiled together with it).
+
+/*
+#cgo CXXFLAGS: -fno-rtti
+#include <llvm-c/Types.h>
+void tinygo_addressSanitizer(LLVMModuleRef mod);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// AddressSanitizer inserts AddressSanitizer checks in all functions with the
+// sanitize_address attribute, and adds redzones around all globals. Like in
+// Clang, it must be run after the module has been optimized.
+func AddressSanitizer(mod llvm.Module) {
+	C.tinygo_addressSanitizer(C.LLVMModuleRef(unsafe.Pointer(mod.C)))
+}
@@ -0,0 +1,55 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+func TestAddressSanitizer(t *testing.T) {
+	t.Parallel()
+
+	// The IR is created using the API instead of being read from a file,
+	// because the textual IR differs between LLVM versions.
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	mod := ctx.NewModule("asan")
+	defer mod.Dispose()
+	mod.SetTarget("x86_64-unknown-linux-musl")
+	mod.SetDataLayout("e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128")
+	builder := ctx.NewBuilder()
+	defer builder.Dispose()
+
+	// Globals get a redzone, which is registered in the module constructor.
+	global := llvm.AddGlobal(mod, ctx.Int32Type(), "main.global")
+	global.SetInitializer(llvm.ConstInt(ctx.Int32Type(), 0, false))
+	ptrType := llvm.PointerType(ctx.Int32Type(), 0)
+	fnType := llvm.FunctionType(ctx.Int32Type(), []llvm.Type{ptrType, ptrType}, false)
+
+	// Only functions with the sanitize_address attribute are instrumented.
+	for _, name := range []string{"main.instrumented", "runtime.notInstrumented"} {
+		fn := llvm.AddFunction(mod, name, fnType)
+		if name == "main.instrumented" {
+			fn.AddFunctionAttr(ctx.CreateEnumAttribute(llvm.AttributeKindID("sanitize_address"), 0))
+		}
+		builder.SetInsertPointAtEnd(ctx.AddBasicBlock(fn, "entry"))
+		value := builder.CreateLoad(ctx.Int32Type(), fn.Param(0), "")
+		builder.CreateStore(value, fn.Param(1))
+		builder.CreateRet(value)
+	}
+
+	transform.AddressSanitizer(mod)
+	if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
+		t.Fatal("IR verification failed")
+	}
+
+	for _, name := range []string{"__asan_report_load4", "__asan_report_store4", "__asan_init", "__asan_register_globals"} {
+		if fn := mod.NamedFunction(name); fn.IsNil() || fn.FirstUse().IsNil() {
+			t.Errorf("expected a call to %s", name)
+		}
+	}
+	if fn := mod.NamedFunction("runtime.notInstrumented"); fn.FirstBasicBlock() != fn.LastBasicBlock() {
+		t.Error("function without sanitize_address attribute was instrumented")
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+func main() {
+	println(lookupIndex([]int{1, 2, 3}, 5))
+}
+
+// Small function that would normally be inlined in lookupIndex.
+//
+//go:checkfree
+func checkedLookup(s []int, i int) int {
+	return s[i]
+}
+
+func lookupIndex(s []int, i int) int {
+	return checkedLookup(s, i)
+}
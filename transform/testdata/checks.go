@@ -0,0 +1,25 @@
+package main
+
+func main() {
+}
+
+func lookup(s []int, i int) int {
+	return s[i] // OUT: found bounds check in main.lookup
+}
+
+func divide(a, b int) int {
+	return a / b // OUT: found divide by zero check in main.divide
+}
+
+func constantLookup() int {
+	var a [4]int
+	return a[2]
+}
+
+//go:checkfree
+func first(s []int) int {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[0] // OUT: found bounds check in main.first
+}
@@ -0,0 +1,301 @@
+package transform
+
+// This file explains why a given function or global is part of the program, by
+// finding the shortest chain of references from a root of the program (such as
+// main or an interrupt handler) to it. This is useful to find out why an
+// unexpected package (fmt, reflect, unicode tables, ...) ends up in a binary.
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// Reference is a single step in a chain of references returned by
+// ReferenceChain.
+type Reference struct {
+	// Name of the function or global.
+	Name string
+
+	// How this value is referenced by the previous value in the chain:
+	// "root" for the first value in the chain, "call" for a function call,
+	// "interface call" for a method called through an interface, and
+	// "reference" for any other reference (storing a function pointer,
+	// a global in the initializer of another global, etc).
+	Kind string
+
+	// Extra information about the reference. For roots, this is why it is a
+	// root. For interface calls, this is the concrete type whose method is
+	// called.
+	Note string
+}
+
+// InterfaceCalls records which functions are called through an interface
+// method call, mapped to the concrete type (as named in the type code) that
+// made them reachable. It is collected by FindInterfaceCalls.
+type InterfaceCalls map[string]string
+
+// FindInterfaceCalls finds all functions that are called through an interface.
+// It must be run right after LowerInterfaces: the interface method thunks
+// created there are inlined during optimization, after which it is no longer
+// possible to see which calls were interface method calls.
+//
+// Functions that are also called directly (not through an interface) are not
+// included, as there is no way to distinguish the two kinds of calls after
+// optimization.
+func FindInterfaceCalls(mod llvm.Module) InterfaceCalls {
+	calls := make(InterfaceCalls)
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() || fn.GetStringAttributeAtIndex(-1, "tinygo-invoke").IsNil() {
+			continue
+		}
+
+		// Method calls through an interface are lowered to a type switch over
+		// all types that implement the interface, where each basic block is
+		// named after the concrete type.
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			typeName := bb.AsValue().Name()
+			if typeName == "entry" || strings.HasSuffix(typeName, ".next") {
+				continue
+			}
+			forEachCall(bb, func(callee llvm.Value) {
+				calls[callee.Name()] = typeName
+				if strings.HasSuffix(callee.Name(), "$invoke") {
+					// Wrapper that unpacks the receiver: the method that
+					// it calls is also called through the interface.
+					for bb := callee.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+						forEachCall(bb, func(method llvm.Value) {
+							calls[method.Name()] = typeName
+						})
+					}
+				}
+			})
+		}
+	}
+
+	// Remove all functions that are also called directly.
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if _, ok := calls[fn.Name()]; ok && strings.HasSuffix(fn.Name(), "$invoke") {
+			continue
+		}
+		if !fn.GetStringAttributeAtIndex(-1, "tinygo-invoke").IsNil() {
+			continue
+		}
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			forEachCall(bb, func(callee llvm.Value) {
+				delete(calls, callee.Name())
+			})
+		}
+	}
+	return calls
+}
+
+// forEachCall calls the callback for every function that is called directly
+// from the given basic block.
+func forEachCall(bb llvm.BasicBlock, callback func(callee llvm.Value)) {
+	for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+		if inst.IsACallInst().IsNil() {
+			continue
+		}
+		callee := inst.CalledValue()
+		if callee.IsAFunction().IsNil() || callee.IsDeclaration() {
+			continue
+		}
+		callback(callee)
+	}
+}
+
+// referenceEdge is an edge in the reference graph, as found in the search.
+type referenceEdge struct {
+	from llvm.Value
+	kind string
+	note string
+}
+
+// ReferenceChain returns the shortest chain of references from a root of the
+// program to the function or global with the given name. Roots are all
+// functions and globals that are visible outside the module: main, interrupt
+// handlers, //export functions, and values marked as used.
+//
+// This should be run after optimizations (and therefore after interface
+// lowering), so that only references that survived optimization are reported.
+// Calls to functions in interfaceCalls (as found by FindInterfaceCalls before
+// optimizing) are reported as interface calls, with the concrete type that
+// made the method reachable. The interfaceCalls map may be nil.
+//
+// It returns nil if the value doesn't exist in the module (it may have been
+// optimized away) or is not reachable from any root.
+func ReferenceChain(mod llvm.Module, name string, interfaceCalls InterfaceCalls) []Reference {
+	target := mod.NamedFunction(name)
+	if target.IsNil() {
+		target = mod.NamedGlobal(name)
+	}
+	if target.IsNil() {
+		return nil
+	}
+
+	// Collect all roots, with main first so that chains start at main when
+	// there is a choice.
+	edges := make(map[llvm.Value]referenceEdge)
+	var queue []llvm.Value
+	addRoot := func(value llvm.Value, note string) {
+		if _, ok := edges[value]; ok {
+			return
+		}
+		edges[value] = referenceEdge{kind: "root", note: note}
+		queue = append(queue, value)
+	}
+	if main := mod.NamedFunction("main"); !main.IsNil() && !main.IsDeclaration() {
+		addRoot(main, "entry point")
+	}
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if !fn.IsDeclaration() && isVisibleOutsideModule(fn) {
+			addRoot(fn, "exported")
+		}
+	}
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if global.IsDeclaration() {
+			continue
+		}
+		switch {
+		case global.Name() == "llvm.used" || global.Name() == "llvm.compiler.used":
+			addRoot(global, "marked as used")
+		case isVisibleOutsideModule(global):
+			addRoot(global, "exported")
+		}
+	}
+
+	// Do a breadth-first search through the reference graph, until the target
+	// is found.
+	for len(queue) != 0 && edges[target].kind == "" {
+		value := queue[0]
+		queue = queue[1:]
+		forEachReference(value, func(referenced llvm.Value, kind string) {
+			if _, ok := edges[referenced]; ok {
+				return
+			}
+			note := ""
+			if typeName, ok := interfaceCalls[referenced.Name()]; ok && kind == "call" {
+				// Calls from a method that was itself called through the
+				// interface (such as the $invoke wrapper, when it wasn't
+				// inlined) are regular calls.
+				if _, ok := interfaceCalls[value.Name()]; !ok {
+					kind = "interface call"
+					note = typeName
+				}
+			}
+			edges[referenced] = referenceEdge{from: value, kind: kind, note: note}
+			queue = append(queue, referenced)
+		})
+	}
+	if edges[target].kind == "" {
+		return nil
+	}
+
+	// Walk back from the target to the root.
+	var chain []Reference
+	for value := target; !value.IsNil(); value = edges[value].from {
+		edge := edges[value]
+		chain = append(chain, Reference{
+			Name: value.Name(),
+			Kind: edge.kind,
+			Note: edge.note,
+		})
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// isVisibleOutsideModule returns whether the given function or global may be
+// referenced from outside the module, for example by the linker or from
+// assembly.
+func isVisibleOutsideModule(value llvm.Value) bool {
+	switch value.Linkage() {
+	case llvm.InternalLinkage, llvm.PrivateLinkage:
+		return false
+	default:
+		return true
+	}
+}
+
+// forEachReference calls the callback for every function and global that is
+// referenced by the given function (in its instructions) or global (in its
+// initializer).
+func forEachReference(value llvm.Value, callback func(referenced llvm.Value, kind string)) {
+	if !value.IsAFunction().IsNil() {
+		for bb := value.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				numOperands := inst.OperandsCount()
+				for i := 0; i < numOperands; i++ {
+					kind := "reference"
+					if !inst.IsACallInst().IsNil() && i == numOperands-1 {
+						// The last operand of a call instruction is the
+						// called function.
+						kind = "call"
+					}
+					forEachGlobalValue(inst.Operand(i), func(referenced llvm.Value) {
+						callback(referenced, kind)
+					})
+				}
+			}
+		}
+		return
+	}
+	if !value.IsAGlobalVariable().IsNil() {
+		if initializer := value.Initializer(); !initializer.IsNil() {
+			forEachGlobalValue(initializer, func(referenced llvm.Value) {
+				callback(referenced, "reference")
+			})
+		}
+		return
+	}
+	if !value.IsAGlobalAlias().IsNil() {
+		forEachGlobalValue(value.Operand(0), func(referenced llvm.Value) {
+			callback(referenced, "reference")
+		})
+	}
+}
+
+// forEachGlobalValue calls the callback for every function, global or alias in
+// the given value, looking through constant expressions and aggregates.
+func forEachGlobalValue(value llvm.Value, callback func(llvm.Value)) {
+	if value.IsNil() {
+		return
+	}
+	if !value.IsAGlobalValue().IsNil() {
+		callback(value)
+		return
+	}
+	if value.IsAConstant().IsNil() {
+		// Instructions, arguments, basic blocks, metadata, etc.
+		return
+	}
+	for i := 0; i < value.OperandsCount(); i++ {
+		forEachGlobalValue(value.Operand(i), callback)
+	}
+}
+
+// printReferenceChain prints the chain of references to name, as returned by
+// ReferenceChain, in a human readable form.
+func printReferenceChain(w io.Writer, name string, chain []Reference) {
+	if len(chain) == 0 {
+		fmt.Fprintf(w, "%s is not part of the program (it may have been optimized away)\n", name)
+		return
+	}
+	for _, ref := range chain {
+		switch ref.Kind {
+		case "root":
+			fmt.Fprintf(w, "%s (%s)\n", ref.Name, ref.Note)
+		case "call":
+			fmt.Fprintf(w, "  calls %s\n", ref.Name)
+		case "interface call":
+			fmt.Fprintf(w, "  calls %s through an interface, for type %s\n", ref.Name, ref.Note)
+		default:
+			fmt.Fprintf(w, "  references %s\n", ref.Name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package transform_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+func TestReferenceChain(t *testing.T) {
+	t.Parallel()
+
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	buf, err := llvm.NewMemoryBufferFromFile("testdata/interface.ll")
+	if err != nil {
+		t.Fatal("could not read file:", err)
+	}
+	mod, err := ctx.ParseIR(buf)
+	if err != nil {
+		t.Fatal("could not load module:", err)
+	}
+	defer mod.Dispose()
+
+	// Like in a real program, only exported functions are visible outside the
+	// module.
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if !fn.IsDeclaration() && fn.Name() != "printInterfaces" {
+			fn.SetLinkage(llvm.InternalLinkage)
+		}
+	}
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if !global.IsDeclaration() {
+			global.SetLinkage(llvm.InternalLinkage)
+		}
+	}
+
+	err = transform.LowerInterfaces(mod, defaultTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interfaceCalls := transform.FindInterfaceCalls(mod)
+
+	// The method is only reachable through the interface method call.
+	chain := transform.ReferenceChain(mod, "(Number).Double", interfaceCalls)
+	expected := []transform.Reference{
+		{Name: "printInterfaces", Kind: "root", Note: "exported"},
+		{Name: "printInterface", Kind: "call"},
+		{Name: "Doubler.Double$invoke", Kind: "call"},
+		{Name: "(Number).Double$invoke", Kind: "interface call", Note: "named:Number"},
+		{Name: "(Number).Double", Kind: "call"},
+	}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("unexpected reference chain for (Number).Double:\nexpected: %v\nactual:   %v", expected, chain)
+	}
+
+	// Globals are reached through references.
+	chain = transform.ReferenceChain(mod, "reflect/types.type:basic:uint8", interfaceCalls)
+	expected = []transform.Reference{
+		{Name: "printInterfaces", Kind: "root", Note: "exported"},
+		{Name: "reflect/types.type:basic:uint8", Kind: "reference"},
+	}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("unexpected reference chain for a type code:\nexpected: %v\nactual:   %v", expected, chain)
+	}
+
+	// Values that don't exist have no chain.
+	if chain := transform.ReferenceChain(mod, "doesNotExist", interfaceCalls); chain != nil {
+		t.Errorf("expected no reference chain, got %v", chain)
+	}
+}
+
+func TestReferenceChainOptimized(t *testing.T) {
+	t.Parallel()
+
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	buf, err := llvm.NewMemoryBufferFromFile("testdata/interface.ll")
+	if err != nil {
+		t.Fatal("could not read file:", err)
+	}
+	mod, err := ctx.ParseIR(buf)
+	if err != nil {
+		t.Fatal("could not load module:", err)
+	}
+	defer mod.Dispose()
+
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if !fn.IsDeclaration() && fn.Name() != "printInterfaces" {
+			fn.SetLinkage(llvm.InternalLinkage)
+		}
+	}
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if !global.IsDeclaration() {
+			global.SetLinkage(llvm.InternalLinkage)
+		}
+	}
+
+	// Keep the method itself around, so that there is something to look for
+	// after optimization.
+	mod.NamedFunction("(Number).Double").AddFunctionAttr(ctx.CreateEnumAttribute(llvm.AttributeKindID("noinline"), 0))
+
+	err = transform.LowerInterfaces(mod, defaultTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interfaceCalls := transform.FindInterfaceCalls(mod)
+
+	// Optimize the module, including the inliner. This inlines the interface
+	// method thunk and the $invoke wrapper.
+	builder := llvm.NewPassManagerBuilder()
+	defer builder.Dispose()
+	builder.SetOptLevel(2)
+	builder.UseInlinerWithThreshold(225)
+	pm := llvm.NewPassManager()
+	defer pm.Dispose()
+	builder.Populate(pm)
+	pm.Run(mod)
+	if !mod.NamedFunction("Doubler.Double$invoke").IsNil() {
+		t.Fatal("expected the interface method thunk to be inlined")
+	}
+
+	// The call is still reported as an interface call, even though the thunk
+	// doesn't exist anymore.
+	chain := transform.ReferenceChain(mod, "(Number).Double", interfaceCalls)
+	if len(chain) < 2 {
+		t.Fatalf("unexpected reference chain for (Number).Double: %v", chain)
+	}
+	if root := chain[0]; root != (transform.Reference{Name: "printInterfaces", Kind: "root", Note: "exported"}) {
+		t.Errorf("unexpected root: %v", root)
+	}
+	expected := transform.Reference{Name: "(Number).Double", Kind: "interface call", Note: "named:Number"}
+	if last := chain[len(chain)-1]; last != expected {
+		t.Errorf("unexpected last reference:\nexpected: %v\nactual:   %v", expected, last)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinygo-org/tinygo/builder"
+)
+
+// Test sockets on WASI preview 1, which are pre-opened by wasmtime. The test
+// program accepts a connection while another goroutine runs the GC, and reads
+// from it with and without a deadline.
+func TestWASISockets(t *testing.T) {
+	t.Parallel()
+	for _, target := range []string{"wasi", "wasi-threads"} {
+		target := target
+		t.Run(target, func(t *testing.T) {
+			t.Parallel()
+			testWASISockets(t, target)
+		})
+	}
+}
+
+func testWASISockets(t *testing.T, target string) {
+	options := optionsFromTarget(target, sema)
+	emuCheck(t, options)
+
+	config, err := builder.NewConfig(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := builder.Build("testdata/wasi-sockets.go", "", t.TempDir(), config)
+	if err != nil {
+		printCompilerError(t.Log, err)
+		t.FailNow()
+	}
+
+	// Find a free port for wasmtime to listen on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	emulator, err := config.Emulator("", result.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := append([]string{emulator[1], "--tcplisten=" + addr}, emulator[2:]...)
+	cmd := exec.Command(emulator[0], args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	timer := time.AfterFunc(time.Minute, func() {
+		cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	lines := bufio.NewScanner(stdout)
+	expectLine := func(expected string) {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("expected %q, got end of output (%v)", expected, lines.Err())
+		}
+		if line := strings.TrimRight(lines.Text(), "\r"); line != expected {
+			t.Fatalf("expected %q, got %q", expected, line)
+		}
+	}
+
+	expectLine("listening")
+	expectLine("gc ok")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectLine("read timeout: true")
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	expectLine("read: hello")
+	reply := make([]byte, len("echo: hello"))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "echo: hello" {
+		t.Errorf("unexpected reply: %q", reply)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+	expectLine("eof: true")
+
+	if err := cmd.Wait(); err != nil {
+		t.Error("failed to run:", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/tinygo-org/tinygo/builder"
+)
+
+// Test calling //go:wasmexport functions that block on a channel, both in a
+// reactor module (after _initialize) and in a command module (after main has
+// returned). Every call must run in a goroutine, and the goroutines started
+// earlier must keep running between calls.
+func TestWasmExport(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name      string
+		buildMode string
+		start     string
+		next      uint64 // first value returned by next()
+		output    string
+	}{
+		{"Reactor", "c-shared", "_initialize", 1, ""},
+		{"Command", "", "_start", 2, "main: 1\n"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			options := optionsFromTarget("wasi", sema)
+			options.BuildMode = tc.buildMode
+			config, err := builder.NewConfig(&options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := builder.Build("testdata/wasmexport.go", "", t.TempDir(), config)
+			if err != nil {
+				printCompilerError(t.Log, err)
+				t.FailNow()
+			}
+			binary, err := os.ReadFile(result.Binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			r := wazero.NewRuntime(ctx)
+			defer r.Close(ctx)
+			wasi_snapshot_preview1.MustInstantiate(ctx, r)
+			var stdout bytes.Buffer
+			mod, err := r.InstantiateWithConfig(ctx, binary, wazero.NewModuleConfig().
+				WithStdout(&stdout).
+				WithStderr(&stdout).
+				WithSysNanotime().
+				WithSysNanosleep().
+				WithStartFunctions(tc.start))
+			if err != nil {
+				t.Fatalf("could not instantiate: %v\n%s", err, stdout.String())
+			}
+			if stdout.String() != tc.output {
+				t.Errorf("unexpected output: %q", stdout.String())
+			}
+
+			call := func(name string, params ...uint64) uint64 {
+				t.Helper()
+				results, err := mod.ExportedFunction(name).Call(ctx, params...)
+				if err != nil {
+					t.Fatalf("call %s: %v\n%s", name, err, stdout.String())
+				}
+				return results[0]
+			}
+			for i := uint64(0); i < 5; i++ {
+				if value := call("next"); value != tc.next+i {
+					t.Errorf("next() returned %d, expected %d", value, tc.next+i)
+				}
+				if value := call("sleep", 1); value != 1 {
+					t.Errorf("sleep(1) returned %d", value)
+				}
+			}
+		})
+	}
+}
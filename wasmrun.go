@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmRunOptions is the parsed command line of `tinygo wasmrun`, which runs a
+// WASI (preview 1) binary with the builtin WebAssembly runtime. It accepts the
+// subset of the wasmtime command line that TinyGo itself uses, so that
+// -wasm-runtime=builtin can be a drop-in replacement for wasmtime.
+type wasmRunOptions struct {
+	binary string
+	args   []string    // program arguments, without the program name
+	env    [][2]string // environment variables (key, value)
+	mounts [][2]string // preopened directories (host path, guest path)
+}
+
+// runWasm implements `tinygo wasmrun`, and returns the exit code of the
+// program.
+func runWasm(args []string) int {
+	opts, err := parseWasmRunArgs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wasmrun:", err)
+		return 2
+	}
+	exitCode, err := opts.run(os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wasmrun:", err)
+		return 1
+	}
+	return exitCode
+}
+
+// parseWasmRunArgs parses a wasmtime-like command line:
+//
+//	[run] [flags] binary [flags] [--] [args...]
+//
+// The flags are --dir=path (or --dir=host::guest), --mapdir=guest::host and
+// --env=KEY=VALUE, each also accepted with the value as a separate argument.
+// Anything after the binary that is not one of these flags starts the program
+// arguments.
+func parseWasmRunArgs(args []string) (*wasmRunOptions, error) {
+	opts := &wasmRunOptions{}
+	if len(args) != 0 && args[0] == "run" {
+		args = args[1:]
+	}
+	for len(args) != 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			if opts.binary != "" {
+				break // start of the program arguments
+			}
+			opts.binary = arg
+			args = args[1:]
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		if name != "dir" && name != "mapdir" && name != "env" {
+			if opts.binary != "" {
+				break // a program argument that looks like a flag
+			}
+			return nil, fmt.Errorf("unknown flag: %s", arg)
+		}
+		if hasValue {
+			args = args[1:]
+		} else {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			value = args[1]
+			args = args[2:]
+		}
+		switch name {
+		case "dir":
+			host, guest := value, value
+			if h, g, ok := strings.Cut(value, "::"); ok {
+				host, guest = h, g
+			}
+			opts.mounts = append(opts.mounts, [2]string{host, guest})
+		case "mapdir":
+			guest, host, ok := strings.Cut(value, "::")
+			if !ok {
+				return nil, fmt.Errorf("invalid --mapdir=%s: expected guest::host", value)
+			}
+			opts.mounts = append(opts.mounts, [2]string{host, guest})
+		case "env":
+			key, val, ok := strings.Cut(value, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid --env=%s: expected KEY=VALUE", value)
+			}
+			opts.env = append(opts.env, [2]string{key, val})
+		}
+	}
+	if opts.binary == "" {
+		return nil, errors.New("no WebAssembly binary given")
+	}
+	opts.args = args
+	return opts, nil
+}
+
+// run instantiates the binary, which runs its _start function, and returns the
+// exit code passed to proc_exit (or 0 if _start returned normally). An error is
+// returned if the binary could not be loaded or if it trapped.
+func (opts *wasmRunOptions) run(stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	binary, err := os.ReadFile(opts.binary)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	fsConfig := wazero.NewFSConfig()
+	for _, mount := range opts.mounts {
+		fsConfig = fsConfig.WithDirMount(mount[0], mount[1])
+	}
+	config := wazero.NewModuleConfig().
+		WithArgs(append([]string{opts.binary}, opts.args...)...).
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	for _, kv := range opts.env {
+		config = config.WithEnv(kv[0], kv[1])
+	}
+
+	_, err = r.InstantiateWithConfig(ctx, binary, config)
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			return int(exitErr.ExitCode()), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseWasmRunArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		opts wasmRunOptions
+		err  string
+	}{
+		{
+			// Command line of `tinygo run -target=wasi`.
+			args: []string{"--mapdir=/tmp::/host/tmp", "main.wasm", "--dir=.", "--env", "A=1", "--", "-flag", "arg"},
+			opts: wasmRunOptions{
+				binary: "main.wasm",
+				args:   []string{"-flag", "arg"},
+				env:    [][2]string{{"A", "1"}},
+				mounts: [][2]string{{"/host/tmp", "/tmp"}, {".", "."}},
+			},
+		},
+		{
+			// Command line of `tinygo test -target=wasi`, which adds parent
+			// directories and test flags.
+			args: []string{"run", "--dir=..", "--mapdir=/tmp::/host/tmp", "main.wasm", "--dir=.", "-test.v"},
+			opts: wasmRunOptions{
+				binary: "main.wasm",
+				args:   []string{"-test.v"},
+				mounts: [][2]string{{"..", ".."}, {"/host/tmp", "/tmp"}, {".", "."}},
+			},
+		},
+		{
+			args: []string{"--dir=/host::/guest", "main.wasm", "--unknown"},
+			opts: wasmRunOptions{
+				binary: "main.wasm",
+				args:   []string{"--unknown"},
+				mounts: [][2]string{{"/host", "/guest"}},
+			},
+		},
+		{args: []string{"--unknown", "main.wasm"}, err: "unknown flag: --unknown"},
+		{args: []string{"--mapdir=/tmp", "main.wasm"}, err: "invalid --mapdir=/tmp: expected guest::host"},
+		{args: []string{"main.wasm", "--env"}, err: "flag needs an argument: --env"},
+		{args: []string{"--dir=."}, err: "no WebAssembly binary given"},
+	} {
+		opts, err := parseWasmRunArgs(tc.args)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%q: expected error %q, got %v", tc.args, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.args, err)
+			continue
+		}
+		if len(opts.args) == 0 {
+			opts.args = nil
+		}
+		if !reflect.DeepEqual(*opts, tc.opts) {
+			t.Errorf("%q: unexpected result:\nexpected: %+v\nactual:   %+v", tc.args, tc.opts, *opts)
+		}
+	}
+}
+
+// Minimal WASI program that writes "hello\n" to stdout and exits with code 7.
+var wasmRunTestBinary = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+
+	// type section: fd_write, proc_exit, _start
+	0x01, 0x10, 0x03,
+	0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f,
+	0x60, 0x01, 0x7f, 0x00,
+	0x60, 0x00, 0x00,
+
+	// import section
+	0x02, 0x46, 0x02,
+	0x16, 'w', 'a', 's', 'i', '_', 's', 'n', 'a', 'p', 's', 'h', 'o', 't', '_', 'p', 'r', 'e', 'v', 'i', 'e', 'w', '1',
+	0x08, 'f', 'd', '_', 'w', 'r', 'i', 't', 'e', 0x00, 0x00,
+	0x16, 'w', 'a', 's', 'i', '_', 's', 'n', 'a', 'p', 's', 'h', 'o', 't', '_', 'p', 'r', 'e', 'v', 'i', 'e', 'w', '1',
+	0x09, 'p', 'r', 'o', 'c', '_', 'e', 'x', 'i', 't', 0x00, 0x01,
+
+	// function section
+	0x03, 0x02, 0x01, 0x02,
+
+	// memory section: one page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+
+	// export section
+	0x07, 0x13, 0x02,
+	0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x02,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+
+	// code section: fd_write(1, iovs=0, 1, nwritten=16); proc_exit(7)
+	0x0a, 0x13, 0x01, 0x11, 0x00,
+	0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x10, 0x10, 0x00, 0x1a,
+	0x41, 0x07, 0x10, 0x01,
+	0x0b,
+
+	// data section: iovec {buf: 8, len: 6} followed by the string
+	0x0b, 0x14, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x0e,
+	0x08, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+	'h', 'e', 'l', 'l', 'o', '\n',
+}
+
+func TestWasmRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.wasm")
+	if err := os.WriteFile(path, wasmRunTestBinary, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	opts := &wasmRunOptions{binary: path}
+	var stdout bytes.Buffer
+	exitCode, err := opts.run(nil, &stdout, os.Stderr)
+	if err != nil {
+		t.Fatal("could not run:", err)
+	}
+	if exitCode != 7 {
+		t.Errorf("expected exit code 7, got %d", exitCode)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("unexpected output: %q", stdout.String())
+	}
+}